@@ -23,6 +23,7 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/google/uuid"
+	"github.com/oam-dev/kubevela/apis/types"
 
 	"github.com/kubevela/velaux/pkg/server/infrastructure/datastore"
 )
@@ -58,6 +59,9 @@ type Config struct {
 	// WorkflowVersion is the version of workflow
 	WorkflowVersion string
 
+	// DefinitionNamespace the namespace of the definitions and their schemas
+	DefinitionNamespace string
+
 	PluginConfig PluginConfig
 
 	DexServerURL string
@@ -99,7 +103,8 @@ func NewConfig() *Config {
 			CorePluginPath:   "core-plugins",
 			CustomPluginPath: []string{"plugins"},
 		},
-		DexServerURL: "http://dex.vela-system:5556",
+		DexServerURL:        "http://dex.vela-system:5556",
+		DefinitionNamespace: types.DefaultKubeVelaNS,
 	}
 }
 
@@ -131,5 +136,6 @@ func (s *Config) AddFlags(fs *pflag.FlagSet, c *Config) {
 	fs.IntVar(&s.KubeBurst, "kube-api-burst", c.KubeBurst, "the burst for kube clients. Recommend setting it qps*3.")
 	fs.StringVar(&s.WorkflowVersion, "workflow-version", c.WorkflowVersion, "the version of workflow to meet controller requirement.")
 	fs.StringVar(&s.DexServerURL, "dex-server", c.DexServerURL, "the URL of the dex server.")
+	fs.StringVar(&s.DefinitionNamespace, "definition-namespace", c.DefinitionNamespace, "the namespace of the definitions and their schemas.")
 	fs.StringArrayVar(&s.PluginConfig.CustomPluginPath, "plugin-path", c.PluginConfig.CustomPluginPath, "the path of the plugin directory")
 }
@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import "fmt"
+
+func init() {
+	RegisterModel(&DefinitionFavorite{})
+}
+
+// DefinitionFavorite the definition pinned by the user
+type DefinitionFavorite struct {
+	BaseModel
+	Username       string `json:"username"`
+	DefinitionName string `json:"definitionName"`
+	// DefinitionType the type of the definition, such as component and trait
+	DefinitionType string `json:"definitionType"`
+}
+
+// TableName return custom table name
+func (d *DefinitionFavorite) TableName() string {
+	return tableNamePrefix + "definition_favorite"
+}
+
+// ShortTableName is the compressed version of table name for kubeapi storage and others
+func (d *DefinitionFavorite) ShortTableName() string {
+	return "def_fav"
+}
+
+// PrimaryKey return custom primary key. Both the username and the definition name may contain "-" and ".",
+// so the length of the username splits them, the type goes first because it contains neither of them.
+func (d *DefinitionFavorite) PrimaryKey() string {
+	return fmt.Sprintf("%s-%d-%s-%s", d.DefinitionType, len(d.Username), d.Username, d.DefinitionName)
+}
+
+// Index return custom index
+func (d *DefinitionFavorite) Index() map[string]interface{} {
+	index := make(map[string]interface{})
+	if d.Username != "" {
+		index["username"] = d.Username
+	}
+	if d.DefinitionName != "" {
+		index["definitionName"] = d.DefinitionName
+	}
+	if d.DefinitionType != "" {
+		index["definitionType"] = d.DefinitionType
+	}
+	return index
+}
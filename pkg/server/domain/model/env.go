@@ -31,10 +31,62 @@ type Env struct {
 	Project string `json:"project"`
 	// Namespace defines the K8s namespace of the Env in control plane
 	Namespace string `json:"namespace"`
+	// Namespaces defines the additional K8s namespaces of the Env in control plane,
+	// the labels and the privileges of the Env are applied to them as well as the Namespace.
+	Namespaces []string `json:"namespaces,omitempty"`
 
 	// Targets defines the name of delivery target that belongs to this env
 	// In one project, a delivery target can only belong to one env.
 	Targets []string `json:"targets,omitempty"`
+
+	// Labels are also patched to the namespace of the Env
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// TargetSelector selects the targets of the project by the tags, the selected targets are resolved into the Targets.
+	// It is kept so that the targets added later could be reconciled.
+	TargetSelector map[string]string `json:"targetSelector,omitempty"`
+
+	// Archived means the Env is soft deleted, the privileges are revoked but the namespace is kept
+	Archived bool `json:"archived,omitempty"`
+
+	// Default means the Env is the default env of the project, at most one Env in a project is the default
+	Default bool `json:"default,omitempty"`
+
+	// AppQuota limits the number of the applications in the Env, there is no limit if it is 0
+	AppQuota int `json:"appQuota,omitempty"`
+
+	// Variables the values shared by the applications in the Env, such as the registry and the base domain.
+	// They don't change the namespaces, the application rendering reads them.
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// DefaultAppLabels are stamped onto every application deployed to the Env, the labels of the application take precedence
+	DefaultAppLabels map[string]string `json:"defaultAppLabels,omitempty"`
+
+	// Annotations are the free-form metadata of the Env, such as the runbook URL or the owner email, they are not applied to the namespaces
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// AnnotationNamespaceCreatedByEnv the annotation records the env that created the namespace,
+// only the namespaces created by the env could be deleted with it.
+const AnnotationNamespaceCreatedByEnv = "velaux.oam.dev/created-by-env"
+
+// EnvLabelIndexKey return the index key of the env label, it could be used to filter the envs
+func EnvLabelIndexKey(key string) string {
+	return "labels." + key
+}
+
+// AllNamespaces return the Namespace and the additional namespaces without the duplicated ones,
+// the Env created before the additional namespaces are supported only has the Namespace.
+func (p *Env) AllNamespaces() []string {
+	namespaces := []string{p.Namespace}
+	seen := map[string]bool{p.Namespace: true}
+	for _, ns := range p.Namespaces {
+		if ns != "" && !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
 }
 
 // TableName return custom table name
@@ -64,5 +116,14 @@ func (p *Env) Index() map[string]interface{} {
 	if p.Project != "" {
 		index["project"] = p.Project
 	}
+	for k, v := range p.Labels {
+		index[EnvLabelIndexKey(k)] = v
+	}
+	if p.Archived {
+		index["archived"] = "true"
+	}
+	if p.Default {
+		index["default"] = "true"
+	}
 	return index
 }
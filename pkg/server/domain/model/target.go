@@ -30,6 +30,8 @@ type Target struct {
 	Description string                 `json:"description,omitempty"`
 	Cluster     *ClusterTarget         `json:"cluster,omitempty"`
 	Variable    map[string]interface{} `json:"variable,omitempty"`
+	// Tags group the targets, the envs could select the targets by the tags
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 // TableName return custom table name
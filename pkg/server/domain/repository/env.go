@@ -19,7 +19,14 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -32,39 +39,123 @@ import (
 	"github.com/kubevela/velaux/pkg/server/utils/bcode"
 )
 
-// CreateEnv create the environment
-func CreateEnv(ctx context.Context, kubeClient client.Client, ds datastore.DataStore, env *model.Env) error {
+// CreateEnv create the environment, return the namespaces created for the env so that they could be deleted if the env is rolled back.
+// The namespaces are released if the env fails to be created.
+func CreateEnv(ctx context.Context, kubeClient client.Client, ds datastore.DataStore, env *model.Env) ([]string, error) {
 	tenv := &model.Env{}
 	tenv.Name = env.Name
 
 	exist, err := ds.IsExist(ctx, tenv)
 	if err != nil {
 		klog.Errorf("check if env name exists failure %s", err.Error())
-		return err
+		return nil, err
 	}
 	if exist {
-		return bcode.ErrEnvAlreadyExists
+		return nil, bcode.ErrEnvAlreadyExists
 	}
 	if env.Namespace == "" {
 		env.Namespace = env.Name
 	}
+	namespaces := env.AllNamespaces()
+	env.Namespaces = namespaces[1:]
+	if len(env.Namespaces) == 0 {
+		env.Namespaces = nil
+	}
+	for _, ns := range namespaces {
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return nil, bcode.ErrEnvNamespaceInvalid.SetMessage(fmt.Sprintf("the namespace %s of the env is invalid: %s", ns, strings.Join(errs, ", ")))
+		}
+	}
+	// Refuse to share the namespaces that already belong to another env, all of them are checked before labeling any
+	var created []string
+	for _, ns := range namespaces {
+		var namespace corev1.Namespace
+		if err := kubeClient.Get(ctx, k8stypes.NamespacedName{Name: ns}, &namespace); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			created = append(created, ns)
+		} else if owner := namespace.Labels[oam.LabelNamespaceOfEnvName]; owner != "" && owner != env.Name {
+			return nil, bcode.ErrEnvNamespaceAlreadyBound
+		}
+	}
 
-	// Creating the namespace at first.
-	err = util.CreateOrUpdateNamespace(ctx, kubeClient, env.Namespace,
-		util.MergeOverrideLabels(map[string]string{
-			oam.LabelControlPlaneNamespaceUsage: oam.VelaNamespaceUsageEnv,
-		}), util.MergeNoConflictLabels(map[string]string{
-			oam.LabelNamespaceOfEnvName: env.Name,
-		}))
-	if err != nil {
-		if velaerr.IsLabelConflict(err) {
-			return bcode.ErrEnvNamespaceAlreadyBound
+	// Creating the namespaces at first.
+	for _, ns := range namespaces {
+		err = util.CreateOrUpdateNamespace(ctx, kubeClient, ns,
+			util.MergeOverrideLabels(env.Labels),
+			util.MergeOverrideLabels(map[string]string{
+				oam.LabelControlPlaneNamespaceUsage: oam.VelaNamespaceUsageEnv,
+			}), util.MergeNoConflictLabels(map[string]string{
+				oam.LabelNamespaceOfEnvName: env.Name,
+			}), MarkNamespaceCreatedByEnv(env.Name))
+		if err != nil {
+			if e := ReleaseEnvNamespaces(ctx, kubeClient, env, created); e != nil {
+				klog.Errorf("failed to release the namespaces of the env %s: %s", env.Name, e.Error())
+			}
+			if velaerr.IsLabelConflict(err) {
+				return nil, bcode.ErrEnvNamespaceAlreadyBound
+			}
+			klog.Errorf("update namespace label failure %s", err.Error())
+			return nil, bcode.ErrEnvNamespaceFail
 		}
-		klog.Errorf("update namespace label failure %s", err.Error())
-		return bcode.ErrEnvNamespaceFail
 	}
 	if err = ds.Add(ctx, env); err != nil {
-		return err
+		if e := ReleaseEnvNamespaces(ctx, kubeClient, env, created); e != nil {
+			klog.Errorf("failed to release the namespaces of the env %s: %s", env.Name, e.Error())
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+// MarkNamespaceCreatedByEnv annotate the namespace with the env if the namespace is being created,
+// the existing namespaces are not changed.
+func MarkNamespaceCreatedByEnv(envName string) util.MutateOption {
+	return func(object metav1.Object) error {
+		if object.GetResourceVersion() != "" {
+			return nil
+		}
+		annotations := object.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[model.AnnotationNamespaceCreatedByEnv] = envName
+		object.SetAnnotations(annotations)
+		return nil
+	}
+}
+
+// ReleaseEnvNamespaces clear the labels that bind the namespaces to the env and delete the namespaces created for the env,
+// the namespaces bound to another env or not existing are skipped.
+func ReleaseEnvNamespaces(ctx context.Context, kubeClient client.Client, env *model.Env, created []string) error {
+	var errs []string
+	for _, ns := range env.AllNamespaces() {
+		var namespace corev1.Namespace
+		if err := kubeClient.Get(ctx, k8stypes.NamespacedName{Name: ns}, &namespace); err != nil {
+			if !apierrors.IsNotFound(err) {
+				errs = append(errs, err.Error())
+			}
+			continue
+		}
+		if owner := namespace.Labels[oam.LabelNamespaceOfEnvName]; owner != "" && owner != env.Name {
+			continue
+		}
+		var err error
+		if util.StringsContain(created, ns) {
+			err = kubeClient.Delete(ctx, &namespace)
+		} else {
+			err = util.UpdateNamespace(ctx, kubeClient, ns, util.MergeOverrideLabels(map[string]string{
+				oam.LabelNamespaceOfEnvName:         "",
+				oam.LabelControlPlaneNamespaceUsage: "",
+			}))
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
 	}
 	return nil
 }
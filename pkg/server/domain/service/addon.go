@@ -182,7 +182,7 @@ func (u *addonServiceImpl) GetAddon(ctx context.Context, name string, registry s
 		return nil, bcode.ErrAddonNotExist
 	}
 
-	addon.UISchema = renderAddonCustomUISchema(ctx, u.KubeClient, name, renderDefaultUISchema(addon.APISchema))
+	addon.UISchema = renderAddonCustomUISchema(ctx, u.KubeClient, name, renderDefaultUISchema(addon.APISchema, ""))
 
 	a, err := AddonImpl2AddonRes(addon, u.KubeConfig)
 	if err != nil {
@@ -288,7 +288,7 @@ func (u *addonServiceImpl) ListAddons(ctx context.Context, registry, query strin
 
 	for _, addon := range addons {
 		// render default ui schema
-		addon.UISchema = renderDefaultUISchema(addon.APISchema)
+		addon.UISchema = renderDefaultUISchema(addon.APISchema, "")
 	}
 
 	var addonResources []*apis.DetailAddonResponse
@@ -760,6 +760,20 @@ func (c *applicationServiceImpl) Deploy(ctx context.Context, app *model.Applicat
 	if err != nil {
 		return nil, err
 	}
+	// the quota is only checked if the application is created in the env for the first time
+	existing := &v1beta1.Application{}
+	if err := c.KubeClient.Get(ctx, types.NamespacedName{Name: oamApp.Name, Namespace: oamApp.Namespace}, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		env, err := c.EnvService.GetEnv(ctx, workflow.EnvName)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.EnvService.CheckAppQuota(ctx, env); err != nil {
+			return nil, err
+		}
+	}
 
 	// step2: check and create application revision
 	if !req.Force {
@@ -865,6 +879,24 @@ func (c *applicationServiceImpl) Deploy(ctx context.Context, app *model.Applicat
 	return res, nil
 }
 
+// renderAppLabels merge the default application labels of the env, the labels of the application and the labels managed by VelaUX,
+// the latter ones take precedence.
+func renderAppLabels(env *model.Env, appModel *model.Application) map[string]string {
+	labels := make(map[string]string)
+	if env != nil {
+		for key, value := range env.DefaultAppLabels {
+			labels[key] = value
+		}
+	}
+	for key, value := range appModel.Labels {
+		labels[key] = value
+	}
+	labels[oam.AnnotationAppName] = appModel.Name
+	// To take over the application
+	labels[velatypes.LabelSourceOfTruth] = velatypes.FromUX
+	return labels
+}
+
 func (c *applicationServiceImpl) renderOAMApplication(ctx context.Context, appModel *model.Application, reqWorkflowName, envName, version string) (*v1beta1.Application, error) {
 	// Priority 1 uses the requested workflow as release .
 	// Priority 2 uses the default workflow as release .
@@ -894,13 +926,7 @@ func (c *applicationServiceImpl) renderOAMApplication(ctx context.Context, appMo
 	if err != nil {
 		return nil, fmt.Errorf("failed to load the env binding: %w", err)
 	}
-	labels := make(map[string]string)
-	for key, value := range appModel.Labels {
-		labels[key] = value
-	}
-	labels[oam.AnnotationAppName] = appModel.Name
-	// To take over the application
-	labels[velatypes.LabelSourceOfTruth] = velatypes.FromUX
+	labels := renderAppLabels(env, appModel)
 
 	deployAppName := envbinding.AppDeployName
 	if deployAppName == "" {
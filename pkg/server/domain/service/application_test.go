@@ -463,6 +463,27 @@ var _ = Describe("Test application service function", func() {
 		Expect(err).Should(BeNil())
 	})
 
+	It("Test redeploying the application in the env at the quota", func() {
+		appModel, err := appService.GetApplication(context.TODO(), testApp)
+		Expect(err).Should(BeNil())
+		_, err = appService.Deploy(context.TODO(), appModel, v1.ApplicationDeployRequest{WorkflowName: repository.ConvertWorkflowName("app-dev"), Force: true})
+		Expect(err).Should(BeNil())
+		env, err := envService.GetEnv(context.TODO(), "app-dev")
+		Expect(err).Should(BeNil())
+		count, err := envService.GetAppCountInEnv(context.TODO(), env)
+		Expect(err).Should(BeNil())
+		_, err = envService.UpdateEnv(context.TODO(), "app-dev", v1.UpdateEnvRequest{AppQuota: &count})
+		Expect(err).Should(BeNil())
+
+		// the application already exists in the env, so it doesn't consume the quota
+		_, err = appService.Deploy(context.TODO(), appModel, v1.ApplicationDeployRequest{WorkflowName: repository.ConvertWorkflowName("app-dev"), Force: true})
+		Expect(err).Should(BeNil())
+
+		noQuota := 0
+		_, err = envService.UpdateEnv(context.TODO(), "app-dev", v1.UpdateEnvRequest{AppQuota: &noQuota})
+		Expect(err).Should(BeNil())
+	})
+
 	It("Test ListRecords function", func() {
 		By("no running records in application")
 		ctx := context.TODO()
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"time"
+
+	apisv1 "github.com/kubevela/velaux/pkg/server/interfaces/api/dto/v1"
+)
+
+const (
+	// AuditActionCreate the resource is created
+	AuditActionCreate = "create"
+	// AuditActionUpdate the resource is updated
+	AuditActionUpdate = "update"
+	// AuditActionDelete the resource is deleted
+	AuditActionDelete = "delete"
+	// AuditActionGrantPrivileges the privileges are granted to the resource
+	AuditActionGrantPrivileges = "grantPrivileges"
+	// AuditActionRevokePrivileges the privileges are revoked from the resource
+	AuditActionRevokePrivileges = "revokePrivileges"
+	// AuditActionArchive the resource is archived
+	AuditActionArchive = "archive"
+	// AuditActionUnarchive the resource is unarchived
+	AuditActionUnarchive = "unarchive"
+	// AuditActionReconcile the drift of the resource is corrected
+	AuditActionReconcile = "reconcile"
+)
+
+// AuditEvent records who did what to which resource
+type AuditEvent struct {
+	// User the user who performs the operation, it is empty if the operation is not triggered by a user
+	User string
+	// Resource the kind of the resource, such as env
+	Resource string
+	// Name the name of the resource
+	Name   string
+	Action string
+	// OldTargets and NewTargets record the change of the targets
+	OldTargets []string
+	NewTargets []string
+	Message    string
+	Time       time.Time
+}
+
+// AuditLogger the pluggable audit event sink
+type AuditLogger interface {
+	Audit(ctx context.Context, event *AuditEvent)
+}
+
+type noopAuditLogger struct{}
+
+// NewNoopAuditLogger new an audit logger that drops all events
+func NewNoopAuditLogger() AuditLogger {
+	return &noopAuditLogger{}
+}
+
+// Audit drop the event
+func (n *noopAuditLogger) Audit(ctx context.Context, event *AuditEvent) {}
+
+// newAuditEvent new an audit event with the user in the context
+func newAuditEvent(ctx context.Context, resource, name, action string) *AuditEvent {
+	userName, _ := ctx.Value(&apisv1.CtxKeyUser).(string)
+	return &AuditEvent{
+		User:     userName,
+		Resource: resource,
+		Name:     name,
+		Action:   action,
+		Time:     time.Now(),
+	}
+}
@@ -319,7 +319,7 @@ var _ = Describe("Test authentication service functions", func() {
 
 		err = targetService.DeleteTarget(context.TODO(), model.DefaultInitName)
 		Expect(err).Should(BeNil())
-		err = envService.DeleteEnv(context.TODO(), model.DefaultInitName)
+		err = envService.DeleteEnv(context.TODO(), model.DefaultInitName, false, false)
 		Expect(err).Should(BeNil())
 	})
 })
@@ -109,7 +109,7 @@ func (u *configServiceImpl) GetTemplate(ctx context.Context, tem config.Namespac
 		}
 		return nil, err
 	}
-	defaultUISchema := renderDefaultUISchema(template.Schema)
+	defaultUISchema := renderDefaultUISchema(template.Schema, "")
 	t := &apis.ConfigTemplateDetail{
 		ConfigTemplate: apis.ConfigTemplate{
 			Alias:       template.Alias,
@@ -122,7 +122,7 @@ func (u *configServiceImpl) GetTemplate(ctx context.Context, tem config.Namespac
 		},
 		APISchema: template.Schema,
 		// TODO: Support to define the custom UI schema in the template cue script.
-		UISchema: renderCustomUISchema(ctx, u.KubeClient, template.Name, "config", defaultUISchema),
+		UISchema: renderCustomUISchema(getCustomUISchemaConfigMap(ctx, u.KubeClient, types.DefaultKubeVelaNS, template.Name, "config"), defaultUISchema),
 	}
 	return t, nil
 }
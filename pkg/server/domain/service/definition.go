@@ -17,11 +17,20 @@ limitations under the License.
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/oam-dev/kubevela/pkg/utils/addon"
 	"github.com/oam-dev/kubevela/pkg/utils/filters"
@@ -35,14 +44,21 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
 	"github.com/oam-dev/kubevela/pkg/utils"
 
+	"github.com/kubevela/velaux/pkg/server/domain/model"
+	"github.com/kubevela/velaux/pkg/server/infrastructure/datastore"
 	apisv1 "github.com/kubevela/velaux/pkg/server/interfaces/api/dto/v1"
+	apiutils "github.com/kubevela/velaux/pkg/server/utils"
 	"github.com/kubevela/velaux/pkg/server/utils/bcode"
 )
 
@@ -51,32 +67,127 @@ type DefinitionService interface {
 	// ListDefinitions list definition base info
 	ListDefinitions(ctx context.Context, ops DefinitionQueryOption) ([]*apisv1.DefinitionBase, error)
 	// DetailDefinition get definition detail
-	DetailDefinition(ctx context.Context, name, defType string) (*apisv1.DetailDefinitionResponse, error)
-	// AddDefinitionUISchema add or update custom definition ui schema
-	AddDefinitionUISchema(ctx context.Context, name, defType string, schema []*schema.UIParameter) ([]*schema.UIParameter, error)
+	DetailDefinition(ctx context.Context, name, defType string, ops DetailDefinitionOption) (*apisv1.DetailDefinitionResponse, error)
+	// AddDefinitionUISchema add or update custom definition ui schema, the custom ui schema of the inherited definition is merged before it if inheritsFrom is set
+	AddDefinitionUISchema(ctx context.Context, name, defType string, schema []*schema.UIParameter, inheritsFrom string) ([]*schema.UIParameter, error)
+	// ListDefinitionUISchemaHistory list the edits of the custom ui schema newest-first, only the edits of the user are listed if modifiedBy is set
+	ListDefinitionUISchemaHistory(ctx context.Context, name, defType string, page, pageSize int, modifiedBy string) (*apisv1.ListUISchemaHistoryResponse, error)
+	// ResetDefinitionUISchema remove the custom definition ui schema, return the default ui schema
+	ResetDefinitionUISchema(ctx context.Context, name, defType string) ([]*schema.UIParameter, error)
 	// UpdateDefinitionStatus update the status of definition
 	UpdateDefinitionStatus(ctx context.Context, name string, status apisv1.UpdateDefinitionStatusRequest) (*apisv1.DetailDefinitionResponse, error)
+	// RegenerateAllDefinitionSchemas delete the schema configmaps of the definitions so that the controller generates them again
+	RegenerateAllDefinitionSchemas(ctx context.Context, req apisv1.RegenerateDefinitionSchemasRequest) (*apisv1.RegenerateDefinitionSchemasResponse, error)
+	// SchemaCacheStats return the counters of the definition schema cache
+	SchemaCacheStats(ctx context.Context) (*apisv1.DefinitionSchemaCacheStats, error)
+	// BatchUpdateDefinitionStatus update the status of the definitions one by one, the failures don't abort the others
+	BatchUpdateDefinitionStatus(ctx context.Context, updates []apisv1.UpdateDefinitionStatusRequest) (*apisv1.BatchUpdateDefinitionStatusResponse, error)
+	// DiffDefinitionSchema compare the parameter schemas of two revisions of the definition
+	DiffDefinitionSchema(ctx context.Context, name, defType string, fromRev, toRev string) (*apisv1.DefinitionSchemaDiffResponse, error)
+	// ExportDefinitionJSONSchema convert the parameter schema of the definition to the draft-07 JSON schema
+	ExportDefinitionJSONSchema(ctx context.Context, name, defType string) ([]byte, error)
+	// CountDefinitionUsage count the applications managed by VelaUX that use the definition
+	CountDefinitionUsage(ctx context.Context, name, defType string) (*apisv1.DefinitionUsageResponse, error)
+	// DeleteDefinition delete the custom definition and its schema and ui schema configmaps
+	DeleteDefinition(ctx context.Context, name, defType string) error
+	// ResolveDependencies check which dependencies of the definition are installed and which are missing
+	ResolveDependencies(ctx context.Context, name, defType string) (*apisv1.DefinitionDependenciesResponse, error)
+	// ListCompatibleComponents list the component definitions that the trait could be attached to
+	ListCompatibleComponents(ctx context.Context, traitName string) ([]*apisv1.DefinitionBase, error)
+	// ApplyDefinition create or update the definition from the YAML manifest
+	ApplyDefinition(ctx context.Context, manifest []byte) (*apisv1.DetailDefinitionResponse, error)
+	// ValidateParameters validate the parameter values against the schema of the definition
+	ValidateParameters(ctx context.Context, name, defType string, values map[string]interface{}) (*apisv1.ValidateParametersResponse, error)
+	// RenderUISchemaForSchema render the default ui schema for the openapi schema in JSON, no definition is needed
+	RenderUISchemaForSchema(ctx context.Context, openapiJSON []byte) ([]*schema.UIParameter, error)
+	// RegisterSchemaChangeCallback register the callback invoked when the schema of a definition is changed
+	RegisterSchemaChangeCallback(callback DefinitionSchemaChangeCallback)
+	// CountDefinitionsByType count the definitions of all types, the type in the options is ignored
+	CountDefinitionsByType(ctx context.Context, ops DefinitionQueryOption) (map[string]int, error)
+	// AddFavoriteDefinition pin the definition for the login user
+	AddFavoriteDefinition(ctx context.Context, name, defType string) error
+	// RemoveFavoriteDefinition unpin the definition for the login user
+	RemoveFavoriteDefinition(ctx context.Context, name, defType string) error
+	// ListFavoriteDefinitions list the definitions pinned by the login user, all types are listed if the type is empty
+	ListFavoriteDefinitions(ctx context.Context, defType string) ([]*apisv1.DefinitionBase, error)
 }
 
 // DefinitionHidden means the definition can not be used in VelaUX
 const DefinitionHidden = "true"
 
 type definitionServiceImpl struct {
-	KubeClient client.Client `inject:"kubeClient"`
+	KubeClient client.Client       `inject:"kubeClient"`
+	Store      datastore.DataStore `inject:"datastore"`
+	// SystemNamespace the namespace of the definitions and their schemas, default is vela-system
+	SystemNamespace string
+
+	schemaWatcher *definitionSchemaWatcher
+	schemaCache   *apiutils.MemoryCacheStore
+	// schemaCacheHits and schemaCacheMisses count the lookups of the schema cache,
+	// schemaCacheInvalidations counts the cached schemas dropped because the definition is changed
+	schemaCacheHits          atomic.Int64
+	schemaCacheMisses        atomic.Int64
+	schemaCacheInvalidations atomic.Int64
+}
+
+// DetailDefinitionOption the options of getting the definition detail
+type DetailDefinitionOption struct {
+	// Cluster the cluster to read the definition from, default is the control plane
+	Cluster string
+	// ExcludeHidden refuse to return the definition hidden in UI if the user is not the platform admin
+	ExcludeHidden bool
+	// IfNoneMatch the ETags of the definition that the caller has, separated by commas like the If-None-Match header.
+	// The ErrDefinitionNotModified is returned if the definition is not changed, the rendering is skipped.
+	IfNoneMatch string
+	// NoCache read the schema from the cluster instead of the cache, the cache is refreshed with the read schema.
+	// The cached schema is invalidated by the change of the definition, but not by the change of the schema configmap only.
+	NoCache bool
+	// Locale the locale of the labels and the descriptions of the parameters, such as zh-CN.
+	// The translations come from the x-vela-i18n extension, the default ones are used if there is no translation.
+	Locale string
 }
 
 // DefinitionQueryOption define a set of query options
 type DefinitionQueryOption struct {
-	Type             string `json:"type"`
-	AppliedWorkloads string `json:"appliedWorkloads"`
-	OwnerAddon       string `json:"sourceAddon"`
-	QueryAll         bool   `json:"queryAll"`
-	Scope            string `json:"scope"`
+	Type             string   `json:"type"`
+	AppliedWorkloads string   `json:"appliedWorkloads"`
+	OwnerAddon       string   `json:"sourceAddon"`
+	OwnerAddons      []string `json:"sourceAddons"`
+	QueryAll         bool     `json:"queryAll"`
+	Scope            string   `json:"scope"`
+	// SortBy the key to sort the definitions, support name, alias and createTime, default is name
+	SortBy    string              `json:"sortBy"`
+	SortOrder datastore.SortOrder `json:"sortOrder"`
+	// Brief means only the identity and the description fields are returned, the specs of the definitions are skipped
+	Brief bool `json:"brief"`
+	// Cluster the cluster to read the definitions from, default is the control plane
+	Cluster string `json:"cluster"`
+	// Category only list the definitions of the category
+	Category string `json:"category"`
+	// IncludeSchemaStats count the parameters of the definitions, the schema of every definition is loaded
+	IncludeSchemaStats bool `json:"includeSchemaStats"`
+	// IncludeUsage count the applications that use each definition, the applications are listed once for all definitions
+	IncludeUsage bool `json:"includeUsage"`
+	// Keyword only list the definitions whose name or alias contains the keyword, case-insensitive
+	Keyword string `json:"keyword"`
+	// ParameterKeyword only list the definitions that have the parameter of the name at any level, case-insensitive.
+	// The schemas of the candidates are loaded, so it is refused if there are too many candidates.
+	ParameterKeyword string `json:"parameterKeyword"`
+	// SchemaReady only list the definitions whose schema is generated or not, keep all if it is nil
+	SchemaReady *bool `json:"schemaReady"`
+	// IncludeDeprecated list the deprecated definitions as well, they are excluded by default
+	IncludeDeprecated bool `json:"includeDeprecated"`
 }
 
-// String return cache key string
+// String return cache key string, every field is included and the strings are quoted,
+// so the different options never share the same key.
 func (d DefinitionQueryOption) String() string {
-	return fmt.Sprintf("type:%s/appliedWorkloads:%s/ownerAddon:%s/queryAll:%v", d.Type, d.AppliedWorkloads, d.OwnerAddon, d.QueryAll)
+	schemaReady := "any"
+	if d.SchemaReady != nil {
+		schemaReady = strconv.FormatBool(*d.SchemaReady)
+	}
+	return fmt.Sprintf("type:%q/appliedWorkloads:%q/ownerAddon:%q/ownerAddons:%q/queryAll:%v/scope:%q/sortBy:%q/sortOrder:%d/brief:%v/cluster:%q/category:%q/includeSchemaStats:%v/includeUsage:%v/keyword:%q/parameterKeyword:%q/schemaReady:%s/includeDeprecated:%v",
+		d.Type, d.AppliedWorkloads, d.OwnerAddon, d.OwnerAddons, d.QueryAll, d.Scope, d.SortBy, d.SortOrder, d.Brief, d.Cluster, d.Category, d.IncludeSchemaStats, d.IncludeUsage, d.Keyword, d.ParameterKeyword, schemaReady, d.IncludeDeprecated)
 }
 
 const (
@@ -87,9 +198,33 @@ const (
 	kindPolicyDefinition       = "PolicyDefinition"
 )
 
+// definitionTypes all the definition types supported
+var definitionTypes = []string{"component", "trait", "workflowstep", "policy"}
+
+const (
+	// DefinitionSortByName sort the definitions by the name
+	DefinitionSortByName = "name"
+	// DefinitionSortByAlias sort the definitions by the alias
+	DefinitionSortByAlias = "alias"
+	// DefinitionSortByCreateTime sort the definitions by the creation timestamp
+	DefinitionSortByCreateTime = "createTime"
+)
+
 // NewDefinitionService new definition service
-func NewDefinitionService() DefinitionService {
-	return &definitionServiceImpl{}
+func NewDefinitionService(systemNamespace string) DefinitionService {
+	return &definitionServiceImpl{
+		SystemNamespace: systemNamespace,
+		schemaWatcher:   newDefinitionSchemaWatcher(),
+		schemaCache:     apiutils.NewMemoryCacheStore(context.Background()),
+	}
+}
+
+// systemNamespace return the namespace of the definitions and their schemas
+func (d *definitionServiceImpl) systemNamespace() string {
+	if d.SystemNamespace == "" {
+		return types.DefaultKubeVelaNS
+	}
+	return d.SystemNamespace
 }
 
 func (d *definitionServiceImpl) ListDefinitions(ctx context.Context, ops DefinitionQueryOption) ([]*apisv1.DefinitionBase, error) {
@@ -103,14 +238,501 @@ func (d *definitionServiceImpl) ListDefinitions(ctx context.Context, ops Definit
 	return d.listDefinitions(ctx, defs, kind, ops)
 }
 
+// CountDefinitionsByType count the definitions of all types with the same filters as ListDefinitions
+func (d *definitionServiceImpl) CountDefinitionsByType(ctx context.Context, ops DefinitionQueryOption) (map[string]int, error) {
+	counts := make(map[string]int, len(definitionTypes))
+	// the schema configmaps of all types are listed once
+	var schemaConfigMaps map[string]bool
+	for _, defType := range definitionTypes {
+		version, kind, err := getKindAndVersion(defType)
+		if err != nil {
+			return nil, err
+		}
+		defs := &unstructured.UnstructuredList{}
+		defs.SetAPIVersion(version)
+		defs.SetKind(kind)
+		items, err := d.listFilteredDefinitions(ctx, defs, ops)
+		if err != nil {
+			return nil, err
+		}
+		if ops.SchemaReady != nil {
+			if schemaConfigMaps == nil {
+				if schemaConfigMaps, err = d.listSchemaConfigMapNames(withDefinitionCluster(ctx, ops.Cluster)); err != nil {
+					return nil, err
+				}
+			}
+			items = filterDefinitionsBySchemaReady(items, defType, schemaConfigMaps, *ops.SchemaReady)
+		}
+		if ops.ParameterKeyword != "" {
+			if items, _, _, err = d.filterDefinitionsByParameter(withDefinitionCluster(ctx, ops.Cluster), items, defType, ops.ParameterKeyword); err != nil {
+				return nil, err
+			}
+		}
+		counts[defType] = len(items)
+	}
+	return counts, nil
+}
+
 func (d *definitionServiceImpl) listDefinitions(ctx context.Context, list *unstructured.UnstructuredList, kind string, ops DefinitionQueryOption) ([]*apisv1.DefinitionBase, error) {
-	matchLabels := metav1.LabelSelector{
-		MatchExpressions: []metav1.LabelSelectorRequirement{
-			{
-				Key:      types.LabelDefinitionDeprecated,
-				Operator: metav1.LabelSelectorOpDoesNotExist,
-			},
-		},
+	items, err := d.listFilteredDefinitions(ctx, list, ops)
+	if err != nil {
+		return nil, err
+	}
+	schemaConfigMaps, err := d.listSchemaConfigMapNames(withDefinitionCluster(ctx, ops.Cluster))
+	if err != nil {
+		return nil, err
+	}
+	if ops.SchemaReady != nil {
+		items = filterDefinitionsBySchemaReady(items, ops.Type, schemaConfigMaps, *ops.SchemaReady)
+	}
+	favorites, err := d.listUserFavorites(ctx, ops.Type)
+	if err != nil {
+		return nil, err
+	}
+	var matchedParameters map[string][]string
+	var schemas map[string]*openapi3.Schema
+	if ops.ParameterKeyword != "" {
+		if items, matchedParameters, schemas, err = d.filterDefinitionsByParameter(withDefinitionCluster(ctx, ops.Cluster), items, ops.Type, ops.ParameterKeyword); err != nil {
+			return nil, err
+		}
+	}
+	if err := sortDefinitions(items, ops.SortBy, ops.SortOrder); err != nil {
+		return nil, err
+	}
+
+	var usages map[string]int
+	if ops.IncludeUsage {
+		if usages, err = d.countDefinitionUsages(ctx, ops.Type); err != nil {
+			return nil, err
+		}
+	}
+
+	var defs []*apisv1.DefinitionBase
+	for _, def := range items {
+		var definition *apisv1.DefinitionBase
+		if ops.Brief {
+			definition = convertDefinitionBrief(def, kind, d.systemNamespace())
+		} else {
+			definition, err = convertDefinitionBase(def, kind, d.systemNamespace())
+			if err != nil {
+				klog.Errorf("convert definition to base failure %s", err.Error())
+				continue
+			}
+		}
+		if ops.IncludeSchemaStats {
+			// reuse the schema loaded by the parameter search
+			apiSchema, loaded := schemas[def.GetName()]
+			if !loaded {
+				apiSchema, err = d.getDefinitionSchema(withDefinitionCluster(ctx, ops.Cluster), def.GetName(), ops.Type, "")
+				var schemaErr *definitionSchemaError
+				if errors.As(err, &schemaErr) {
+					klog.Warning(schemaErr.Error())
+					definition.SchemaError = schemaErr.Error()
+				} else if err != nil {
+					return nil, err
+				}
+			}
+			definition.ParameterCount, definition.RequiredCount = countSchemaParameters(apiSchema)
+		}
+		definition.MatchedParameters = matchedParameters[def.GetName()]
+		definition.SchemaReady = schemaConfigMaps[schemaConfigMapName(ops.Type, def.GetName())]
+		definition.Favorited = favorites[def.GetName()]
+		if ops.IncludeUsage {
+			usage := usages[def.GetName()]
+			definition.UsageCount = &usage
+		}
+		defs = append(defs, definition)
+	}
+	return defs, nil
+}
+
+// ResolveDependencies check which dependencies of the definition are installed in the system namespace and which are missing
+func (d *definitionServiceImpl) ResolveDependencies(ctx context.Context, name, defType string) (*apisv1.DefinitionDependenciesResponse, error) {
+	version, kind, err := getKindAndVersion(defType)
+	if err != nil {
+		return nil, err
+	}
+	def := &unstructured.Unstructured{}
+	def.SetAPIVersion(version)
+	def.SetKind(kind)
+	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: d.systemNamespace(), Name: name}, def); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, bcode.ErrDefinitionNotFound
+		}
+		return nil, err
+	}
+	resp := &apisv1.DefinitionDependenciesResponse{Name: name, Type: defType, Installed: []apisv1.DefinitionReference{}, Missing: []apisv1.DefinitionReference{}}
+	for _, dependency := range parseDefinitionDependencies(def.GetAnnotations()[AnnoDefinitionDependsOn], defType) {
+		installed, err := d.isDefinitionInstalled(ctx, dependency)
+		if err != nil {
+			return nil, err
+		}
+		if installed {
+			resp.Installed = append(resp.Installed, dependency)
+		} else {
+			resp.Missing = append(resp.Missing, dependency)
+		}
+	}
+	return resp, nil
+}
+
+// ListCompatibleComponents list the component definitions whose name or workload type is in the appliesToWorkloads of the trait,
+// the trait could be attached to all components if the appliesToWorkloads is empty or contains "*".
+func (d *definitionServiceImpl) ListCompatibleComponents(ctx context.Context, traitName string) ([]*apisv1.DefinitionBase, error) {
+	trait := &v1beta1.TraitDefinition{}
+	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: d.systemNamespace(), Name: traitName}, trait); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, bcode.ErrDefinitionNotFound
+		}
+		return nil, err
+	}
+	components, err := d.ListDefinitions(ctx, DefinitionQueryOption{Type: "component", Brief: true})
+	if err != nil {
+		return nil, err
+	}
+	appliesTo := trait.Spec.AppliesToWorkloads
+	if len(appliesTo) == 0 || utils.StringsContain(appliesTo, "*") {
+		return components, nil
+	}
+	compatible := []*apisv1.DefinitionBase{}
+	for _, component := range components {
+		if utils.StringsContain(appliesTo, component.Name) || (component.WorkloadType != "" && utils.StringsContain(appliesTo, component.WorkloadType)) {
+			compatible = append(compatible, component)
+		}
+	}
+	return compatible, nil
+}
+
+// isDefinitionInstalled check whether the definition exists in the system namespace, the definition of the unknown type is never installed
+func (d *definitionServiceImpl) isDefinitionInstalled(ctx context.Context, ref apisv1.DefinitionReference) (bool, error) {
+	version, kind, err := getKindAndVersion(ref.Type)
+	if err != nil {
+		return false, nil
+	}
+	def := &unstructured.Unstructured{}
+	def.SetAPIVersion(version)
+	def.SetKind(kind)
+	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: d.systemNamespace(), Name: ref.Name}, def); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// parseDefinitionDependencies parse the dependencies from the annotation, the duplicated ones are removed
+func parseDefinitionDependencies(annotation, defType string) []apisv1.DefinitionReference {
+	var dependencies []apisv1.DefinitionReference
+	seen := map[apisv1.DefinitionReference]bool{}
+	for _, item := range strings.Split(annotation, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		dependency := apisv1.DefinitionReference{Name: item, Type: defType}
+		if i := strings.Index(item, "/"); i >= 0 {
+			dependency.Type, dependency.Name = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		}
+		if dependency.Name == "" || seen[dependency] {
+			continue
+		}
+		seen[dependency] = true
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies
+}
+
+// CountDefinitionUsage count the applications managed by VelaUX that use the definition
+func (d *definitionServiceImpl) CountDefinitionUsage(ctx context.Context, name, defType string) (*apisv1.DefinitionUsageResponse, error) {
+	if _, _, err := getKindAndVersion(defType); err != nil {
+		return nil, err
+	}
+	usages, err := d.countDefinitionUsages(ctx, defType)
+	if err != nil {
+		return nil, err
+	}
+	return &apisv1.DefinitionUsageResponse{Name: name, Type: defType, UsageCount: usages[name]}, nil
+}
+
+// DeleteDefinition delete the definition that is added by the users, with the schema configmaps of all revisions
+// and the custom ui schema. It is refused if any application still uses the definition, no matter it is created
+// from VelaUX or not, or the definition is installed with KubeVela or by an addon.
+func (d *definitionServiceImpl) DeleteDefinition(ctx context.Context, name, defType string) error {
+	version, kind, err := getKindAndVersion(defType)
+	if err != nil {
+		return err
+	}
+	def := &unstructured.Unstructured{}
+	def.SetAPIVersion(version)
+	def.SetKind(kind)
+	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: d.systemNamespace(), Name: name}, def); err != nil {
+		if apierrors.IsNotFound(err) {
+			return bcode.ErrDefinitionNotFound
+		}
+		return err
+	}
+	if isDefinitionProtected(def) {
+		return bcode.ErrDefinitionProtected
+	}
+	apps, uxApps, err := d.countDefinitionReferences(ctx, name, defType)
+	if err != nil {
+		return err
+	}
+	if apps > 0 || uxApps > 0 {
+		return bcode.ErrDefinitionInUse.SetMessage(fmt.Sprintf("the definition is used by %d applications and %d applications of VelaUX, it can't be deleted", apps, uxApps))
+	}
+	if err := d.KubeClient.Delete(ctx, def); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	klog.Infof("the %s definition %s is deleted", defType, name)
+
+	// the schema configmaps are owned by the definition, but they are deleted at once instead of waiting for the garbage collection
+	var cms v1.ConfigMapList
+	if err := d.KubeClient.List(ctx, &cms, client.InNamespace(d.systemNamespace()), client.MatchingLabels{types.LabelDefinition: "schema"}); err != nil {
+		return err
+	}
+	for i := range cms.Items {
+		if !isDefinitionSchemaConfigMap(cms.Items[i].Name, defType, name) || isOwnedByOtherDefinition(&cms.Items[i], kind, name) {
+			continue
+		}
+		if err := d.KubeClient.Delete(ctx, &cms.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	uiSchemaCM := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: d.systemNamespace(), Name: fmt.Sprintf("%s-uischema-%s", defType, name)}}
+	if err := d.KubeClient.Delete(ctx, uiSchemaCM); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// isDefinitionProtected check whether the definition is installed by an addon or with KubeVela by the helm chart
+func isDefinitionProtected(def *unstructured.Unstructured) bool {
+	for _, ownerRef := range def.GetOwnerReferences() {
+		if strings.HasPrefix(ownerRef.Name, addon.AddonAppPrefix) {
+			return true
+		}
+	}
+	return def.GetLabels()["app.kubernetes.io/managed-by"] == "Helm"
+}
+
+// isOwnedByOtherDefinition check whether the configmap is the latest schema of another definition,
+// e.g. the schema of the revision 2 of the scaler has the same name as the schema of the scaler-v2
+func isOwnedByOtherDefinition(cm *v1.ConfigMap, kind, name string) bool {
+	for _, ownerRef := range cm.OwnerReferences {
+		if ownerRef.Kind == kind && ownerRef.Name != name {
+			return true
+		}
+	}
+	return false
+}
+
+// isDefinitionSchemaConfigMap check whether the configmap is the latest schema or the schema of a revision of the definition
+func isDefinitionSchemaConfigMap(cmName, defType, name string) bool {
+	latest := schemaConfigMapName(defType, name)
+	if cmName == latest {
+		return true
+	}
+	revision := strings.TrimPrefix(cmName, latest+"-v")
+	if revision == cmName || revision == "" {
+		return false
+	}
+	_, err := strconv.ParseUint(revision, 10, 64)
+	return err == nil
+}
+
+// countDefinitionUsages count the applications that use each definition of the type.
+// The applications in all namespaces are listed with one request, instead of listing the namespaces one by one.
+func (d *definitionServiceImpl) countDefinitionUsages(ctx context.Context, defType string) (map[string]int, error) {
+	var apps v1beta1.ApplicationList
+	if err := d.KubeClient.List(ctx, &apps, client.MatchingLabels{types.LabelSourceOfTruth: types.FromUX}); err != nil {
+		return nil, err
+	}
+	usages := make(map[string]int)
+	for i := range apps.Items {
+		for name := range listApplicationDefinitions(&apps.Items[i], defType) {
+			usages[name]++
+		}
+	}
+	return usages, nil
+}
+
+// countDefinitionReferences count the applications in all namespaces that use the definition, no matter how they are created,
+// and the applications of VelaUX whose components, traits, policies or workflow steps in the datastore use the definition.
+// The applications of VelaUX are counted even if they are not deployed.
+func (d *definitionServiceImpl) countDefinitionReferences(ctx context.Context, name, defType string) (apps int, uxApps int, err error) {
+	var appList v1beta1.ApplicationList
+	if err := d.KubeClient.List(ctx, &appList); err != nil {
+		return 0, 0, err
+	}
+	for i := range appList.Items {
+		if listApplicationDefinitions(&appList.Items[i], defType)[name] {
+			apps++
+		}
+	}
+	referenced := make(map[string]bool)
+	switch defType {
+	case "component":
+		entities, err := d.Store.List(ctx, &model.ApplicationComponent{Type: name}, nil)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, entity := range entities {
+			referenced[entity.(*model.ApplicationComponent).AppPrimaryKey] = true
+		}
+	case "trait":
+		// the traits are not indexed, check the traits of all components
+		entities, err := d.Store.List(ctx, &model.ApplicationComponent{}, nil)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, entity := range entities {
+			component := entity.(*model.ApplicationComponent)
+			for _, trait := range component.Traits {
+				if trait.Type == name {
+					referenced[component.AppPrimaryKey] = true
+				}
+			}
+		}
+	case "policy":
+		entities, err := d.Store.List(ctx, &model.ApplicationPolicy{Type: name}, nil)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, entity := range entities {
+			referenced[entity.(*model.ApplicationPolicy).AppPrimaryKey] = true
+		}
+	case "workflowstep":
+		entities, err := d.Store.List(ctx, &model.Workflow{}, nil)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, entity := range entities {
+			workflow := entity.(*model.Workflow)
+			for _, step := range workflow.Steps {
+				if step.Type == name {
+					referenced[workflow.AppPrimaryKey] = true
+				}
+				for _, subStep := range step.SubSteps {
+					if subStep.Type == name {
+						referenced[workflow.AppPrimaryKey] = true
+					}
+				}
+			}
+		}
+	}
+	return apps, len(referenced), nil
+}
+
+// listApplicationDefinitions return the names of the definitions of the type that the application uses
+func listApplicationDefinitions(app *v1beta1.Application, defType string) map[string]bool {
+	names := make(map[string]bool)
+	switch defType {
+	case "component":
+		for _, component := range app.Spec.Components {
+			names[component.Type] = true
+		}
+	case "trait":
+		for _, component := range app.Spec.Components {
+			for _, trait := range component.Traits {
+				names[trait.Type] = true
+			}
+		}
+	case "policy":
+		for _, policy := range app.Spec.Policies {
+			names[policy.Type] = true
+		}
+	case "workflowstep":
+		if app.Spec.Workflow != nil {
+			for _, step := range app.Spec.Workflow.Steps {
+				names[step.Type] = true
+				for _, subStep := range step.SubSteps {
+					names[subStep.Type] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// countSchemaParameters return the count of the top level parameters and the required parameters
+func countSchemaParameters(apiSchema *openapi3.Schema) (parameters, required int) {
+	if apiSchema == nil {
+		return 0, 0
+	}
+	return len(apiSchema.Properties), len(apiSchema.Required)
+}
+
+// maxParameterSearchDefinitions the max count of the definitions whose schemas are loaded to search the parameter
+const maxParameterSearchDefinitions = 200
+
+// filterDefinitionsByParameter keep the definitions that have the parameter of the name, return the paths of the matched parameters
+// and the loaded schemas keyed by the definition name. It is refused if there are too many candidates, narrow them with the other filters.
+func (d *definitionServiceImpl) filterDefinitionsByParameter(ctx context.Context, items []unstructured.Unstructured, defType, keyword string) ([]unstructured.Unstructured, map[string][]string, map[string]*openapi3.Schema, error) {
+	if len(items) > maxParameterSearchDefinitions {
+		return nil, nil, nil, bcode.ErrDefinitionParameterSearchTooBroad
+	}
+	var matched []unstructured.Unstructured
+	matchedParameters := map[string][]string{}
+	schemas := map[string]*openapi3.Schema{}
+	for _, def := range items {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, nil, err
+		}
+		apiSchema, err := d.getDefinitionSchema(ctx, def.GetName(), defType, "")
+		var schemaErr *definitionSchemaError
+		if errors.As(err, &schemaErr) {
+			// the definition with the broken schema has no parameter to match
+			klog.Warning(schemaErr.Error())
+			continue
+		} else if err != nil {
+			return nil, nil, nil, err
+		}
+		schemas[def.GetName()] = apiSchema
+		var paths []string
+		findSchemaParameters("", apiSchema, keyword, &paths)
+		if len(paths) == 0 {
+			continue
+		}
+		sort.Strings(paths)
+		matched = append(matched, def)
+		matchedParameters[def.GetName()] = paths
+	}
+	return matched, matchedParameters, schemas, nil
+}
+
+// findSchemaParameters collect the paths of the parameters of the name at any level, the path of the array item is like ports[].port
+func findSchemaParameters(prefix string, apiSchema *openapi3.Schema, name string, into *[]string) {
+	if apiSchema == nil {
+		return
+	}
+	for key, property := range apiSchema.Properties {
+		if property == nil || property.Value == nil {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if strings.EqualFold(key, name) {
+			*into = append(*into, path)
+		}
+		findSchemaParameters(path, property.Value, name, into)
+		if property.Value.Items != nil {
+			findSchemaParameters(path+"[]", property.Value.Items.Value, name, into)
+		}
+	}
+}
+
+// listFilteredDefinitions list the definitions and apply the visibility, scope, owner addon, category and keyword filters
+func (d *definitionServiceImpl) listFilteredDefinitions(ctx context.Context, list *unstructured.UnstructuredList, ops DefinitionQueryOption) ([]unstructured.Unstructured, error) {
+	matchLabels := metav1.LabelSelector{}
+	if !ops.IncludeDeprecated {
+		matchLabels.MatchExpressions = append(matchLabels.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      types.LabelDefinitionDeprecated,
+			Operator: metav1.LabelSelectorOpDoesNotExist,
+		})
 	}
 	if ops.Scope != "" {
 		var filterScope string
@@ -135,7 +757,7 @@ func (d *definitionServiceImpl) listDefinitions(ctx context.Context, list *unstr
 	if err != nil {
 		return nil, err
 	}
-	if err := d.KubeClient.List(ctx, list, &client.ListOptions{
+	if err := d.KubeClient.List(withDefinitionCluster(ctx, ops.Cluster), list, &client.ListOptions{
 		LabelSelector: selector,
 	}); err != nil {
 		return nil, err
@@ -146,33 +768,168 @@ func (d *definitionServiceImpl) listDefinitions(ctx context.Context, list *unstr
 		// Filter by applied workload
 		filters.ByAppliedWorkload(ops.AppliedWorkloads),
 		// Filter by which addon installed this definition
-		filters.ByOwnerAddon(ops.OwnerAddon),
+		byOwnerAddons(append([]string{ops.OwnerAddon}, ops.OwnerAddons...)...),
+		// Filter by the category
+		byCategory(ops.Category),
+		// Filter by the name and the alias
+		byKeyword(ops.Keyword),
+		// Filter out the deprecated definitions
+		byDeprecated(ops.IncludeDeprecated),
 	)
+	return filteredList.Items, nil
+}
 
-	var defs []*apisv1.DefinitionBase
-	for _, def := range filteredList.Items {
-		definition, err := convertDefinitionBase(def, kind)
-		if err != nil {
-			klog.Errorf("convert definition to base failure %s", err.Error())
-			continue
-		}
-		defs = append(defs, definition)
+// schemaConfigMapName return the name of the configmap that the schema of the definition is generated to
+func schemaConfigMapName(defType, name string) string {
+	return fmt.Sprintf("%s-schema-%s", defType, name)
+}
+
+// listSchemaConfigMapNames list the names of the schema configmaps generated by the controller in the system namespace
+func (d *definitionServiceImpl) listSchemaConfigMapNames(ctx context.Context) (map[string]bool, error) {
+	var cms v1.ConfigMapList
+	if err := d.KubeClient.List(ctx, &cms, client.InNamespace(d.systemNamespace()), client.MatchingLabels{types.LabelDefinition: "schema"}); err != nil {
+		return nil, err
 	}
-	return defs, nil
+	names := make(map[string]bool, len(cms.Items))
+	for _, cm := range cms.Items {
+		names[cm.Name] = true
+	}
+	return names, nil
 }
 
-func getKindAndVersion(defType string) (apiVersion, kind string, err error) {
-	switch defType {
-	case "component":
-		return definitionAPIVersion, kindComponentDefinition, nil
+// filterDefinitionsBySchemaReady keep the definitions whose schema configmap exists or not
+func filterDefinitionsBySchemaReady(items []unstructured.Unstructured, defType string, schemaConfigMaps map[string]bool, schemaReady bool) []unstructured.Unstructured {
+	var filtered []unstructured.Unstructured
+	for _, item := range items {
+		if schemaConfigMaps[schemaConfigMapName(defType, item.GetName())] == schemaReady {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
 
-	case "trait":
-		return definitionAPIVersion, kindTraitDefinition, nil
+// getDefinitionCategory return the category of the definition from the annotations
+func getDefinitionCategory(def unstructured.Unstructured) string {
+	if category := def.GetAnnotations()[AnnoDefinitionCategoryV2]; category != "" {
+		return category
+	}
+	return def.GetAnnotations()[AnnoDefinitionCategory]
+}
 
-	case "workflowstep":
-		return definitionAPIVersion, kindWorkflowStepDefinition, nil
+// byCategory filter the definitions by the category, keep all if the category is empty
+func byCategory(category string) filters.Filter {
+	if category == "" {
+		return filters.KeepAll()
+	}
+	return func(obj unstructured.Unstructured) bool {
+		return getDefinitionCategory(obj) == category
+	}
+}
 
-	case "policy":
+// byKeyword filter the definitions whose name or alias contains the keyword, keep all if the keyword is empty
+func byKeyword(keyword string) filters.Filter {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	if keyword == "" {
+		return filters.KeepAll()
+	}
+	return func(obj unstructured.Unstructured) bool {
+		return strings.Contains(strings.ToLower(obj.GetName()), keyword) ||
+			strings.Contains(strings.ToLower(obj.GetAnnotations()[types.AnnoDefinitionAlias]), keyword)
+	}
+}
+
+// byDeprecated filter out the definitions deprecated by the annotation, keep all if the deprecated definitions are included
+func byDeprecated(includeDeprecated bool) filters.Filter {
+	if includeDeprecated {
+		return filters.KeepAll()
+	}
+	return func(obj unstructured.Unstructured) bool {
+		return parseDefinitionDeprecation(obj) == nil
+	}
+}
+
+// parseDefinitionDeprecation return the deprecation of the definition declared by the annotations or the deprecated label,
+// return nil if the definition is not deprecated
+func parseDefinitionDeprecation(def unstructured.Unstructured) *apisv1.DefinitionDeprecation {
+	annotations := def.GetAnnotations()
+	deprecated, _ := strconv.ParseBool(annotations[AnnoDefinitionDeprecated])
+	if _, exist := def.GetLabels()[types.LabelDefinitionDeprecated]; !deprecated && !exist {
+		return nil
+	}
+	return &apisv1.DefinitionDeprecation{
+		Replacement: annotations[AnnoDefinitionDeprecatedReplacement],
+		Message:     annotations[AnnoDefinitionDeprecatedMessage],
+	}
+}
+
+// byOwnerAddons returns a filter that keeps the definitions installed by any of the given addons.
+// Empty addon names will keep everything.
+func byOwnerAddons(addonNames ...string) filters.Filter {
+	var ownerFilters []filters.Filter
+	for _, addonName := range addonNames {
+		if addonName != "" {
+			ownerFilters = append(ownerFilters, filters.ByOwnerAddon(addonName))
+		}
+	}
+	if len(ownerFilters) == 0 {
+		return filters.KeepAll()
+	}
+	return func(obj unstructured.Unstructured) bool {
+		for _, filter := range ownerFilters {
+			if filter(obj) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sortDefinitions sort the definitions by the given key, the definitions with the same key are sorted by name
+func sortDefinitions(items []unstructured.Unstructured, sortBy string, order datastore.SortOrder) error {
+	var less func(i, j int) bool
+	switch sortBy {
+	case "", DefinitionSortByName:
+		less = func(i, j int) bool { return items[i].GetName() < items[j].GetName() }
+	case DefinitionSortByAlias:
+		less = func(i, j int) bool {
+			x, y := items[i].GetAnnotations()[types.AnnoDefinitionAlias], items[j].GetAnnotations()[types.AnnoDefinitionAlias]
+			if x == y {
+				return items[i].GetName() < items[j].GetName()
+			}
+			return x < y
+		}
+	case DefinitionSortByCreateTime:
+		less = func(i, j int) bool {
+			x, y := items[i].GetCreationTimestamp(), items[j].GetCreationTimestamp()
+			if x.Equal(&y) {
+				return items[i].GetName() < items[j].GetName()
+			}
+			return x.Before(&y)
+		}
+	default:
+		return bcode.ErrDefinitionSortByNotSupport
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if order == datastore.SortOrderDescending {
+			return less(j, i)
+		}
+		return less(i, j)
+	})
+	return nil
+}
+
+func getKindAndVersion(defType string) (apiVersion, kind string, err error) {
+	switch defType {
+	case "component":
+		return definitionAPIVersion, kindComponentDefinition, nil
+
+	case "trait":
+		return definitionAPIVersion, kindTraitDefinition, nil
+
+	case "workflowstep":
+		return definitionAPIVersion, kindWorkflowStepDefinition, nil
+
+	case "policy":
 		return definitionAPIVersion, kindPolicyDefinition, nil
 
 	default:
@@ -180,119 +937,1090 @@ func getKindAndVersion(defType string) (apiVersion, kind string, err error) {
 	}
 }
 
-// AnnoDefinitionCategory TODO : Import this variable from types.AnnoDefinitionCategory
-const AnnoDefinitionCategory = "custom.definition.oam.dev/category"
+// getDefinitionType return the definition type of the kind, it is the reverse of getKindAndVersion
+func getDefinitionType(kind string) string {
+	switch kind {
+	case kindComponentDefinition:
+		return "component"
+	case kindTraitDefinition:
+		return "trait"
+	case kindWorkflowStepDefinition:
+		return "workflowstep"
+	case kindPolicyDefinition:
+		return "policy"
+	default:
+		return ""
+	}
+}
+
+// AnnoDefinitionCategory TODO : Import this variable from types.AnnoDefinitionCategory
+const AnnoDefinitionCategory = "custom.definition.oam.dev/category"
+
+// AnnoDefinitionCategoryV2 the category of the definition, it takes precedence over AnnoDefinitionCategory
+const AnnoDefinitionCategoryV2 = "definition.oam.dev/category"
+
+// AnnoDefinitionDependsOn the definitions that the definition depends on, separated by commas, such as policy/topology,workflowstep/deploy.
+// The type of the dependency without the type prefix is the same as the definition.
+const AnnoDefinitionDependsOn = "definition.oam.dev/depends-on"
+
+// AnnoDefinitionDeprecated marks the definition deprecated if it is true
+const AnnoDefinitionDeprecated = "definition.oam.dev/deprecated"
+
+// AnnoDefinitionDeprecatedReplacement the definition of the same type that replaces the deprecated definition
+const AnnoDefinitionDeprecatedReplacement = "definition.oam.dev/deprecated-replacement"
+
+// AnnoDefinitionDeprecatedMessage the reason of the deprecation or the guide to migrate
+const AnnoDefinitionDeprecatedMessage = "definition.oam.dev/deprecated-message"
+
+// AnnoUISchemaLastModifiedBy the user who last updated the custom ui schema
+const AnnoUISchemaLastModifiedBy = "velaux.oam.dev/last-modified-by"
+
+// AnnoUISchemaLastModifiedTime the time when the custom ui schema was last updated, in RFC3339 format
+const AnnoUISchemaLastModifiedTime = "velaux.oam.dev/last-modified-time"
+
+// AnnoUISchemaInheritsFrom the definition of the same type whose custom ui schema is inherited
+const AnnoUISchemaInheritsFrom = "velaux.oam.dev/ui-schema-inherits-from"
+
+// AnnoUISchemaHistory the edit history of the custom ui schema in JSON, the newest one is the first
+const AnnoUISchemaHistory = "velaux.oam.dev/ui-schema-history"
+
+// maxUISchemaHistoryLength the max number of the edits retained in the history, so the configmap doesn't grow unbounded
+const maxUISchemaHistoryLength = 50
+
+// maxUISchemaHistorySummaryKeys the max number of the parameters named in each part of the summary
+const maxUISchemaHistorySummaryKeys = 5
+
+// maxUISchemaInheritanceDepth the max length of the inheritance chain of the custom ui schema
+const maxUISchemaInheritanceDepth = 10
+
+func convertDefinitionBase(def unstructured.Unstructured, kind, systemNamespace string) (*apisv1.DefinitionBase, error) {
+	definition := convertDefinitionBrief(def, kind, systemNamespace)
+	if kind == kindComponentDefinition {
+		compDef := &v1beta1.ComponentDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, compDef); err != nil {
+			return nil, errors.Wrap(err, "invalid component definition")
+		}
+		definition.WorkloadType = compDef.Spec.Workload.Type
+		definition.Component = &compDef.Spec
+	}
+	if kind == kindTraitDefinition {
+		traitDef := &v1beta1.TraitDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, traitDef); err != nil {
+			return nil, errors.Wrap(err, "invalid trait definition")
+		}
+		definition.Trait = &traitDef.Spec
+	}
+	if kind == kindWorkflowStepDefinition {
+		workflowStepDef := &v1beta1.WorkflowStepDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, workflowStepDef); err != nil {
+			return nil, errors.Wrap(err, "invalid trait definition")
+		}
+		definition.WorkflowStep = &workflowStepDef.Spec
+	}
+	if kind == kindPolicyDefinition {
+		policyDef := &v1beta1.PolicyDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, policyDef); err != nil {
+			return nil, errors.Wrap(err, "invalid trait definition")
+		}
+		definition.Policy = &policyDef.Spec
+	}
+	return definition, nil
+}
+
+// convertDefinitionBrief only convert the identity and the description fields of the definition, the spec is skipped
+func convertDefinitionBrief(def unstructured.Unstructured, kind, systemNamespace string) *apisv1.DefinitionBase {
+	definition := &apisv1.DefinitionBase{
+		Name:        def.GetName(),
+		Alias:       def.GetAnnotations()[types.AnnoDefinitionAlias],
+		Description: def.GetAnnotations()[types.AnnoDefinitionDescription],
+		Icon:        def.GetAnnotations()[types.AnnoDefinitionIcon],
+		Labels:      def.GetLabels(),
+		Category:    getDefinitionCategory(def),
+		Status: func() string {
+			if _, exist := def.GetLabels()[types.LabelDefinitionHidden]; exist {
+				return "disable"
+			}
+			return "enable"
+		}(),
+	}
+	// Set OwnerAddon field
+	for _, ownerRef := range def.GetOwnerReferences() {
+		if strings.HasPrefix(ownerRef.Name, addon.AddonAppPrefix) {
+			definition.OwnerAddon = addon.AppName2Addon(ownerRef.Name)
+			// We are only interested in one owner addon
+			break
+		}
+	}
+	definition.BuiltIn = definition.OwnerAddon == "" && def.GetNamespace() == systemNamespace
+	definition.Dependencies = parseDefinitionDependencies(def.GetAnnotations()[AnnoDefinitionDependsOn], getDefinitionType(kind))
+	definition.Deprecated = parseDefinitionDeprecation(def)
+	if kind == kindComponentDefinition {
+		definition.WorkloadType, _, _ = unstructured.NestedString(def.Object, "spec", "workload", "type")
+	}
+	return definition
+}
+
+// DetailDefinition get definition detail
+func (d *definitionServiceImpl) DetailDefinition(ctx context.Context, name, defType string, ops DetailDefinitionOption) (*apisv1.DetailDefinitionResponse, error) {
+	clusterCtx := withDefinitionCluster(ctx, ops.Cluster)
+	if defType == "" {
+		detected, err := d.detectDefinitionType(clusterCtx, name)
+		if err != nil {
+			return nil, err
+		}
+		defType = detected
+	}
+	def := &unstructured.Unstructured{}
+	version, kind, err := getKindAndVersion(defType)
+	if err != nil {
+		return nil, err
+	}
+	def.SetAPIVersion(version)
+	def.SetKind(kind)
+	if err := d.KubeClient.Get(clusterCtx, k8stypes.NamespacedName{Namespace: d.systemNamespace(), Name: name}, def); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		// the exact name is matched at first, then try the name as the alias
+		def, err = d.getDefinitionByAlias(clusterCtx, version, kind, name)
+		if err != nil {
+			return nil, err
+		}
+		name = def.GetName()
+	}
+	_, hidden := def.GetLabels()[types.LabelDefinitionHidden]
+	if hidden && ops.ExcludeHidden && !d.isPlatformAdmin(ctx) {
+		return nil, bcode.ErrDefinitionHidden
+	}
+	apiSchema, schemaVersion, err := d.getCachedDefinitionSchema(clusterCtx, ops.Cluster, def, defType, ops.NoCache)
+	var schemaErr *definitionSchemaError
+	if errors.As(err, &schemaErr) {
+		// the broken schema should not break the detail, it is returned without the schema
+		klog.Warning(schemaErr.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	uiSchemaCM := getCustomUISchemaConfigMap(ctx, d.KubeClient, d.systemNamespace(), name, defType)
+	uiSchemaCMs := listInheritedUISchemaConfigMaps(ctx, d.KubeClient, uiSchemaCM, defType)
+	etag := computeDefinitionETag(ops.Cluster, ops.Locale, def, schemaVersion, uiSchemaCMs)
+	if matchETag(ops.IfNoneMatch, etag) {
+		return &apisv1.DetailDefinitionResponse{ETag: etag}, bcode.ErrDefinitionNotModified
+	}
+
+	base, err := convertDefinitionBase(*def, kind, d.systemNamespace())
+	if err != nil {
+		return nil, err
+	}
+	template := renderDefinitionTemplate(base)
+	withheld := hasSensitiveDefaults(apiSchema)
+	if withheld {
+		// the template declares the default values of the sensitive parameters, so neither it nor the schematic is returned
+		template = ""
+		clearDefinitionSchematic(base)
+	}
+	definition := &apisv1.DetailDefinitionResponse{
+		DefinitionBase:   *base,
+		APISchema:        redactSensitiveDefaults(apiSchema),
+		Template:         template,
+		TemplateWithheld: withheld,
+		HiddenInUI:       hidden,
+		ETag:             etag,
+	}
+	definition.SchemaReady = schemaVersion != ""
+	if schemaErr != nil {
+		definition.SchemaError = schemaErr.Error()
+	}
+
+	if uiSchemaCM != nil {
+		definition.LastModifiedBy = uiSchemaCM.Annotations[AnnoUISchemaLastModifiedBy]
+		if modifiedTime, err := time.Parse(time.RFC3339, uiSchemaCM.Annotations[AnnoUISchemaLastModifiedTime]); err == nil {
+			definition.LastModifiedTime = &modifiedTime
+		}
+	}
+	if definition.APISchema != nil {
+		// render default ui schema
+		defaultUISchema, variantGroups := renderObjectParameters(definition.APISchema, ops.Locale)
+		// patch from custom ui schema, the inherited custom ui schemas are patched at first
+		definition.UISchema = defaultUISchema
+		for _, cm := range uiSchemaCMs {
+			definition.UISchema = renderCustomUISchema(cm, definition.UISchema)
+		}
+		definition.Placeholders = renderSchemaPlaceholders(definition.APISchema)
+		definition.UIGroups = renderUIGroups(definition.APISchema, definition.UISchema, variantGroups)
+		definition.UIHints = renderSchemaUIHints(definition.APISchema)
+	}
+
+	return definition, nil
+}
+
+// detectDefinitionType probe the definition of all types by the name, it is used when the caller only knows the name.
+// The alias is not matched, there must be exactly one definition with the name.
+func (d *definitionServiceImpl) detectDefinitionType(ctx context.Context, name string) (string, error) {
+	var matched []string
+	for _, defType := range definitionTypes {
+		version, kind, err := getKindAndVersion(defType)
+		if err != nil {
+			return "", err
+		}
+		def := &unstructured.Unstructured{}
+		def.SetAPIVersion(version)
+		def.SetKind(kind)
+		if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: d.systemNamespace(), Name: name}, def); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		matched = append(matched, defType)
+	}
+	switch len(matched) {
+	case 0:
+		return "", bcode.ErrDefinitionNotFound
+	case 1:
+		return matched[0], nil
+	default:
+		return "", bcode.ErrDefinitionTypeAmbiguous.SetDetails(matched)
+	}
+}
+
+// getDefinitionByAlias get the only definition of the kind that has the alias
+func (d *definitionServiceImpl) getDefinitionByAlias(ctx context.Context, version, kind, alias string) (*unstructured.Unstructured, error) {
+	defs := &unstructured.UnstructuredList{}
+	defs.SetAPIVersion(version)
+	defs.SetKind(kind)
+	if err := d.KubeClient.List(ctx, defs, client.InNamespace(d.systemNamespace())); err != nil {
+		return nil, err
+	}
+	matched := matchDefinitionAlias(defs.Items, alias)
+	switch len(matched) {
+	case 0:
+		return nil, bcode.ErrDefinitionNotFound
+	case 1:
+		return &matched[0], nil
+	default:
+		var names []string
+		for _, def := range matched {
+			names = append(names, def.GetName())
+		}
+		sort.Strings(names)
+		return nil, bcode.ErrDefinitionAliasAmbiguous.SetDetails(names)
+	}
+}
+
+// matchDefinitionAlias return the definitions whose alias is the same as the given one
+func matchDefinitionAlias(defs []unstructured.Unstructured, alias string) []unstructured.Unstructured {
+	var matched []unstructured.Unstructured
+	if alias == "" {
+		return matched
+	}
+	for _, def := range defs {
+		if def.GetAnnotations()[types.AnnoDefinitionAlias] == alias {
+			matched = append(matched, def)
+		}
+	}
+	return matched
+}
+
+// isPlatformAdmin check whether the login user has the platform admin role
+func (d *definitionServiceImpl) isPlatformAdmin(ctx context.Context) bool {
+	userName, ok := ctx.Value(&apisv1.CtxKeyUser).(string)
+	if !ok || userName == "" {
+		return false
+	}
+	user := &model.User{Name: userName}
+	if err := d.Store.Get(ctx, user); err != nil {
+		return false
+	}
+	return user.IsAdmin()
+}
+
+// AddFavoriteDefinition pin the definition for the login user, it's fine to pin a definition twice
+func (d *definitionServiceImpl) AddFavoriteDefinition(ctx context.Context, name, defType string) error {
+	userName, ok := ctx.Value(&apisv1.CtxKeyUser).(string)
+	if !ok || userName == "" {
+		return bcode.ErrUnauthorized
+	}
+	version, kind, err := getKindAndVersion(defType)
+	if err != nil {
+		return err
+	}
+	def := &unstructured.Unstructured{}
+	def.SetAPIVersion(version)
+	def.SetKind(kind)
+	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: d.systemNamespace(), Name: name}, def); err != nil {
+		if apierrors.IsNotFound(err) {
+			return bcode.ErrDefinitionNotFound
+		}
+		return err
+	}
+	favorite := &model.DefinitionFavorite{Username: userName, DefinitionName: name, DefinitionType: defType}
+	if err := d.Store.Add(ctx, favorite); err != nil && !errors.Is(err, datastore.ErrRecordExist) {
+		return err
+	}
+	return nil
+}
+
+// RemoveFavoriteDefinition unpin the definition for the login user, it's fine if the definition is not pinned
+func (d *definitionServiceImpl) RemoveFavoriteDefinition(ctx context.Context, name, defType string) error {
+	userName, ok := ctx.Value(&apisv1.CtxKeyUser).(string)
+	if !ok || userName == "" {
+		return bcode.ErrUnauthorized
+	}
+	if _, _, err := getKindAndVersion(defType); err != nil {
+		return err
+	}
+	favorite := &model.DefinitionFavorite{Username: userName, DefinitionName: name, DefinitionType: defType}
+	if err := d.Store.Delete(ctx, favorite); err != nil && !errors.Is(err, datastore.ErrRecordNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ListFavoriteDefinitions list the definitions pinned by the login user, the definitions that have been deleted are skipped
+func (d *definitionServiceImpl) ListFavoriteDefinitions(ctx context.Context, defType string) ([]*apisv1.DefinitionBase, error) {
+	userName, ok := ctx.Value(&apisv1.CtxKeyUser).(string)
+	if !ok || userName == "" {
+		return nil, bcode.ErrUnauthorized
+	}
+	if defType != "" {
+		if _, _, err := getKindAndVersion(defType); err != nil {
+			return nil, err
+		}
+	}
+	entities, err := d.Store.List(ctx, &model.DefinitionFavorite{Username: userName, DefinitionType: defType}, &datastore.ListOptions{
+		SortBy: []datastore.SortOption{{Key: "createTime", Order: datastore.SortOrderAscending}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defs := []*apisv1.DefinitionBase{}
+	for _, entity := range entities {
+		favorite := entity.(*model.DefinitionFavorite)
+		version, kind, err := getKindAndVersion(favorite.DefinitionType)
+		if err != nil {
+			continue
+		}
+		def := &unstructured.Unstructured{}
+		def.SetAPIVersion(version)
+		def.SetKind(kind)
+		if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: d.systemNamespace(), Name: favorite.DefinitionName}, def); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		definition := convertDefinitionBrief(*def, kind, d.systemNamespace())
+		definition.Favorited = true
+		defs = append(defs, definition)
+	}
+	return defs, nil
+}
+
+// listUserFavorites return the names of the definitions of the type pinned by the login user, return nil if no user is login
+func (d *definitionServiceImpl) listUserFavorites(ctx context.Context, defType string) (map[string]bool, error) {
+	userName, ok := ctx.Value(&apisv1.CtxKeyUser).(string)
+	if !ok || userName == "" || d.Store == nil {
+		return nil, nil
+	}
+	entities, err := d.Store.List(ctx, &model.DefinitionFavorite{Username: userName, DefinitionType: defType}, &datastore.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	favorites := make(map[string]bool, len(entities))
+	for _, entity := range entities {
+		favorites[entity.(*model.DefinitionFavorite).DefinitionName] = true
+	}
+	return favorites, nil
+}
+
+// withDefinitionCluster return the context to read the definitions from the cluster, the control plane is used if the cluster is empty
+func withDefinitionCluster(ctx context.Context, cluster string) context.Context {
+	if cluster == "" || cluster == multicluster.ClusterLocalName {
+		return ctx
+	}
+	return multicluster.ContextWithClusterName(ctx, cluster)
+}
+
+// getDefinitionSchema load the parameter schema of the definition, the change of the latest schema is notified to the callbacks
+func (d *definitionServiceImpl) getDefinitionSchema(ctx context.Context, name, defType, revision string) (*openapi3.Schema, error) {
+	apiSchema, _, err := d.getDefinitionSchemaWithVersion(ctx, name, defType, revision)
+	return apiSchema, err
+}
+
+// getDefinitionSchemaWithVersion get the schema of the definition and the resource version of the schema configmap
+func (d *definitionServiceImpl) getDefinitionSchemaWithVersion(ctx context.Context, name, defType, revision string) (*openapi3.Schema, string, error) {
+	apiSchema, resourceVersion, err := loadDefinitionSchema(ctx, d.KubeClient, d.systemNamespace(), name, defType, revision)
+	if err != nil {
+		return nil, resourceVersion, err
+	}
+	if revision == "" && resourceVersion != "" {
+		d.schemaWatcher.observe(ctx, name, defType, resourceVersion)
+	}
+	return apiSchema, resourceVersion, nil
+}
+
+// definitionSchemaCacheDuration how long the schema of the definition is cached at most
+const definitionSchemaCacheDuration = time.Minute
+
+// cachedDefinitionSchema the schema of the definition cached for the resource version of the definition
+type cachedDefinitionSchema struct {
+	definitionVersion string
+	schema            *openapi3.Schema
+	schemaVersion     string
+}
+
+// getCachedDefinitionSchema get the latest schema of the definition from the cache if the definition is not changed,
+// otherwise load it from the cluster and cache it. The cache is skipped but refreshed if noCache is true.
+func (d *definitionServiceImpl) getCachedDefinitionSchema(ctx context.Context, cluster string, def *unstructured.Unstructured, defType string, noCache bool) (*openapi3.Schema, string, error) {
+	if d.schemaCache == nil {
+		return d.getDefinitionSchemaWithVersion(ctx, def.GetName(), defType, "")
+	}
+	key := fmt.Sprintf("%s/%s/%s/%s", cluster, d.systemNamespace(), defType, def.GetName())
+	if !noCache {
+		cached, ok := d.schemaCache.Get(key).(*cachedDefinitionSchema)
+		if ok && cached.definitionVersion == def.GetResourceVersion() {
+			d.schemaCacheHits.Add(1)
+			return cached.schema, cached.schemaVersion, nil
+		}
+		d.schemaCacheMisses.Add(1)
+		if ok {
+			d.schemaCacheInvalidations.Add(1)
+		}
+	}
+	apiSchema, schemaVersion, err := d.getDefinitionSchemaWithVersion(ctx, def.GetName(), defType, "")
+	if err != nil {
+		return nil, schemaVersion, err
+	}
+	d.schemaCache.Put(key, &cachedDefinitionSchema{
+		definitionVersion: def.GetResourceVersion(),
+		schema:            apiSchema,
+		schemaVersion:     schemaVersion,
+	}, definitionSchemaCacheDuration)
+	return apiSchema, schemaVersion, nil
+}
+
+// SchemaCacheStats return the counters of the definition schema cache since the service started.
+// The evictions include the expired schemas and the schemas dropped because the definition is changed.
+func (d *definitionServiceImpl) SchemaCacheStats(ctx context.Context) (*apisv1.DefinitionSchemaCacheStats, error) {
+	stats := &apisv1.DefinitionSchemaCacheStats{
+		Enabled:   d.schemaCache != nil,
+		Hits:      d.schemaCacheHits.Load(),
+		Misses:    d.schemaCacheMisses.Load(),
+		Evictions: d.schemaCacheInvalidations.Load(),
+	}
+	if d.schemaCache != nil {
+		stats.Entries = d.schemaCache.Len()
+		stats.Evictions += d.schemaCache.Evictions()
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	return stats, nil
+}
+
+// computeDefinitionETag compute the ETag of the definition detail from the versions of everything it is rendered from,
+// including the definition, the schema and the custom ui schemas of the inheritance chain.
+func computeDefinitionETag(cluster, locale string, def *unstructured.Unstructured, schemaVersion string, uiSchemaCMs []*v1.ConfigMap) string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "cluster:%q/definition:%q/%q/schema:%q", cluster, def.GetUID(), def.GetResourceVersion(), schemaVersion)
+	if locale != "" {
+		_, _ = fmt.Fprintf(hash, "/locale:%q", locale)
+	}
+	for _, cm := range uiSchemaCMs {
+		_, _ = fmt.Fprintf(hash, "/uischema:%q/%q", cm.Name, cm.ResourceVersion)
+	}
+	return fmt.Sprintf("%q", hex.EncodeToString(hash.Sum(nil))[:32])
+}
+
+// matchETag check whether the ETag is one of the ETags in the If-None-Match header, the weak ETags are compared by the value
+func matchETag(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDefinitionSchema load the parameter schema of the definition and the resource version from the schema configmap.
+// The revision could be like v1 or 1, the latest schema is loaded if it is empty. Return nil if the schema is not found.
+func loadDefinitionSchema(ctx context.Context, cli client.Client, namespace, name, defType, revision string) (*openapi3.Schema, string, error) {
+	schemaName := name
+	if revision != "" {
+		schemaName = fmt.Sprintf("%s-v%s", name, strings.TrimPrefix(revision, "v"))
+	}
+	var cm v1.ConfigMap
+	if err := cli.Get(ctx, k8stypes.NamespacedName{
+		Namespace: namespace,
+		Name:      schemaConfigMapName(defType, schemaName),
+	}, &cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	data, ok := cm.Data[types.OpenapiV3JSONSchema]
+	if !ok {
+		return nil, cm.ResourceVersion, nil
+	}
+	apiSchema := &openapi3.Schema{}
+	if err := apiSchema.UnmarshalJSON([]byte(data)); err != nil {
+		return nil, cm.ResourceVersion, &definitionSchemaError{name: name, defType: defType, err: err}
+	}
+	return apiSchema, cm.ResourceVersion, nil
+}
+
+// definitionSchemaError the schema configmap of the definition exists but the schema can't be parsed
+type definitionSchemaError struct {
+	name    string
+	defType string
+	err     error
+}
+
+func (e *definitionSchemaError) Error() string {
+	return fmt.Sprintf("the schema of the %s definition %s is invalid: %s", e.defType, e.name, e.err.Error())
+}
+
+func (e *definitionSchemaError) Unwrap() error {
+	return e.err
+}
+
+// DefinitionSchemaChange the change of the schema configmap of the definition
+type DefinitionSchemaChange struct {
+	Name               string
+	Type               string
+	OldResourceVersion string
+	NewResourceVersion string
+}
+
+// DefinitionSchemaChangeCallback is invoked when the schema of the definition is changed, it should not block
+type DefinitionSchemaChangeCallback func(ctx context.Context, change DefinitionSchemaChange)
+
+// NoopDefinitionSchemaChangeCallback the default callback that ignores the changes
+func NoopDefinitionSchemaChangeCallback(ctx context.Context, change DefinitionSchemaChange) {}
+
+// definitionSchemaWatcher track the resource versions of the schema configmaps that have been read,
+// the callbacks are invoked when a newer resource version is read.
+type definitionSchemaWatcher struct {
+	mutex     sync.Mutex
+	versions  map[string]string
+	callbacks []DefinitionSchemaChangeCallback
+}
+
+func newDefinitionSchemaWatcher() *definitionSchemaWatcher {
+	return &definitionSchemaWatcher{
+		versions:  map[string]string{},
+		callbacks: []DefinitionSchemaChangeCallback{NoopDefinitionSchemaChangeCallback},
+	}
+}
+
+func (w *definitionSchemaWatcher) register(callback DefinitionSchemaChangeCallback) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.callbacks = append(w.callbacks, callback)
+}
+
+// observe record the resource version of the schema, the first version of a schema is only recorded
+func (w *definitionSchemaWatcher) observe(ctx context.Context, name, defType, resourceVersion string) {
+	if w == nil {
+		return
+	}
+	key := fmt.Sprintf("%s/%s/%s", multicluster.ClusterNameInContext(ctx), defType, name)
+	w.mutex.Lock()
+	oldVersion, exist := w.versions[key]
+	w.versions[key] = resourceVersion
+	callbacks := w.callbacks
+	w.mutex.Unlock()
+	if !exist || oldVersion == resourceVersion {
+		return
+	}
+	change := DefinitionSchemaChange{Name: name, Type: defType, OldResourceVersion: oldVersion, NewResourceVersion: resourceVersion}
+	for _, callback := range callbacks {
+		callback(ctx, change)
+	}
+}
+
+// RegisterSchemaChangeCallback register the callback invoked when the schema of a definition is changed
+func (d *definitionServiceImpl) RegisterSchemaChangeCallback(callback DefinitionSchemaChangeCallback) {
+	if d.schemaWatcher == nil {
+		d.schemaWatcher = newDefinitionSchemaWatcher()
+	}
+	d.schemaWatcher.register(callback)
+}
+
+// jsonSchemaDraft07 the meta schema of the exported JSON schema
+const jsonSchemaDraft07 = "http://json-schema.org/draft-07/schema#"
+
+// ExportDefinitionJSONSchema convert the parameter schema of the definition to the draft-07 JSON schema
+func (d *definitionServiceImpl) ExportDefinitionJSONSchema(ctx context.Context, name, defType string) ([]byte, error) {
+	if _, _, err := getKindAndVersion(defType); err != nil {
+		return nil, err
+	}
+	apiSchema, err := d.getDefinitionSchema(ctx, name, defType, "")
+	if err != nil {
+		return nil, err
+	}
+	if apiSchema == nil {
+		return nil, bcode.ErrDefinitionNoSchema
+	}
+	jsonSchema := convertToJSONSchema(redactSensitiveDefaults(apiSchema), map[*openapi3.Schema]bool{})
+	jsonSchema["$schema"] = jsonSchemaDraft07
+	if _, ok := jsonSchema["title"]; !ok {
+		jsonSchema["title"] = name
+	}
+	return json.MarshalIndent(jsonSchema, "", "  ")
+}
+
+// ValidateParameters validate the parameter values against the schema of the definition, all of the field errors are returned
+func (d *definitionServiceImpl) ValidateParameters(ctx context.Context, name, defType string, values map[string]interface{}) (*apisv1.ValidateParametersResponse, error) {
+	if _, _, err := getKindAndVersion(defType); err != nil {
+		return nil, err
+	}
+	apiSchema, err := d.getDefinitionSchema(ctx, name, defType, "")
+	if err != nil {
+		return nil, err
+	}
+	if apiSchema == nil {
+		return nil, bcode.ErrDefinitionNoSchema
+	}
+	return validateParameters(apiSchema, values)
+}
+
+// RenderUISchemaForSchema render the default ui schema for the openapi schema in JSON, it previews the ui schema
+// of the definition that is being designed.
+func (d *definitionServiceImpl) RenderUISchemaForSchema(ctx context.Context, openapiJSON []byte) ([]*schema.UIParameter, error) {
+	if len(bytes.TrimSpace(openapiJSON)) == 0 {
+		return nil, bcode.ErrDefinitionSchemaInvalid.SetMessage("the openapi schema is empty")
+	}
+	apiSchema := &openapi3.Schema{}
+	if err := apiSchema.UnmarshalJSON(openapiJSON); err != nil {
+		return nil, bcode.ErrDefinitionSchemaInvalid.SetMessage(fmt.Sprintf("the openapi schema is invalid: %s", err.Error()))
+	}
+	if err := apiSchema.Validate(ctx); err != nil {
+		return nil, bcode.ErrDefinitionSchemaInvalid.SetMessage(fmt.Sprintf("the openapi schema is invalid: %s", err.Error()))
+	}
+	return renderDefaultUISchema(apiSchema, ""), nil
+}
+
+func validateParameters(apiSchema *openapi3.Schema, values map[string]interface{}) (*apisv1.ValidateParametersResponse, error) {
+	// the schema only accepts the JSON types, so convert the values such as []string at first
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, bcode.ErrInvalidProperties
+	}
+	var value interface{} = map[string]interface{}{}
+	if values != nil {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, bcode.ErrInvalidProperties
+		}
+	}
+	resp := &apisv1.ValidateParametersResponse{Valid: true}
+	if err := apiSchema.VisitJSON(value, openapi3.MultiErrors()); err != nil {
+		resp.Valid = false
+		resp.Errors = collectParameterErrors(err)
+		sort.SliceStable(resp.Errors, func(i, j int) bool {
+			return resp.Errors[i].Field < resp.Errors[j].Field
+		})
+	}
+	return resp, nil
+}
+
+// collectParameterErrors flatten the errors of the schema validation into the field errors
+func collectParameterErrors(err error) []*apisv1.ParameterError {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var errs []*apisv1.ParameterError
+		for _, item := range e {
+			errs = append(errs, collectParameterErrors(item)...)
+		}
+		return errs
+	case *openapi3.SchemaError:
+		return []*apisv1.ParameterError{{Field: strings.Join(e.JSONPointer(), "."), Rule: e.SchemaField, Reason: e.Reason}}
+	default:
+		return []*apisv1.ParameterError{{Reason: err.Error()}}
+	}
+}
+
+// convertToJSONSchema convert the openapi schema to the draft-07 JSON schema.
+// The resolved refs are inlined, the unresolved or recursive refs are converted to the empty schema which accepts any value.
+func convertToJSONSchema(apiSchema *openapi3.Schema, visiting map[*openapi3.Schema]bool) map[string]interface{} {
+	out := map[string]interface{}{}
+	if apiSchema == nil || visiting[apiSchema] {
+		return out
+	}
+	visiting[apiSchema] = true
+	defer delete(visiting, apiSchema)
+
+	convertRef := func(ref *openapi3.SchemaRef) map[string]interface{} {
+		if ref == nil || ref.Value == nil {
+			return map[string]interface{}{}
+		}
+		return convertToJSONSchema(ref.Value, visiting)
+	}
+	convertRefs := func(refs openapi3.SchemaRefs) []interface{} {
+		var schemas []interface{}
+		for _, ref := range refs {
+			schemas = append(schemas, convertRef(ref))
+		}
+		return schemas
+	}
+
+	if apiSchema.Type != "" {
+		if apiSchema.Nullable {
+			out["type"] = []string{apiSchema.Type, "null"}
+		} else {
+			out["type"] = apiSchema.Type
+		}
+	}
+	for key, value := range map[string]string{
+		"title":       apiSchema.Title,
+		"description": apiSchema.Description,
+		"format":      apiSchema.Format,
+		"pattern":     apiSchema.Pattern,
+	} {
+		if value != "" {
+			out[key] = value
+		}
+	}
+	if len(apiSchema.Enum) > 0 {
+		enum := apiSchema.Enum
+		if apiSchema.Nullable {
+			enum = append(append([]interface{}{}, enum...), nil)
+		}
+		out["enum"] = enum
+	}
+	if apiSchema.Default != nil {
+		out["default"] = apiSchema.Default
+	}
+	// the example of openapi is the examples array of JSON schema
+	if apiSchema.Example != nil {
+		out["examples"] = []interface{}{apiSchema.Example}
+	}
+	if apiSchema.ReadOnly {
+		out["readOnly"] = true
+	}
+	if apiSchema.WriteOnly {
+		out["writeOnly"] = true
+	}
+
+	// the exclusive bounds are booleans in openapi, but numbers in draft-07
+	if apiSchema.Min != nil {
+		if apiSchema.ExclusiveMin {
+			out["exclusiveMinimum"] = *apiSchema.Min
+		} else {
+			out["minimum"] = *apiSchema.Min
+		}
+	}
+	if apiSchema.Max != nil {
+		if apiSchema.ExclusiveMax {
+			out["exclusiveMaximum"] = *apiSchema.Max
+		} else {
+			out["maximum"] = *apiSchema.Max
+		}
+	}
+	if apiSchema.MultipleOf != nil {
+		out["multipleOf"] = *apiSchema.MultipleOf
+	}
+	if apiSchema.MinLength > 0 {
+		out["minLength"] = apiSchema.MinLength
+	}
+	if apiSchema.MaxLength != nil {
+		out["maxLength"] = *apiSchema.MaxLength
+	}
+
+	if apiSchema.Items != nil {
+		out["items"] = convertRef(apiSchema.Items)
+	}
+	if apiSchema.MinItems > 0 {
+		out["minItems"] = apiSchema.MinItems
+	}
+	if apiSchema.MaxItems != nil {
+		out["maxItems"] = *apiSchema.MaxItems
+	}
+	if apiSchema.UniqueItems {
+		out["uniqueItems"] = true
+	}
+
+	if len(apiSchema.Properties) > 0 {
+		properties := map[string]interface{}{}
+		for key, property := range apiSchema.Properties {
+			properties[key] = convertRef(property)
+		}
+		out["properties"] = properties
+	}
+	if len(apiSchema.Required) > 0 {
+		out["required"] = apiSchema.Required
+	}
+	if apiSchema.MinProps > 0 {
+		out["minProperties"] = apiSchema.MinProps
+	}
+	if apiSchema.MaxProps != nil {
+		out["maxProperties"] = *apiSchema.MaxProps
+	}
+	switch {
+	case apiSchema.AdditionalProperties != nil:
+		out["additionalProperties"] = convertRef(apiSchema.AdditionalProperties)
+	case apiSchema.AdditionalPropertiesAllowed != nil:
+		out["additionalProperties"] = *apiSchema.AdditionalPropertiesAllowed
+	}
+
+	if len(apiSchema.OneOf) > 0 {
+		out["oneOf"] = convertRefs(apiSchema.OneOf)
+	}
+	if len(apiSchema.AnyOf) > 0 {
+		out["anyOf"] = convertRefs(apiSchema.AnyOf)
+	}
+	if len(apiSchema.AllOf) > 0 {
+		out["allOf"] = convertRefs(apiSchema.AllOf)
+	}
+	if apiSchema.Not != nil {
+		out["not"] = convertRef(apiSchema.Not)
+	}
+	return out
+}
+
+// DiffDefinitionSchema compare the parameter schemas of two revisions of the definition
+func (d *definitionServiceImpl) DiffDefinitionSchema(ctx context.Context, name, defType string, fromRev, toRev string) (*apisv1.DefinitionSchemaDiffResponse, error) {
+	if _, _, err := getKindAndVersion(defType); err != nil {
+		return nil, err
+	}
+	fromSchema, err := d.getDefinitionSchema(ctx, name, defType, fromRev)
+	if err != nil {
+		return nil, err
+	}
+	toSchema, err := d.getDefinitionSchema(ctx, name, defType, toRev)
+	if err != nil {
+		return nil, err
+	}
+	if fromSchema == nil || toSchema == nil {
+		return nil, bcode.ErrDefinitionRevisionNotFound
+	}
+	return diffDefinitionSchema(fromSchema, toSchema, &apisv1.DefinitionSchemaDiffResponse{
+		Name:         name,
+		Type:         defType,
+		FromRevision: fromRev,
+		ToRevision:   toRev,
+	}), nil
+}
+
+func diffDefinitionSchema(fromSchema, toSchema *openapi3.Schema, diff *apisv1.DefinitionSchemaDiffResponse) *apisv1.DefinitionSchemaDiffResponse {
+	fromProperties := make(map[string]*apisv1.DefinitionPropertySummary)
+	flattenSchemaProperties("", fromSchema, fromProperties)
+	toProperties := make(map[string]*apisv1.DefinitionPropertySummary)
+	flattenSchemaProperties("", toSchema, toProperties)
 
-func convertDefinitionBase(def unstructured.Unstructured, kind string) (*apisv1.DefinitionBase, error) {
-	definition := &apisv1.DefinitionBase{
-		Name:        def.GetName(),
-		Alias:       def.GetAnnotations()[types.AnnoDefinitionAlias],
-		Description: def.GetAnnotations()[types.AnnoDefinitionDescription],
-		Icon:        def.GetAnnotations()[types.AnnoDefinitionIcon],
-		Labels:      def.GetLabels(),
-		Category:    def.GetAnnotations()[AnnoDefinitionCategory],
-		Status: func() string {
-			if _, exist := def.GetLabels()[types.LabelDefinitionHidden]; exist {
-				return "disable"
-			}
-			return "enable"
-		}(),
+	var paths []string
+	for path := range fromProperties {
+		paths = append(paths, path)
 	}
-	// Set OwnerAddon field
-	for _, ownerRef := range def.GetOwnerReferences() {
-		if strings.HasPrefix(ownerRef.Name, addon.AddonAppPrefix) {
-			definition.OwnerAddon = addon.AppName2Addon(ownerRef.Name)
-			// We are only interested in one owner addon
-			break
+	for path := range toProperties {
+		if _, exist := fromProperties[path]; !exist {
+			paths = append(paths, path)
 		}
 	}
-	if kind == kindComponentDefinition {
-		compDef := &v1beta1.ComponentDefinition{}
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, compDef); err != nil {
-			return nil, errors.Wrap(err, "invalid component definition")
+	sort.Strings(paths)
+	diff.Added = []apisv1.DefinitionPropertyDiff{}
+	diff.Removed = []apisv1.DefinitionPropertyDiff{}
+	diff.Changed = []apisv1.DefinitionPropertyDiff{}
+	for _, path := range paths {
+		from, to := fromProperties[path], toProperties[path]
+		switch {
+		case from == nil:
+			diff.Added = append(diff.Added, apisv1.DefinitionPropertyDiff{Path: path, To: to})
+		case to == nil:
+			diff.Removed = append(diff.Removed, apisv1.DefinitionPropertyDiff{Path: path, From: from})
+		case !reflect.DeepEqual(from, to):
+			diff.Changed = append(diff.Changed, apisv1.DefinitionPropertyDiff{Path: path, From: from, To: to})
 		}
-		definition.WorkloadType = compDef.Spec.Workload.Type
-		definition.Component = &compDef.Spec
 	}
-	if kind == kindTraitDefinition {
-		traitDef := &v1beta1.TraitDefinition{}
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, traitDef); err != nil {
-			return nil, errors.Wrap(err, "invalid trait definition")
-		}
-		definition.Trait = &traitDef.Spec
+	return diff
+}
+
+// flattenSchemaProperties collect the properties of the schema by the dot separated path, the items of an array is suffixed with []
+func flattenSchemaProperties(prefix string, apiSchema *openapi3.Schema, into map[string]*apisv1.DefinitionPropertySummary) {
+	if apiSchema == nil {
+		return
 	}
-	if kind == kindWorkflowStepDefinition {
-		workflowStepDef := &v1beta1.WorkflowStepDefinition{}
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, workflowStepDef); err != nil {
-			return nil, errors.Wrap(err, "invalid trait definition")
+	for key, property := range apiSchema.Properties {
+		if property == nil || property.Value == nil {
+			continue
 		}
-		definition.WorkflowStep = &workflowStepDef.Spec
-	}
-	if kind == kindPolicyDefinition {
-		policyDef := &v1beta1.PolicyDefinition{}
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, policyDef); err != nil {
-			return nil, errors.Wrap(err, "invalid trait definition")
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		into[path] = &apisv1.DefinitionPropertySummary{
+			Type:        property.Value.Type,
+			Required:    utils.StringsContain(apiSchema.Required, key),
+			Default:     property.Value.Default,
+			Enum:        property.Value.Enum,
+			Description: property.Value.Description,
+		}
+		flattenSchemaProperties(path, property.Value, into)
+		if property.Value.Items != nil {
+			flattenSchemaProperties(path+"[]", property.Value.Items.Value, into)
 		}
-		definition.Policy = &policyDef.Spec
 	}
-	return definition, nil
 }
 
-// DetailDefinition get definition detail
-func (d *definitionServiceImpl) DetailDefinition(ctx context.Context, name, defType string) (*apisv1.DetailDefinitionResponse, error) {
-	def := &unstructured.Unstructured{}
-	version, kind, err := getKindAndVersion(defType)
-	if err != nil {
-		return nil, err
+// renderSchemaPlaceholders render the examples of the parameters as the placeholders, keyed by the parameter path.
+// The examples of the array and object parameters are rendered as JSON.
+func renderSchemaPlaceholders(apiSchema *openapi3.Schema) map[string]string {
+	placeholders := map[string]string{}
+	collectSchemaPlaceholders("", apiSchema, placeholders)
+	if len(placeholders) == 0 {
+		return nil
 	}
-	def.SetAPIVersion(version)
-	def.SetKind(kind)
-	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: types.DefaultKubeVelaNS, Name: name}, def); err != nil {
-		if apierrors.IsNotFound(err) {
-			return nil, bcode.ErrDefinitionNotFound
+	return placeholders
+}
+
+func collectSchemaPlaceholders(prefix string, apiSchema *openapi3.Schema, into map[string]string) {
+	if apiSchema == nil {
+		return
+	}
+	for key, property := range apiSchema.Properties {
+		if property == nil || property.Value == nil {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if placeholder, ok := renderPlaceholder(property.Value.Example); ok && !isSensitive(property.Value) {
+			into[path] = placeholder
+		}
+		collectSchemaPlaceholders(path, property.Value, into)
+		if property.Value.Items != nil && property.Value.Items.Value != nil {
+			if placeholder, ok := renderPlaceholder(property.Value.Items.Value.Example); ok && !isSensitive(property.Value) {
+				into[path+"[]"] = placeholder
+			}
+			collectSchemaPlaceholders(path+"[]", property.Value.Items.Value, into)
 		}
-		return nil, err
 	}
-	base, err := convertDefinitionBase(*def, kind)
-	if err != nil {
-		return nil, err
+}
+
+// renderPlaceholder stringify the example, the non-string examples are rendered as JSON
+func renderPlaceholder(example interface{}) (string, bool) {
+	switch v := example.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, v != ""
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			klog.Warningf("fail to render the example as the placeholder: %s", err.Error())
+			return "", false
+		}
+		return string(data), true
 	}
-	var cm v1.ConfigMap
-	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{
-		Namespace: types.DefaultKubeVelaNS,
-		Name:      fmt.Sprintf("%s-schema-%s", defType, name),
-	}, &cm); err != nil && !apierrors.IsNotFound(err) {
-		return nil, err
+}
+
+// clearDefinitionSchematic remove the schematic from the spec of the definition
+func clearDefinitionSchematic(base *apisv1.DefinitionBase) {
+	switch {
+	case base.Component != nil:
+		base.Component.Schematic = nil
+	case base.Trait != nil:
+		base.Trait.Schematic = nil
+	case base.WorkflowStep != nil:
+		base.WorkflowStep.Schematic = nil
+	case base.Policy != nil:
+		base.Policy.Schematic = nil
 	}
+}
 
-	definition := &apisv1.DetailDefinitionResponse{
-		DefinitionBase: *base,
+// renderDefinitionTemplate return the source of the definition schematic, return empty if the definition has no schematic
+func renderDefinitionTemplate(base *apisv1.DefinitionBase) string {
+	var schematic *common.Schematic
+	switch {
+	case base.Component != nil:
+		schematic = base.Component.Schematic
+	case base.Trait != nil:
+		schematic = base.Trait.Schematic
+	case base.WorkflowStep != nil:
+		schematic = base.WorkflowStep.Schematic
+	case base.Policy != nil:
+		schematic = base.Policy.Schematic
 	}
-	data, ok := cm.Data[types.OpenapiV3JSONSchema]
-	if ok {
-		schema := &openapi3.Schema{}
-		if err := schema.UnmarshalJSON([]byte(data)); err != nil {
-			return nil, err
+	if schematic == nil {
+		return ""
+	}
+	switch {
+	case schematic.CUE != nil:
+		return schematic.CUE.Template
+	case schematic.KUBE != nil:
+		return string(schematic.KUBE.Template.Raw)
+	case schematic.HELM != nil:
+		data, err := json.Marshal(schematic.HELM)
+		if err != nil {
+			klog.Errorf("marshal the helm schematic failure %s", err.Error())
+			return ""
 		}
-		definition.APISchema = schema
-		// render default ui schema
-		defaultUISchema := renderDefaultUISchema(schema)
-		// patch from custom ui schema
-		definition.UISchema = renderCustomUISchema(ctx, d.KubeClient, name, defType, defaultUISchema)
+		return string(data)
+	case schematic.Terraform != nil:
+		return schematic.Terraform.Configuration
 	}
-
-	return definition, nil
+	return ""
 }
 
-func renderCustomUISchema(ctx context.Context, cli client.Client, name, defType string, defaultSchema []*schema.UIParameter) []*schema.UIParameter {
+// getCustomUISchemaConfigMap return nil if the custom ui schema configmap does not exist
+func getCustomUISchemaConfigMap(ctx context.Context, cli client.Client, namespace, name, defType string) *v1.ConfigMap {
 	var cm v1.ConfigMap
 	if err := cli.Get(ctx, k8stypes.NamespacedName{
-		Namespace: types.DefaultKubeVelaNS,
+		Namespace: namespace,
 		Name:      fmt.Sprintf("%s-uischema-%s", defType, name),
 	}, &cm); err != nil {
 		if !apierrors.IsNotFound(err) {
 			klog.Errorf("find uischema configmap from cluster failure %s", err.Error())
 		}
+		return nil
+	}
+	return &cm
+}
+
+// listInheritedUISchemaConfigMaps return the custom ui schema configmaps of the inheritance chain, the root base is the first one
+func listInheritedUISchemaConfigMaps(ctx context.Context, cli client.Client, cm *v1.ConfigMap, defType string) []*v1.ConfigMap {
+	var chain []*v1.ConfigMap
+	visited := map[string]bool{}
+	for cm != nil {
+		if visited[cm.Name] || len(chain) >= maxUISchemaInheritanceDepth {
+			klog.Errorf("the inheritance of the custom ui schema %s is too deep or has a cycle", cm.Name)
+			break
+		}
+		visited[cm.Name] = true
+		chain = append([]*v1.ConfigMap{cm}, chain...)
+		base := cm.Annotations[AnnoUISchemaInheritsFrom]
+		if base == "" {
+			break
+		}
+		cm = getCustomUISchemaConfigMap(ctx, cli, cm.Namespace, base, defType)
+	}
+	return chain
+}
+
+// checkUISchemaInheritance check the inheritance chain from the base, it's invalid if it leads back to the definition
+func checkUISchemaInheritance(ctx context.Context, cli client.Client, namespace, name, defType, inheritsFrom string) error {
+	base := inheritsFrom
+	for depth := 0; base != ""; depth++ {
+		if base == name {
+			return bcode.ErrDefinitionUISchemaInheritanceCycle.SetMessage(fmt.Sprintf("the custom ui schema of %s can't inherit from %s, it makes a cycle", name, inheritsFrom))
+		}
+		if depth >= maxUISchemaInheritanceDepth {
+			return bcode.ErrDefinitionUISchemaInheritanceCycle.SetMessage(fmt.Sprintf("the inheritance of the custom ui schema is deeper than %d", maxUISchemaInheritanceDepth))
+		}
+		cm := getCustomUISchemaConfigMap(ctx, cli, namespace, base, defType)
+		if cm == nil {
+			return nil
+		}
+		base = cm.Annotations[AnnoUISchemaInheritsFrom]
+	}
+	return nil
+}
+
+func renderCustomUISchema(cm *v1.ConfigMap, defaultSchema []*schema.UIParameter) []*schema.UIParameter {
+	if cm == nil {
 		return defaultSchema
 	}
 	data, ok := cm.Data[types.UISchema]
@@ -308,22 +2036,40 @@ func renderCustomUISchema(ctx context.Context, cli client.Client, name, defType
 }
 
 // AddDefinitionUISchema add definition custom ui schema config
-func (d *definitionServiceImpl) AddDefinitionUISchema(ctx context.Context, name, defType string, schema []*schema.UIParameter) ([]*schema.UIParameter, error) {
-	dataBate, err := json.Marshal(schema)
+func (d *definitionServiceImpl) AddDefinitionUISchema(ctx context.Context, name, defType string, uiSchema []*schema.UIParameter, inheritsFrom string) ([]*schema.UIParameter, error) {
+	dataBate, err := json.Marshal(uiSchema)
 	if err != nil {
 		klog.Errorf("json marshal failure %s", err.Error())
 		return nil, bcode.ErrInvalidDefinitionUISchema
 	}
+	if err := checkUISchemaInheritance(ctx, d.KubeClient, d.systemNamespace(), name, defType, inheritsFrom); err != nil {
+		return nil, err
+	}
+	userName, _ := ctx.Value(&apisv1.CtxKeyUser).(string)
+	now := time.Now()
+	modifiedAnnotations := map[string]string{
+		AnnoUISchemaLastModifiedBy:   userName,
+		AnnoUISchemaLastModifiedTime: now.Format(time.RFC3339),
+	}
 	var cm v1.ConfigMap
+	if inheritsFrom != "" {
+		modifiedAnnotations[AnnoUISchemaInheritsFrom] = inheritsFrom
+	}
 	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{
-		Namespace: types.DefaultKubeVelaNS,
+		Namespace: d.systemNamespace(),
 		Name:      fmt.Sprintf("%s-uischema-%s", defType, name),
 	}, &cm); err != nil {
 		if apierrors.IsNotFound(err) {
+			modifiedAnnotations[AnnoUISchemaHistory] = appendUISchemaHistory(nil, &apisv1.UISchemaHistoryEntry{
+				ModifiedBy:   userName,
+				ModifiedTime: now,
+				Summary:      summarizeUISchemaChange(nil, uiSchema),
+			})
 			err = d.KubeClient.Create(ctx, &v1.ConfigMap{
 				ObjectMeta: metav1.ObjectMeta{
-					Namespace: types.DefaultKubeVelaNS,
-					Name:      fmt.Sprintf("%s-uischema-%s", defType, name),
+					Namespace:   d.systemNamespace(),
+					Name:        fmt.Sprintf("%s-uischema-%s", defType, name),
+					Annotations: modifiedAnnotations,
 				},
 				Data: map[string]string{
 					types.UISchema: string(dataBate),
@@ -334,13 +2080,157 @@ func (d *definitionServiceImpl) AddDefinitionUISchema(ctx context.Context, name,
 			return nil, err
 		}
 	} else {
+		var existing []*schema.UIParameter
+		if err := json.Unmarshal([]byte(cm.Data[types.UISchema]), &existing); err != nil {
+			klog.Warningf("the existing custom ui schema of %s is invalid: %s", name, err.Error())
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
 		cm.Data[types.UISchema] = string(dataBate)
+		if cm.Annotations == nil {
+			cm.Annotations = map[string]string{}
+		}
+		modifiedAnnotations[AnnoUISchemaHistory] = appendUISchemaHistory(parseUISchemaHistory(&cm), &apisv1.UISchemaHistoryEntry{
+			ModifiedBy:   userName,
+			ModifiedTime: now,
+			Summary:      summarizeUISchemaChange(existing, uiSchema),
+		})
+		delete(cm.Annotations, AnnoUISchemaInheritsFrom)
+		for k, v := range modifiedAnnotations {
+			cm.Annotations[k] = v
+		}
 		err := d.KubeClient.Update(ctx, &cm)
 		if err != nil {
 			return nil, err
 		}
 	}
-	res, err := d.DetailDefinition(ctx, name, defType)
+	res, err := d.DetailDefinition(ctx, name, defType, DetailDefinitionOption{})
+	if err != nil {
+		return nil, err
+	}
+	return res.UISchema, nil
+}
+
+// ListDefinitionUISchemaHistory list the edits of the custom ui schema, the history is removed along with the custom ui schema when it's reset
+func (d *definitionServiceImpl) ListDefinitionUISchemaHistory(ctx context.Context, name, defType string, page, pageSize int, modifiedBy string) (*apisv1.ListUISchemaHistoryResponse, error) {
+	if _, _, err := getKindAndVersion(defType); err != nil {
+		return nil, err
+	}
+	entries := []*apisv1.UISchemaHistoryEntry{}
+	if cm := getCustomUISchemaConfigMap(ctx, d.KubeClient, d.systemNamespace(), name, defType); cm != nil {
+		for _, entry := range parseUISchemaHistory(cm) {
+			if modifiedBy == "" || entry.ModifiedBy == modifiedBy {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	total := len(entries)
+	if page > 0 && pageSize > 0 {
+		start := (page - 1) * pageSize
+		if start > total {
+			start = total
+		}
+		end := start + pageSize
+		if end > total {
+			end = total
+		}
+		entries = entries[start:end]
+	}
+	return &apisv1.ListUISchemaHistoryResponse{Entries: entries, Total: int64(total), Page: page, PageSize: pageSize}, nil
+}
+
+// parseUISchemaHistory return the edit history recorded in the custom ui schema configmap, the invalid history is ignored
+func parseUISchemaHistory(cm *v1.ConfigMap) []*apisv1.UISchemaHistoryEntry {
+	history := cm.Annotations[AnnoUISchemaHistory]
+	if history == "" {
+		return nil
+	}
+	var entries []*apisv1.UISchemaHistoryEntry
+	if err := json.Unmarshal([]byte(history), &entries); err != nil {
+		klog.Warningf("the ui schema history of %s is invalid: %s", cm.Name, err.Error())
+		return nil
+	}
+	return entries
+}
+
+// appendUISchemaHistory put the entry at the head of the history and drop the oldest ones beyond the limit
+func appendUISchemaHistory(entries []*apisv1.UISchemaHistoryEntry, entry *apisv1.UISchemaHistoryEntry) string {
+	entries = append([]*apisv1.UISchemaHistoryEntry{entry}, entries...)
+	if len(entries) > maxUISchemaHistoryLength {
+		entries = entries[:maxUISchemaHistoryLength]
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		klog.Errorf("failed to marshal the ui schema history: %s", err.Error())
+		return ""
+	}
+	return string(data)
+}
+
+// summarizeUISchemaChange describe the top level parameters added, removed or changed between the custom ui schemas
+func summarizeUISchemaChange(before, after []*schema.UIParameter) string {
+	encode := func(params []*schema.UIParameter) map[string]string {
+		res := make(map[string]string, len(params))
+		for _, param := range params {
+			if param == nil {
+				continue
+			}
+			data, _ := json.Marshal(param)
+			res[param.JSONKey] = string(data)
+		}
+		return res
+	}
+	beforeMap, afterMap := encode(before), encode(after)
+	var added, removed, changed []string
+	for key, value := range afterMap {
+		if old, exist := beforeMap[key]; !exist {
+			added = append(added, key)
+		} else if old != value {
+			changed = append(changed, key)
+		}
+	}
+	for key := range beforeMap {
+		if _, exist := afterMap[key]; !exist {
+			removed = append(removed, key)
+		}
+	}
+	var parts []string
+	for _, part := range []struct {
+		action string
+		keys   []string
+	}{{"added", added}, {"removed", removed}, {"changed", changed}} {
+		if len(part.keys) == 0 {
+			continue
+		}
+		sort.Strings(part.keys)
+		keys := part.keys
+		if len(keys) > maxUISchemaHistorySummaryKeys {
+			keys = append(keys[:maxUISchemaHistorySummaryKeys:maxUISchemaHistorySummaryKeys], "...")
+		}
+		parts = append(parts, fmt.Sprintf("%s %d: %s", part.action, len(part.keys), strings.Join(keys, ", ")))
+	}
+	if len(parts) == 0 {
+		return "no parameter changed"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ResetDefinitionUISchema delete the custom ui schema configmap, so the ui schema is rendered from the api schema
+func (d *definitionServiceImpl) ResetDefinitionUISchema(ctx context.Context, name, defType string) ([]*schema.UIParameter, error) {
+	if _, _, err := getKindAndVersion(defType); err != nil {
+		return nil, err
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: d.systemNamespace(),
+			Name:      fmt.Sprintf("%s-uischema-%s", defType, name),
+		},
+	}
+	if err := d.KubeClient.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	res, err := d.DetailDefinition(ctx, name, defType, DetailDefinitionOption{})
 	if err != nil {
 		return nil, err
 	}
@@ -349,6 +2239,9 @@ func (d *definitionServiceImpl) AddDefinitionUISchema(ctx context.Context, name,
 
 // UpdateDefinitionStatus update the status of the definition
 func (d *definitionServiceImpl) UpdateDefinitionStatus(ctx context.Context, name string, update apisv1.UpdateDefinitionStatusRequest) (*apisv1.DetailDefinitionResponse, error) {
+	if update.DryRun {
+		return d.dryRunDefinitionStatus(ctx, name, update)
+	}
 	def := &unstructured.Unstructured{}
 	version, kind, err := getKindAndVersion(update.DefinitionType)
 	if err != nil {
@@ -356,7 +2249,7 @@ func (d *definitionServiceImpl) UpdateDefinitionStatus(ctx context.Context, name
 	}
 	def.SetAPIVersion(version)
 	def.SetKind(kind)
-	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: types.DefaultKubeVelaNS, Name: name}, def); err != nil {
+	if err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: d.systemNamespace(), Name: name}, def); err != nil {
 		if apierrors.IsNotFound(err) {
 			return nil, bcode.ErrDefinitionNotFound
 		}
@@ -371,17 +2264,277 @@ func (d *definitionServiceImpl) UpdateDefinitionStatus(ctx context.Context, name
 			return nil, err
 		}
 	}
-	if !exist && update.HiddenInUI {
-		labels := def.GetLabels()
+	if !exist && update.HiddenInUI {
+		labels := def.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[types.LabelDefinitionHidden] = DefinitionHidden
+		def.SetLabels(labels)
+		if err := d.KubeClient.Update(ctx, def); err != nil {
+			return nil, err
+		}
+	}
+	return d.DetailDefinition(ctx, name, update.DefinitionType, DetailDefinitionOption{})
+}
+
+// ApplyDefinition create or update the definition from the YAML manifest in the system namespace,
+// only the component, trait, workflow step and policy definitions are supported.
+func (d *definitionServiceImpl) ApplyDefinition(ctx context.Context, manifest []byte) (*apisv1.DetailDefinitionResponse, error) {
+	def := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(manifest, &def.Object); err != nil || def.Object == nil {
+		return nil, bcode.ErrDefinitionManifestInvalid
+	}
+	defType := getDefinitionType(def.GetKind())
+	if def.GetAPIVersion() != definitionAPIVersion || defType == "" {
+		return nil, bcode.ErrDefinitionTypeNotSupport
+	}
+	if err := validateDefinitionManifest(def, d.systemNamespace()); err != nil {
+		return nil, err
+	}
+	def.SetNamespace(d.systemNamespace())
+	// the fields managed by the cluster are ignored
+	def.SetResourceVersion("")
+	def.SetUID("")
+	def.SetManagedFields(nil)
+	unstructured.RemoveNestedField(def.Object, "status")
+
+	existing := &unstructured.Unstructured{}
+	existing.SetAPIVersion(def.GetAPIVersion())
+	existing.SetKind(def.GetKind())
+	err := d.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: def.GetNamespace(), Name: def.GetName()}, existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		if err := d.KubeClient.Create(ctx, def); err != nil {
+			return nil, err
+		}
+		klog.Infof("the %s definition %s is created", defType, def.GetName())
+	case err != nil:
+		return nil, err
+	default:
+		if isDefinitionProtected(existing) {
+			return nil, bcode.ErrDefinitionProtected
+		}
+		mergeDefinitionManifest(existing, def)
+		if err := d.KubeClient.Update(ctx, existing); err != nil {
+			return nil, err
+		}
+		klog.Infof("the %s definition %s is updated", defType, def.GetName())
+	}
+	return d.DetailDefinition(ctx, def.GetName(), defType, DetailDefinitionOption{NoCache: true})
+}
+
+// mergeDefinitionManifest merge the spec, the labels and the annotations of the manifest into the existing definition,
+// the other metadata of the existing definition such as the owner references are kept.
+func mergeDefinitionManifest(existing, def *unstructured.Unstructured) {
+	existing.Object["spec"] = def.Object["spec"]
+	labels := existing.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range def.GetLabels() {
+		labels[k] = v
+	}
+	existing.SetLabels(labels)
+	annotations := existing.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for k, v := range def.GetAnnotations() {
+		annotations[k] = v
+	}
+	existing.SetAnnotations(annotations)
+}
+
+// validateDefinitionManifest check the name, the namespace and the spec of the definition to apply
+func validateDefinitionManifest(def *unstructured.Unstructured, systemNamespace string) error {
+	if errs := validation.IsDNS1123Subdomain(def.GetName()); len(errs) > 0 {
+		return bcode.ErrDefinitionManifestInvalid.SetMessage(fmt.Sprintf("the name of the definition is invalid: %s", strings.Join(errs, ", ")))
+	}
+	if def.GetNamespace() != "" && def.GetNamespace() != systemNamespace {
+		return bcode.ErrDefinitionManifestInvalid.SetMessage(fmt.Sprintf("the definition must be in the namespace %s", systemNamespace))
+	}
+	var typed interface{}
+	switch def.GetKind() {
+	case kindComponentDefinition:
+		typed = &v1beta1.ComponentDefinition{}
+	case kindTraitDefinition:
+		typed = &v1beta1.TraitDefinition{}
+	case kindWorkflowStepDefinition:
+		typed = &v1beta1.WorkflowStepDefinition{}
+	case kindPolicyDefinition:
+		typed = &v1beta1.PolicyDefinition{}
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, typed); err != nil {
+		return bcode.ErrDefinitionManifestInvalid.SetMessage(fmt.Sprintf("the spec of the definition is invalid: %s", err.Error()))
+	}
+	if _, exist, _ := unstructured.NestedMap(def.Object, "spec", "schematic"); !exist {
+		return bcode.ErrDefinitionManifestInvalid.SetMessage("the schematic of the definition is required")
+	}
+	return nil
+}
+
+// AnnoDefinitionSchemaRegeneratedAt the time when the schema regeneration of the definition was triggered, in RFC3339 format.
+// Updating it makes the controller reconcile the definition and generate the schema configmap again.
+const AnnoDefinitionSchemaRegeneratedAt = "velaux.oam.dev/schema-regenerated-at"
+
+const (
+	// SchemaRegenerationStatusRegenerated the stale schema is removed and the controller is triggered to generate it again
+	SchemaRegenerationStatusRegenerated = "Regenerated"
+	// SchemaRegenerationStatusSkipped the definition has no schematic, so it has no schema
+	SchemaRegenerationStatusSkipped = "Skipped"
+	// SchemaRegenerationStatusFailed failed to trigger the regeneration
+	SchemaRegenerationStatusFailed = "Failed"
+)
+
+// defaultSchemaRegenerationConcurrency and maxSchemaRegenerationConcurrency limit the definitions regenerated at the same time,
+// so the api server is not flooded after upgrading KubeVela
+const (
+	defaultSchemaRegenerationConcurrency = 5
+	maxSchemaRegenerationConcurrency     = 20
+)
+
+// RegenerateAllDefinitionSchemas delete the latest schema configmaps of the definitions and touch the definitions,
+// so the controller generates the schemas again. The failure of one definition doesn't abort the others.
+func (d *definitionServiceImpl) RegenerateAllDefinitionSchemas(ctx context.Context, req apisv1.RegenerateDefinitionSchemasRequest) (*apisv1.RegenerateDefinitionSchemasResponse, error) {
+	defTypes := definitionTypes
+	if req.DefinitionType != "" {
+		if _, _, err := getKindAndVersion(req.DefinitionType); err != nil {
+			return nil, err
+		}
+		defTypes = []string{req.DefinitionType}
+	}
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultSchemaRegenerationConcurrency
+	}
+	if concurrency > maxSchemaRegenerationConcurrency {
+		concurrency = maxSchemaRegenerationConcurrency
+	}
+	type definitionOfType struct {
+		defType string
+		def     unstructured.Unstructured
+	}
+	var defs []definitionOfType
+	for _, defType := range defTypes {
+		version, kind, err := getKindAndVersion(defType)
+		if err != nil {
+			return nil, err
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetAPIVersion(version)
+		list.SetKind(kind)
+		if err := d.KubeClient.List(ctx, list, client.InNamespace(d.systemNamespace())); err != nil {
+			return nil, err
+		}
+		for _, def := range list.Items {
+			defs = append(defs, definitionOfType{defType: defType, def: def})
+		}
+	}
+
+	resp := &apisv1.RegenerateDefinitionSchemasResponse{Total: len(defs), Results: make([]*apisv1.RegenerateDefinitionSchemaResult, len(defs))}
+	now := time.Now().Format(time.RFC3339)
+	limiter := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range defs {
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			def, defType := defs[i].def, defs[i].defType
+			result := &apisv1.RegenerateDefinitionSchemaResult{Name: def.GetName(), DefinitionType: defType, Status: SchemaRegenerationStatusRegenerated}
+			resp.Results[i] = result
+			if _, found, _ := unstructured.NestedMap(def.Object, "spec", "schematic"); !found {
+				result.Status = SchemaRegenerationStatusSkipped
+				result.Message = "the definition has no schematic, so no schema is generated"
+				return
+			}
+			if err := d.regenerateDefinitionSchema(ctx, &def, defType, now); err != nil {
+				klog.Warningf("failed to regenerate the schema of the %s definition %s: %s", defType, def.GetName(), err.Error())
+				result.Status = SchemaRegenerationStatusFailed
+				result.Message = err.Error()
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, result := range resp.Results {
+		switch result.Status {
+		case SchemaRegenerationStatusRegenerated:
+			resp.Regenerated++
+		case SchemaRegenerationStatusSkipped:
+			resp.Skipped++
+		default:
+			resp.Failed++
+		}
+	}
+	klog.Infof("triggered the schema regeneration of %d definitions, %d skipped, %d failed", resp.Regenerated, resp.Skipped, resp.Failed)
+	return resp, nil
+}
+
+// regenerateDefinitionSchema delete the latest schema configmap of the definition and update the annotation to trigger the controller
+func (d *definitionServiceImpl) regenerateDefinitionSchema(ctx context.Context, def *unstructured.Unstructured, defType, now string) error {
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: def.GetNamespace(), Name: schemaConfigMapName(defType, def.GetName())}}
+	if err := d.KubeClient.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	patch := client.MergeFrom(def.DeepCopy())
+	annotations := def.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnoDefinitionSchemaRegeneratedAt] = now
+	def.SetAnnotations(annotations)
+	return d.KubeClient.Patch(ctx, def, patch)
+}
+
+// BatchUpdateDefinitionStatus update the status of the definitions one by one, the failure of one definition doesn't abort the rest
+func (d *definitionServiceImpl) BatchUpdateDefinitionStatus(ctx context.Context, updates []apisv1.UpdateDefinitionStatusRequest) (*apisv1.BatchUpdateDefinitionStatusResponse, error) {
+	resp := &apisv1.BatchUpdateDefinitionStatusResponse{Results: []*apisv1.BatchUpdateDefinitionStatusResult{}}
+	for _, update := range updates {
+		result := &apisv1.BatchUpdateDefinitionStatusResult{Name: update.Name, DefinitionType: update.DefinitionType}
+		resp.Results = append(resp.Results, result)
+		if update.Name == "" {
+			result.Message = "the name of the definition is required"
+			continue
+		}
+		detail, err := d.UpdateDefinitionStatus(ctx, update.Name, update)
+		if err != nil {
+			klog.Warningf("failed to update the status of the definition %s: %s", utils.Sanitize(update.Name), err.Error())
+			result.Message = err.Error()
+			continue
+		}
+		result.Success = true
+		result.HiddenInUI = detail.HiddenInUI
+	}
+	return resp, nil
+}
+
+// dryRunDefinitionStatus render the definition detail as it would be after the status update, without writing to the cluster
+func (d *definitionServiceImpl) dryRunDefinitionStatus(ctx context.Context, name string, update apisv1.UpdateDefinitionStatusRequest) (*apisv1.DetailDefinitionResponse, error) {
+	detail, err := d.DetailDefinition(ctx, name, update.DefinitionType, DetailDefinitionOption{})
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string, len(detail.Labels)+1)
+	for k, v := range detail.Labels {
+		labels[k] = v
+	}
+	if update.HiddenInUI {
 		labels[types.LabelDefinitionHidden] = DefinitionHidden
-		def.SetLabels(labels)
-		if err := d.KubeClient.Update(ctx, def); err != nil {
-			return nil, err
-		}
+		detail.Status = "disable"
+	} else {
+		delete(labels, types.LabelDefinitionHidden)
+		detail.Status = "enable"
 	}
-	return d.DetailDefinition(ctx, name, update.DefinitionType)
+	detail.HiddenInUI = update.HiddenInUI
+	detail.Labels = labels
+	return detail, nil
 }
 
+// patchSchema merge the custom schema onto the default schema, the parameters disabled by the custom schema are removed
 func patchSchema(defaultSchema, customSchema []*schema.UIParameter) []*schema.UIParameter {
 	var customSchemaMap = make(map[string]*schema.UIParameter, len(customSchema))
 	for i, custom := range customSchema {
@@ -390,9 +2543,14 @@ func patchSchema(defaultSchema, customSchema []*schema.UIParameter) []*schema.UI
 	if len(defaultSchema) == 0 {
 		return customSchema
 	}
+	var patched []*schema.UIParameter
 	for i := range defaultSchema {
 		dSchema := defaultSchema[i]
 		if cusSchema, exist := customSchemaMap[dSchema.JSONKey]; exist {
+			// remove the parameter, unless it could be enabled by the conditions
+			if cusSchema.Disable != nil && *cusSchema.Disable && len(cusSchema.Conditions) == 0 && len(dSchema.Conditions) == 0 {
+				continue
+			}
 			if cusSchema.Description != "" {
 				dSchema.Description = cusSchema.Description
 			}
@@ -427,40 +2585,263 @@ func patchSchema(defaultSchema, customSchema []*schema.UIParameter) []*schema.UI
 				dSchema.Conditions = cusSchema.Conditions
 			}
 		}
+		patched = append(patched, dSchema)
 	}
-	sort.Slice(defaultSchema, func(i, j int) bool {
-		return defaultSchema[i].Sort < defaultSchema[j].Sort
+	sort.Slice(patched, func(i, j int) bool {
+		return patched[i].Sort < patched[j].Sort
 	})
-	return defaultSchema
+	return patched
+}
+
+// renderDefaultUISchema render the ui schema from the openapi schema, the labels and the descriptions are translated
+// to the locale if the parameters declare the translations, the default ones are used if the locale is empty.
+// The groups of the top-level variants are not returned, use renderObjectParameters if the groups are needed.
+func renderDefaultUISchema(apiSchema *openapi3.Schema, locale string) []*schema.UIParameter {
+	params, _ := renderObjectParameters(apiSchema, locale)
+	return params
 }
 
-func renderDefaultUISchema(apiSchema *openapi3.Schema) []*schema.UIParameter {
+// renderObjectParameters render the parameters of the object schema. The properties of allOf are merged into the
+// parameters, the properties of the oneOf or anyOf variants are appended to the parameters and every variant is
+// returned as a group of the parameters, so the variant could be chosen in the form.
+func renderObjectParameters(apiSchema *openapi3.Schema, locale string) ([]*schema.UIParameter, []schema.GroupOption) {
 	if apiSchema == nil {
-		return nil
+		return nil, nil
 	}
+	apiSchema = mergeAllOf(apiSchema)
 	var params []*schema.UIParameter
 	for key, property := range apiSchema.Properties {
 		if property.Value != nil {
-			param := renderUIParameter(key, schema.FirstUpper(key), property, apiSchema.Required)
+			param := renderUIParameter(key, schema.FirstUpper(key), property, apiSchema.Required, locale)
 			params = append(params, param)
 		}
 	}
+	var groups []schema.GroupOption
+	if hasObjectVariants(apiSchema) {
+		params, groups = appendVariantParameters(params, apiSchema, locale)
+	}
 	sortDefaultUISchema(params)
-	return params
+	return params, groups
+}
+
+// mergeAllOf return the schema with the properties and the required keys of the allOf schemas merged,
+// the properties declared by the schema itself or the former allOf schemas take precedence.
+// The schema is returned as it is if there is no allOf.
+func mergeAllOf(apiSchema *openapi3.Schema) *openapi3.Schema {
+	if len(apiSchema.AllOf) == 0 {
+		return apiSchema
+	}
+	merged := *apiSchema
+	merged.AllOf = nil
+	merged.Properties = openapi3.Schemas{}
+	for key, property := range apiSchema.Properties {
+		merged.Properties[key] = property
+	}
+	merged.Required = append([]string{}, apiSchema.Required...)
+	for _, member := range apiSchema.AllOf {
+		if member == nil || member.Value == nil {
+			continue
+		}
+		value := mergeAllOf(member.Value)
+		if merged.Type == "" {
+			merged.Type = value.Type
+		}
+		for key, property := range value.Properties {
+			if _, exist := merged.Properties[key]; !exist {
+				merged.Properties[key] = property
+			}
+		}
+		for _, key := range value.Required {
+			if !utils.StringsContain(merged.Required, key) {
+				merged.Required = append(merged.Required, key)
+			}
+		}
+		if len(merged.OneOf) == 0 && len(merged.AnyOf) == 0 {
+			merged.OneOf, merged.AnyOf = value.OneOf, value.AnyOf
+		}
+	}
+	return &merged
+}
+
+// VariantSelectorKey the key of the selector rendered for choosing the variant if no property discriminates the variants,
+// it only exists in the form and the value should not be submitted as a parameter
+const VariantSelectorKey = "x-vela-variant"
+
+// getVariants return the oneOf schemas, or the anyOf schemas if there is no oneOf
+func getVariants(apiSchema *openapi3.Schema) openapi3.SchemaRefs {
+	if len(apiSchema.OneOf) > 0 {
+		return apiSchema.OneOf
+	}
+	return apiSchema.AnyOf
+}
+
+// hasObjectVariants check whether any oneOf or anyOf variant declares the properties,
+// the variants of the scalar types are not rendered as the choice
+func hasObjectVariants(apiSchema *openapi3.Schema) bool {
+	for _, variant := range getVariants(apiSchema) {
+		if variant != nil && variant.Value != nil && len(mergeAllOf(variant.Value).Properties) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// appendVariantParameters append the parameters of the oneOf or anyOf variants and return a group for every variant.
+// If the variants are discriminated by a property, the property is rendered as the selector of the variants, otherwise
+// a selector with the key VariantSelectorKey is added. The parameters of the variants are enabled by the selected value.
+func appendVariantParameters(params []*schema.UIParameter, apiSchema *openapi3.Schema, locale string) ([]*schema.UIParameter, []schema.GroupOption) {
+	var variants []*openapi3.Schema
+	for _, variant := range getVariants(apiSchema) {
+		// the variants of the scalar types can't be rendered as the parameters
+		if variant != nil && variant.Value != nil && len(mergeAllOf(variant.Value).Properties) > 0 {
+			variants = append(variants, mergeAllOf(variant.Value))
+		}
+	}
+	discriminator, values := getVariantDiscriminator(apiSchema, variants)
+	rendered := map[string]*schema.UIParameter{}
+	for _, param := range params {
+		rendered[param.JSONKey] = param
+	}
+	if discriminator == "" {
+		// no property discriminates the variants, render a selector only for choosing the variant in the form
+		discriminator = VariantSelectorKey
+		for i, variant := range variants {
+			values = append(values, variantLabel(variant, nil, i))
+		}
+		selector := &schema.UIParameter{JSONKey: discriminator, Label: "Variant", Validate: &schema.Validate{}, Sort: 100}
+		rendered[discriminator] = selector
+		params = append(params, selector)
+	}
+	// the keys declared by the object itself are always enabled
+	common := map[string]bool{}
+	for key := range apiSchema.Properties {
+		common[key] = true
+	}
+	var groups []schema.GroupOption
+	enabledBy := map[string][]interface{}{}
+	for i, variant := range variants {
+		group := schema.GroupOption{Label: variantLabel(variant, values, i)}
+		keys := make([]string, 0, len(variant.Properties))
+		for key := range variant.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property := variant.Properties[key]
+			if property == nil || property.Value == nil {
+				continue
+			}
+			group.Keys = append(group.Keys, key)
+			if values != nil {
+				enabledBy[key] = append(enabledBy[key], values[i])
+			}
+			if _, exist := rendered[key]; exist {
+				continue
+			}
+			param := renderUIParameter(key, schema.FirstUpper(key), property, variant.Required, locale)
+			rendered[key] = param
+			params = append(params, param)
+		}
+		groups = append(groups, group)
+	}
+	selector := rendered[discriminator]
+	selector.UIType = "Select"
+	selector.Validate.Required = true
+	if _, pinned := getSortNumber(apiSchemaProperty(apiSchema, variants, discriminator)); !pinned {
+		// the selector comes before the parameters of the variants
+		for _, param := range params {
+			if param != selector && param.Sort > 0 && param.Sort <= selector.Sort {
+				selector.Sort = param.Sort - 1
+			}
+		}
+	}
+	selector.Validate.Options = nil
+	for i, variant := range variants {
+		selector.Validate.Options = append(selector.Validate.Options, schema.Option{Label: variantLabel(variant, values, i), Value: values[i]})
+	}
+	for key, param := range rendered {
+		if key == discriminator || common[key] || len(enabledBy[key]) == len(variants) {
+			continue
+		}
+		param.Conditions = append(param.Conditions, schema.Condition{JSONKey: discriminator, Op: "in", Value: enabledBy[key], Action: "enable"})
+		// the parameter is only required by the variants that enable it
+		if param.Validate != nil {
+			param.Validate.Required = false
+		}
+	}
+	return params, groups
+}
+
+// apiSchemaProperty return the property declared by the schema itself or the first variant that declares it
+func apiSchemaProperty(apiSchema *openapi3.Schema, variants []*openapi3.Schema, key string) *openapi3.Schema {
+	if property := apiSchema.Properties[key]; property != nil && property.Value != nil {
+		return property.Value
+	}
+	for _, variant := range variants {
+		if property := variant.Properties[key]; property != nil && property.Value != nil {
+			return property.Value
+		}
+	}
+	return &openapi3.Schema{}
+}
+
+// getVariantDiscriminator return the property that discriminates the variants and its value in every variant.
+// The property is the discriminator of the schema, or the property that every variant declares with only one distinct enum value.
+func getVariantDiscriminator(apiSchema *openapi3.Schema, variants []*openapi3.Schema) (string, []interface{}) {
+	var candidates []string
+	if apiSchema.Discriminator != nil && apiSchema.Discriminator.PropertyName != "" {
+		candidates = []string{apiSchema.Discriminator.PropertyName}
+	} else if len(variants) > 0 {
+		for key := range variants[0].Properties {
+			candidates = append(candidates, key)
+		}
+		sort.Strings(candidates)
+	}
+	for _, candidate := range candidates {
+		values := make([]interface{}, 0, len(variants))
+		seen := map[string]bool{}
+		for _, variant := range variants {
+			property := variant.Properties[candidate]
+			if property == nil || property.Value == nil || len(property.Value.Enum) != 1 || seen[fmt.Sprintf("%v", property.Value.Enum[0])] {
+				break
+			}
+			seen[fmt.Sprintf("%v", property.Value.Enum[0])] = true
+			values = append(values, property.Value.Enum[0])
+		}
+		if len(values) == len(variants) && len(values) > 0 {
+			return candidate, values
+		}
+	}
+	return "", nil
+}
+
+// variantLabel return the title of the variant, the discriminator value, or the index of the variant
+func variantLabel(variant *openapi3.Schema, values []interface{}, index int) string {
+	if variant.Title != "" {
+		return variant.Title
+	}
+	if values != nil {
+		return schema.RenderLabel(values[index])
+	}
+	return fmt.Sprintf("Option %d", index+1)
 }
 
 // Sort Default UISchema
-// 1.Check validate.required. It is True, the sort number will be lower.
-// 2.Check subParameters. The more subparameters, the larger the sort number.
-// 3.If validate.required or subParameters is equal, sort by Label
+// 1.Check the sort number seeded by the x-vela-sort extension, the parameters without it are seeded with 100.
+// 2.Check validate.required. It is True, the sort number will be lower.
+// 3.Check subParameters. The more subparameters, the larger the sort number.
+// 4.If validate.required or subParameters is equal, sort by Label
+// 5.If Label is equal, sort by JSONKey, so the order doesn't depend on the order of the input
 //
-// The sort number starts with 100.
+// The sort number starts with 100 at every level, the subParameters are sorted in the same way recursively.
 func sortDefaultUISchema(params []*schema.UIParameter) {
-	sort.Slice(params, func(i, j int) bool {
+	sort.SliceStable(params, func(i, j int) bool {
 		switch {
-		case params[i].Validate.Required && !params[j].Validate.Required:
+		case params[i].Sort != params[j].Sort:
+			return params[i].Sort < params[j].Sort
+		case isParameterRequired(params[i]) && !isParameterRequired(params[j]):
 			return true
-		case !params[i].Validate.Required && params[j].Validate.Required:
+		case !isParameterRequired(params[i]) && isParameterRequired(params[j]):
 			return false
 		default:
 			switch {
@@ -468,44 +2849,557 @@ func sortDefaultUISchema(params []*schema.UIParameter) {
 				return true
 			case len(params[i].SubParameters) > len(params[j].SubParameters):
 				return false
-			default:
+			case params[i].Label != params[j].Label:
 				return params[i].Label < params[j].Label
+			default:
+				return params[i].JSONKey < params[j].JSONKey
 			}
 		}
 	})
 	for i, param := range params {
-		param.Sort += uint(i)
+		param.Sort = 100 + uint(i)
+		sortDefaultUISchema(param.SubParameters)
+	}
+}
+
+func isParameterRequired(param *schema.UIParameter) bool {
+	return param.Validate != nil && param.Validate.Required
+}
+
+// normalizeDefaultValue convert the default value decoded from JSON to the type of the schema, such as the integer
+func normalizeDefaultValue(schemaType string, defaultValue interface{}) interface{} {
+	if number, ok := defaultValue.(float64); ok && schemaType == openapi3.TypeInteger && number == math.Trunc(number) {
+		return int64(number)
+	}
+	return defaultValue
+}
+
+// inheritDefaultValue set the default values of the sub parameters from the default value of the parent object
+// if they don't have their own default values.
+func inheritDefaultValue(params []*schema.UIParameter, objectSchema *openapi3.Schema, objectDefault map[string]interface{}) {
+	for _, param := range params {
+		value, exist := objectDefault[param.JSONKey]
+		property := objectSchema.Properties[param.JSONKey]
+		if !exist || param.Validate == nil || property == nil || property.Value == nil {
+			continue
+		}
+		if param.Validate.DefaultValue == nil {
+			param.Validate.DefaultValue = normalizeDefaultValue(property.Value.Type, value)
+		}
+		if subDefault, ok := param.Validate.DefaultValue.(map[string]interface{}); ok {
+			inheritDefaultValue(param.SubParameters, property.Value, subDefault)
+		}
+	}
+}
+
+// EnumLabelsExtension the openapi extension that maps the enum values to the labels shown in UI, such as {"pvc": "Persistent Volume Claim"}
+const EnumLabelsExtension = "x-vela-enum-labels"
+
+// getEnumLabels return the labels of the enum values from the extension, return nil if the extension is absent or invalid
+func getEnumLabels(property *openapi3.Schema) map[string]string {
+	extension, ok := property.Extensions[EnumLabelsExtension]
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(extension)
+	if err != nil {
+		return nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		klog.Warningf("the %s extension should be a map from the enum value to the label: %s", EnumLabelsExtension, err.Error())
+		return nil
+	}
+	return labels
+}
+
+// ConditionsExtension the openapi extension that defines the conditions of the parameter,
+// such as [{"jsonKey": "type", "op": "==", "value": "pvc"}]
+const ConditionsExtension = "x-vela-conditions"
+
+// getConditions return the conditions from the extension, the invalid conditions are ignored
+func getConditions(property *openapi3.Schema) []schema.Condition {
+	extension, ok := property.Extensions[ConditionsExtension]
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(extension)
+	if err != nil {
+		return nil
+	}
+	var conditions []schema.Condition
+	if err := json.Unmarshal(data, &conditions); err != nil {
+		klog.Warningf("the %s extension should be a list of the conditions: %s", ConditionsExtension, err.Error())
+		return nil
+	}
+	var validConditions []schema.Condition
+	for _, condition := range conditions {
+		if err := condition.Validate(); err != nil {
+			klog.Warningf("ignore the invalid condition of the %s extension: %s", ConditionsExtension, err.Error())
+			continue
+		}
+		validConditions = append(validConditions, condition)
+	}
+	return validConditions
+}
+
+// GroupExtension the openapi extension that puts the parameter into a group of the form, such as "Advanced"
+const GroupExtension = "x-vela-group"
+
+// DefaultUIGroup the group of the parameters that don't declare the group
+const DefaultUIGroup = "Basic"
+
+// getGroup return the group of the parameter from the extension, return empty if the extension is absent or invalid
+func getGroup(property *openapi3.Schema) string {
+	extension, ok := property.Extensions[GroupExtension]
+	if !ok {
+		return ""
+	}
+	data, err := json.Marshal(extension)
+	if err != nil {
+		return ""
+	}
+	var group string
+	if err := json.Unmarshal(data, &group); err != nil {
+		klog.Warningf("the %s extension should be the name of the group: %s", GroupExtension, err.Error())
+		return ""
+	}
+	return strings.TrimSpace(group)
+}
+
+// UITypeExtension the openapi extension that specifies the ui widget of the parameter instead of inferring it from the type, such as "SecretSelect"
+const UITypeExtension = "x-vela-ui-type"
+
+// uiTypeCompatibleTypes the json types of the parameter that each ui widget could edit, the widget could edit any type if it is nil
+var uiTypeCompatibleTypes = map[string][]string{
+	"Input":                  {"string"},
+	"Password":               {"string"},
+	"Select":                 {"string", "number", "integer"},
+	"ImageInput":             {"string"},
+	"HelmChartSelect":        {"string"},
+	"HelmChartVersionSelect": {"string"},
+	"HelmRepoSelect":         {"string"},
+	"SecretSelect":           {"string"},
+	"SecretKeySelect":        {"string"},
+	"CertBase64":             {"string"},
+	"MemoryNumber":           {"string"},
+	"DiskNumber":             {"string"},
+	"CPUNumber":              {"string", "number", "integer"},
+	"Number":                 {"number", "integer"},
+	"Switch":                 {"boolean"},
+	"Strings":                {"array"},
+	"Numbers":                {"array"},
+	"Structs":                {"array"},
+	"PolicySelect":           {"array"},
+	"ComponentSelect":        {"array"},
+	"ComponentPatches":       {"array"},
+	"K8sObjectsCode":         {"array"},
+	"KV":                     {"object"},
+	"Group":                  {"object"},
+	"HelmValues":             {"object"},
+	"Ignore":                 nil,
+}
+
+// getUIType return the ui widget from the extension, return empty if the extension is absent,
+// the widget is unknown or it can't edit the type of the parameter, so the inferred widget is used.
+func getUIType(property *openapi3.Schema) string {
+	extension, ok := property.Extensions[UITypeExtension]
+	if !ok {
+		return ""
+	}
+	data, err := json.Marshal(extension)
+	if err != nil {
+		return ""
+	}
+	var uiType string
+	if err := json.Unmarshal(data, &uiType); err != nil {
+		klog.Warningf("the %s extension should be the name of the ui widget: %s", UITypeExtension, err.Error())
+		return ""
+	}
+	uiType = strings.TrimSpace(uiType)
+	compatibleTypes, known := uiTypeCompatibleTypes[uiType]
+	if !known {
+		klog.Warningf("ignore the unknown ui widget %q of the %s extension", uiType, UITypeExtension)
+		return ""
+	}
+	if compatibleTypes != nil && !utils.StringsContain(compatibleTypes, property.Type) {
+		klog.Warningf("ignore the ui widget %q of the %s extension, it can't edit the %s parameter", uiType, UITypeExtension, property.Type)
+		return ""
+	}
+	return uiType
+}
+
+// UnitExtension the openapi extension that declares the unit of the parameter, such as "MiB" or "core"
+const UnitExtension = "x-vela-unit"
+
+// FormatExtension the openapi extension that declares the format of the parameter, such as "byte" or "duration",
+// it takes precedence over the openapi format
+const FormatExtension = "x-vela-format"
+
+// getStringExtension return the extension of the property as a string, return empty if the extension is absent or not a string
+func getStringExtension(property *openapi3.Schema, name string) string {
+	extension, ok := property.Extensions[name]
+	if !ok {
+		return ""
+	}
+	data, err := json.Marshal(extension)
+	if err != nil {
+		return ""
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		klog.Warningf("the %s extension should be a string: %s", name, err.Error())
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// renderSchemaUIHints render the unit and format hints of the parameters, keyed by the parameter path.
+// Return nil if no parameter declares the hints.
+func renderSchemaUIHints(apiSchema *openapi3.Schema) map[string]apisv1.UIParameterHint {
+	hints := map[string]apisv1.UIParameterHint{}
+	collectSchemaUIHints("", apiSchema, hints)
+	if len(hints) == 0 {
+		return nil
+	}
+	return hints
+}
+
+func collectSchemaUIHints(prefix string, apiSchema *openapi3.Schema, into map[string]apisv1.UIParameterHint) {
+	if apiSchema == nil {
+		return
+	}
+	for key, property := range apiSchema.Properties {
+		if property == nil || property.Value == nil {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		hint := apisv1.UIParameterHint{Unit: getStringExtension(property.Value, UnitExtension), Format: getStringExtension(property.Value, FormatExtension)}
+		if hint.Format == "" {
+			hint.Format = property.Value.Format
+		}
+		hint.Sensitive = isSensitive(property.Value)
+		if hint.Unit != "" || hint.Format != "" || hint.Sensitive {
+			into[path] = hint
+		}
+		collectSchemaUIHints(path, property.Value, into)
+		if property.Value.Items != nil && property.Value.Items.Value != nil {
+			collectSchemaUIHints(path+"[]", property.Value.Items.Value, into)
+		}
+	}
+}
+
+// I18nExtension the openapi extension that declares the translations of the parameter keyed by the locale,
+// such as {"zh-CN": {"label": "副本数", "description": "工作负载的副本数"}}
+const I18nExtension = "x-vela-i18n"
+
+// parameterTranslation the translated label and description of the parameter
+type parameterTranslation struct {
+	Label       string `json:"label,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// getTranslation return the translation of the parameter for the locale. The locale is matched case-insensitively,
+// then the language without the region is matched, e.g. zh-CN falls back to zh. Return false if there is no translation.
+func getTranslation(property *openapi3.Schema, locale string) (parameterTranslation, bool) {
+	locale = strings.TrimSpace(locale)
+	if locale == "" {
+		return parameterTranslation{}, false
+	}
+	extension, ok := property.Extensions[I18nExtension]
+	if !ok {
+		return parameterTranslation{}, false
+	}
+	data, err := json.Marshal(extension)
+	if err != nil {
+		return parameterTranslation{}, false
+	}
+	var translations map[string]parameterTranslation
+	if err := json.Unmarshal(data, &translations); err != nil {
+		klog.Warningf("the %s extension should be a map of the locale to the label and the description: %s", I18nExtension, err.Error())
+		return parameterTranslation{}, false
+	}
+	candidates := []string{locale}
+	if language, _, found := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-"); found {
+		candidates = append(candidates, language)
+	}
+	for _, candidate := range candidates {
+		for key, translation := range translations {
+			if strings.EqualFold(strings.ReplaceAll(key, "_", "-"), strings.ReplaceAll(candidate, "_", "-")) {
+				return translation, true
+			}
+		}
+	}
+	return parameterTranslation{}, false
+}
+
+// SensitiveExtension the openapi extension that marks the parameter as a secret, such as a password or a token.
+// The parameter is rendered as the password input and its default value is not returned.
+const SensitiveExtension = "x-vela-sensitive"
+
+// isSensitive check whether the parameter is marked as sensitive by the extension
+func isSensitive(property *openapi3.Schema) bool {
+	extension, ok := property.Extensions[SensitiveExtension]
+	if !ok {
+		return false
+	}
+	data, err := json.Marshal(extension)
+	if err != nil {
+		return false
+	}
+	var sensitive bool
+	if err := json.Unmarshal(data, &sensitive); err != nil {
+		klog.Warningf("the %s extension should be a boolean: %s", SensitiveExtension, err.Error())
+		return false
+	}
+	return sensitive
+}
+
+// redactSensitiveDefaults return a copy of the schema without the default values of the sensitive parameters,
+// the schema is returned as it is if there is no sensitive parameter, so the cached schema is never changed.
+func redactSensitiveDefaults(apiSchema *openapi3.Schema) *openapi3.Schema {
+	if !hasSensitiveDefaults(apiSchema) {
+		return apiSchema
+	}
+	data, err := json.Marshal(apiSchema)
+	if err != nil {
+		return apiSchema
 	}
+	redacted := &openapi3.Schema{}
+	if err := redacted.UnmarshalJSON(data); err != nil {
+		return apiSchema
+	}
+	redactSchemaDefaults(redacted, true)
+	return redacted
+}
+
+// hasSensitiveDefaults check whether any sensitive parameter of the schema has the default value or the example
+func hasSensitiveDefaults(apiSchema *openapi3.Schema) bool {
+	return apiSchema != nil && redactSchemaDefaults(apiSchema, false)
+}
+
+// redactSchemaDefaults find the sensitive parameters that have the default values or the examples, and remove them if redact is true.
+// Return whether any value is found.
+func redactSchemaDefaults(apiSchema *openapi3.Schema, redact bool) bool {
+	found := false
+	var walk func(property *openapi3.Schema)
+	walk = func(property *openapi3.Schema) {
+		if property == nil {
+			return
+		}
+		if isSensitive(property) && (property.Default != nil || property.Example != nil) {
+			found = true
+			if redact {
+				property.Default, property.Example = nil, nil
+			}
+		}
+		if objectDefault, ok := property.Default.(map[string]interface{}); ok {
+			if redacted, removed := omitSensitiveValues(objectDefault, property); removed {
+				found = true
+				if redact {
+					property.Default = redacted
+				}
+			}
+		}
+		for _, sub := range property.Properties {
+			if sub != nil {
+				walk(sub.Value)
+			}
+		}
+		if property.Items != nil {
+			walk(property.Items.Value)
+		}
+		if property.AdditionalProperties != nil {
+			walk(property.AdditionalProperties.Value)
+		}
+		for _, refs := range []openapi3.SchemaRefs{property.AllOf, property.OneOf, property.AnyOf} {
+			for _, ref := range refs {
+				if ref != nil {
+					walk(ref.Value)
+				}
+			}
+		}
+	}
+	walk(apiSchema)
+	return found
+}
+
+// omitSensitiveValues return a copy of the object value without the values of the sensitive properties, the nested objects
+// are checked as well. Return false and the value itself if there is no sensitive value.
+func omitSensitiveValues(value map[string]interface{}, objectSchema *openapi3.Schema) (map[string]interface{}, bool) {
+	var omitted map[string]interface{}
+	for key, sub := range value {
+		property := objectSchema.Properties[key]
+		if property == nil || property.Value == nil {
+			continue
+		}
+		sensitive := isSensitive(property.Value)
+		var replaced map[string]interface{}
+		if !sensitive {
+			subObject, ok := sub.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var removed bool
+			if replaced, removed = omitSensitiveValues(subObject, property.Value); !removed {
+				continue
+			}
+		}
+		if omitted == nil {
+			omitted = make(map[string]interface{}, len(value))
+			for k, v := range value {
+				omitted[k] = v
+			}
+		}
+		if sensitive {
+			delete(omitted, key)
+		} else {
+			omitted[key] = replaced
+		}
+	}
+	if omitted == nil {
+		return value, false
+	}
+	return omitted, true
+}
+
+// SortExtension the openapi extension that pins the order of the parameter in the form, the lower one comes first.
+// The parameters without it are sorted as 100.
+const SortExtension = "x-vela-sort"
+
+// getSortNumber return the sort number from the extension, return false if the extension is absent or not a non-negative integer
+func getSortNumber(property *openapi3.Schema) (uint, bool) {
+	extension, ok := property.Extensions[SortExtension]
+	if !ok {
+		return 0, false
+	}
+	data, err := json.Marshal(extension)
+	if err != nil {
+		return 0, false
+	}
+	var sortNumber uint
+	if err := json.Unmarshal(data, &sortNumber); err != nil {
+		klog.Warningf("the %s extension should be a non-negative integer: %s", SortExtension, err.Error())
+		return 0, false
+	}
+	return sortNumber, true
+}
+
+// renderUIGroups group the top-level parameters of the ui schema by the group extension.
+// The default group comes first and the others are sorted by the name, the parameters in a group keep the order of the ui schema.
+// If no parameter declares the group, the groups of the top-level variants are returned, which are nil if there is no variant,
+// so the form is rendered without the sections.
+func renderUIGroups(apiSchema *openapi3.Schema, uiSchema []*schema.UIParameter, variantGroups []schema.GroupOption) []schema.GroupOption {
+	if apiSchema == nil {
+		return nil
+	}
+	params := make([]*schema.UIParameter, len(uiSchema))
+	copy(params, uiSchema)
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].Sort < params[j].Sort
+	})
+	grouped := false
+	keys := map[string][]string{}
+	for _, param := range params {
+		group := ""
+		if property := apiSchema.Properties[param.JSONKey]; property != nil && property.Value != nil {
+			group = getGroup(property.Value)
+		}
+		if group == "" {
+			group = DefaultUIGroup
+		} else {
+			grouped = true
+		}
+		keys[group] = append(keys[group], param.JSONKey)
+	}
+	if !grouped {
+		return variantGroups
+	}
+	var names []string
+	for name := range keys {
+		if name != DefaultUIGroup {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := keys[DefaultUIGroup]; ok {
+		names = append([]string{DefaultUIGroup}, names...)
+	}
+	var groups []schema.GroupOption
+	for _, name := range names {
+		groups = append(groups, schema.GroupOption{Label: name, Keys: keys[name]})
+	}
+	return groups
 }
 
-func renderUIParameter(key, label string, property *openapi3.SchemaRef, required []string) *schema.UIParameter {
+func renderUIParameter(key, label string, property *openapi3.SchemaRef, required []string, locale string) *schema.UIParameter {
 	var parameter schema.UIParameter
+	if len(property.Value.AllOf) > 0 {
+		property = &openapi3.SchemaRef{Ref: property.Ref, Value: mergeAllOf(property.Value)}
+	}
 	subType := ""
 	if property.Value.Items != nil {
 		if property.Value.Items.Value != nil {
 			subType = property.Value.Items.Value.Type
 		}
-		parameter.SubParameters = renderDefaultUISchema(property.Value.Items.Value)
+		parameter.SubParameters, parameter.SubParameterGroupOption = renderObjectParameters(property.Value.Items.Value, locale)
 	}
-	if property.Value.Properties != nil {
-		parameter.SubParameters = renderDefaultUISchema(property.Value)
+	hasVariants := hasObjectVariants(property.Value)
+	if property.Value.Properties != nil || hasVariants {
+		parameter.SubParameters, parameter.SubParameterGroupOption = renderObjectParameters(property.Value, locale)
 	}
 	if property.Value.AdditionalProperties != nil {
-		parameter.SubParameters = renderDefaultUISchema(property.Value.AdditionalProperties.Value)
+		parameter.SubParameters = renderDefaultUISchema(property.Value.AdditionalProperties.Value, locale)
 		var enable = true
 		value := property.Value.AdditionalProperties.Value
-		parameter.AdditionalParameter = renderUIParameter(value.Title, schema.FirstUpper(value.Title), property.Value.AdditionalProperties, value.Required)
+		parameter.AdditionalParameter = renderUIParameter(value.Title, schema.FirstUpper(value.Title), property.Value.AdditionalProperties, value.Required, locale)
 		parameter.Additional = &enable
 	}
 	parameter.Validate = &schema.Validate{}
-	parameter.Validate.DefaultValue = property.Value.Default
+	parameter.Validate.DefaultValue = normalizeDefaultValue(property.Value.Type, property.Value.Default)
+	if objectDefault, ok := parameter.Validate.DefaultValue.(map[string]interface{}); ok {
+		objectDefault, _ = omitSensitiveValues(objectDefault, property.Value)
+		parameter.Validate.DefaultValue = objectDefault
+		inheritDefaultValue(parameter.SubParameters, property.Value, objectDefault)
+	}
+	enumLabels := getEnumLabels(property.Value)
 	for _, enum := range property.Value.Enum {
-		parameter.Validate.Options = append(parameter.Validate.Options, schema.Option{Label: schema.RenderLabel(enum), Value: enum})
+		enumLabel, ok := enumLabels[fmt.Sprintf("%v", enum)]
+		if !ok {
+			enumLabel = schema.RenderLabel(enum)
+		}
+		parameter.Validate.Options = append(parameter.Validate.Options, schema.Option{Label: enumLabel, Value: enum})
 	}
+	parameter.Conditions = getConditions(property.Value)
 	parameter.JSONKey = key
 	parameter.Description = property.Value.Description
 	parameter.Label = label
-	parameter.UIType = schema.GetDefaultUIType(property.Value.Type, len(parameter.Validate.Options) != 0, subType, len(property.Value.Properties) > 0)
+	if translation, ok := getTranslation(property.Value, locale); ok {
+		if translation.Label != "" {
+			parameter.Label = translation.Label
+		}
+		if translation.Description != "" {
+			parameter.Description = translation.Description
+		}
+	}
+	apiType := property.Value.Type
+	if apiType == "" && (len(property.Value.Properties) > 0 || hasVariants) {
+		apiType = "object"
+	}
+	parameter.UIType = schema.GetDefaultUIType(apiType, len(parameter.Validate.Options) != 0, subType, len(property.Value.Properties) > 0 || hasVariants)
+	uiType := getUIType(property.Value)
+	if uiType != "" {
+		parameter.UIType = uiType
+	}
+	if isSensitive(property.Value) {
+		if uiType == "" && (apiType == "string" || apiType == "") {
+			parameter.UIType = "Password"
+		}
+		parameter.Validate.DefaultValue = nil
+	}
 	parameter.Validate.Max = property.Value.Max
 	parameter.Validate.MaxLength = property.Value.MaxLength
 	parameter.Validate.Min = property.Value.Min
@@ -513,5 +3407,8 @@ func renderUIParameter(key, label string, property *openapi3.SchemaRef, required
 	parameter.Validate.Pattern = property.Value.Pattern
 	parameter.Validate.Required = utils.StringsContain(required, property.Value.Title)
 	parameter.Sort = 100
+	if sortNumber, ok := getSortNumber(property.Value); ok {
+		parameter.Sort = sortNumber
+	}
 	return &parameter
 }
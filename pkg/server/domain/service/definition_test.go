@@ -19,24 +19,44 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/go-cmp/cmp"
+	workflowv1alpha1 "github.com/kubevela/workflow/api/v1alpha1"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/yaml"
 
+	oamcommon "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
 	"github.com/oam-dev/kubevela/pkg/utils/schema"
 
+	"github.com/kubevela/velaux/pkg/server/domain/model"
+	"github.com/kubevela/velaux/pkg/server/infrastructure/datastore"
+	"github.com/kubevela/velaux/pkg/server/infrastructure/datastore/kubeapi"
 	v1 "github.com/kubevela/velaux/pkg/server/interfaces/api/dto/v1"
+	apiutils "github.com/kubevela/velaux/pkg/server/utils"
+	"github.com/kubevela/velaux/pkg/server/utils/bcode"
 )
 
 var _ = Describe("Test namespace service functions", func() {
@@ -72,6 +92,19 @@ var _ = Describe("Test namespace service functions", func() {
 		Expect(selectDefinition.Description).ShouldNot(BeEmpty())
 		Expect(selectDefinition.Alias).Should(Equal("test-alias"))
 
+		By("List component definitions by the category")
+		categoryDef, err := os.ReadFile("./testdata/category-cd.yaml")
+		Expect(err).Should(Succeed())
+		var categoryCD v1beta1.ComponentDefinition
+		Expect(yaml.Unmarshal(categoryDef, &categoryCD)).Should(Succeed())
+		Expect(k8sClient.Create(context.Background(), &categoryCD)).Should(Succeed())
+		categoryDefinitions, err := definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "component", Category: "Database"})
+		Expect(err).Should(BeNil())
+		Expect(len(categoryDefinitions)).Should(Equal(1))
+		Expect(categoryDefinitions[0].Name).Should(Equal("category-test"))
+		Expect(categoryDefinitions[0].Category).Should(Equal("Database"))
+		Expect(categoryDefinitions[0].Icon).Should(Equal("https://example.com/mysql.svg"))
+
 		By("List trait definitions")
 		myingress, err := os.ReadFile("./testdata/myingress-td.yaml")
 		Expect(err).Should(Succeed())
@@ -87,10 +120,55 @@ var _ = Describe("Test namespace service functions", func() {
 		Expect(cmp.Diff(traits[0].Name, "myingress")).Should(BeEmpty())
 		// The OwnAddon field of myingress should not be fluxcd
 		Expect(traits[0].OwnerAddon).Should(Equal("fluxcd"))
+		Expect(traits[0].BuiltIn).Should(BeFalse())
+		Expect(traits[1].Name).Should(Equal("scaler"))
+		Expect(traits[1].BuiltIn).Should(BeTrue())
 		Expect(traits[0].Description).ShouldNot(BeEmpty())
 		Expect(traits[0].Trait).ShouldNot(BeNil())
 		Expect(traits[0].Alias).Should(Equal("test-alias"))
 
+		By("List the trait definitions from the local cluster")
+		localTraits, err := definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", Cluster: "local"})
+		Expect(err).Should(BeNil())
+		Expect(localTraits).Should(Equal(traits))
+
+		By("List the component definitions with the schema stats")
+		schemaData, err := os.ReadFile("./testdata/api-schema.json")
+		Expect(err).Should(Succeed())
+		var detail v1.DetailDefinitionResponse
+		Expect(json.Unmarshal(schemaData, &detail)).Should(Succeed())
+		apiSchemaData, err := detail.APISchema.MarshalJSON()
+		Expect(err).Should(Succeed())
+		Expect(k8sClient.Create(context.Background(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "component-schema-webservice-test", Namespace: "vela-system"},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: string(apiSchemaData)},
+		})).Should(Succeed())
+		statsDefinitions, err := definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "component", IncludeSchemaStats: true})
+		Expect(err).Should(BeNil())
+		for _, definition := range statsDefinitions {
+			if definition.Name == "webservice-test" {
+				Expect(definition.ParameterCount).Should(Equal(12))
+				Expect(definition.RequiredCount).Should(Equal(3))
+			}
+		}
+
+		By("List the brief trait definitions")
+		briefTraits, err := definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", Brief: true})
+		Expect(err).Should(BeNil())
+		Expect(cmp.Diff(len(briefTraits), 2)).Should(BeEmpty())
+		Expect(briefTraits[0].Name).Should(Equal("myingress"))
+		Expect(briefTraits[0].Alias).Should(Equal("test-alias"))
+		Expect(briefTraits[0].OwnerAddon).Should(Equal("fluxcd"))
+		Expect(briefTraits[0].Trait).Should(BeNil())
+		briefComponents, err := definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "component", Brief: true})
+		Expect(err).Should(BeNil())
+		for _, definition := range briefComponents {
+			Expect(definition.Component).Should(BeNil())
+			if definition.Name == "webservice-test" {
+				Expect(definition.WorkloadType).Should(Equal("deployments.apps"))
+			}
+		}
+
 		By("List workflow step definitions")
 		step, err := os.ReadFile("./testdata/applyapplication-sd.yaml")
 		Expect(err).Should(Succeed())
@@ -161,6 +239,56 @@ var _ = Describe("Test namespace service functions", func() {
 		// We should see myingress being kept because fluxcd is its owner
 		Expect(len(list) >= 1).Should(Equal(true))
 		Expect(list[0].Name).Should(Equal("myingress"))
+
+		By("Filtering list by multiple owner addons")
+		list, err = definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", OwnerAddons: []string{"non-existent-addon", "terraform"}})
+		Expect(err).Should(Succeed())
+		Expect(list).Should(HaveLen(0))
+
+		list, err = definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", OwnerAddons: []string{"terraform", "fluxcd"}})
+		Expect(err).Should(Succeed())
+		Expect(list).Should(HaveLen(1))
+		Expect(list[0].Name).Should(Equal("myingress"))
+
+		list, err = definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", OwnerAddon: "terraform", OwnerAddons: []string{"fluxcd"}})
+		Expect(err).Should(Succeed())
+		Expect(list).Should(HaveLen(1))
+
+		By("Sorting the list")
+		list, err = definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", SortBy: DefinitionSortByName, SortOrder: datastore.SortOrderDescending})
+		Expect(err).Should(Succeed())
+		Expect(list).Should(HaveLen(2))
+		Expect(list[0].Name).Should(Equal("scaler"))
+
+		list, err = definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", SortBy: DefinitionSortByAlias})
+		Expect(err).Should(Succeed())
+		Expect(list[0].Name).Should(Equal("scaler"))
+
+		list, err = definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", SortBy: DefinitionSortByCreateTime, SortOrder: datastore.SortOrderDescending})
+		Expect(err).Should(Succeed())
+		Expect(list[0].Name).Should(Equal("myingress"))
+
+		_, err = definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", SortBy: "unknown"})
+		Expect(err).Should(Equal(bcode.ErrDefinitionSortByNotSupport))
+
+		By("Counting the definitions by type")
+		counts, err := definitionService.CountDefinitionsByType(context.TODO(), DefinitionQueryOption{})
+		Expect(err).Should(Succeed())
+		for _, defType := range []string{"component", "trait", "workflowstep", "policy"} {
+			list, err := definitionService.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: defType, Brief: true})
+			Expect(err).Should(Succeed())
+			Expect(counts[defType]).Should(Equal(len(list)))
+		}
+		Expect(counts["workflowstep"]).Should(Equal(2))
+
+		counts, err = definitionService.CountDefinitionsByType(context.TODO(), DefinitionQueryOption{QueryAll: true})
+		Expect(err).Should(Succeed())
+		Expect(counts["workflowstep"]).Should(Equal(3))
+
+		counts, err = definitionService.CountDefinitionsByType(context.TODO(), DefinitionQueryOption{OwnerAddon: "fluxcd"})
+		Expect(err).Should(Succeed())
+		Expect(counts["trait"]).Should(Equal(1))
+		Expect(counts["policy"]).Should(Equal(0))
 	})
 
 	It("Test DetailDefinition function", func() {
@@ -183,7 +311,7 @@ var _ = Describe("Test namespace service functions", func() {
 		}
 		err = k8sClient.Create(context.Background(), cm)
 		Expect(err).Should(Succeed())
-		definitionDetail, err := definitionService.DetailDefinition(context.TODO(), "apply-object", "workflowstep")
+		definitionDetail, err := definitionService.DetailDefinition(context.TODO(), "apply-object", "workflowstep", DetailDefinitionOption{})
 		Expect(err).Should(Succeed())
 
 		schemaFromCM := &openapi3.Schema{}
@@ -192,6 +320,53 @@ var _ = Describe("Test namespace service functions", func() {
 
 		Expect(definitionDetail.APISchema).Should(Equal(schemaFromCM))
 		Expect(definitionDetail.WorkflowStep).ShouldNot(BeNil())
+		Expect(definitionDetail.Template).Should(Equal(cd.Spec.Schematic.CUE.Template))
+
+		By("the definition without schematic should return the empty template")
+		policyDetail, err := definitionService.DetailDefinition(context.TODO(), "health", "policy", DetailDefinitionOption{})
+		Expect(err).Should(Succeed())
+		Expect(policyDetail.Template).Should(BeEmpty())
+
+		By("export the parameters as the JSON schema")
+		data, err := definitionService.ExportDefinitionJSONSchema(context.TODO(), "apply-object", "workflowstep")
+		Expect(err).Should(Succeed())
+		jsonSchema := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &jsonSchema)).Should(Succeed())
+		Expect(jsonSchema["$schema"]).Should(Equal("http://json-schema.org/draft-07/schema#"))
+		Expect(jsonSchema["title"]).Should(Equal("apply-object"))
+		Expect(jsonSchema["required"]).Should(Equal([]interface{}{"targetRevision", "targetSize"}))
+		_, err = definitionService.ExportDefinitionJSONSchema(context.TODO(), "health", "policy")
+		Expect(err).Should(Equal(bcode.ErrDefinitionNoSchema))
+	})
+
+	It("Test DiffDefinitionSchema function", func() {
+		revisions := map[string]string{
+			"workflowstep-schema-apply-object-v1": `{"properties":{"replicas":{"type":"integer","default":1},"image":{"type":"string"},"volumes":{"type":"array","items":{"properties":{"name":{"type":"string"},"type":{"type":"string","enum":["pvc","configMap"]}},"type":"object"}}},"required":["image"],"type":"object"}`,
+			"workflowstep-schema-apply-object-v2": `{"properties":{"replicas":{"type":"integer","default":2},"image":{"type":"string"},"port":{"type":"integer"},"volumes":{"type":"array","items":{"properties":{"type":{"type":"string","enum":["pvc","configMap","secret"]}},"type":"object"}}},"required":["image","port"],"type":"object"}`,
+		}
+		for name, data := range revisions {
+			Expect(k8sClient.Create(context.Background(), &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "vela-system"},
+				Data:       map[string]string{types.OpenapiV3JSONSchema: data},
+			})).Should(Succeed())
+		}
+		diff, err := definitionService.DiffDefinitionSchema(context.TODO(), "apply-object", "workflowstep", "v1", "2")
+		Expect(err).Should(Succeed())
+		Expect(diff.Added).Should(Equal([]v1.DefinitionPropertyDiff{{Path: "port", To: &v1.DefinitionPropertySummary{Type: "integer", Required: true}}}))
+		Expect(diff.Removed).Should(Equal([]v1.DefinitionPropertyDiff{{Path: "volumes[].name", From: &v1.DefinitionPropertySummary{Type: "string"}}}))
+		var changed []string
+		for _, c := range diff.Changed {
+			changed = append(changed, c.Path)
+		}
+		Expect(changed).Should(Equal([]string{"replicas", "volumes[].type"}))
+		Expect(diff.Changed[0].From.Default).Should(BeEquivalentTo(1))
+		Expect(diff.Changed[0].To.Default).Should(BeEquivalentTo(2))
+
+		_, err = json.Marshal(diff)
+		Expect(err).Should(Succeed())
+
+		_, err = definitionService.DiffDefinitionSchema(context.TODO(), "apply-object", "workflowstep", "v1", "v3")
+		Expect(err).Should(Equal(bcode.ErrDefinitionRevisionNotFound))
 	})
 
 	It("Test renderDefaultUISchema", func() {
@@ -201,8 +376,85 @@ var _ = Describe("Test namespace service functions", func() {
 		err = json.Unmarshal(data, schema)
 		Expect(err).Should(Succeed())
 		Expect(cmp.Diff(len(schema.APISchema.Required), 3)).Should(BeEmpty())
-		uiSchema := renderDefaultUISchema(schema.APISchema)
+		uiSchema := renderDefaultUISchema(schema.APISchema, "")
 		Expect(cmp.Diff(len(uiSchema), 12)).Should(BeEmpty())
+		parameters, required := countSchemaParameters(schema.APISchema)
+		Expect(parameters).Should(Equal(12))
+		Expect(required).Should(Equal(3))
+	})
+
+	It("Test the labels of the enum values", func() {
+		apiSchema := &openapi3.Schema{}
+		err := apiSchema.UnmarshalJSON([]byte(`{"properties":{"volumes":{"title":"volumes","type":"string","enum":["pvc","configMap","secret"],"x-vela-enum-labels":{"pvc":"Persistent Volume Claim","secret":"Secret Volume"}},"replicas":{"title":"replicas","type":"integer","enum":[1,3]}},"type":"object"}`))
+		Expect(err).Should(Succeed())
+		uiSchema := renderDefaultUISchema(apiSchema, "")
+		options := map[string][]schema.Option{}
+		for _, param := range uiSchema {
+			options[param.JSONKey] = param.Validate.Options
+		}
+		Expect(options["volumes"]).Should(Equal([]schema.Option{
+			{Label: "Persistent Volume Claim", Value: "pvc"},
+			{Label: "ConfigMap", Value: "configMap"},
+			{Label: "Secret Volume", Value: "secret"},
+		}))
+		// fall back to the raw value if the extension is absent
+		Expect(options["replicas"]).Should(Equal([]schema.Option{{Label: "1", Value: float64(1)}, {Label: "3", Value: float64(3)}}))
+	})
+
+	It("Test the conditions of the ui schema", func() {
+		apiSchema := &openapi3.Schema{}
+		err := apiSchema.UnmarshalJSON([]byte(`{"properties":{"type":{"title":"type","type":"string","enum":["pvc","secret"]},"claimName":{"title":"claimName","type":"string","x-vela-conditions":[{"jsonKey":"type","op":"==","value":"pvc"},{"jsonKey":"type","action":"unknown","value":"secret"}]},"secretName":{"title":"secretName","type":"string"}},"type":"object"}`))
+		Expect(err).Should(Succeed())
+		conditions := map[string][]schema.Condition{}
+		for _, param := range renderDefaultUISchema(apiSchema, "") {
+			conditions[param.JSONKey] = param.Conditions
+		}
+		// the invalid condition is ignored
+		Expect(conditions["claimName"]).Should(Equal([]schema.Condition{{JSONKey: "type", Op: "==", Value: "pvc"}}))
+		Expect(conditions["secretName"]).Should(BeNil())
+		Expect(schema.UISchema(renderDefaultUISchema(apiSchema, "")).Validate()).Should(Succeed())
+	})
+
+	It("Test the default values of the ui schema", func() {
+		data, err := os.ReadFile("./testdata/api-schema-defaults.json")
+		Expect(err).Should(Succeed())
+		apiSchema := &openapi3.Schema{}
+		Expect(apiSchema.UnmarshalJSON(data)).Should(Succeed())
+		defaults := map[string]interface{}{}
+		var collect func(prefix string, params []*schema.UIParameter)
+		collect = func(prefix string, params []*schema.UIParameter) {
+			for _, param := range params {
+				defaults[prefix+param.JSONKey] = param.Validate.DefaultValue
+				collect(prefix+param.JSONKey+".", param.SubParameters)
+			}
+		}
+		collect("", renderDefaultUISchema(apiSchema, ""))
+		Expect(defaults["image"]).Should(Equal("nginx"))
+		Expect(defaults["cpu"]).Should(Equal(0.5))
+		Expect(defaults["replicas"]).Should(Equal(int64(3)))
+		Expect(defaults["expose"]).Should(Equal(true))
+		Expect(defaults["ports"]).Should(Equal([]interface{}{float64(80), float64(443)}))
+		// the sub parameters inherit the default values from the object
+		Expect(defaults["probe.path"]).Should(Equal("/healthz"))
+		Expect(defaults["probe.timeout"]).Should(Equal(int64(5)))
+		Expect(defaults["probe.http.port"]).Should(Equal(int64(8080)))
+		Expect(defaults["probe.http.scheme"]).Should(Equal("HTTP"))
+	})
+
+	It("Test the placeholders rendered from the examples", func() {
+		data, err := os.ReadFile("./testdata/api-schema-examples.json")
+		Expect(err).Should(Succeed())
+		apiSchema := &openapi3.Schema{}
+		Expect(apiSchema.UnmarshalJSON(data)).Should(Succeed())
+		placeholders := renderSchemaPlaceholders(apiSchema)
+		Expect(placeholders["image"]).Should(Equal("nginx:1.21"))
+		Expect(placeholders["replicas"]).Should(Equal("3"))
+		Expect(placeholders["cmd"]).Should(Equal(`["sleep","1000"]`))
+		Expect(placeholders["cmd[]"]).Should(Equal("sleep"))
+		Expect(placeholders["resources"]).Should(Equal(`{"cpu":"500m"}`))
+		Expect(placeholders["resources.cpu"]).Should(Equal("0.5"))
+		Expect(placeholders).ShouldNot(HaveKey("resources.memory"))
+		Expect(renderSchemaPlaceholders(&openapi3.Schema{})).Should(BeNil())
 	})
 
 	It("Test patchSchema", func() {
@@ -212,7 +464,7 @@ var _ = Describe("Test namespace service functions", func() {
 		err = json.Unmarshal(data, ddr)
 		Expect(err).Should(Succeed())
 		Expect(cmp.Diff(len(ddr.APISchema.Required), 3)).Should(BeEmpty())
-		defaultschema := renderDefaultUISchema(ddr.APISchema)
+		defaultschema := renderDefaultUISchema(ddr.APISchema, "")
 
 		customschema := []*schema.UIParameter{}
 		cdata, err := os.ReadFile("./testdata/ui-custom-schema.yaml")
@@ -221,9 +473,30 @@ var _ = Describe("Test namespace service functions", func() {
 		Expect(err).Should(Succeed())
 
 		uiSchema := patchSchema(defaultschema, customschema)
-		Expect(cmp.Diff(len(uiSchema), 12)).Should(BeEmpty())
+		// the disabled parameters are removed
+		Expect(cmp.Diff(len(uiSchema), 9)).Should(BeEmpty())
 		Expect(cmp.Diff(uiSchema[7].JSONKey, "livenessProbe")).Should(BeEmpty())
-		Expect(cmp.Diff(len(uiSchema[7].SubParameters), 8)).Should(BeEmpty())
+		Expect(cmp.Diff(len(uiSchema[7].SubParameters), 7)).Should(BeEmpty())
+		var livenessProbeKeys []string
+		for _, param := range uiSchema[7].SubParameters {
+			livenessProbeKeys = append(livenessProbeKeys, param.JSONKey)
+		}
+		Expect(livenessProbeKeys).ShouldNot(ContainElement("exec"))
+		Expect(livenessProbeKeys).Should(ContainElement("tcpSocket"))
+		Expect(uiSchema[6].SubParameters).Should(HaveLen(8))
+
+		By("the disabled parameter with the conditions is kept")
+		disable := true
+		uiSchema = patchSchema(renderDefaultUISchema(ddr.APISchema, ""), []*schema.UIParameter{{
+			JSONKey:    "cmd",
+			Disable:    &disable,
+			Conditions: []schema.Condition{{JSONKey: "image", Op: "!=", Value: ""}},
+		}})
+		var keys []string
+		for _, param := range uiSchema {
+			keys = append(keys, param.JSONKey)
+		}
+		Expect(keys).Should(ContainElement("cmd"))
 	})
 
 	It("Test sortDefaultUISchema", testSortDefaultUISchema)
@@ -237,7 +510,8 @@ var _ = Describe("Test namespace service functions", func() {
 		var schema schema.UISchema
 		err = yaml.Unmarshal(cdata, &schema)
 		Expect(err).Should(Succeed())
-		uiSchema, err := du.AddDefinitionUISchema(context.TODO(), "apply-object", "workflowstep", schema)
+		userCtx := context.WithValue(context.TODO(), &v1.CtxKeyUser, "admin")
+		uiSchema, err := du.AddDefinitionUISchema(userCtx, "apply-object", "workflowstep", schema, "")
 		Expect(err).Should(Succeed())
 		for _, param := range uiSchema {
 			if param.JSONKey == "batchPartition" {
@@ -246,6 +520,21 @@ var _ = Describe("Test namespace service functions", func() {
 				Expect(param.Sort).Should(Equal(uint(77)))
 			}
 		}
+		detail, err := du.DetailDefinition(context.TODO(), "apply-object", "workflowstep", DetailDefinitionOption{})
+		Expect(err).Should(Succeed())
+		Expect(detail.LastModifiedBy).Should(Equal("admin"))
+		Expect(detail.LastModifiedTime).ShouldNot(BeNil())
+
+		By("reset the ui schema to the default")
+		defaultSchema, err := du.ResetDefinitionUISchema(userCtx, "apply-object", "workflowstep")
+		Expect(err).Should(Succeed())
+		detail, err = du.DetailDefinition(context.TODO(), "apply-object", "workflowstep", DetailDefinitionOption{})
+		Expect(err).Should(Succeed())
+		Expect(detail.LastModifiedBy).Should(BeEmpty())
+		Expect(defaultSchema).Should(Equal(detail.UISchema))
+		Expect(cmp.Diff(defaultSchema, renderDefaultUISchema(detail.APISchema, ""))).Should(BeEmpty())
+		_, err = du.ResetDefinitionUISchema(userCtx, "apply-object", "workflowstep")
+		Expect(err).Should(Succeed())
 	})
 
 	It("Test update status of the definition", func() {
@@ -258,6 +547,17 @@ var _ = Describe("Test namespace service functions", func() {
 		})
 		Expect(err).Should(Succeed())
 		Expect(detail.Status).Should(Equal("disable"))
+		Expect(detail.HiddenInUI).Should(BeTrue())
+
+		By("the hidden definition should be refused for the non-admin user")
+		_, err = definitionService.DetailDefinition(context.WithValue(context.TODO(), &v1.CtxKeyUser, "non-admin"), "apply-object", "workflowstep", DetailDefinitionOption{ExcludeHidden: true})
+		Expect(err).Should(Equal(bcode.ErrDefinitionHidden))
+		ok, err := InitTestAdmin(userService)
+		Expect(err).Should(BeNil())
+		Expect(ok).Should(BeTrue())
+		detail, err = definitionService.DetailDefinition(context.WithValue(context.TODO(), &v1.CtxKeyUser, FakeAdminName), "apply-object", "workflowstep", DetailDefinitionOption{ExcludeHidden: true})
+		Expect(err).Should(Succeed())
+		Expect(detail.HiddenInUI).Should(BeTrue())
 
 		detail, err = du.UpdateDefinitionStatus(context.TODO(), "apply-object", v1.UpdateDefinitionStatusRequest{
 			DefinitionType: "workflowstep",
@@ -265,6 +565,19 @@ var _ = Describe("Test namespace service functions", func() {
 		})
 		Expect(err).Should(Succeed())
 		Expect(detail.Status).Should(Equal("enable"))
+		Expect(detail.HiddenInUI).Should(BeFalse())
+
+		By("dry run should not change the status of the definition")
+		detail, err = du.UpdateDefinitionStatus(context.TODO(), "apply-object", v1.UpdateDefinitionStatusRequest{
+			DefinitionType: "workflowstep",
+			HiddenInUI:     true,
+			DryRun:         true,
+		})
+		Expect(err).Should(Succeed())
+		Expect(detail.Status).Should(Equal("disable"))
+		detail, err = du.DetailDefinition(context.TODO(), "apply-object", "workflowstep", DetailDefinitionOption{})
+		Expect(err).Should(Succeed())
+		Expect(detail.Status).Should(Equal("enable"))
 	})
 
 })
@@ -286,9 +599,16 @@ func testSortDefaultUISchema() {
 				Required: true,
 			},
 			SubParameters: []*schema.UIParameter{
-				{Label: "T2S1"},
-				{Label: "T2S2"},
 				{Label: "T2S3"},
+				{Label: "T2S1"},
+				{
+					Label:    "T2S2",
+					Validate: &schema.Validate{Required: true},
+					SubParameters: []*schema.UIParameter{
+						{Label: "T2S2B"},
+						{Label: "T2S2A", Validate: &schema.Validate{}},
+					},
+				},
 			},
 			Sort: 100,
 		}, {
@@ -389,6 +709,30 @@ func testSortDefaultUISchema() {
 		Expect(param.Label).Should(Equal(expectedParams[i].Label))
 		Expect(param.Sort).Should(Equal(expectedParams[i].Sort))
 	}
+
+	// the sub parameters are sorted in the same way at every level
+	labelsAndSorts := func(params []*schema.UIParameter) []string {
+		var result []string
+		for _, param := range params {
+			result = append(result, fmt.Sprintf("%s:%d", param.Label, param.Sort))
+		}
+		return result
+	}
+	t2 := params[3]
+	Expect(labelsAndSorts(t2.SubParameters)).Should(Equal([]string{"T2S2:100", "T2S1:101", "T2S3:102"}))
+	Expect(labelsAndSorts(t2.SubParameters[0].SubParameters)).Should(Equal([]string{"T2S2A:100", "T2S2B:101"}))
+	Expect(labelsAndSorts(params[2].SubParameters)).Should(Equal([]string{"P6S1:100", "P6S2:101", "P6S3:102"}))
+
+	// sorting again doesn't change the sort numbers
+	sortDefaultUISchema(params)
+	Expect(labelsAndSorts(t2.SubParameters)).Should(Equal([]string{"T2S2:100", "T2S1:101", "T2S3:102"}))
+	Expect(labelsAndSorts(params)).Should(Equal([]string{"P1:100", "T5:101", "P6:102", "T2:103", "P4:104", "T3:105"}))
+}
+
+// newTestDefinitionService builds the definition service on a fake client with the given objects
+func newTestDefinitionService(objs ...client.Object) (*definitionServiceImpl, client.Client) {
+	cli := fake.NewClientBuilder().WithScheme(common.Scheme).WithObjects(objs...).Build()
+	return &definitionServiceImpl{KubeClient: cli}, cli
 }
 
 func TestDefinitionQueryOption(t *testing.T) {
@@ -398,4 +742,1582 @@ func TestDefinitionQueryOption(t *testing.T) {
 		Type:     "workflowstep",
 		QueryAll: true,
 	}.String(), false)
+
+	testCases := map[string]struct {
+		a DefinitionQueryOption
+		b DefinitionQueryOption
+	}{
+		"type":               {a: DefinitionQueryOption{Type: "component"}, b: DefinitionQueryOption{Type: "trait"}},
+		"appliedWorkloads":   {a: DefinitionQueryOption{AppliedWorkloads: "deployments.apps"}, b: DefinitionQueryOption{}},
+		"ownerAddon":         {a: DefinitionQueryOption{OwnerAddon: "fluxcd"}, b: DefinitionQueryOption{}},
+		"ownerAddons":        {a: DefinitionQueryOption{OwnerAddons: []string{"a", "b"}}, b: DefinitionQueryOption{OwnerAddons: []string{"a,b"}}},
+		"queryAll":           {a: DefinitionQueryOption{QueryAll: true}, b: DefinitionQueryOption{}},
+		"scope":              {a: DefinitionQueryOption{Scope: "WorkflowRun"}, b: DefinitionQueryOption{Scope: "Application"}},
+		"sortBy":             {a: DefinitionQueryOption{SortBy: DefinitionSortByName}, b: DefinitionQueryOption{SortBy: DefinitionSortByAlias}},
+		"sortOrder":          {a: DefinitionQueryOption{SortOrder: datastore.SortOrderAscending}, b: DefinitionQueryOption{SortOrder: datastore.SortOrderDescending}},
+		"brief":              {a: DefinitionQueryOption{Brief: true}, b: DefinitionQueryOption{}},
+		"cluster":            {a: DefinitionQueryOption{Cluster: "local"}, b: DefinitionQueryOption{Cluster: "cluster-1"}},
+		"category":           {a: DefinitionQueryOption{Category: "Scaling"}, b: DefinitionQueryOption{}},
+		"includeSchemaStats": {a: DefinitionQueryOption{IncludeSchemaStats: true}, b: DefinitionQueryOption{}},
+		"keyword":            {a: DefinitionQueryOption{Keyword: "scaler"}, b: DefinitionQueryOption{ParameterKeyword: "scaler"}},
+		"schemaReady":        {a: DefinitionQueryOption{SchemaReady: pointer.Bool(true)}, b: DefinitionQueryOption{SchemaReady: pointer.Bool(false)}},
+		"separator in value": {a: DefinitionQueryOption{Type: "a/ownerAddon:b"}, b: DefinitionQueryOption{Type: "a", OwnerAddon: "b"}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.NotEqual(t, tc.a.String(), tc.b.String())
+		})
+	}
+
+	// every field must be a part of the key, including the fields added in the future
+	empty := DefinitionQueryOption{}.String()
+	optionType := reflect.TypeOf(DefinitionQueryOption{})
+	for i := 0; i < optionType.NumField(); i++ {
+		option := DefinitionQueryOption{}
+		field := reflect.ValueOf(&option).Elem().Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString("x")
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Int, reflect.Int64:
+			field.SetInt(1)
+		case reflect.Slice:
+			field.Set(reflect.ValueOf([]string{"x"}))
+		case reflect.Ptr:
+			field.Set(reflect.New(field.Type().Elem()))
+		default:
+			t.Fatalf("the kind of the field %s is not covered", optionType.Field(i).Name)
+		}
+		assert.NotEqual(t, empty, option.String(), optionType.Field(i).Name)
+	}
+}
+
+func TestSortDefaultUISchemaDeterministic(t *testing.T) {
+	newParams := func() []*schema.UIParameter {
+		return []*schema.UIParameter{
+			{Label: "Name", JSONKey: "name", Validate: &schema.Validate{Required: true}, Sort: 100},
+			{Label: "Name", JSONKey: "alias", Validate: &schema.Validate{Required: true}, Sort: 100},
+			{Label: "Port", JSONKey: "port", Validate: &schema.Validate{}, Sort: 100},
+			{Label: "Image", JSONKey: "image", Validate: &schema.Validate{}, Sort: 100},
+			{Label: "Image", JSONKey: "imageRef", Validate: &schema.Validate{}, Sort: 100},
+			{Label: "Env", JSONKey: "env", Validate: &schema.Validate{}, Sort: 100, SubParameters: []*schema.UIParameter{{Label: "Value"}}},
+		}
+	}
+	var expected []string
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		params := newParams()
+		r.Shuffle(len(params), func(i, j int) { params[i], params[j] = params[j], params[i] })
+		sortDefaultUISchema(params)
+		var keys []string
+		for j, param := range params {
+			keys = append(keys, param.JSONKey)
+			assert.Equal(t, uint(100+j), param.Sort)
+		}
+		if expected == nil {
+			expected = keys
+		}
+		assert.Equal(t, expected, keys)
+	}
+	assert.Equal(t, []string{"alias", "name", "image", "imageRef", "port", "env"}, expected)
+}
+
+func TestWithDefinitionCluster(t *testing.T) {
+	ctx := context.TODO()
+	assert.Equal(t, ctx, withDefinitionCluster(ctx, ""))
+	assert.Equal(t, ctx, withDefinitionCluster(ctx, multicluster.ClusterLocalName))
+	assert.Equal(t, "cluster-worker", multicluster.ClusterNameInContext(withDefinitionCluster(ctx, "cluster-worker")))
+}
+
+func TestUISchemaInheritance(t *testing.T) {
+	newUISchemaConfigMap := func(name, inheritsFrom string, uiSchema []*schema.UIParameter) *corev1.ConfigMap {
+		data, err := json.Marshal(uiSchema)
+		assert.NoError(t, err)
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-uischema-" + name, Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.UISchema: string(data)},
+		}
+		if inheritsFrom != "" {
+			cm.Annotations = map[string]string{AnnoUISchemaInheritsFrom: inheritsFrom}
+		}
+		return cm
+	}
+	cli := fake.NewClientBuilder().WithObjects(
+		newUISchemaConfigMap("base", "", []*schema.UIParameter{{JSONKey: "replicas", Label: "Base Replicas", Sort: 10}}),
+		newUISchemaConfigMap("middle", "base", []*schema.UIParameter{{JSONKey: "replicas", Sort: 20}}),
+		newUISchemaConfigMap("child", "middle", []*schema.UIParameter{{JSONKey: "image", Label: "Child Image"}}),
+	).Build()
+	ctx := context.TODO()
+
+	chain := listInheritedUISchemaConfigMaps(ctx, cli, getCustomUISchemaConfigMap(ctx, cli, types.DefaultKubeVelaNS, "child", "trait"), "trait")
+	var names []string
+	for _, cm := range chain {
+		names = append(names, cm.Name)
+	}
+	assert.Equal(t, []string{"trait-uischema-base", "trait-uischema-middle", "trait-uischema-child"}, names)
+
+	uiSchema := []*schema.UIParameter{
+		{JSONKey: "replicas", Label: "Replicas", Validate: &schema.Validate{}},
+		{JSONKey: "image", Label: "Image", Validate: &schema.Validate{}},
+	}
+	for _, cm := range chain {
+		uiSchema = renderCustomUISchema(cm, uiSchema)
+	}
+	params := map[string]*schema.UIParameter{}
+	for _, param := range uiSchema {
+		params[param.JSONKey] = param
+	}
+	assert.Equal(t, "Base Replicas", params["replicas"].Label)
+	assert.Equal(t, uint(20), params["replicas"].Sort)
+	assert.Equal(t, "Child Image", params["image"].Label)
+
+	assert.NoError(t, checkUISchemaInheritance(ctx, cli, types.DefaultKubeVelaNS, "another", "trait", "child"))
+	assert.NoError(t, checkUISchemaInheritance(ctx, cli, types.DefaultKubeVelaNS, "child", "trait", ""))
+	err := checkUISchemaInheritance(ctx, cli, types.DefaultKubeVelaNS, "base", "trait", "child")
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionUISchemaInheritanceCycle))
+	err = checkUISchemaInheritance(ctx, cli, types.DefaultKubeVelaNS, "child", "trait", "child")
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionUISchemaInheritanceCycle))
+}
+
+func TestConvertToJSONSchema(t *testing.T) {
+	apiSchema := &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON([]byte(`{
+		"type": "object",
+		"required": ["image"],
+		"properties": {
+			"image": {"type": "string", "pattern": "^[a-z]+", "example": "nginx"},
+			"protocol": {"type": "string", "enum": ["TCP", "UDP"], "default": "TCP", "nullable": true},
+			"replicas": {"type": "integer", "minimum": 0, "exclusiveMinimum": true, "maximum": 10},
+			"ports": {"type": "array", "minItems": 1, "items": {"type": "object", "required": ["port"], "properties": {"port": {"type": "integer"}}}},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"volume": {"$ref": "#/components/schemas/volume"}
+		}
+	}`)))
+	// the recursive schema should not overflow
+	apiSchema.Properties["self"] = &openapi3.SchemaRef{Value: apiSchema}
+
+	jsonSchema := convertToJSONSchema(apiSchema, map[*openapi3.Schema]bool{})
+	data, err := json.Marshal(jsonSchema)
+	assert.NoError(t, err)
+	var out map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &out))
+
+	assert.Equal(t, "object", out["type"])
+	assert.Equal(t, []interface{}{"image"}, out["required"])
+	properties := out["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "string", "pattern": "^[a-z]+", "examples": []interface{}{"nginx"}}, properties["image"])
+	assert.Equal(t, map[string]interface{}{"type": []interface{}{"string", "null"}, "enum": []interface{}{"TCP", "UDP", nil}, "default": "TCP"}, properties["protocol"])
+	assert.Equal(t, map[string]interface{}{"type": "integer", "exclusiveMinimum": float64(0), "maximum": float64(10)}, properties["replicas"])
+	assert.Equal(t, map[string]interface{}{
+		"type":     "array",
+		"minItems": float64(1),
+		"items": map[string]interface{}{
+			"type":       "object",
+			"required":   []interface{}{"port"},
+			"properties": map[string]interface{}{"port": map[string]interface{}{"type": "integer"}},
+		},
+	}, properties["ports"])
+	assert.Equal(t, map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}}, properties["labels"])
+	assert.Equal(t, map[string]interface{}{}, properties["volume"])
+	assert.Equal(t, map[string]interface{}{}, properties["self"])
+}
+
+func TestDefinitionSchemaChangeCallback(t *testing.T) {
+	ctx := context.TODO()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: types.DefaultKubeVelaNS},
+		Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`},
+	}
+	du, cli := newTestDefinitionService(cm)
+	var changes []DefinitionSchemaChange
+	du.RegisterSchemaChangeCallback(func(ctx context.Context, change DefinitionSchemaChange) {
+		changes = append(changes, change)
+	})
+
+	// the first read only records the version
+	_, err := du.getDefinitionSchema(ctx, "scaler", "trait", "")
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	assert.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(cm), cm))
+	oldVersion := cm.ResourceVersion
+	cm.Data[types.OpenapiV3JSONSchema] = `{"properties":{"replicas":{"type":"integer"},"step":{"type":"integer"}},"type":"object"}`
+	assert.NoError(t, cli.Update(ctx, cm))
+	_, err = du.getDefinitionSchema(ctx, "scaler", "trait", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []DefinitionSchemaChange{{Name: "scaler", Type: "trait", OldResourceVersion: oldVersion, NewResourceVersion: cm.ResourceVersion}}, changes)
+
+	// the unchanged schema should not be notified again
+	_, err = du.getDefinitionSchema(ctx, "scaler", "trait", "")
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+}
+
+func TestDefinitionBuiltIn(t *testing.T) {
+	load := func(file string) unstructured.Unstructured {
+		data, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		var def unstructured.Unstructured
+		assert.NoError(t, yaml.Unmarshal(data, &def.Object))
+		return def
+	}
+	scaler := load("./testdata/scaler.yaml")
+	assert.True(t, convertDefinitionBrief(scaler, kindTraitDefinition, types.DefaultKubeVelaNS).BuiltIn)
+	myingress := load("./testdata/myingress-td.yaml")
+	base := convertDefinitionBrief(myingress, kindTraitDefinition, types.DefaultKubeVelaNS)
+	assert.Equal(t, "fluxcd", base.OwnerAddon)
+	assert.False(t, base.BuiltIn)
+
+	// the definitions out of the system namespace are created by the users
+	scaler.SetNamespace("default")
+	assert.False(t, convertDefinitionBrief(scaler, kindTraitDefinition, types.DefaultKubeVelaNS).BuiltIn)
+}
+
+func TestDetailDefinitionByAlias(t *testing.T) {
+	newTrait := func(name, alias string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS, Annotations: map[string]string{types.AnnoDefinitionAlias: alias}},
+		}
+	}
+	newSchema := func(name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-" + name, Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`},
+		}
+	}
+	du, _ := newTestDefinitionService(
+		newTrait("scaler", "Scaler"), newSchema("scaler"),
+		newTrait("gateway", "Ingress"), newSchema("gateway"),
+		newTrait("ingress", "Gateway"), newSchema("ingress"),
+		newTrait("ingress-v2", "Gateway"), newSchema("ingress-v2"),
+	)
+
+	detail, err := du.DetailDefinition(context.TODO(), "Scaler", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, "scaler", detail.Name)
+	assert.NotNil(t, detail.APISchema)
+
+	// the exact name is matched before the alias
+	detail, err = du.DetailDefinition(context.TODO(), "ingress", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ingress", detail.Name)
+
+	_, err = du.DetailDefinition(context.TODO(), "Gateway", "trait", DetailDefinitionOption{})
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionAliasAmbiguous))
+	assert.Equal(t, []string{"ingress", "ingress-v2"}, err.(*bcode.Bcode).Details)
+
+	_, err = du.DetailDefinition(context.TODO(), "not-exist", "trait", DetailDefinitionOption{})
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionNotFound))
+}
+
+func TestDetailDefinitionDetectType(t *testing.T) {
+	newSchema := func(defType, name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: defType + "-schema-" + name, Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"image":{"type":"string"}},"type":"object"}`},
+		}
+	}
+	du, _ := newTestDefinitionService(
+		&v1beta1.ComponentDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "ComponentDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "webservice", Namespace: types.DefaultKubeVelaNS},
+		}, newSchema("component", "webservice"),
+		&v1beta1.ComponentDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "ComponentDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: types.DefaultKubeVelaNS},
+		}, newSchema("component", "shared"),
+		&v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: types.DefaultKubeVelaNS},
+		}, newSchema("trait", "shared"),
+	)
+
+	detail, err := du.DetailDefinition(context.TODO(), "webservice", "", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, "webservice", detail.Name)
+	assert.NotNil(t, detail.APISchema)
+
+	_, err = du.DetailDefinition(context.TODO(), "shared", "", DetailDefinitionOption{})
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionTypeAmbiguous))
+	assert.Equal(t, []string{"component", "trait"}, err.(*bcode.Bcode).Details)
+
+	// the explicit type is not affected by the definitions of the other types
+	detail, err = du.DetailDefinition(context.TODO(), "shared", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, "shared", detail.Name)
+
+	_, err = du.DetailDefinition(context.TODO(), "not-exist", "", DetailDefinitionOption{})
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionNotFound))
+}
+
+func TestBatchUpdateDefinitionStatus(t *testing.T) {
+	newTrait := func(name string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS, Labels: map[string]string{}},
+		}
+	}
+	newSchema := func(name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-" + name, Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`},
+		}
+	}
+	du, cli := newTestDefinitionService(
+		newTrait("addon-trait-1"), newSchema("addon-trait-1"),
+		newTrait("addon-trait-2"), newSchema("addon-trait-2"),
+	)
+
+	resp, err := du.BatchUpdateDefinitionStatus(context.TODO(), []v1.UpdateDefinitionStatusRequest{
+		{Name: "addon-trait-1", DefinitionType: "trait", HiddenInUI: true},
+		{Name: "not-exist", DefinitionType: "trait", HiddenInUI: true},
+		{Name: "addon-trait-2", DefinitionType: "trait", HiddenInUI: true},
+		{DefinitionType: "trait", HiddenInUI: true},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 4)
+	assert.True(t, resp.Results[0].Success)
+	assert.True(t, resp.Results[0].HiddenInUI)
+	assert.False(t, resp.Results[1].Success)
+	assert.Equal(t, bcode.ErrDefinitionNotFound.Error(), resp.Results[1].Message)
+	assert.True(t, resp.Results[2].Success)
+	assert.False(t, resp.Results[3].Success)
+
+	for _, name := range []string{"addon-trait-1", "addon-trait-2"} {
+		var trait v1beta1.TraitDefinition
+		assert.NoError(t, cli.Get(context.TODO(), client.ObjectKey{Namespace: types.DefaultKubeVelaNS, Name: name}, &trait))
+		assert.Equal(t, DefinitionHidden, trait.Labels[types.LabelDefinitionHidden])
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	data, err := os.ReadFile("./testdata/api-schema.json")
+	assert.NoError(t, err)
+	var detail v1.DetailDefinitionResponse
+	assert.NoError(t, json.Unmarshal(data, &detail))
+
+	resp, err := validateParameters(detail.APISchema, map[string]interface{}{"addRevisionLabel": false, "image": "nginx", "port": 80, "cmd": []string{"sleep"}})
+	assert.NoError(t, err)
+	assert.True(t, resp.Valid)
+	assert.Empty(t, resp.Errors)
+
+	// the required fields
+	resp, err = validateParameters(detail.APISchema, map[string]interface{}{"image": "nginx"})
+	assert.NoError(t, err)
+	assert.False(t, resp.Valid)
+	var fields []string
+	for _, e := range resp.Errors {
+		assert.Equal(t, "required", e.Rule)
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"addRevisionLabel", "port"}, fields)
+
+	// the type and the enum
+	resp, err = validateParameters(detail.APISchema, map[string]interface{}{
+		"addRevisionLabel": false, "image": "nginx", "port": "80",
+		"volumes": []interface{}{map[string]interface{}{"name": "data", "mountPath": "/data", "type": "hostPath"}},
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.Valid)
+	assert.Len(t, resp.Errors, 2)
+	assert.Equal(t, "port", resp.Errors[0].Field)
+	assert.Equal(t, "type", resp.Errors[0].Rule)
+	assert.Equal(t, "volumes.0.type", resp.Errors[1].Field)
+	assert.Equal(t, "enum", resp.Errors[1].Rule)
+
+	// the minimum and the maximum
+	min, max := float64(1), float64(10)
+	resp, err = validateParameters(&openapi3.Schema{Type: "object", Properties: openapi3.Schemas{
+		"replicas": &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "integer", Min: &min, Max: &max}},
+	}}, map[string]interface{}{"replicas": 11})
+	assert.NoError(t, err)
+	assert.False(t, resp.Valid)
+	assert.Equal(t, "maximum", resp.Errors[0].Rule)
+}
+
+func TestCountDefinitionUsage(t *testing.T) {
+	newApp := func(name, namespace string, fromUX bool, spec v1beta1.ApplicationSpec) *v1beta1.Application {
+		app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Spec: spec}
+		if fromUX {
+			app.Labels = map[string]string{types.LabelSourceOfTruth: types.FromUX}
+		}
+		return app
+	}
+	du, _ := newTestDefinitionService(
+		newApp("app-1", "env-1", true, v1beta1.ApplicationSpec{
+			Components: []oamcommon.ApplicationComponent{
+				{Name: "web", Type: "webservice", Traits: []oamcommon.ApplicationTrait{{Type: "scaler"}}},
+				{Name: "worker", Type: "webservice", Traits: []oamcommon.ApplicationTrait{{Type: "scaler"}, {Type: "gateway"}}},
+			},
+			Policies: []v1beta1.AppPolicy{{Name: "topology", Type: "topology"}},
+			Workflow: &v1beta1.Workflow{Steps: []workflowv1alpha1.WorkflowStep{{
+				WorkflowStepBase: workflowv1alpha1.WorkflowStepBase{Type: "step-group"},
+				SubSteps:         []workflowv1alpha1.WorkflowStepBase{{Type: "deploy"}},
+			}}},
+		}),
+		newApp("app-2", "env-2", true, v1beta1.ApplicationSpec{
+			Components: []oamcommon.ApplicationComponent{{Name: "web", Type: "webservice", Traits: []oamcommon.ApplicationTrait{{Type: "scaler"}}}},
+		}),
+		// the applications not managed by VelaUX are not counted
+		newApp("app-3", "default", false, v1beta1.ApplicationSpec{
+			Components: []oamcommon.ApplicationComponent{{Name: "web", Type: "webservice"}},
+		}),
+	)
+
+	usage, err := du.CountDefinitionUsage(context.TODO(), "webservice", "component")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, usage.UsageCount)
+	usage, err = du.CountDefinitionUsage(context.TODO(), "scaler", "trait")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, usage.UsageCount)
+	usage, err = du.CountDefinitionUsage(context.TODO(), "gateway", "trait")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, usage.UsageCount)
+	usage, err = du.CountDefinitionUsage(context.TODO(), "topology", "policy")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, usage.UsageCount)
+	usage, err = du.CountDefinitionUsage(context.TODO(), "deploy", "workflowstep")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, usage.UsageCount)
+	usage, err = du.CountDefinitionUsage(context.TODO(), "unused", "trait")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, usage.UsageCount)
+}
+
+func TestDeleteDefinition(t *testing.T) {
+	newTrait := func(name string, labels map[string]string, owners ...metav1.OwnerReference) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS, Labels: labels, OwnerReferences: owners},
+		}
+	}
+	newCM := func(name string, labels map[string]string, owners ...metav1.OwnerReference) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS, Labels: labels, OwnerReferences: owners}}
+	}
+	schemaLabels := map[string]string{types.LabelDefinition: "schema"}
+	du, cli := newTestDefinitionService(
+		newTrait("my-scaler", nil),
+		newCM("trait-schema-my-scaler", schemaLabels),
+		newCM("trait-schema-my-scaler-v1", schemaLabels),
+		newCM("trait-uischema-my-scaler", nil),
+		// the schemas of the other definitions whose names share the prefix are kept
+		newTrait("my-scaler-v2", nil),
+		newCM("trait-schema-my-scaler-v2", schemaLabels, metav1.OwnerReference{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition", Name: "my-scaler-v2", UID: "uid"}),
+		newCM("trait-schema-my-scaler-v2-v1", schemaLabels),
+		newTrait("used", nil),
+		newTrait("used-by-cli", nil),
+		newTrait("used-by-draft", nil),
+		&v1beta1.PolicyDefinition{ObjectMeta: metav1.ObjectMeta{Name: "draft-policy", Namespace: types.DefaultKubeVelaNS}},
+		&v1beta1.WorkflowStepDefinition{ObjectMeta: metav1.ObjectMeta{Name: "draft-step", Namespace: types.DefaultKubeVelaNS}},
+		newTrait("scaler", map[string]string{"app.kubernetes.io/managed-by": "Helm"}),
+		newTrait("ingress", nil, metav1.OwnerReference{APIVersion: "core.oam.dev/v1beta1", Kind: "Application", Name: "addon-fluxcd", UID: "uid"}),
+		&v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{types.LabelSourceOfTruth: types.FromUX}},
+			Spec: v1beta1.ApplicationSpec{Components: []oamcommon.ApplicationComponent{
+				{Name: "web", Type: "webservice", Traits: []oamcommon.ApplicationTrait{{Type: "used"}}},
+			}},
+		},
+		// the application is created by the CLI or GitOps
+		&v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "cli-app", Namespace: "gitops"},
+			Spec: v1beta1.ApplicationSpec{Components: []oamcommon.ApplicationComponent{
+				{Name: "web", Type: "webservice", Traits: []oamcommon.ApplicationTrait{{Type: "used-by-cli"}}},
+			}},
+		},
+	)
+	ctx := context.TODO()
+	ds, err := kubeapi.New(ctx, datastore.Config{Database: "definition-delete"}, cli)
+	assert.NoError(t, err)
+	// the applications of VelaUX that are not deployed yet
+	assert.NoError(t, ds.Add(ctx, &model.ApplicationComponent{AppPrimaryKey: "draft", Name: "web", Type: "webservice", Traits: []model.ApplicationTrait{{Type: "used-by-draft"}}}))
+	assert.NoError(t, ds.Add(ctx, &model.ApplicationPolicy{AppPrimaryKey: "draft", Name: "policy", Type: "draft-policy"}))
+	assert.NoError(t, ds.Add(ctx, &model.Workflow{AppPrimaryKey: "draft", Name: "workflow", Steps: []model.WorkflowStep{
+		{WorkflowStepBase: model.WorkflowStepBase{Name: "group", Type: "step-group"}, SubSteps: []model.WorkflowStepBase{{Name: "step", Type: "draft-step"}}},
+	}}))
+	du.Store = ds
+
+	assert.True(t, errors.Is(du.DeleteDefinition(ctx, "my-scaler", "unknown"), bcode.ErrDefinitionTypeNotSupport))
+	assert.True(t, errors.Is(du.DeleteDefinition(ctx, "not-exist", "trait"), bcode.ErrDefinitionNotFound))
+	assert.True(t, errors.Is(du.DeleteDefinition(ctx, "scaler", "trait"), bcode.ErrDefinitionProtected))
+	assert.True(t, errors.Is(du.DeleteDefinition(ctx, "ingress", "trait"), bcode.ErrDefinitionProtected))
+	err = du.DeleteDefinition(ctx, "used", "trait")
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionInUse))
+	assert.Contains(t, err.Error(), "used by 1 applications")
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Name: "used", Namespace: types.DefaultKubeVelaNS}, &v1beta1.TraitDefinition{}))
+	err = du.DeleteDefinition(ctx, "used-by-cli", "trait")
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionInUse))
+	assert.Contains(t, err.Error(), "used by 1 applications and 0 applications of VelaUX")
+	for _, used := range []struct{ name, defType string }{{"used-by-draft", "trait"}, {"draft-policy", "policy"}, {"draft-step", "workflowstep"}} {
+		err = du.DeleteDefinition(ctx, used.name, used.defType)
+		assert.True(t, errors.Is(err, bcode.ErrDefinitionInUse), used.name)
+		assert.Contains(t, err.Error(), "used by 0 applications and 1 applications of VelaUX", used.name)
+	}
+
+	assert.NoError(t, du.DeleteDefinition(ctx, "my-scaler", "trait"))
+	assert.True(t, apierrors.IsNotFound(cli.Get(ctx, k8stypes.NamespacedName{Name: "my-scaler", Namespace: types.DefaultKubeVelaNS}, &v1beta1.TraitDefinition{})))
+	var cms corev1.ConfigMapList
+	assert.NoError(t, cli.List(ctx, &cms, client.InNamespace(types.DefaultKubeVelaNS)))
+	var names []string
+	for _, cm := range cms.Items {
+		names = append(names, cm.Name)
+	}
+	assert.Equal(t, []string{"trait-schema-my-scaler-v2", "trait-schema-my-scaler-v2-v1"}, names)
+}
+
+func TestRenderUIGroups(t *testing.T) {
+	data, err := os.ReadFile("./testdata/api-schema-groups.json")
+	assert.NoError(t, err)
+	apiSchema := &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON(data))
+	uiSchema := renderDefaultUISchema(apiSchema, "")
+	assert.Equal(t, []schema.GroupOption{
+		{Label: DefaultUIGroup, Keys: []string{"image", "cmd"}},
+		{Label: "Advanced", Keys: []string{"livenessProbe"}},
+		{Label: "Network", Keys: []string{"exposeType", "port"}},
+		{Label: "Resources", Keys: []string{"cpu", "memory"}},
+	}, renderUIGroups(apiSchema, uiSchema, nil))
+
+	// the groups follow the order of the patched ui schema
+	for _, param := range uiSchema {
+		if param.JSONKey == "port" {
+			param.Sort = 1
+		}
+	}
+	assert.Equal(t, []string{"port", "exposeType"}, renderUIGroups(apiSchema, uiSchema, nil)[2].Keys)
+
+	// no sections if no parameter declares the group
+	data, err = os.ReadFile("./testdata/api-schema.json")
+	assert.NoError(t, err)
+	detail := &v1.DetailDefinitionResponse{}
+	assert.NoError(t, json.Unmarshal(data, detail))
+	assert.Nil(t, renderUIGroups(detail.APISchema, renderDefaultUISchema(detail.APISchema, ""), nil))
+
+	// the variants of the top-level parameters are the groups if no parameter declares the group
+	data, err = os.ReadFile("./testdata/api-schema-oneof.json")
+	assert.NoError(t, err)
+	apiSchema = &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON(data))
+	uiSchema, variantGroups := renderObjectParameters(apiSchema.Properties["source"].Value, "")
+	assert.Equal(t, 3, len(variantGroups))
+	assert.Equal(t, variantGroups, renderUIGroups(apiSchema.Properties["source"].Value, uiSchema, variantGroups))
+}
+
+func TestRenderDefaultUISchemaSort(t *testing.T) {
+	data, err := os.ReadFile("./testdata/api-schema-sort.json")
+	assert.NoError(t, err)
+	apiSchema := &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON(data))
+	uiSchema := renderDefaultUISchema(apiSchema, "")
+	var keys []string
+	for i, param := range uiSchema {
+		keys = append(keys, param.JSONKey)
+		assert.Equal(t, uint(100+i), param.Sort)
+	}
+	// the pinned parameters come in the order of x-vela-sort, the required one comes first within the tie,
+	// the others are sorted as 100 and the invalid extension is ignored
+	assert.Equal(t, []string{"port", "memory", "cpu", "name", "replicas", "zone", "resources", "debug"}, keys)
+	assert.Equal(t, "b", uiSchema[6].SubParameters[0].JSONKey)
+	assert.Equal(t, "a", uiSchema[6].SubParameters[1].JSONKey)
+}
+
+func TestRenderSchemaUIHints(t *testing.T) {
+	data, err := os.ReadFile("./testdata/api-schema-units.json")
+	assert.NoError(t, err)
+	apiSchema := &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON(data))
+	assert.Equal(t, map[string]v1.UIParameterHint{
+		"cpu":                  {Unit: "core"},
+		"memory":               {Unit: "MiB", Format: "byte"},
+		"timeout":              {Format: "duration"},
+		"volumes[].targetSize": {Unit: "GiB"},
+	}, renderSchemaUIHints(apiSchema))
+
+	// no hints if no parameter declares the unit or the format
+	data, err = os.ReadFile("./testdata/api-schema-groups.json")
+	assert.NoError(t, err)
+	apiSchema = &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON(data))
+	assert.Nil(t, renderSchemaUIHints(apiSchema))
+}
+
+func TestRenderDefaultUISchemaI18n(t *testing.T) {
+	data, err := os.ReadFile("./testdata/api-schema-i18n.json")
+	assert.NoError(t, err)
+	apiSchema := &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON(data))
+	render := func(locale string) map[string][2]string {
+		texts := map[string][2]string{}
+		var collect func(prefix string, params []*schema.UIParameter)
+		collect = func(prefix string, params []*schema.UIParameter) {
+			for _, param := range params {
+				texts[prefix+param.JSONKey] = [2]string{param.Label, param.Description}
+				collect(prefix+param.JSONKey+".", param.SubParameters)
+			}
+		}
+		collect("", renderDefaultUISchema(apiSchema, locale))
+		return texts
+	}
+
+	defaultTexts := map[string][2]string{
+		"replicas":      {"Replicas", "the replicas of the workload"},
+		"image":         {"Image", "the image of the container"},
+		"resources":     {"Resources", ""},
+		"resources.cpu": {"Cpu", "the cpu limit"},
+	}
+	assert.Equal(t, defaultTexts, render(""))
+	assert.Equal(t, defaultTexts, render("fr-FR"))
+
+	// the locale is matched case-insensitively, the language is matched if there is no translation of the region
+	assert.Equal(t, map[string][2]string{
+		"replicas":      {"副本数", "工作负载的副本数"},
+		"image":         {"Image", "the image of the container"},
+		"resources":     {"Resources", ""},
+		"resources.cpu": {"Cpu", "CPU 限制"},
+	}, render("zh-cn"))
+	assert.Equal(t, [2]string{"レプリカ数", "the replicas of the workload"}, render("ja-JP")["replicas"])
+}
+
+func TestRenderDefaultUISchemaComposition(t *testing.T) {
+	load := func(file string) []*schema.UIParameter {
+		data, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		apiSchema := &openapi3.Schema{}
+		assert.NoError(t, apiSchema.UnmarshalJSON(data))
+		return renderDefaultUISchema(apiSchema, "")
+	}
+	find := func(params []*schema.UIParameter, key string) *schema.UIParameter {
+		for _, param := range params {
+			if param.JSONKey == key {
+				return param
+			}
+		}
+		return nil
+	}
+
+	// the properties of allOf are merged, the properties of the schema itself take precedence
+	params := load("./testdata/api-schema-allof.json")
+	var keys []string
+	for _, param := range params {
+		keys = append(keys, param.JSONKey)
+	}
+	assert.Equal(t, []string{"image", "port", "labels"}, keys)
+	assert.Equal(t, "Input", find(params, "image").UIType)
+	assert.True(t, find(params, "port").Validate.Required)
+	assert.Equal(t, "KV", find(params, "labels").UIType)
+
+	// the oneOf variants are discriminated by the type
+	source := find(load("./testdata/api-schema-oneof.json"), "source")
+	assert.Equal(t, "Group", source.UIType)
+	assert.Equal(t, []schema.GroupOption{
+		{Label: "Git", Keys: []string{"branch", "type", "url"}},
+		{Label: "Helm", Keys: []string{"chart", "type", "url"}},
+		{Label: "OSS Bucket", Keys: []string{"bucket", "type"}},
+	}, source.SubParameterGroupOption)
+	selector := find(source.SubParameters, "type")
+	assert.Equal(t, "type", source.SubParameters[0].JSONKey)
+	assert.Equal(t, "Select", selector.UIType)
+	assert.True(t, selector.Validate.Required)
+	assert.Equal(t, []schema.Option{{Label: "Git", Value: "git"}, {Label: "Helm", Value: "helm"}, {Label: "OSS Bucket", Value: "oss"}}, selector.Validate.Options)
+	assert.Empty(t, selector.Conditions)
+	assert.Empty(t, find(source.SubParameters, "timeout").Conditions)
+	assert.Equal(t, []schema.Condition{{JSONKey: "type", Op: "in", Value: []interface{}{"git", "helm"}, Action: "enable"}}, find(source.SubParameters, "url").Conditions)
+	assert.False(t, find(source.SubParameters, "url").Validate.Required)
+	assert.Equal(t, []schema.Condition{{JSONKey: "type", Op: "in", Value: []interface{}{"helm"}, Action: "enable"}}, find(source.SubParameters, "chart").Conditions)
+	assert.Equal(t, []schema.Condition{{JSONKey: "type", Op: "in", Value: []interface{}{"oss"}, Action: "enable"}}, find(source.SubParameters, "bucket").Conditions)
+
+	// the anyOf variants without the discriminator are chosen by the selector of the form, the scalar variants are ignored
+	params = load("./testdata/api-schema-anyof.json")
+	volumes := find(params, "volumes")
+	assert.Equal(t, "Structs", volumes.UIType)
+	assert.Equal(t, []schema.GroupOption{
+		{Label: "ConfigMap", Keys: []string{"configMapName"}},
+		{Label: "Secret", Keys: []string{"secretName"}},
+	}, volumes.SubParameterGroupOption)
+	assert.Equal(t, 4, len(volumes.SubParameters))
+	selector = volumes.SubParameters[0]
+	assert.Equal(t, VariantSelectorKey, selector.JSONKey)
+	assert.Equal(t, "Select", selector.UIType)
+	assert.True(t, selector.Validate.Required)
+	assert.Equal(t, []schema.Option{{Label: "ConfigMap", Value: "ConfigMap"}, {Label: "Secret", Value: "Secret"}}, selector.Validate.Options)
+	assert.Empty(t, find(volumes.SubParameters, "name").Conditions)
+	assert.True(t, find(volumes.SubParameters, "name").Validate.Required)
+	configMapName := find(volumes.SubParameters, "configMapName")
+	assert.Equal(t, []schema.Condition{{JSONKey: VariantSelectorKey, Op: "in", Value: []interface{}{"ConfigMap"}, Action: "enable"}}, configMapName.Conditions)
+	// the parameter required by the variant is not required if the other variant is chosen
+	assert.False(t, configMapName.Validate.Required)
+	assert.Equal(t, []schema.Condition{{JSONKey: VariantSelectorKey, Op: "in", Value: []interface{}{"Secret"}, Action: "enable"}}, find(volumes.SubParameters, "secretName").Conditions)
+	replicas := find(params, "replicas")
+	assert.Equal(t, "Input", replicas.UIType)
+	assert.Empty(t, replicas.SubParameters)
+	assert.Empty(t, replicas.SubParameterGroupOption)
+}
+
+func TestRenderSensitiveParameters(t *testing.T) {
+	data, err := os.ReadFile("./testdata/api-schema-sensitive.json")
+	assert.NoError(t, err)
+	apiSchema := &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON(data))
+	params := map[string]*schema.UIParameter{}
+	for _, param := range renderDefaultUISchema(apiSchema, "") {
+		params[param.JSONKey] = param
+		for _, sub := range param.SubParameters {
+			params[param.JSONKey+"."+sub.JSONKey] = sub
+		}
+	}
+	assert.Equal(t, "Input", params["username"].UIType)
+	assert.Equal(t, "admin", params["username"].Validate.DefaultValue)
+	assert.Equal(t, "Password", params["password"].UIType)
+	assert.Nil(t, params["password"].Validate.DefaultValue)
+	// the ui type declared explicitly is kept
+	assert.Equal(t, "SecretSelect", params["secretRef"].UIType)
+	assert.Equal(t, map[string]interface{}{"host": "db"}, params["database"].Validate.DefaultValue)
+	assert.Equal(t, "db", params["database.host"].Validate.DefaultValue)
+	assert.Equal(t, "Password", params["database.token"].UIType)
+	assert.Nil(t, params["database.token"].Validate.DefaultValue)
+	// the invalid extension is ignored
+	assert.Equal(t, "Switch", params["debug"].UIType)
+
+	hints := renderSchemaUIHints(apiSchema)
+	assert.True(t, hints["password"].Sensitive)
+	assert.True(t, hints["database.token"].Sensitive)
+	assert.False(t, hints["username"].Sensitive)
+	assert.NotContains(t, renderSchemaPlaceholders(apiSchema), "password")
+
+	// the defaults are removed from a copy of the schema
+	redacted := redactSensitiveDefaults(apiSchema)
+	assert.Nil(t, redacted.Properties["password"].Value.Default)
+	assert.Nil(t, redacted.Properties["password"].Value.Example)
+	assert.Equal(t, map[string]interface{}{"host": "db"}, redacted.Properties["database"].Value.Default)
+	assert.Equal(t, "admin", redacted.Properties["username"].Value.Default)
+	assert.Equal(t, "changeme", apiSchema.Properties["password"].Value.Default)
+	assert.Equal(t, map[string]interface{}{"host": "db", "token": "t0ken"}, apiSchema.Properties["database"].Value.Default)
+
+	data, err = os.ReadFile("./testdata/api-schema-units.json")
+	assert.NoError(t, err)
+	apiSchema = &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON(data))
+	assert.Same(t, apiSchema, redactSensitiveDefaults(apiSchema))
+}
+
+func TestDefinitionSensitiveDefaults(t *testing.T) {
+	apiSchema, err := os.ReadFile("./testdata/api-schema-sensitive.json")
+	assert.NoError(t, err)
+	newTrait := func(name, template string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS},
+			Spec:       v1beta1.TraitDefinitionSpec{Schematic: &oamcommon.Schematic{CUE: &oamcommon.CUE{Template: template}}},
+		}
+	}
+	du, _ := newTestDefinitionService(
+		newTrait("db", `parameter: password: *"changeme" | string`),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-db", Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: string(apiSchema)},
+		},
+		newTrait("scaler", `parameter: replicas: *1 | int`),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer","default":1}},"type":"object"}`},
+		},
+	)
+	ctx := context.TODO()
+
+	// the template declaring the sensitive defaults is withheld
+	detail, err := du.DetailDefinition(ctx, "db", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.True(t, detail.TemplateWithheld)
+	assert.Empty(t, detail.Template)
+	assert.Nil(t, detail.Trait.Schematic)
+	assert.Nil(t, detail.APISchema.Properties["password"].Value.Default)
+	detail, err = du.DetailDefinition(ctx, "scaler", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.False(t, detail.TemplateWithheld)
+	assert.Equal(t, `parameter: replicas: *1 | int`, detail.Template)
+
+	// the exported JSON schema doesn't have the sensitive defaults and examples
+	data, err := du.ExportDefinitionJSONSchema(ctx, "db", "trait")
+	assert.NoError(t, err)
+	for _, secret := range []string{"changeme", "s3cret", "t0ken"} {
+		assert.NotContains(t, string(data), secret)
+	}
+	var jsonSchema map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &jsonSchema))
+	properties := jsonSchema["properties"].(map[string]interface{})
+	assert.Equal(t, "admin", properties["username"].(map[string]interface{})["default"])
+	assert.Equal(t, map[string]interface{}{"host": "db"}, properties["database"].(map[string]interface{})["default"])
+}
+
+func TestSearchDefinitionsByParameter(t *testing.T) {
+	newTrait := func(name, alias string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS, Annotations: map[string]string{types.AnnoDefinitionAlias: alias}},
+		}
+	}
+	newSchema := func(name, schema string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-" + name, Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: schema},
+		}
+	}
+	du, _ := newTestDefinitionService(
+		newTrait("scaler", "Scaler"), newSchema("scaler", `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`),
+		newTrait("hpa", "Autoscaler"), newSchema("hpa", `{"properties":{"min":{"type":"integer"},"targets":{"type":"array","items":{"type":"object","properties":{"Replicas":{"type":"integer"}}}}},"type":"object"}`),
+		newTrait("gateway", "Gateway"), newSchema("gateway", `{"properties":{"domain":{"type":"string"}},"type":"object"}`),
+		// the definition without the schema is never matched by the parameter
+		newTrait("labels", "Labels"),
+	)
+	names := func(defs []*v1.DefinitionBase) map[string][]string {
+		matched := map[string][]string{}
+		for _, def := range defs {
+			matched[def.Name] = def.MatchedParameters
+		}
+		return matched
+	}
+
+	defs, err := du.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", Brief: true, ParameterKeyword: "replicas"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"scaler": {"replicas"}, "hpa": {"targets[].Replicas"}}, names(defs))
+
+	// combined with the keyword of the name and the alias
+	defs, err = du.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", Brief: true, Keyword: "auto", ParameterKeyword: "replicas", IncludeSchemaStats: true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"hpa": {"targets[].Replicas"}}, names(defs))
+	assert.Equal(t, 2, defs[0].ParameterCount)
+
+	defs, err = du.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "trait", Brief: true, Keyword: "GATE"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"gateway": nil}, names(defs))
+
+	counts, err := du.CountDefinitionsByType(context.TODO(), DefinitionQueryOption{ParameterKeyword: "replicas"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, counts["trait"])
+
+	var items []unstructured.Unstructured
+	for i := 0; i <= maxParameterSearchDefinitions; i++ {
+		items = append(items, unstructured.Unstructured{})
+	}
+	_, _, _, err = du.filterDefinitionsByParameter(context.TODO(), items, "trait", "replicas")
+	assert.Equal(t, bcode.ErrDefinitionParameterSearchTooBroad, err)
+}
+
+func TestRenderUISchemaForSchema(t *testing.T) {
+	du := &definitionServiceImpl{}
+	data, err := os.ReadFile("./testdata/api-schema-groups.json")
+	assert.NoError(t, err)
+	uiSchema, err := du.RenderUISchemaForSchema(context.TODO(), data)
+	assert.NoError(t, err)
+	apiSchema := &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON(data))
+	assert.Equal(t, renderDefaultUISchema(apiSchema, ""), uiSchema)
+	assert.Len(t, uiSchema, 7)
+	assert.Equal(t, "image", uiSchema[0].JSONKey)
+	assert.True(t, uiSchema[0].Validate.Required)
+
+	for name, invalid := range map[string]string{
+		"empty":        " ",
+		"not json":     "replicas: 1",
+		"invalid type": `{"type":"object","properties":{"replicas":{"type":"int"}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := du.RenderUISchemaForSchema(context.TODO(), []byte(invalid))
+			assert.True(t, errors.Is(err, bcode.ErrDefinitionSchemaInvalid), err)
+		})
+	}
+}
+
+func TestResolveDefinitionDependencies(t *testing.T) {
+	step := &v1beta1.WorkflowStepDefinition{
+		TypeMeta: metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "WorkflowStepDefinition"},
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy-with-topology", Namespace: types.DefaultKubeVelaNS, Annotations: map[string]string{
+			AnnoDefinitionDependsOn: "policy/topology, policy/override, notification,, policy/topology, unknown/x",
+		}},
+	}
+	du, _ := newTestDefinitionService(
+		step,
+		&v1beta1.PolicyDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "PolicyDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "topology", Namespace: types.DefaultKubeVelaNS},
+		},
+		&v1beta1.WorkflowStepDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "WorkflowStepDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "notification", Namespace: types.DefaultKubeVelaNS},
+		},
+	)
+
+	defs, err := du.ListDefinitions(context.TODO(), DefinitionQueryOption{Type: "workflowstep", Brief: true})
+	assert.NoError(t, err)
+	dependencies := map[string][]v1.DefinitionReference{}
+	for _, def := range defs {
+		dependencies[def.Name] = def.Dependencies
+	}
+	assert.Nil(t, dependencies["notification"])
+	assert.Equal(t, []v1.DefinitionReference{
+		{Name: "topology", Type: "policy"},
+		{Name: "override", Type: "policy"},
+		{Name: "notification", Type: "workflowstep"},
+		{Name: "x", Type: "unknown"},
+	}, dependencies["deploy-with-topology"])
+
+	resp, err := du.ResolveDependencies(context.TODO(), "deploy-with-topology", "workflowstep")
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.DefinitionReference{{Name: "topology", Type: "policy"}, {Name: "notification", Type: "workflowstep"}}, resp.Installed)
+	assert.Equal(t, []v1.DefinitionReference{{Name: "override", Type: "policy"}, {Name: "x", Type: "unknown"}}, resp.Missing)
+
+	resp, err = du.ResolveDependencies(context.TODO(), "notification", "workflowstep")
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Installed)
+	assert.Empty(t, resp.Missing)
+
+	_, err = du.ResolveDependencies(context.TODO(), "not-exist", "workflowstep")
+	assert.Equal(t, bcode.ErrDefinitionNotFound, err)
+}
+
+func TestDetailDefinitionETag(t *testing.T) {
+	trait := &v1beta1.TraitDefinition{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+		ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: types.DefaultKubeVelaNS},
+	}
+	du, cli := newTestDefinitionService(trait, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: types.DefaultKubeVelaNS},
+		Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`},
+	})
+	ctx := context.TODO()
+
+	detail, err := du.DetailDefinition(ctx, "scaler", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	etag := detail.ETag
+	assert.NotEmpty(t, etag)
+
+	detail, err = du.DetailDefinition(ctx, "scaler", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, etag, detail.ETag)
+
+	detail, err = du.DetailDefinition(ctx, "scaler", "trait", DetailDefinitionOption{IfNoneMatch: `"other", W/` + etag})
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionNotModified))
+	assert.Equal(t, etag, detail.ETag)
+
+	// the custom ui schema changes the etag
+	assert.NoError(t, cli.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "trait-uischema-scaler", Namespace: types.DefaultKubeVelaNS},
+		Data:       map[string]string{types.UISchema: `[{"jsonKey":"replicas","label":"Replicas"}]`},
+	}))
+	detail, err = du.DetailDefinition(ctx, "scaler", "trait", DetailDefinitionOption{IfNoneMatch: etag})
+	assert.NoError(t, err)
+	assert.NotEqual(t, etag, detail.ETag)
+	etag = detail.ETag
+
+	// updating the definition changes the etag
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Name: "scaler", Namespace: types.DefaultKubeVelaNS}, trait))
+	trait.Annotations = map[string]string{types.AnnoDefinitionDescription: "scale the workload"}
+	assert.NoError(t, cli.Update(ctx, trait))
+	detail, err = du.DetailDefinition(ctx, "scaler", "trait", DetailDefinitionOption{IfNoneMatch: etag})
+	assert.NoError(t, err)
+	assert.NotEqual(t, etag, detail.ETag)
+}
+
+func TestDefinitionSystemNamespace(t *testing.T) {
+	systemNamespace := "kubevela-system"
+	newTrait := func(name, alias, namespace string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: map[string]string{types.AnnoDefinitionAlias: alias}},
+		}
+	}
+	du, cli := newTestDefinitionService(
+		newTrait("scaler", "Scaler", systemNamespace),
+		newTrait("gateway", "Gateway", types.DefaultKubeVelaNS),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: systemNamespace},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`},
+		},
+	)
+	du.SystemNamespace = systemNamespace
+	ctx := context.TODO()
+
+	defs, err := du.ListDefinitions(ctx, DefinitionQueryOption{Type: "trait"})
+	assert.NoError(t, err)
+	builtIn := map[string]bool{}
+	for _, def := range defs {
+		builtIn[def.Name] = def.BuiltIn
+	}
+	assert.Equal(t, map[string]bool{"scaler": true, "gateway": false}, builtIn)
+
+	detail, err := du.DetailDefinition(ctx, "Scaler", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, "scaler", detail.Name)
+	assert.NotNil(t, detail.APISchema)
+	_, err = du.DetailDefinition(ctx, "gateway", "trait", DetailDefinitionOption{})
+	assert.True(t, errors.Is(err, bcode.ErrDefinitionNotFound))
+
+	_, err = du.AddDefinitionUISchema(ctx, "scaler", "trait", []*schema.UIParameter{{JSONKey: "replicas", Label: "Replicas"}}, "")
+	assert.NoError(t, err)
+	var cm corev1.ConfigMap
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Namespace: systemNamespace, Name: "trait-uischema-scaler"}, &cm))
+	detail, err = du.DetailDefinition(ctx, "scaler", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Replicas", detail.UISchema[0].Label)
+
+	_, err = du.ResetDefinitionUISchema(ctx, "scaler", "trait")
+	assert.NoError(t, err)
+	assert.True(t, apierrors.IsNotFound(cli.Get(ctx, k8stypes.NamespacedName{Namespace: systemNamespace, Name: "trait-uischema-scaler"}, &cm)))
+}
+
+func TestDefinitionSchemaReady(t *testing.T) {
+	newTrait := func(name string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS},
+		}
+	}
+	du, _ := newTestDefinitionService(
+		newTrait("scaler"), newTrait("gateway"),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: types.DefaultKubeVelaNS, Labels: map[string]string{types.LabelDefinition: "schema"}},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`},
+		},
+	)
+	ctx := context.TODO()
+
+	listSchemaReady := func(schemaReady *bool) map[string]bool {
+		defs, err := du.ListDefinitions(ctx, DefinitionQueryOption{Type: "trait", Brief: true, SchemaReady: schemaReady})
+		assert.NoError(t, err)
+		ready := map[string]bool{}
+		for _, def := range defs {
+			ready[def.Name] = def.SchemaReady
+		}
+		return ready
+	}
+	ready, notReady := true, false
+	assert.Equal(t, map[string]bool{"scaler": true, "gateway": false}, listSchemaReady(nil))
+	assert.Equal(t, map[string]bool{"scaler": true}, listSchemaReady(&ready))
+	assert.Equal(t, map[string]bool{"gateway": false}, listSchemaReady(&notReady))
+
+	counts, err := du.CountDefinitionsByType(ctx, DefinitionQueryOption{SchemaReady: &notReady})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counts["trait"])
+	assert.Equal(t, 0, counts["component"])
+
+	detail, err := du.DetailDefinition(ctx, "scaler", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.True(t, detail.SchemaReady)
+	detail, err = du.DetailDefinition(ctx, "gateway", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.False(t, detail.SchemaReady)
+	assert.Nil(t, detail.APISchema)
+
+	assert.NotEqual(t, DefinitionQueryOption{SchemaReady: &ready}.String(), DefinitionQueryOption{}.String())
+}
+
+func TestRenderUIType(t *testing.T) {
+	apiSchema := &openapi3.Schema{}
+	assert.NoError(t, apiSchema.UnmarshalJSON([]byte(`{
+		"type": "object",
+		"properties": {
+			"secret": {"type": "string", "x-vela-ui-type": "SecretSelect"},
+			"cpu": {"type": "number", "x-vela-ui-type": "CPUNumber"},
+			"values": {"type": "object", "x-vela-ui-type": "HelmValues"},
+			"hidden": {"type": "boolean", "x-vela-ui-type": "Ignore"},
+			"replicas": {"type": "integer", "x-vela-ui-type": "Switch"},
+			"image": {"type": "string", "x-vela-ui-type": "FancyPicker"},
+			"labels": {"type": "object", "x-vela-ui-type": ["KV"]},
+			"name": {"type": "string"}
+		}
+	}`)))
+	uiTypes := map[string]string{}
+	for _, param := range renderDefaultUISchema(apiSchema, "") {
+		uiTypes[param.JSONKey] = param.UIType
+	}
+	assert.Equal(t, map[string]string{
+		"secret": "SecretSelect",
+		"cpu":    "CPUNumber",
+		"values": "HelmValues",
+		"hidden": "Ignore",
+		// the widget is incompatible with the type
+		"replicas": "Number",
+		// the widget is unknown
+		"image": "Input",
+		// the extension is invalid
+		"labels": "KV",
+		"name":   "Input",
+	}, uiTypes)
+}
+
+func TestDefinitionFavorites(t *testing.T) {
+	newTrait := func(name string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS},
+		}
+	}
+	du, cli := newTestDefinitionService(newTrait("scaler"), newTrait("gateway"), newTrait("trait-scaler"))
+	ds, err := kubeapi.New(context.TODO(), datastore.Config{Database: "definition-favorites"}, cli)
+	assert.NoError(t, err)
+	du.Store = ds
+	ctx := context.WithValue(context.TODO(), &v1.CtxKeyUser, "alice")
+
+	assert.Equal(t, bcode.ErrUnauthorized, du.AddFavoriteDefinition(context.TODO(), "scaler", "trait"))
+	assert.Equal(t, bcode.ErrDefinitionNotFound, du.AddFavoriteDefinition(ctx, "none", "trait"))
+	assert.NoError(t, du.AddFavoriteDefinition(ctx, "scaler", "trait"))
+	// pin twice is fine
+	assert.NoError(t, du.AddFavoriteDefinition(ctx, "scaler", "trait"))
+
+	favorited := func(ctx context.Context) map[string]bool {
+		defs, err := du.ListDefinitions(ctx, DefinitionQueryOption{Type: "trait", Brief: true})
+		assert.NoError(t, err)
+		res := map[string]bool{}
+		for _, def := range defs {
+			res[def.Name] = def.Favorited
+		}
+		return res
+	}
+	assert.Equal(t, map[string]bool{"scaler": true, "gateway": false, "trait-scaler": false}, favorited(ctx))
+	assert.Equal(t, map[string]bool{"scaler": false, "gateway": false, "trait-scaler": false}, favorited(context.WithValue(context.TODO(), &v1.CtxKeyUser, "bob")))
+
+	// the favorites of the users whose names join to the same string don't overwrite each other
+	traitUserCtx := context.WithValue(context.TODO(), &v1.CtxKeyUser, "alice-trait")
+	assert.NoError(t, du.AddFavoriteDefinition(traitUserCtx, "scaler", "trait"))
+	assert.NoError(t, du.AddFavoriteDefinition(ctx, "trait-scaler", "trait"))
+	assert.Equal(t, map[string]bool{"scaler": true, "gateway": false, "trait-scaler": false}, favorited(traitUserCtx))
+	assert.Equal(t, map[string]bool{"scaler": true, "gateway": false, "trait-scaler": true}, favorited(ctx))
+	assert.NoError(t, du.RemoveFavoriteDefinition(traitUserCtx, "scaler", "trait"))
+	assert.NoError(t, du.RemoveFavoriteDefinition(ctx, "trait-scaler", "trait"))
+
+	favorites, err := du.ListFavoriteDefinitions(ctx, "trait")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(favorites))
+	assert.Equal(t, "scaler", favorites[0].Name)
+	assert.True(t, favorites[0].Favorited)
+
+	// the deleted definition is skipped
+	assert.NoError(t, cli.Delete(context.TODO(), newTrait("scaler")))
+	favorites, err = du.ListFavoriteDefinitions(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(favorites))
+
+	assert.NoError(t, du.RemoveFavoriteDefinition(ctx, "scaler", "trait"))
+	assert.NoError(t, du.RemoveFavoriteDefinition(ctx, "scaler", "trait"))
+	_, err = du.ListFavoriteDefinitions(context.TODO(), "")
+	assert.Equal(t, bcode.ErrUnauthorized, err)
+}
+
+func TestListCompatibleComponents(t *testing.T) {
+	newComponent := func(name, workloadType string) *v1beta1.ComponentDefinition {
+		return &v1beta1.ComponentDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "ComponentDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS},
+			Spec:       v1beta1.ComponentDefinitionSpec{Workload: oamcommon.WorkloadTypeDescriptor{Type: workloadType}},
+		}
+	}
+	newTrait := func(name string, appliesTo ...string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS},
+			Spec:       v1beta1.TraitDefinitionSpec{AppliesToWorkloads: appliesTo},
+		}
+	}
+	du, _ := newTestDefinitionService(
+		newComponent("webservice", "deployments.apps"),
+		newComponent("worker", "deployments.apps"),
+		newComponent("task", "jobs.batch"),
+		newComponent("k8s-objects", ""),
+		newTrait("scaler", "deployments.apps"),
+		newTrait("labels", "*"),
+		newTrait("annotations"),
+		newTrait("json-patch", "task", "autodetects.core.oam.dev"),
+	)
+	ctx := context.TODO()
+
+	names := func(traitName string) []string {
+		defs, err := du.ListCompatibleComponents(ctx, traitName)
+		assert.NoError(t, err)
+		res := []string{}
+		for _, def := range defs {
+			res = append(res, def.Name)
+		}
+		return res
+	}
+	assert.ElementsMatch(t, []string{"webservice", "worker"}, names("scaler"))
+	assert.ElementsMatch(t, []string{"webservice", "worker", "task", "k8s-objects"}, names("labels"))
+	assert.ElementsMatch(t, []string{"webservice", "worker", "task", "k8s-objects"}, names("annotations"))
+	assert.ElementsMatch(t, []string{"task"}, names("json-patch"))
+
+	_, err := du.ListCompatibleComponents(ctx, "not-exist")
+	assert.Equal(t, bcode.ErrDefinitionNotFound, err)
+}
+
+func TestDetailDefinitionNoCache(t *testing.T) {
+	trait := &v1beta1.TraitDefinition{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+		ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: types.DefaultKubeVelaNS},
+	}
+	schemaCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: types.DefaultKubeVelaNS},
+		Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`},
+	}
+	du, cli := newTestDefinitionService(trait, schemaCM)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	du.schemaCache = apiutils.NewMemoryCacheStore(ctx)
+
+	properties := func(ops DetailDefinitionOption) []string {
+		detail, err := du.DetailDefinition(ctx, "scaler", "trait", ops)
+		assert.NoError(t, err)
+		var names []string
+		for name := range detail.APISchema.Properties {
+			names = append(names, name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"replicas"}, properties(DetailDefinitionOption{}))
+
+	// only the schema is changed, the cached schema is returned
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Name: schemaCM.Name, Namespace: schemaCM.Namespace}, schemaCM))
+	schemaCM.Data[types.OpenapiV3JSONSchema] = `{"properties":{"min":{"type":"integer"}},"type":"object"}`
+	assert.NoError(t, cli.Update(ctx, schemaCM))
+	assert.Equal(t, []string{"replicas"}, properties(DetailDefinitionOption{}))
+
+	// bypass the cache and refresh it
+	assert.Equal(t, []string{"min"}, properties(DetailDefinitionOption{NoCache: true}))
+	assert.Equal(t, []string{"min"}, properties(DetailDefinitionOption{}))
+
+	// updating the definition invalidates the cache
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Name: schemaCM.Name, Namespace: schemaCM.Namespace}, schemaCM))
+	schemaCM.Data[types.OpenapiV3JSONSchema] = `{"properties":{"max":{"type":"integer"}},"type":"object"}`
+	assert.NoError(t, cli.Update(ctx, schemaCM))
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Name: "scaler", Namespace: types.DefaultKubeVelaNS}, trait))
+	trait.Annotations = map[string]string{types.AnnoDefinitionDescription: "scale the workload"}
+	assert.NoError(t, cli.Update(ctx, trait))
+	assert.Equal(t, []string{"max"}, properties(DetailDefinitionOption{}))
+}
+
+func TestDefinitionSchemaCacheStats(t *testing.T) {
+	trait := &v1beta1.TraitDefinition{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+		ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: types.DefaultKubeVelaNS},
+	}
+	schemaCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: types.DefaultKubeVelaNS},
+		Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`},
+	}
+	du, cli := newTestDefinitionService(trait, schemaCM)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	stats, err := du.SchemaCacheStats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, v1.DefinitionSchemaCacheStats{}, *stats)
+
+	du.schemaCache = apiutils.NewMemoryCacheStore(ctx)
+	detail := func(ops DetailDefinitionOption) {
+		_, err := du.DetailDefinition(ctx, "scaler", "trait", ops)
+		assert.NoError(t, err)
+	}
+	detail(DetailDefinitionOption{})
+	detail(DetailDefinitionOption{})
+	detail(DetailDefinitionOption{})
+	// bypassing the cache is not a lookup
+	detail(DetailDefinitionOption{NoCache: true})
+	stats, err = du.SchemaCacheStats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, v1.DefinitionSchemaCacheStats{Enabled: true, Hits: 2, Misses: 1, HitRate: 2.0 / 3, Entries: 1}, *stats)
+
+	// the cached schema of the changed definition is evicted
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Name: "scaler", Namespace: types.DefaultKubeVelaNS}, trait))
+	trait.Annotations = map[string]string{types.AnnoDefinitionDescription: "scale the workload"}
+	assert.NoError(t, cli.Update(ctx, trait))
+	detail(DetailDefinitionOption{})
+	stats, err = du.SchemaCacheStats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.Equal(t, int64(1), stats.Evictions)
+	assert.Equal(t, 0.5, stats.HitRate)
+	assert.Equal(t, 1, stats.Entries)
+}
+
+func TestApplyDefinition(t *testing.T) {
+	newTrait := func(name string, labels map[string]string, owners ...metav1.OwnerReference) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS, Labels: labels, OwnerReferences: owners},
+			Spec:       v1beta1.TraitDefinitionSpec{AppliesToWorkloads: []string{"*"}},
+		}
+	}
+	du, cli := newTestDefinitionService(
+		newTrait("scaler", map[string]string{"app.kubernetes.io/managed-by": "Helm"}),
+		newTrait("ingress", nil, metav1.OwnerReference{APIVersion: "core.oam.dev/v1beta1", Kind: "Application", Name: "addon-fluxcd", UID: "uid"}),
+	)
+	ctx := context.TODO()
+
+	manifest := `apiVersion: core.oam.dev/v1beta1
+kind: TraitDefinition
+metadata:
+  name: my-scaler
+  annotations:
+    definition.oam.dev/description: %s
+spec:
+  appliesToWorkloads: ["deployments.apps"]
+  schematic:
+    cue:
+      template: |
+        parameter: replicas: *1 | int
+`
+	detail, err := du.ApplyDefinition(ctx, []byte(fmt.Sprintf(manifest, "scale the workload")))
+	assert.NoError(t, err)
+	assert.Equal(t, "my-scaler", detail.Name)
+	assert.Equal(t, "scale the workload", detail.Description)
+	assert.Equal(t, []string{"deployments.apps"}, detail.Trait.AppliesToWorkloads)
+
+	// applying again updates the definition and keeps the metadata not in the manifest
+	trait := &v1beta1.TraitDefinition{}
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Namespace: types.DefaultKubeVelaNS, Name: "my-scaler"}, trait))
+	trait.Labels = map[string]string{"team": "platform"}
+	trait.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "uid"}}
+	assert.NoError(t, cli.Update(ctx, trait))
+	detail, err = du.ApplyDefinition(ctx, []byte(fmt.Sprintf(manifest, "scale the replicas")))
+	assert.NoError(t, err)
+	assert.Equal(t, "scale the replicas", detail.Description)
+	trait = &v1beta1.TraitDefinition{}
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Namespace: types.DefaultKubeVelaNS, Name: "my-scaler"}, trait))
+	assert.Equal(t, "scale the replicas", trait.Annotations[types.AnnoDefinitionDescription])
+	assert.Equal(t, "platform", trait.Labels["team"])
+	assert.Equal(t, "owner", trait.OwnerReferences[0].Name)
+
+	// the definitions installed with KubeVela or by an addon can't be overwritten
+	for _, name := range []string{"scaler", "ingress"} {
+		_, err = du.ApplyDefinition(ctx, []byte(strings.Replace(fmt.Sprintf(manifest, "overwrite"), "my-scaler", name, 1)))
+		assert.True(t, errors.Is(err, bcode.ErrDefinitionProtected), name)
+		protected := &v1beta1.TraitDefinition{}
+		assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Namespace: types.DefaultKubeVelaNS, Name: name}, protected))
+		assert.Equal(t, []string{"*"}, protected.Spec.AppliesToWorkloads)
+	}
+
+	_, err = du.ApplyDefinition(ctx, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n"))
+	assert.Equal(t, bcode.ErrDefinitionTypeNotSupport, err)
+	_, err = du.ApplyDefinition(ctx, []byte("apiVersion: core.oam.dev/v1beta1\nkind: ApplicationRevision\nmetadata:\n  name: rev\n"))
+	assert.Equal(t, bcode.ErrDefinitionTypeNotSupport, err)
+	_, err = du.ApplyDefinition(ctx, []byte("{invalid"))
+	assert.Equal(t, bcode.ErrDefinitionManifestInvalid, err)
+
+	invalids := []string{
+		// the name is invalid
+		"apiVersion: core.oam.dev/v1beta1\nkind: PolicyDefinition\nmetadata:\n  name: Invalid_Name\nspec:\n  schematic:\n    cue:\n      template: ''\n",
+		// the namespace is not the system namespace
+		"apiVersion: core.oam.dev/v1beta1\nkind: PolicyDefinition\nmetadata:\n  name: topo\n  namespace: default\nspec:\n  schematic:\n    cue:\n      template: ''\n",
+		// the schematic is missing
+		"apiVersion: core.oam.dev/v1beta1\nkind: PolicyDefinition\nmetadata:\n  name: topo\nspec: {}\n",
+		// the spec doesn't match the kind
+		"apiVersion: core.oam.dev/v1beta1\nkind: TraitDefinition\nmetadata:\n  name: topo\nspec:\n  appliesToWorkloads: deployments.apps\n  schematic:\n    cue:\n      template: ''\n",
+	}
+	for _, invalid := range invalids {
+		_, err = du.ApplyDefinition(ctx, []byte(invalid))
+		assert.True(t, errors.Is(err, bcode.ErrDefinitionManifestInvalid), invalid)
+	}
+}
+
+func TestDefinitionDeprecation(t *testing.T) {
+	newTrait := func(name string, labels, annotations map[string]string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS, Labels: labels, Annotations: annotations},
+		}
+	}
+	du, _ := newTestDefinitionService(
+		newTrait("scaler", nil, nil),
+		newTrait("old-scaler", nil, map[string]string{
+			AnnoDefinitionDeprecated:            "true",
+			AnnoDefinitionDeprecatedReplacement: "scaler",
+			AnnoDefinitionDeprecatedMessage:     "use the scaler trait instead",
+		}),
+		newTrait("legacy", map[string]string{types.LabelDefinitionDeprecated: "true"}, nil),
+		newTrait("not-deprecated", nil, map[string]string{AnnoDefinitionDeprecated: "false"}),
+	)
+	ctx := context.TODO()
+
+	list := func(includeDeprecated bool) map[string]*v1.DefinitionDeprecation {
+		defs, err := du.ListDefinitions(ctx, DefinitionQueryOption{Type: "trait", Brief: true, IncludeDeprecated: includeDeprecated})
+		assert.NoError(t, err)
+		res := map[string]*v1.DefinitionDeprecation{}
+		for _, def := range defs {
+			res[def.Name] = def.Deprecated
+		}
+		return res
+	}
+	assert.Equal(t, map[string]*v1.DefinitionDeprecation{"scaler": nil, "not-deprecated": nil}, list(false))
+	assert.Equal(t, map[string]*v1.DefinitionDeprecation{
+		"scaler":         nil,
+		"not-deprecated": nil,
+		"old-scaler":     {Replacement: "scaler", Message: "use the scaler trait instead"},
+		"legacy":         {},
+	}, list(true))
+
+	counts, err := du.CountDefinitionsByType(ctx, DefinitionQueryOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, counts["trait"])
+	counts, err = du.CountDefinitionsByType(ctx, DefinitionQueryOption{IncludeDeprecated: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, counts["trait"])
+
+	detail, err := du.DetailDefinition(ctx, "old-scaler", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, &v1.DefinitionDeprecation{Replacement: "scaler", Message: "use the scaler trait instead"}, detail.Deprecated)
+}
+
+func TestDefinitionInvalidSchema(t *testing.T) {
+	invalidSchema, err := os.ReadFile("./testdata/api-schema-invalid.json")
+	assert.NoError(t, err)
+	newTrait := func(name string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: types.DefaultKubeVelaNS},
+		}
+	}
+	du, _ := newTestDefinitionService(
+		newTrait("scaler"), newTrait("broken"),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"required":["replicas"],"type":"object"}`},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-broken", Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: string(invalidSchema)},
+		},
+	)
+	ctx := context.TODO()
+
+	detail, err := du.DetailDefinition(ctx, "broken", "trait", DetailDefinitionOption{})
+	assert.NoError(t, err)
+	assert.Nil(t, detail.APISchema)
+	assert.Nil(t, detail.UISchema)
+	assert.True(t, detail.SchemaReady)
+	assert.Contains(t, detail.SchemaError, "the schema of the trait definition broken is invalid")
+
+	defs, err := du.ListDefinitions(ctx, DefinitionQueryOption{Type: "trait", IncludeSchemaStats: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(defs))
+	for _, def := range defs {
+		if def.Name == "broken" {
+			assert.NotEmpty(t, def.SchemaError)
+			assert.Equal(t, 0, def.ParameterCount)
+		} else {
+			assert.Empty(t, def.SchemaError)
+			assert.Equal(t, 1, def.RequiredCount)
+		}
+	}
+
+	defs, err = du.ListDefinitions(ctx, DefinitionQueryOption{Type: "trait", ParameterKeyword: "replicas"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(defs))
+	assert.Equal(t, "scaler", defs[0].Name)
+}
+
+func TestDefinitionUISchemaHistory(t *testing.T) {
+	du, _ := newTestDefinitionService(
+		&v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: types.DefaultKubeVelaNS},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"},"cpu":{"type":"string"}},"type":"object"}`},
+		},
+	)
+	ctx := context.TODO()
+
+	history, err := du.ListDefinitionUISchemaHistory(ctx, "scaler", "trait", 0, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), history.Total)
+
+	_, err = du.AddDefinitionUISchema(context.WithValue(ctx, &v1.CtxKeyUser, "alice"), "scaler", "trait", []*schema.UIParameter{{JSONKey: "replicas", Label: "Replicas"}}, "")
+	assert.NoError(t, err)
+	_, err = du.AddDefinitionUISchema(context.WithValue(ctx, &v1.CtxKeyUser, "bob"), "scaler", "trait", []*schema.UIParameter{{JSONKey: "replicas", Label: "Replica Number"}, {JSONKey: "cpu", Label: "CPU"}}, "")
+	assert.NoError(t, err)
+	_, err = du.AddDefinitionUISchema(context.WithValue(ctx, &v1.CtxKeyUser, "alice"), "scaler", "trait", []*schema.UIParameter{{JSONKey: "cpu", Label: "CPU"}}, "")
+	assert.NoError(t, err)
+
+	history, err = du.ListDefinitionUISchemaHistory(ctx, "scaler", "trait", 0, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), history.Total)
+	assert.Equal(t, "removed 1: replicas", history.Entries[0].Summary)
+	assert.Equal(t, "added 1: cpu; changed 1: replicas", history.Entries[1].Summary)
+	assert.Equal(t, "bob", history.Entries[1].ModifiedBy)
+	assert.Equal(t, "added 1: replicas", history.Entries[2].Summary)
+
+	history, err = du.ListDefinitionUISchemaHistory(ctx, "scaler", "trait", 2, 1, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), history.Total)
+	assert.Equal(t, 1, len(history.Entries))
+	assert.Equal(t, "added 1: replicas", history.Entries[0].Summary)
+	history, err = du.ListDefinitionUISchemaHistory(ctx, "scaler", "trait", 3, 1, "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, history.Entries)
+
+	// the retained history is capped
+	for i := 0; i < maxUISchemaHistoryLength; i++ {
+		_, err = du.AddDefinitionUISchema(ctx, "scaler", "trait", []*schema.UIParameter{{JSONKey: "cpu", Label: "CPU"}}, "")
+		assert.NoError(t, err)
+	}
+	history, err = du.ListDefinitionUISchemaHistory(ctx, "scaler", "trait", 0, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(maxUISchemaHistoryLength), history.Total)
+	assert.Equal(t, "no parameter changed", history.Entries[0].Summary)
+
+	_, err = du.ListDefinitionUISchemaHistory(ctx, "scaler", "invalid", 0, 0, "")
+	assert.Error(t, err)
+}
+
+func TestRegenerateAllDefinitionSchemas(t *testing.T) {
+	schematic := &oamcommon.Schematic{CUE: &oamcommon.CUE{Template: "parameter: {}"}}
+	du, cli := newTestDefinitionService(
+		&v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: types.DefaultKubeVelaNS},
+			Spec:       v1beta1.TraitDefinitionSpec{Schematic: schematic},
+		},
+		&v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "TraitDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "no-schematic", Namespace: types.DefaultKubeVelaNS},
+		},
+		&v1beta1.ComponentDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "core.oam.dev/v1beta1", Kind: "ComponentDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "webservice", Namespace: types.DefaultKubeVelaNS},
+			Spec:       v1beta1.ComponentDefinitionSpec{Schematic: schematic},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trait-schema-scaler", Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{types.OpenapiV3JSONSchema: `{"properties":{"replicas":{"type":"integer"}},"type":"object"}`},
+		},
+	)
+	ctx := context.TODO()
+
+	_, err := du.RegenerateAllDefinitionSchemas(ctx, v1.RegenerateDefinitionSchemasRequest{DefinitionType: "invalid"})
+	assert.Error(t, err)
+
+	resp, err := du.RegenerateAllDefinitionSchemas(ctx, v1.RegenerateDefinitionSchemasRequest{Concurrency: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, resp.Total)
+	assert.Equal(t, 2, resp.Regenerated)
+	assert.Equal(t, 1, resp.Skipped)
+	assert.Equal(t, 0, resp.Failed)
+	for _, result := range resp.Results {
+		if result.Name == "no-schematic" {
+			assert.Equal(t, SchemaRegenerationStatusSkipped, result.Status)
+		}
+	}
+	var cm corev1.ConfigMap
+	assert.True(t, apierrors.IsNotFound(cli.Get(ctx, k8stypes.NamespacedName{Namespace: types.DefaultKubeVelaNS, Name: "trait-schema-scaler"}, &cm)))
+	var trait v1beta1.TraitDefinition
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Namespace: types.DefaultKubeVelaNS, Name: "scaler"}, &trait))
+	assert.NotEmpty(t, trait.Annotations[AnnoDefinitionSchemaRegeneratedAt])
+	assert.NoError(t, cli.Get(ctx, k8stypes.NamespacedName{Namespace: types.DefaultKubeVelaNS, Name: "no-schematic"}, &trait))
+	assert.Empty(t, trait.Annotations[AnnoDefinitionSchemaRegeneratedAt])
+
+	resp, err = du.RegenerateAllDefinitionSchemas(ctx, v1.RegenerateDefinitionSchemasRequest{DefinitionType: "component"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resp.Total)
+	assert.Equal(t, "webservice", resp.Results[0].Name)
+	assert.Equal(t, SchemaRegenerationStatusRegenerated, resp.Results[0].Status)
 }
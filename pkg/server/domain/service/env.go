@@ -20,16 +20,34 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/auth"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	util "github.com/oam-dev/kubevela/pkg/utils"
 
@@ -41,25 +59,114 @@ import (
 	"github.com/kubevela/velaux/pkg/server/utils/bcode"
 )
 
+const clusterProbeTimeout = 3 * time.Second
+
+const targetStatusCacheDuration = 30 * time.Second
+
+const (
+	// TargetStatusHealthy the cluster of the target is reachable and the namespace exists
+	TargetStatusHealthy = "Healthy"
+	// TargetStatusClusterUnreachable the cluster of the target can't be contacted
+	TargetStatusClusterUnreachable = "ClusterUnreachable"
+	// TargetStatusNamespaceNotExist the namespace of the target doesn't exist in the cluster
+	TargetStatusNamespaceNotExist = "NamespaceNotExist"
+	// TargetStatusUnknown the target is not found or it has no cluster
+	TargetStatusUnknown = "Unknown"
+)
+
+const (
+	// EnvPrivilegeGranted the role binding exists and contains the identity
+	EnvPrivilegeGranted = "Granted"
+	// EnvPrivilegeMissing the role binding doesn't exist or doesn't contain the identity
+	EnvPrivilegeMissing = "Missing"
+	// EnvPrivilegeUnknown the role binding can't be read
+	EnvPrivilegeUnknown = "Unknown"
+)
+
+const (
+	// EnvSortByName sort the envs by the name
+	EnvSortByName = "name"
+	// EnvSortByAlias sort the envs by the alias, case-insensitive
+	EnvSortByAlias = "alias"
+	// EnvSortByCreateTime sort the envs by the creation time
+	EnvSortByCreateTime = "createTime"
+)
+
+// privilegesBackoff the backoff to retry granting or revoking the privileges of the env, the api server may fail transiently
+var privilegesBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 200 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
 // EnvService defines the API of Env.
 type EnvService interface {
 	GetEnv(ctx context.Context, envName string) (*model.Env, error)
+	GetEnvDetail(ctx context.Context, envName string) (*apisv1.Env, error)
 	ListEnvs(ctx context.Context, page, pageSize int, listOption apisv1.ListEnvOptions) (*apisv1.ListEnvResponse, error)
+	ListAllEnvs(ctx context.Context, page, pageSize int, listOption apisv1.ListEnvOptions) (*apisv1.ListEnvResponse, error)
+	ListEnvsGroupedByProject(ctx context.Context) (*apisv1.ListEnvsGroupedByProjectResponse, error)
 	ListEnvCount(ctx context.Context, listOption apisv1.ListEnvOptions) (int64, error)
-	DeleteEnv(ctx context.Context, envName string) error
+	DeleteEnv(ctx context.Context, envName string, force, deleteNamespace bool) error
 	CreateEnv(ctx context.Context, req apisv1.CreateEnvRequest) (*apisv1.Env, error)
 	UpdateEnv(ctx context.Context, envName string, req apisv1.UpdateEnvRequest) (*apisv1.Env, error)
+	CloneEnv(ctx context.Context, sourceName string, req apisv1.CloneEnvRequest) (*apisv1.Env, error)
+	BatchCreateEnv(ctx context.Context, reqs []apisv1.CreateEnvRequest) (*apisv1.BatchCreateEnvResponse, error)
+	ListUnassignedTargets(ctx context.Context, project string) (*apisv1.ListTargetResponse, error)
+	PreviewEnvPrivileges(ctx context.Context, req apisv1.CreateEnvRequest) (*apisv1.PreviewEnvPrivilegesResponse, error)
+	ArchiveEnv(ctx context.Context, envName string) (*apisv1.Env, error)
+	UnarchiveEnv(ctx context.Context, envName string) (*apisv1.Env, error)
+	ExportEnv(ctx context.Context, envName string) (*apisv1.ExportEnvResponse, error)
+	ImportEnv(ctx context.Context, req apisv1.ImportEnvRequest) (*apisv1.Env, error)
+	GetDefaultEnv(ctx context.Context, project string) (*apisv1.Env, error)
+	ReconcileEnv(ctx context.Context, envName string) (*apisv1.ReconcileEnvResponse, error)
+	GetEnvAccess(ctx context.Context, envName string) (*apisv1.EnvAccessResponse, error)
+	CheckAppQuota(ctx context.Context, env *model.Env) error
+	ValidateEnvTargets(ctx context.Context, project, envName string, targets []string) (*apisv1.ValidateEnvTargetsResponse, error)
+	FindOrphanedTargets(ctx context.Context, project string, repair bool) (*apisv1.ListOrphanedEnvTargetsResponse, error)
+	MigrateApplications(ctx context.Context, fromEnv, toEnv string, dryRun bool) (*apisv1.MigrateApplicationsResponse, error)
+	CompareEnvs(ctx context.Context, envA, envB string) (*apisv1.CompareEnvsResponse, error)
+	GetEnvVariable(ctx context.Context, envName, name string) (string, error)
+	SetEnvVariable(ctx context.Context, envName, name, value string) error
 }
 
 type envServiceImpl struct {
-	Store          datastore.DataStore `inject:"datastore"`
-	ProjectService ProjectService      `inject:""`
-	KubeClient     client.Client       `inject:"kubeClient"`
+	Store              datastore.DataStore `inject:"datastore"`
+	ProjectService     ProjectService      `inject:""`
+	RbacService        RBACService         `inject:""`
+	KubeClient         client.Client       `inject:"kubeClient"`
+	AuditLogger        AuditLogger         `inject:""`
+	EnvBindingService  EnvBindingService   `inject:""`
+	ApplicationService ApplicationService  `inject:""`
+	WorkflowService    WorkflowService     `inject:""`
+
+	// clusterProbe checks whether the cluster could be contacted, probeCluster is used if it is not set
+	clusterProbe func(ctx context.Context, clusterName string) error
+	caches       *utils.MemoryCacheStore
 }
 
+const (
+	// EnvTargetStatusAvailable the target exists and doesn't belong to another env of the project
+	EnvTargetStatusAvailable = "Available"
+	// EnvTargetStatusConflict the target belongs to another env of the project
+	EnvTargetStatusConflict = "Conflict"
+	// EnvTargetStatusNotExist the target doesn't exist
+	EnvTargetStatusNotExist = "NotExist"
+
+	// AppMigrationStatusPending the application would be migrated, it is only used by the dry run
+	AppMigrationStatusPending = "Pending"
+	// AppMigrationStatusMigrated the application is migrated to the target env
+	AppMigrationStatusMigrated = "Migrated"
+	// AppMigrationStatusSkipped the application is already in the target env
+	AppMigrationStatusSkipped = "Skipped"
+	// AppMigrationStatusFailed the application fails to be migrated, it may be left in both envs
+	AppMigrationStatusFailed = "Failed"
+)
+
 // NewEnvService new env service
 func NewEnvService() EnvService {
-	return &envServiceImpl{}
+	return &envServiceImpl{caches: utils.NewMemoryCacheStore(context.Background())}
 }
 
 // GetEnv get env
@@ -67,10 +174,44 @@ func (p *envServiceImpl) GetEnv(ctx context.Context, envName string) (*model.Env
 	return repository.GetEnv(ctx, p.Store, envName)
 }
 
+// GetEnvDetail get the env with the aliases of the targets and the project, it is the same as the env listed by ListEnvs
+func (p *envServiceImpl) GetEnvDetail(ctx context.Context, envName string) (*apisv1.Env, error) {
+	env, err := repository.GetEnv(ctx, p.Store, envName)
+	if err != nil {
+		return nil, err
+	}
+	var targets []*model.Target
+	if len(env.Targets) > 0 {
+		targets, err = repository.ListTarget(ctx, p.Store, "", &datastore.ListOptions{
+			FilterOptions: datastore.FilterOptions{
+				In: []datastore.InQueryOption{{Key: "name", Values: env.Targets}},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	resp := convertEnvModel2Base(env, targets)
+	project := &model.Project{Name: env.Project}
+	if err := p.Store.Get(ctx, project); err != nil {
+		if !errors.Is(err, datastore.ErrRecordNotExist) {
+			return nil, err
+		}
+	} else {
+		resp.Project.Alias = project.Alias
+	}
+	if env.AppQuota > 0 {
+		if resp.AppCount, err = p.GetAppCountInEnv(ctx, env); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
 // DeleteEnv delete an env by name
-// the function assume applications contain in env already empty.
-// it won't delete the namespace created by the Env, but it will update the label
-func (p *envServiceImpl) DeleteEnv(ctx context.Context, envName string) error {
+// the function refuses to delete the env if there are applications in it, unless force is set.
+// it won't delete the namespace created by the Env but update the label, unless deleteNamespace is set.
+func (p *envServiceImpl) DeleteEnv(ctx context.Context, envName string, force, deleteNamespace bool) error {
 	env := &model.Env{}
 	env.Name = envName
 
@@ -80,29 +221,278 @@ func (p *envServiceImpl) DeleteEnv(ctx context.Context, envName string) error {
 		}
 		return err
 	}
+	logger := envLogger(ctx, env)
+	if !force {
+		count, err := p.GetAppCountInEnv(ctx, env)
+		if err != nil {
+			return err
+		}
+		// the applications of VelaUX bound to the env but not deployed yet
+		bindings, err := p.Store.Count(ctx, &model.EnvBinding{Name: env.Name}, nil)
+		if err != nil {
+			return err
+		}
+		if count > 0 || bindings > 0 {
+			logger.Info("refused to delete the env with applications", "applications", count, "envBindings", bindings)
+			return bcode.ErrDeleteEnvButAppExist
+		}
+	}
+	var namespaces []*corev1.Namespace
+	if deleteNamespace {
+		var err error
+		if namespaces, err = p.checkEnvNamespacesDeletable(ctx, env); err != nil {
+			return err
+		}
+	}
 	// reset the labels
-	err := util.UpdateNamespace(ctx, p.KubeClient, env.Namespace, util.MergeOverrideLabels(map[string]string{
-		oam.LabelNamespaceOfEnvName:         "",
-		oam.LabelControlPlaneNamespaceUsage: "",
-	}))
-	if err != nil && apierror.IsNotFound(err) {
+	for _, ns := range env.AllNamespaces() {
+		err := util.UpdateNamespace(ctx, p.KubeClient, ns, util.MergeOverrideLabels(map[string]string{
+			oam.LabelNamespaceOfEnvName:         "",
+			oam.LabelControlPlaneNamespaceUsage: "",
+		}))
+		if err != nil && !apierror.IsNotFound(err) {
+			return err
+		}
+	}
+
+	// the env is deleted at last, so the deletion can be retried if deleting the namespaces or revoking the privileges fails
+	for _, namespace := range namespaces {
+		if err := p.KubeClient.Delete(ctx, namespace); err != nil && !apierror.IsNotFound(err) {
+			logger.Error(err, "failed to delete the namespace of the env", "namespace", namespace.Name)
+			return err
+		}
+		logger.Info("deleted the namespace of the env", "namespace", namespace.Name)
+	}
+
+	if err := managePrivilegesForEnvironment(ctx, p.KubeClient, env, true); err != nil {
 		return err
 	}
+	p.audit(ctx, newAuditEvent(ctx, "env", env.Name, AuditActionRevokePrivileges))
 
-	if err = p.Store.Delete(ctx, env); err != nil {
+	if err := p.Store.Delete(ctx, env); err != nil {
 		if errors.Is(err, datastore.ErrRecordNotExist) {
 			return nil
 		}
 		return err
 	}
+	deleteEvent := newAuditEvent(ctx, "env", env.Name, AuditActionDelete)
+	deleteEvent.OldTargets = env.Targets
+	p.audit(ctx, deleteEvent)
+	logger.Info("deleted the env", "force", force, "deleteNamespace", deleteNamespace)
 
-	if err := managePrivilegesForEnvironment(ctx, p.KubeClient, env, true); err != nil {
-		return err
+	return nil
+}
+
+// checkEnvNamespacesDeletable check whether the namespaces can be deleted with the env, the namespaces that don't exist are skipped.
+// Only the namespaces created by the env can be deleted, the namespaces that existed before are only labeled by the env.
+// There must not be the applications not managed by VelaUX or the workloads not belonging to the applications of the env.
+// All namespaces are checked before deleting any of them.
+func (p *envServiceImpl) checkEnvNamespacesDeletable(ctx context.Context, env *model.Env) ([]*corev1.Namespace, error) {
+	var namespaces []*corev1.Namespace
+	for _, ns := range env.AllNamespaces() {
+		namespace, err := util.GetNamespace(ctx, p.KubeClient, ns)
+		if err != nil {
+			if apierror.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if namespace.Annotations[model.AnnotationNamespaceCreatedByEnv] != env.Name {
+			return nil, bcode.ErrEnvNamespaceNotManaged
+		}
+		var apps v1beta1.ApplicationList
+		if err := p.KubeClient.List(ctx, &apps, client.InNamespace(ns)); err != nil {
+			return nil, err
+		}
+		envApps := make(map[string]bool, len(apps.Items))
+		for _, app := range apps.Items {
+			if app.Labels[types.LabelSourceOfTruth] != types.FromUX {
+				return nil, bcode.ErrEnvNamespaceNotEmpty
+			}
+			envApps[app.Name] = true
+		}
+		if err := p.checkNoForeignWorkloads(ctx, ns, envApps); err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, namespace)
 	}
+	return namespaces, nil
+}
 
+// checkNoForeignWorkloads check whether all of the workloads and the data in the namespace belong to the applications.
+// The objects owned by others are skipped as their owners are checked, the service account tokens are created by the cluster.
+func (p *envServiceImpl) checkNoForeignWorkloads(ctx context.Context, namespace string, apps map[string]bool) error {
+	lists := []struct {
+		kind string
+		list client.ObjectList
+	}{
+		{"Deployment", &appsv1.DeploymentList{}},
+		{"StatefulSet", &appsv1.StatefulSetList{}},
+		{"DaemonSet", &appsv1.DaemonSetList{}},
+		{"ReplicaSet", &appsv1.ReplicaSetList{}},
+		{"Job", &batchv1.JobList{}},
+		{"CronJob", &batchv1.CronJobList{}},
+		{"Pod", &corev1.PodList{}},
+		{"PersistentVolumeClaim", &corev1.PersistentVolumeClaimList{}},
+		{"Secret", &corev1.SecretList{}},
+	}
+	for _, l := range lists {
+		if err := p.KubeClient.List(ctx, l.list, client.InNamespace(namespace)); err != nil {
+			return err
+		}
+		items, err := apimeta.ExtractList(l.list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			object, ok := item.(client.Object)
+			if !ok || len(object.GetOwnerReferences()) > 0 || apps[object.GetLabels()[oam.LabelAppName]] {
+				continue
+			}
+			if secret, ok := object.(*corev1.Secret); ok && secret.Type == corev1.SecretTypeServiceAccountToken {
+				continue
+			}
+			return bcode.ErrEnvNamespaceNotEmpty.SetMessage(fmt.Sprintf("the namespace %s can't be deleted as the %s %s doesn't belong to the applications of the env",
+				namespace, l.kind, object.GetName()))
+		}
+	}
 	return nil
 }
 
+// ArchiveEnv archive an env, it's a reversible alternative of DeleteEnv.
+// the privileges of the project are revoked, but the env record and the labels of the namespace are kept.
+func (p *envServiceImpl) ArchiveEnv(ctx context.Context, envName string) (*apisv1.Env, error) {
+	return p.setEnvArchived(ctx, envName, true)
+}
+
+// UnarchiveEnv restore an archived env and grant the privileges of the project again
+func (p *envServiceImpl) UnarchiveEnv(ctx context.Context, envName string) (*apisv1.Env, error) {
+	return p.setEnvArchived(ctx, envName, false)
+}
+
+func (p *envServiceImpl) setEnvArchived(ctx context.Context, envName string, archived bool) (*apisv1.Env, error) {
+	env, err := repository.GetEnv(ctx, p.Store, envName)
+	if err != nil {
+		return nil, err
+	}
+	if env.Archived != archived {
+		// the targets may be claimed by the other envs after the env is archived
+		if !archived {
+			if err := p.checkEnvTarget(ctx, env.Project, env.Name, env.Targets); err != nil {
+				return nil, err
+			}
+		}
+		// the privileges are changed before the state is stored, so that the stored state never claims
+		// the privileges are revoked or granted when they are not, and they are changed back if storing fails.
+		// Updating the role and role binding can't use the login user permissions.
+		updateRoleCtx := utils.WithProject(ctx, "")
+		if err := managePrivilegesForEnvironment(updateRoleCtx, p.KubeClient, env, archived); err != nil {
+			return nil, err
+		}
+		env.Archived = archived
+		if err := p.Store.Put(ctx, env); err != nil {
+			if rollbackErr := managePrivilegesForEnvironment(updateRoleCtx, p.KubeClient, env, !archived); rollbackErr != nil {
+				envLogger(ctx, env).Error(rollbackErr, "failed to restore the privileges of the env")
+			}
+			return nil, err
+		}
+		action, privilegesAction := AuditActionArchive, AuditActionRevokePrivileges
+		if !archived {
+			action, privilegesAction = AuditActionUnarchive, AuditActionGrantPrivileges
+		}
+		p.audit(ctx, newAuditEvent(ctx, "env", env.Name, action))
+		p.audit(ctx, newAuditEvent(ctx, "env", env.Name, privilegesAction))
+	}
+	targets, err := repository.ListTarget(ctx, p.Store, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return convertEnvModel2Base(env, targets), nil
+}
+
+// ReconcileEnv re-apply the expected labels to the namespaces of the env and grant the privileges again,
+// it repairs the env whose namespaces are edited manually.
+func (p *envServiceImpl) ReconcileEnv(ctx context.Context, envName string) (*apisv1.ReconcileEnvResponse, error) {
+	env, err := repository.GetEnv(ctx, p.Store, envName)
+	if err != nil {
+		return nil, err
+	}
+	if env.Archived {
+		return nil, bcode.ErrEnvArchived
+	}
+	report := &apisv1.ReconcileEnvResponse{Name: env.Name}
+	// Reconciling the namespace and the privileges can't use the login user permissions.
+	reconcileCtx := utils.WithProject(ctx, "")
+	for _, ns := range env.AllNamespaces() {
+		created := false
+		var namespace corev1.Namespace
+		if err := p.KubeClient.Get(reconcileCtx, client.ObjectKey{Name: ns}, &namespace); err != nil {
+			if !apierror.IsNotFound(err) {
+				return nil, err
+			}
+			created = true
+		} else if owner := namespace.Labels[oam.LabelNamespaceOfEnvName]; owner != "" && owner != env.Name {
+			return nil, bcode.ErrEnvNamespaceAlreadyBound
+		}
+		drifted := findDriftedLabels(namespace.Labels, expectedEnvNamespaceLabels(env))
+		if created || len(drifted) > 0 {
+			if err := util.CreateOrUpdateNamespace(reconcileCtx, p.KubeClient, ns, util.MergeOverrideLabels(drifted),
+				repository.MarkNamespaceCreatedByEnv(env.Name)); err != nil {
+				klog.Errorf("reconcile the namespace %s of the env %s failure %s", ns, util.Sanitize(env.Name), err.Error())
+				return nil, bcode.ErrEnvNamespaceFail
+			}
+		}
+		report.NamespaceCreated = report.NamespaceCreated || created
+		for k, v := range drifted {
+			if report.CorrectedLabels == nil {
+				report.CorrectedLabels = make(map[string]string)
+			}
+			report.CorrectedLabels[k] = v
+		}
+	}
+	if err := managePrivilegesForEnvironment(reconcileCtx, p.KubeClient, env, false); err != nil {
+		return nil, err
+	}
+	report.PrivilegesGranted = true
+	event := newAuditEvent(ctx, "env", env.Name, AuditActionReconcile)
+	event.Message = fmt.Sprintf("namespace created: %t, corrected labels: %d", report.NamespaceCreated, len(report.CorrectedLabels))
+	p.audit(ctx, event)
+	return report, nil
+}
+
+// expectedEnvNamespaceLabels the labels that the namespace of the env should have
+func expectedEnvNamespaceLabels(env *model.Env) map[string]string {
+	labels := make(map[string]string, len(env.Labels)+2)
+	for k, v := range env.Labels {
+		labels[k] = v
+	}
+	labels[oam.LabelControlPlaneNamespaceUsage] = oam.VelaNamespaceUsageEnv
+	labels[oam.LabelNamespaceOfEnvName] = env.Name
+	return labels
+}
+
+// findDriftedLabels return the expected labels that are missing or have different values in the actual labels
+func findDriftedLabels(actual, expected map[string]string) map[string]string {
+	var drifted map[string]string
+	for k, v := range expected {
+		if value, exist := actual[k]; !exist || value != v {
+			if drifted == nil {
+				drifted = make(map[string]string)
+			}
+			drifted[k] = v
+		}
+	}
+	return drifted
+}
+
+// audit send the event to the audit logger if it is set
+func (p *envServiceImpl) audit(ctx context.Context, event *AuditEvent) {
+	if p.AuditLogger == nil {
+		return
+	}
+	p.AuditLogger.Audit(ctx, event)
+}
+
 // ListEnvs list envs
 func (p *envServiceImpl) ListEnvs(ctx context.Context, page, pageSize int, listOption apisv1.ListEnvOptions) (*apisv1.ListEnvResponse, error) {
 	userName, ok := ctx.Value(&apisv1.CtxKeyUser).(string)
@@ -120,60 +510,316 @@ func (p *envServiceImpl) ListEnvs(ctx context.Context, page, pageSize int, listO
 		projectNameAlias[project.Name] = project.Alias
 	}
 	if len(availableProjectNames) == 0 {
-		return &apisv1.ListEnvResponse{Envs: []*apisv1.Env{}, Total: 0}, nil
+		return newListEnvResponse([]*apisv1.Env{}, 0, page, pageSize), nil
 	}
 	if listOption.Project != "" {
 		if !util.StringsContain(availableProjectNames, listOption.Project) {
-			return &apisv1.ListEnvResponse{Envs: []*apisv1.Env{}, Total: 0}, nil
+			return newListEnvResponse([]*apisv1.Env{}, 0, page, pageSize), nil
 		}
 	}
 	projectNames := []string{listOption.Project}
 	if listOption.Project == "" {
 		projectNames = availableProjectNames
 	}
-	filter := datastore.FilterOptions{
-		In: []datastore.InQueryOption{
-			{
-				Key:    "project",
-				Values: projectNames,
-			},
-		},
+	return p.listEnvsInProjects(ctx, userName, projectNames, projectNameAlias, page, pageSize, listOption)
+}
+
+// ListEnvsGroupedByProject list the envs of the projects that the login user could access and group them by the project.
+// The projects without any env are listed as well, the archived envs are not listed.
+func (p *envServiceImpl) ListEnvsGroupedByProject(ctx context.Context) (*apisv1.ListEnvsGroupedByProjectResponse, error) {
+	userName, ok := ctx.Value(&apisv1.CtxKeyUser).(string)
+	if !ok {
+		return nil, bcode.ErrUnauthorized
 	}
-	entities, err := repository.ListEnvs(ctx, p.Store, &datastore.ListOptions{
-		Page:          page,
-		PageSize:      pageSize,
-		SortBy:        []datastore.SortOption{{Key: "createTime", Order: datastore.SortOrderDescending}},
-		FilterOptions: filter,
-	})
+	projects, err := p.ProjectService.ListUserProjects(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	resp := &apisv1.ListEnvsGroupedByProjectResponse{Projects: []*apisv1.ProjectEnvs{}}
+	if len(projects) == 0 {
+		return resp, nil
+	}
+	var projectNames []string
+	var projectNameAlias = make(map[string]string, len(projects))
+	groups := make(map[string]*apisv1.ProjectEnvs, len(projects))
+	for _, project := range projects {
+		projectNames = append(projectNames, project.Name)
+		projectNameAlias[project.Name] = project.Alias
+		group := &apisv1.ProjectEnvs{Project: apisv1.NameAlias{Name: project.Name, Alias: project.Alias}, Envs: []*apisv1.Env{}}
+		groups[project.Name] = group
+		resp.Projects = append(resp.Projects, group)
+	}
+	envs, err := p.listEnvsInProjects(ctx, userName, projectNames, projectNameAlias, 0, 0, apisv1.ListEnvOptions{SortBy: EnvSortByName})
+	if err != nil {
+		return nil, err
+	}
+	for _, env := range envs.Envs {
+		if group, ok := groups[env.Project.Name]; ok {
+			group.Envs = append(group.Envs, env)
+		}
+	}
+	sort.Slice(resp.Projects, func(i, j int) bool { return resp.Projects[i].Project.Name < resp.Projects[j].Project.Name })
+	return resp, nil
+}
+
+// ListAllEnvs list the envs of all projects, only the platform admin is allowed
+func (p *envServiceImpl) ListAllEnvs(ctx context.Context, page, pageSize int, listOption apisv1.ListEnvOptions) (*apisv1.ListEnvResponse, error) {
+	userName, ok := ctx.Value(&apisv1.CtxKeyUser).(string)
+	if !ok || userName == "" {
+		return nil, bcode.ErrUnauthorized
+	}
+	user := &model.User{Name: userName}
+	if err := p.Store.Get(ctx, user); err != nil || !user.IsAdmin() {
+		return nil, bcode.ErrUnauthorized
+	}
+	entities, err := p.Store.List(ctx, &model.Project{}, &datastore.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
+	var projectNameAlias = make(map[string]string, len(entities))
+	for _, entity := range entities {
+		project := entity.(*model.Project)
+		projectNameAlias[project.Name] = project.Alias
+	}
+	// the envs are not filtered by the project unless it is specified, so the envs whose project is deleted are listed as well
+	var projectNames []string
+	if listOption.Project != "" {
+		projectNames = []string{listOption.Project}
+	}
+	return p.listEnvsInProjects(ctx, userName, projectNames, projectNameAlias, page, pageSize, listOption)
+}
 
-	targets, err := repository.ListTarget(ctx, p.Store, listOption.Project, nil)
+// listEnvsInProjects list the envs of the projects with the filters, the sort and the pagination of the options.
+// The envs of all projects are listed if the projects are nil.
+func (p *envServiceImpl) listEnvsInProjects(ctx context.Context, userName string, projectNames []string, projectNameAlias map[string]string,
+	page, pageSize int, listOption apisv1.ListEnvOptions) (*apisv1.ListEnvResponse, error) {
+	var filter datastore.FilterOptions
+	if projectNames != nil {
+		filter.In = append(filter.In, datastore.InQueryOption{
+			Key:    "project",
+			Values: projectNames,
+		})
+	}
+	for k, v := range listOption.Labels {
+		filter.In = append(filter.In, datastore.InQueryOption{
+			Key:    model.EnvLabelIndexKey(k),
+			Values: []string{v},
+		})
+	}
+	if !listOption.IncludeArchived {
+		filter.IsNotExist = append(filter.IsNotExist, datastore.IsNotExistQueryOption{Key: "archived"})
+	}
+	listOptions := &datastore.ListOptions{FilterOptions: filter}
+	switch listOption.SortBy {
+	case "", EnvSortByCreateTime:
+		order := datastore.SortOrderDescending
+		if listOption.SortOrder == "asc" {
+			order = datastore.SortOrderAscending
+		}
+		listOptions.Page, listOptions.PageSize = page, pageSize
+		listOptions.SortBy = []datastore.SortOption{{Key: "createTime", Order: order}}
+	case EnvSortByName, EnvSortByAlias:
+		// the datastore can't sort the strings case-insensitively, so sort and paginate in memory
+	default:
+		return nil, bcode.ErrEnvSortByNotSupport
+	}
+	// the permissions and the targets can't be filtered by the datastore, so paginate in memory after filtering
+	paginateInMemory := listOptions.SortBy == nil || listOption.WritableOnly || listOption.Target != ""
+	if paginateInMemory {
+		listOptions.Page, listOptions.PageSize = 0, 0
+	}
+	entities, err := repository.ListEnvs(ctx, p.Store, listOptions)
 	if err != nil {
 		return nil, err
 	}
+	if listOption.Target != "" {
+		entities = filterEnvsByTarget(entities, listOption.Target)
+	}
+	if listOption.WritableOnly {
+		entities, err = p.filterWritableEnvs(ctx, userName, entities)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var total int64
+	if paginateInMemory {
+		if listOptions.SortBy == nil {
+			sortEnvs(entities, listOption.SortBy, listOption.SortOrder == "desc")
+		}
+		total = int64(len(entities))
+		entities = paginateEnvs(entities, page, pageSize)
+	} else {
+		total, err = p.Store.Count(ctx, &model.Env{Project: listOption.Project}, &filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// list the targets of the projects once for all envs of the request
+	var targetMap map[string]*model.Target
+	if len(entities) > 0 {
+		targetOptions := &datastore.ListOptions{}
+		if projectNames != nil {
+			targetOptions.In = []datastore.InQueryOption{{Key: "project", Values: projectNames}}
+		}
+		targets, err := repository.ListTarget(ctx, p.Store, "", targetOptions)
+		if err != nil {
+			return nil, err
+		}
+		targetMap = newTargetMap(targets)
+	}
 
 	var envs []*apisv1.Env
 	for _, ee := range entities {
-		envs = append(envs, convertEnvModel2Base(ee, targets))
+		// stop the work if the client is gone
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		envs = append(envs, convertEnvModel2BaseWithTargetMap(ee, targetMap))
 	}
 
 	for i := range envs {
 		envs[i].Project.Alias = projectNameAlias[envs[i].Project.Name]
 	}
 
-	total, err := p.Store.Count(ctx, &model.Env{Project: listOption.Project}, &filter)
+	if (listOption.IncludeAppCount || hasAppQuota(entities)) && len(envs) > 0 {
+		counts, err := p.getAppCountInNamespaces(ctx, entities...)
+		if err != nil {
+			return nil, err
+		}
+		for i := range envs {
+			envs[i].AppCount = counts[envs[i].Namespace]
+			for _, ns := range envs[i].Namespaces {
+				envs[i].AppCount += counts[ns]
+			}
+		}
+	}
+
+	if listOption.IncludeTargetStatus {
+		// Checking the target can't use the login user permissions.
+		checkCtx := utils.WithProject(ctx, "")
+		for i := range envs {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			for j := range envs[i].Targets {
+				envs[i].Targets[j].Status = p.getTargetStatus(checkCtx, targetMap[envs[i].Targets[j].Name])
+			}
+		}
+	}
+
+	return newListEnvResponse(envs, total, page, pageSize), nil
+}
+
+// filterEnvsByTarget return the envs that own the target, the index of the env doesn't include the targets
+func filterEnvsByTarget(envs []*model.Env, target string) []*model.Env {
+	var filtered []*model.Env
+	for _, env := range envs {
+		if util.StringsContain(env.Targets, target) {
+			filtered = append(filtered, env)
+		}
+	}
+	return filtered
+}
+
+// filterWritableEnvs keep the envs that the user has the permissions to update and delete, the permissions are the same as checking the API requests
+func (p *envServiceImpl) filterWritableEnvs(ctx context.Context, userName string, envs []*model.Env) ([]*model.Env, error) {
+	user := &model.User{Name: userName}
+	if err := p.Store.Get(ctx, user); err != nil {
+		return nil, bcode.ErrUnauthorized
+	}
+	path, err := checkResourcePath("environment")
 	if err != nil {
 		return nil, err
 	}
-	return &apisv1.ListEnvResponse{Envs: envs, Total: total}, nil
+	projectPermissions := make(map[string][]*model.Permission)
+	var writable []*model.Env
+	for _, env := range envs {
+		permissions, exist := projectPermissions[env.Project]
+		if !exist {
+			permissions, err = p.RbacService.GetUserPermissions(ctx, user, env.Project, true)
+			if err != nil {
+				return nil, err
+			}
+			projectPermissions[env.Project] = permissions
+		}
+		if isEnvWritable(path, env, permissions) {
+			writable = append(writable, env)
+		}
+	}
+	return writable, nil
+}
+
+// envWriteActions the actions on the env that the writable env allows
+var envWriteActions = []string{"update", "delete"}
+
+// isEnvWritable check whether the permissions allow all write actions on the env, the path is the resource path of the env.
+// The actions are matched one by one, so denying any of them makes the env read-only.
+func isEnvWritable(path string, env *model.Env, permissions []*model.Permission) bool {
+	ra := &RequestResourceAction{}
+	ra.SetResourceWithName(path, func(name string) string {
+		switch name {
+		case ResourceMaps["project"].pathName:
+			return env.Project
+		case ResourceMaps["project"].subResources["environment"].pathName:
+			return env.Name
+		}
+		return ""
+	})
+	for _, action := range envWriteActions {
+		ra.SetActions([]string{action})
+		if !ra.Match(permissions) {
+			return false
+		}
+	}
+	return true
+}
+
+// newListEnvResponse build the response with the pagination metadata, the page size 0 means not paginated
+func newListEnvResponse(envs []*apisv1.Env, total int64, page, pageSize int) *apisv1.ListEnvResponse {
+	totalPages := 1
+	if pageSize > 0 && total > 0 {
+		totalPages = int((total + int64(pageSize) - 1) / int64(pageSize))
+	}
+	return &apisv1.ListEnvResponse{Envs: envs, Total: total, Page: page, PageSize: pageSize, TotalPages: totalPages}
 }
 
 func (p *envServiceImpl) ListEnvCount(ctx context.Context, listOption apisv1.ListEnvOptions) (int64, error) {
 	return p.Store.Count(ctx, &model.Env{Project: listOption.Project}, nil)
 }
 
+// sortEnvs sort the envs by the name or the alias, the envs with the same alias are sorted by the name
+func sortEnvs(envs []*model.Env, sortBy string, desc bool) {
+	sort.SliceStable(envs, func(i, j int) bool {
+		x, y := envs[i].Name, envs[j].Name
+		if sortBy == EnvSortByAlias {
+			if xAlias, yAlias := strings.ToLower(envs[i].Alias), strings.ToLower(envs[j].Alias); xAlias != yAlias {
+				x, y = xAlias, yAlias
+			}
+		}
+		if desc {
+			return x > y
+		}
+		return x < y
+	})
+}
+
+// paginateEnvs return the envs of the page, the page starts from 1, all envs are returned if the page or the page size is not set
+func paginateEnvs(envs []*model.Env, page, pageSize int) []*model.Env {
+	if page <= 0 || pageSize <= 0 {
+		return envs
+	}
+	start := (page - 1) * pageSize
+	if start >= len(envs) {
+		return []*model.Env{}
+	}
+	end := start + pageSize
+	if end > len(envs) {
+		end = len(envs)
+	}
+	return envs[start:end]
+}
+
 func checkEqual(old, new []string) bool {
 	if old == nil && new == nil {
 		return true
@@ -186,28 +832,97 @@ func checkEqual(old, new []string) bool {
 	return reflect.DeepEqual(old, new)
 }
 
+// sameEnvSpec check whether the existing env is created by the same request, the order of the targets is ignored.
+// The Default is not compared because it may be taken over by another env of the project after creating.
+func sameEnvSpec(existing, env *model.Env) bool {
+	namespace := env.Namespace
+	if namespace == "" {
+		namespace = env.Name
+	}
+	expected := &model.Env{Namespace: namespace, Namespaces: env.Namespaces}
+	if existing.Archived ||
+		existing.Alias != env.Alias ||
+		existing.Description != env.Description ||
+		existing.Project != env.Project ||
+		existing.AppQuota != env.AppQuota ||
+		!reflect.DeepEqual(existing.AllNamespaces(), expected.AllNamespaces()) {
+		return false
+	}
+	if (len(existing.Targets) > 0 || len(env.Targets) > 0) &&
+		!checkEqual(append([]string{}, existing.Targets...), append([]string{}, env.Targets...)) {
+		return false
+	}
+	if (len(existing.Labels) > 0 || len(env.Labels) > 0) && !reflect.DeepEqual(existing.Labels, env.Labels) {
+		return false
+	}
+	if (len(existing.TargetSelector) > 0 || len(env.TargetSelector) > 0) && !reflect.DeepEqual(existing.TargetSelector, env.TargetSelector) {
+		return false
+	}
+	if (len(existing.Variables) > 0 || len(env.Variables) > 0) && !reflect.DeepEqual(existing.Variables, env.Variables) {
+		return false
+	}
+	if (len(existing.DefaultAppLabels) > 0 || len(env.DefaultAppLabels) > 0) && !reflect.DeepEqual(existing.DefaultAppLabels, env.DefaultAppLabels) {
+		return false
+	}
+	if (len(existing.Annotations) > 0 || len(env.Annotations) > 0) && !reflect.DeepEqual(existing.Annotations, env.Annotations) {
+		return false
+	}
+	return true
+}
+
 // UpdateEnv update an env for request
 func (p *envServiceImpl) UpdateEnv(ctx context.Context, name string, req apisv1.UpdateEnvRequest) (*apisv1.Env, error) {
 	env := &model.Env{}
 	env.Name = name
 	err := p.Store.Get(ctx, env)
 	if err != nil {
-		klog.Errorf("check if env name exists failure %s", err.Error())
+		envLogger(ctx, env).Error(err, "check if env name exists failure")
 		return nil, bcode.ErrEnvNotExisted
 	}
-	if req.Alias != "" {
+	logger := envLogger(ctx, env)
+	if req.ExpectedUpdateTime != nil && isEnvChanged(env, *req.ExpectedUpdateTime) {
+		return nil, bcode.ErrEnvUpdateConflict
+	}
+	// the env is only written if it is not changed by others after it is loaded
+	loadedUpdateTime := env.UpdateTime
+	updateEvent := newAuditEvent(ctx, "env", env.Name, AuditActionUpdate)
+	updateEvent.OldTargets = env.Targets
+	if req.Alias != "" || req.ClearAlias {
 		env.Alias = req.Alias
 	}
-	if req.Description != "" {
+	if req.Description != "" || req.ClearDescription {
 		env.Description = req.Description
 	}
-
-	pass, err := p.checkEnvTarget(ctx, env.Project, env.Name, req.Targets)
-	if err != nil || !pass {
-		return nil, bcode.ErrEnvTargetConflict
+	if req.Default != nil {
+		env.Default = *req.Default
 	}
-	var targets []*model.Target
-	if len(req.Targets) > 0 {
+	if req.TargetSelector != nil {
+		env.TargetSelector = nil
+		if len(req.TargetSelector) > 0 {
+			selected, err := p.resolveTargetSelector(ctx, env.Project, req.TargetSelector)
+			if err != nil {
+				return nil, err
+			}
+			// the selected targets are added to the existing targets if the targets are not changed
+			if len(req.Targets) == 0 {
+				req.Targets = env.Targets
+			}
+			req.Targets = mergeTargetNames(req.Targets, selected)
+			env.TargetSelector = req.TargetSelector
+		}
+	}
+
+	var losingEnvs []*model.Env
+	if req.AllowTargetSteal {
+		losingEnvs, err = p.listLosingEnvs(ctx, env, req.Targets)
+		if err != nil {
+			return nil, err
+		}
+	} else if err := p.checkEnvTarget(ctx, env.Project, env.Name, req.Targets); err != nil {
+		return nil, err
+	}
+	var targets []*model.Target
+	if len(req.Targets) > 0 {
 		_, _, deleted := util.ThreeWaySliceCompare(req.Targets, env.Targets)
 		if len(deleted) > 0 {
 			count, err := p.GetAppCountInEnv(ctx, env)
@@ -229,15 +944,105 @@ func (p *envServiceImpl) UpdateEnv(ctx context.Context, name string, req apisv1.
 		if err != nil {
 			return nil, err
 		}
-		if len(targets) != len(req.Targets) {
-			return nil, bcode.ErrTargetNotExist
+		if missing := findMissingTargets(req.Targets, targets); len(missing) > 0 {
+			return nil, errTargetsNotExist(missing)
 		}
 		env.Targets = req.Targets
 	}
+	if req.AppQuota != nil {
+		env.AppQuota = *req.AppQuota
+		if _, err := p.checkAppQuotaNotExceeded(ctx, env); err != nil {
+			return nil, err
+		}
+	}
+	if req.Labels != nil {
+		if err := validateEnvLabels(req.Labels); err != nil {
+			return nil, err
+		}
+	}
+	if req.Variables != nil {
+		if err := validateEnvVariables(req.Variables); err != nil {
+			return nil, err
+		}
+		env.Variables = req.Variables
+		if len(env.Variables) == 0 {
+			env.Variables = nil
+		}
+	}
+	if req.DefaultAppLabels != nil {
+		if err := validateDefaultAppLabels(req.DefaultAppLabels); err != nil {
+			return nil, err
+		}
+		env.DefaultAppLabels = req.DefaultAppLabels
+		if len(env.DefaultAppLabels) == 0 {
+			env.DefaultAppLabels = nil
+		}
+	}
+	if req.Annotations != nil {
+		if err := validateEnvAnnotations(req.Annotations); err != nil {
+			return nil, err
+		}
+		env.Annotations = req.Annotations
+		if len(env.Annotations) == 0 {
+			env.Annotations = nil
+		}
+	}
+	// the env may be changed by others while checking the targets, fail early before updating the namespaces
+	latest, err := repository.GetEnv(ctx, p.Store, env.Name)
+	if err != nil {
+		return nil, err
+	}
+	if isEnvChanged(latest, loadedUpdateTime) {
+		return nil, bcode.ErrEnvUpdateConflict
+	}
+	if req.Labels != nil {
+		// Updating the namespace can't use the login user permissions.
+		updateNamespaceCtx := utils.WithProject(ctx, "")
+		for _, ns := range env.AllNamespaces() {
+			if err := util.UpdateNamespace(updateNamespaceCtx, p.KubeClient, ns, replaceEnvLabels(env.Labels, req.Labels)); err != nil {
+				logger.Error(err, "update namespace label failure", "namespace", ns)
+				return nil, bcode.ErrEnvNamespaceFail
+			}
+		}
+		env.Labels = req.Labels
+	}
+
+	// remove the targets from the other envs before assigning them to this env,
+	// they are restored if the targets can't be assigned to this env.
+	var losingEvents []*AuditEvent
+	var originals []*model.Env
+	for _, losingEnv := range losingEnvs {
+		original, err := repository.GetEnv(ctx, p.Store, losingEnv.Name)
+		if err == nil {
+			err = p.Store.PutIfUnchanged(ctx, losingEnv, losingEnv.UpdateTime)
+		}
+		if err != nil {
+			p.restoreLosingEnvs(ctx, originals)
+			return nil, convertEnvPutError(err)
+		}
+		originals = append(originals, original)
+		losingEvent := newAuditEvent(ctx, "env", losingEnv.Name, AuditActionUpdate)
+		losingEvent.OldTargets = original.Targets
+		losingEvent.NewTargets = losingEnv.Targets
+		losingEvent.Message = fmt.Sprintf("the targets are moved to the env %s", env.Name)
+		losingEvents = append(losingEvents, losingEvent)
+	}
 
 	// create namespace at first
-	if err := p.Store.Put(ctx, env); err != nil {
-		return nil, err
+	if err := p.Store.PutIfUnchanged(ctx, env, loadedUpdateTime); err != nil {
+		p.restoreLosingEnvs(ctx, originals)
+		return nil, convertEnvPutError(err)
+	}
+	for _, losingEvent := range losingEvents {
+		p.audit(ctx, losingEvent)
+	}
+	updateEvent.NewTargets = env.Targets
+	p.audit(ctx, updateEvent)
+
+	if env.Default {
+		if err := p.resolveDefaultEnv(ctx, env); err != nil {
+			return nil, err
+		}
 	}
 
 	// Updating the role and role binding can't use the login user permissions.
@@ -245,17 +1050,225 @@ func (p *envServiceImpl) UpdateEnv(ctx context.Context, name string, req apisv1.
 	if err := managePrivilegesForEnvironment(updateRoleCtx, p.KubeClient, env, false); err != nil {
 		return nil, err
 	}
+	p.audit(ctx, newAuditEvent(ctx, "env", env.Name, AuditActionGrantPrivileges))
+	logger.Info("updated the env", "targets", env.Targets)
 
 	resp := convertEnvModel2Base(env, targets)
+	if env.AppQuota > 0 {
+		if count, err := p.GetAppCountInEnv(ctx, env); err == nil {
+			resp.AppCount = count
+		}
+	}
 	return resp, nil
 }
 
+// GetEnvVariable get the value of the variable of the env
+func (p *envServiceImpl) GetEnvVariable(ctx context.Context, envName, name string) (string, error) {
+	env, err := repository.GetEnv(ctx, p.Store, envName)
+	if err != nil {
+		return "", err
+	}
+	value, exist := env.Variables[name]
+	if !exist {
+		return "", bcode.ErrEnvVariableNotExist
+	}
+	return value, nil
+}
+
+// SetEnvVariable add or update the variable of the env, the other variables are kept
+func (p *envServiceImpl) SetEnvVariable(ctx context.Context, envName, name, value string) error {
+	if err := validateEnvVariables(map[string]string{name: value}); err != nil {
+		return err
+	}
+	env, err := repository.GetEnv(ctx, p.Store, envName)
+	if err != nil {
+		return err
+	}
+	if env.Archived {
+		return bcode.ErrEnvArchived
+	}
+	if env.Variables == nil {
+		env.Variables = map[string]string{}
+	}
+	env.Variables[name] = value
+	if err := p.Store.Put(ctx, env); err != nil {
+		return err
+	}
+	event := newAuditEvent(ctx, "env", env.Name, AuditActionUpdate)
+	event.Message = fmt.Sprintf("the variable %s is set", name)
+	p.audit(ctx, event)
+	envLogger(ctx, env).Info("set the env variable", "variable", name)
+	return nil
+}
+
+// validateEnvVariables check the names of the variables, they follow the same rule as the keys of the configmap
+func validateEnvVariables(variables map[string]string) error {
+	for name := range variables {
+		if errs := validation.IsConfigMapKey(name); len(errs) > 0 {
+			return bcode.ErrEnvVariableInvalid.SetMessage(fmt.Sprintf("the name of the env variable %q is invalid: %s", name, strings.Join(errs, ", ")))
+		}
+	}
+	return nil
+}
+
+// reservedEnvLabels the labels that bind the namespace to the env, they can't be set or removed by the labels of the env
+var reservedEnvLabels = []string{oam.LabelNamespaceOfEnvName, oam.LabelControlPlaneNamespaceUsage}
+
+// validateEnvLabels check whether the labels of the env are valid kubernetes labels and don't use the reserved keys
+func validateEnvLabels(labels map[string]string) error {
+	if errs := metav1validation.ValidateLabels(labels, field.NewPath("labels")); len(errs) > 0 {
+		return bcode.ErrEnvLabelsInvalid.SetMessage(errs.ToAggregate().Error())
+	}
+	for _, key := range reservedEnvLabels {
+		if _, exist := labels[key]; exist {
+			return bcode.ErrEnvLabelsInvalid.SetMessage(fmt.Sprintf("the label %s is reserved", key))
+		}
+	}
+	return nil
+}
+
+// validateDefaultAppLabels check whether the default labels of the applications are valid kubernetes labels
+func validateDefaultAppLabels(labels map[string]string) error {
+	if errs := metav1validation.ValidateLabels(labels, field.NewPath("defaultAppLabels")); len(errs) > 0 {
+		return bcode.ErrEnvDefaultAppLabelsInvalid.SetMessage(errs.ToAggregate().Error())
+	}
+	return nil
+}
+
+// validateEnvAnnotations check whether the annotations of the env follow the rules of the kubernetes annotations,
+// the keys are qualified names and the total size is limited
+func validateEnvAnnotations(annotations map[string]string) error {
+	if errs := apivalidation.ValidateAnnotations(annotations, field.NewPath("annotations")); len(errs) > 0 {
+		return bcode.ErrEnvAnnotationsInvalid.SetMessage(errs.ToAggregate().Error())
+	}
+	return nil
+}
+
+// isEnvChanged check whether the stored env is updated after the expected update time.
+// The times are compared in milliseconds because some datastores, such as MongoDB, don't keep the nanoseconds.
+func isEnvChanged(env *model.Env, expectedUpdateTime time.Time) bool {
+	return !env.UpdateTime.Truncate(time.Millisecond).Equal(expectedUpdateTime.Truncate(time.Millisecond))
+}
+
+// convertEnvPutError convert the error of writing an env changed by others to the conflict error.
+func convertEnvPutError(err error) error {
+	if errors.Is(err, datastore.ErrRecordChanged) {
+		return bcode.ErrEnvUpdateConflict
+	}
+	return err
+}
+
+// listLosingEnvs find the other envs in the same project that claim the targets, and remove the targets from them.
+// The targets can not be moved if there are applications in the losing env.
+func (p *envServiceImpl) listLosingEnvs(ctx context.Context, env *model.Env, targets []string) ([]*model.Env, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	entities, err := p.Store.List(ctx, &model.Env{Project: env.Project}, &datastore.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var losingEnvs []*model.Env
+	for _, entity := range entities {
+		losingEnv := entity.(*model.Env)
+		if losingEnv.Name == env.Name {
+			continue
+		}
+		moved, remaining, _ := util.ThreeWaySliceCompare(losingEnv.Targets, targets)
+		if len(moved) == 0 {
+			continue
+		}
+		count, err := p.GetAppCountInEnv(ctx, losingEnv)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, bcode.ErrEnvTargetNotAllowDelete
+		}
+		losingEnv.Targets = remaining
+		losingEnvs = append(losingEnvs, losingEnv)
+	}
+	return losingEnvs, nil
+}
+
+// restoreLosingEnvs restore the targets of the envs that lose the targets, the failures are logged
+func (p *envServiceImpl) restoreLosingEnvs(ctx context.Context, originals []*model.Env) {
+	for _, original := range originals {
+		if err := p.Store.Put(ctx, original); err != nil {
+			envLogger(ctx, original).Error(err, "failed to restore the targets of the env", "targets", original.Targets)
+		}
+	}
+}
+
+// GetAppCountInEnv count the applications created by VelaUX in all namespaces of the env
 func (p *envServiceImpl) GetAppCountInEnv(ctx context.Context, env *model.Env) (int, error) {
-	var appList v1beta1.ApplicationList
-	if err := p.KubeClient.List(ctx, &appList, client.InNamespace(env.Namespace), client.MatchingLabels{types.LabelSourceOfTruth: types.FromUX}); err != nil {
+	counts, err := p.getAppCountInNamespaces(ctx, env)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, c := range counts {
+		count += c
+	}
+	return count, nil
+}
+
+// CheckAppQuota check whether one more application could be created in the env without exceeding the quota
+func (p *envServiceImpl) CheckAppQuota(ctx context.Context, env *model.Env) error {
+	if env.AppQuota <= 0 {
+		return nil
+	}
+	count, err := p.GetAppCountInEnv(ctx, env)
+	if err != nil {
+		return err
+	}
+	if count >= env.AppQuota {
+		return bcode.ErrEnvQuotaExceeded.SetMessage(fmt.Sprintf("the env %s already has %d applications, the quota is %d", env.Name, count, env.AppQuota))
+	}
+	return nil
+}
+
+// checkAppQuotaNotExceeded check whether the existing applications in the env exceed the quota, return the number of them
+func (p *envServiceImpl) checkAppQuotaNotExceeded(ctx context.Context, env *model.Env) (int, error) {
+	if env.AppQuota <= 0 {
+		return 0, nil
+	}
+	count, err := p.GetAppCountInEnv(ctx, env)
+	if err != nil {
 		return 0, err
 	}
-	return len(appList.Items), nil
+	if count > env.AppQuota {
+		return 0, bcode.ErrEnvQuotaExceeded.SetMessage(fmt.Sprintf("the env %s has %d applications, it exceeds the quota %d", env.Name, count, env.AppQuota))
+	}
+	return count, nil
+}
+
+// hasAppQuota check whether any of the envs has the app quota
+func hasAppQuota(envs []*model.Env) bool {
+	for _, env := range envs {
+		if env.AppQuota > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getAppCountInNamespaces count the applications created by VelaUX in the namespaces of the envs, each namespace is only listed once
+func (p *envServiceImpl) getAppCountInNamespaces(ctx context.Context, envs ...*model.Env) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, env := range envs {
+		for _, ns := range env.AllNamespaces() {
+			if _, exist := counts[ns]; exist {
+				continue
+			}
+			var appList v1beta1.ApplicationList
+			if err := p.KubeClient.List(ctx, &appList, client.InNamespace(ns), client.MatchingLabels{types.LabelSourceOfTruth: types.FromUX}); err != nil {
+				return nil, err
+			}
+			counts[ns] = len(appList.Items)
+		}
+	}
+	return counts, nil
 }
 
 // CreateEnv create an env for request
@@ -265,14 +1278,56 @@ func (p *envServiceImpl) CreateEnv(ctx context.Context, req apisv1.CreateEnvRequ
 		Alias:       req.Alias,
 		Description: req.Description,
 		Namespace:   req.Namespace,
+		Namespaces:  req.Namespaces,
 		Project:     req.Project,
 		Targets:     req.Targets,
+		Labels:      req.Labels,
+		Default:     req.Default,
+		AppQuota:    req.AppQuota,
+		Variables:   req.Variables,
+		Annotations: req.Annotations,
+
+		DefaultAppLabels: req.DefaultAppLabels,
+	}
+	logger := envLogger(ctx, newEnv)
+	if err := validateEnvLabels(req.Labels); err != nil {
+		return nil, err
+	}
+	if err := validateEnvVariables(req.Variables); err != nil {
+		return nil, err
+	}
+	if err := validateDefaultAppLabels(req.DefaultAppLabels); err != nil {
+		return nil, err
+	}
+	if err := validateEnvAnnotations(req.Annotations); err != nil {
+		return nil, err
+	}
+
+	if len(req.TargetSelector) > 0 {
+		selected, err := p.resolveTargetSelector(ctx, req.Project, req.TargetSelector)
+		if err != nil {
+			return nil, err
+		}
+		req.Targets = mergeTargetNames(req.Targets, selected)
+		newEnv.Targets = req.Targets
+		newEnv.TargetSelector = req.TargetSelector
+	}
+
+	// The client may retry after the env is created, return the existing env if the request is the same
+	existing := &model.Env{Name: req.Name}
+	if err := p.Store.Get(ctx, existing); err == nil {
+		if !sameEnvSpec(existing, newEnv) {
+			return nil, bcode.ErrEnvSpecConflict
+		}
+		logger.Info("the env already exists with the same spec, skip creating it")
+		return p.GetEnvDetail(ctx, existing.Name)
+	} else if !errors.Is(err, datastore.ErrRecordNotExist) {
+		return nil, err
 	}
 
 	if !req.AllowTargetConflict {
-		pass, err := p.checkEnvTarget(ctx, req.Project, req.Name, req.Targets)
-		if err != nil || !pass {
-			return nil, bcode.ErrEnvTargetConflict
+		if err := p.checkEnvTarget(ctx, req.Project, req.Name, req.Targets); err != nil {
+			return nil, err
 		}
 	}
 
@@ -286,100 +1341,1167 @@ func (p *envServiceImpl) CreateEnv(ctx context.Context, req apisv1.CreateEnvRequ
 		targetMap[existTarget.Name] = targets[i]
 	}
 
-	for _, target := range req.Targets {
-		if _, exist := targetMap[target]; !exist {
-			return nil, bcode.ErrTargetNotExist
-		}
+	if missing := findMissingTargets(req.Targets, targets); len(missing) > 0 {
+		return nil, errTargetsNotExist(missing)
+	}
+
+	// the namespaces may already have the applications
+	appCount, err := p.checkAppQuotaNotExceeded(ctx, newEnv)
+	if err != nil {
+		return nil, err
 	}
 
 	// Creating the namespace can't use the login user permissions.
 	createNamespaceCtx := utils.WithProject(ctx, "")
-	err = repository.CreateEnv(createNamespaceCtx, p.KubeClient, p.Store, newEnv)
+	createdNamespaces, err := repository.CreateEnv(createNamespaceCtx, p.KubeClient, p.Store, newEnv)
 	if err != nil {
 		return nil, err
 	}
 
+	createEvent := newAuditEvent(ctx, "env", newEnv.Name, AuditActionCreate)
+	createEvent.NewTargets = newEnv.Targets
+	p.audit(ctx, createEvent)
+
 	if err := managePrivilegesForEnvironment(createNamespaceCtx, p.KubeClient, newEnv, false); err != nil {
+		// the env can't be used without the privileges, so roll it back
+		p.rollbackCreateEnv(createNamespaceCtx, newEnv, createdNamespaces, err)
 		return nil, err
 	}
+	p.audit(ctx, newAuditEvent(ctx, "env", newEnv.Name, AuditActionGrantPrivileges))
+
+	// The check before creating can't prevent the racing creates from claiming the same target,
+	// so verify it again and roll back if the target is claimed by an earlier env.
+	if !req.AllowTargetConflict {
+		if err := p.verifyEnvTargetOwner(ctx, newEnv); err != nil {
+			p.rollbackCreateEnv(createNamespaceCtx, newEnv, createdNamespaces, err)
+			return nil, err
+		}
+	}
+
+	if newEnv.Default {
+		if err := p.resolveDefaultEnv(ctx, newEnv); err != nil {
+			p.rollbackCreateEnv(createNamespaceCtx, newEnv, createdNamespaces, err)
+			return nil, err
+		}
+	}
+
+	logger.Info("created the env", "namespaces", newEnv.AllNamespaces(), "targets", newEnv.Targets)
 
 	resp := convertEnvModel2Base(newEnv, targets)
+	resp.AppCount = appCount
+	resp.Warnings = p.checkTargetClusters(createNamespaceCtx, newEnv.Targets, targetMap)
 	return resp, nil
 }
 
-// checkEnvTarget In one project, a delivery target can only belong to one env.
-func (p *envServiceImpl) checkEnvTarget(ctx context.Context, project string, envName string, targets []string) (bool, error) {
-	if len(targets) == 0 {
-		return true, nil
+// rollbackCreateEnv undo the steps of creating the env in reverse order when a later step fails: revoke the privileges,
+// release the namespaces and delete the env record. All steps are attempted, the failures are only logged because
+// the cause is returned to the caller.
+func (p *envServiceImpl) rollbackCreateEnv(ctx context.Context, env *model.Env, createdNamespaces []string, cause error) {
+	logger := envLogger(ctx, env)
+	logger.Info("rolling back the env", "cause", cause.Error())
+	if err := managePrivilegesForEnvironment(ctx, p.KubeClient, env, true); err != nil {
+		logger.Error(err, "failed to revoke the privileges when rolling back the env")
 	}
-	entities, err := p.Store.List(ctx, &model.Env{Project: project}, &datastore.ListOptions{})
-	if err != nil {
-		return false, err
+	if err := repository.ReleaseEnvNamespaces(ctx, p.KubeClient, env, createdNamespaces); err != nil {
+		logger.Error(err, "failed to release the namespaces when rolling back the env")
 	}
-	newMap := make(map[string]bool, len(targets))
-	for _, new := range targets {
-		newMap[new] = true
+	if err := p.Store.Delete(ctx, env); err != nil && !errors.Is(err, datastore.ErrRecordNotExist) {
+		logger.Error(err, "failed to delete the env record when rolling back the env")
+		return
 	}
-	for _, entity := range entities {
-		env := entity.(*model.Env)
-		for _, existTarget := range env.Targets {
-			if ok := newMap[existTarget]; ok && env.Name != envName {
-				return false, nil
-			}
+	event := newAuditEvent(ctx, "env", env.Name, AuditActionDelete)
+	event.OldTargets = env.Targets
+	event.Message = fmt.Sprintf("the env is rolled back: %s", cause.Error())
+	p.audit(ctx, event)
+}
+
+// checkTargetClusters return the warnings for the targets whose cluster can't be contacted
+func (p *envServiceImpl) checkTargetClusters(ctx context.Context, targetNames []string, targetMap map[string]*model.Target) []string {
+	var warnings []string
+	probed := make(map[string]error)
+	for _, name := range targetNames {
+		target := targetMap[name]
+		if target == nil || target.Cluster == nil {
+			continue
+		}
+		err, exist := probed[target.Cluster.ClusterName]
+		if !exist {
+			err = p.probeCluster(ctx, target.Cluster.ClusterName)
+			probed[target.Cluster.ClusterName] = err
+		}
+		if err != nil {
+			warning := fmt.Sprintf("the cluster %s of the target %s is unreachable: %s", target.Cluster.ClusterName, name, err.Error())
+			klog.Warning(warning)
+			warnings = append(warnings, warning)
 		}
 	}
-	return true, nil
+	return warnings
 }
 
-func convertEnvModel2Base(env *model.Env, targets []*model.Target) *apisv1.Env {
-	data := apisv1.Env{
-		Name:        env.Name,
-		Alias:       env.Alias,
-		Description: env.Description,
-		Project:     apisv1.NameAlias{Name: env.Project},
-		Namespace:   env.Namespace,
-		CreateTime:  env.CreateTime,
-		UpdateTime:  env.UpdateTime,
+// probeCluster check whether the cluster could be contacted with the clusterProbe if it is set
+func (p *envServiceImpl) probeCluster(ctx context.Context, clusterName string) error {
+	if p.clusterProbe != nil {
+		return p.clusterProbe(ctx, clusterName)
 	}
-	for _, dt := range env.Targets {
-		var t *model.Target
-		for _, tg := range targets {
-			if dt == tg.Name {
-				t = tg
-				break
-			}
+	return probeCluster(ctx, p.KubeClient, clusterName)
+}
+
+// getTargetStatus check the connectivity of the cluster and the existence of the namespace of the target,
+// the result is cached for a while to avoid requesting the managed clusters too often.
+func (p *envServiceImpl) getTargetStatus(ctx context.Context, target *model.Target) string {
+	if target == nil || target.Cluster == nil {
+		return TargetStatusUnknown
+	}
+	cacheKey := fmt.Sprintf("target-status::%s/%s", target.Cluster.ClusterName, target.Cluster.Namespace)
+	if p.caches != nil {
+		if status := p.caches.Get(cacheKey); status != nil {
+			return status.(string)
 		}
-		if t != nil {
-			data.Targets = append(data.Targets, apisv1.NameAlias{
-				Name:  dt,
-				Alias: t.Alias,
-			})
-		} else {
-			data.Targets = append(data.Targets, apisv1.NameAlias{
-				Name: dt,
-			})
+	}
+	status := TargetStatusHealthy
+	if err := p.probeCluster(ctx, target.Cluster.ClusterName); err != nil {
+		status = TargetStatusClusterUnreachable
+	} else if target.Cluster.Namespace != "" {
+		err := p.KubeClient.Get(multicluster.ContextWithClusterName(ctx, target.Cluster.ClusterName), client.ObjectKey{Name: target.Cluster.Namespace}, &corev1.Namespace{})
+		switch {
+		case apierror.IsNotFound(err):
+			status = TargetStatusNamespaceNotExist
+		case err != nil:
+			status = TargetStatusClusterUnreachable
 		}
 	}
-	return &data
+	if p.caches != nil {
+		p.caches.Put(cacheKey, status, targetStatusCacheDuration)
+	}
+	return status
 }
 
-// managePrivilegesForEnvironment grant or revoke privileges for environment
-func managePrivilegesForEnvironment(ctx context.Context, cli client.Client, env *model.Env, revoke bool) error {
-	p := &auth.ApplicationPrivilege{Cluster: types.ClusterLocalName, Namespace: env.Namespace}
-	identity := &auth.Identity{Groups: []string{utils.KubeVelaProjectGroupPrefix + env.Project}}
-	writer := &bytes.Buffer{}
-	f, msg := auth.GrantPrivileges, "GrantPrivileges"
-	if revoke {
-		f, msg = auth.RevokePrivileges, "RevokePrivileges"
+// probeCluster try to get the default namespace of the cluster, the cluster is reachable if the API server responds
+func probeCluster(ctx context.Context, cli client.Client, clusterName string) error {
+	if clusterName == "" || clusterName == multicluster.ClusterLocalName {
+		return nil
 	}
-	if err := f(ctx, cli, []auth.PrivilegeDescription{p}, identity, writer); err != nil {
+	probeCtx, cancel := context.WithTimeout(ctx, clusterProbeTimeout)
+	defer cancel()
+	err := cli.Get(multicluster.ContextWithClusterName(probeCtx, clusterName), client.ObjectKey{Name: metav1.NamespaceDefault}, &corev1.Namespace{})
+	if err != nil && !apierror.IsNotFound(err) && !apierror.IsForbidden(err) {
 		return err
 	}
-	klog.Infof("%s: %s", msg, writer.String())
 	return nil
 }
 
+// CloneEnv create a new env with the alias, description, project and labels of the source env, the targets are given by the request
+func (p *envServiceImpl) CloneEnv(ctx context.Context, sourceName string, req apisv1.CloneEnvRequest) (*apisv1.Env, error) {
+	source := &model.Env{}
+	source.Name = sourceName
+	if err := p.Store.Get(ctx, source); err != nil {
+		if errors.Is(err, datastore.ErrRecordNotExist) {
+			return nil, bcode.ErrEnvNotExisted
+		}
+		return nil, err
+	}
+	return p.CreateEnv(ctx, apisv1.CreateEnvRequest{
+		Name:                req.Name,
+		Alias:               source.Alias,
+		Description:         source.Description,
+		Project:             source.Project,
+		Namespace:           req.Namespace,
+		Targets:             req.Targets,
+		AllowTargetConflict: req.AllowTargetConflict,
+		Labels:              source.Labels,
+		AppQuota:            source.AppQuota,
+		Variables:           source.Variables,
+		DefaultAppLabels:    source.DefaultAppLabels,
+		Annotations:         source.Annotations,
+	})
+}
+
+// ExportEnv export the env as a YAML manifest that could be imported by ImportEnv
+func (p *envServiceImpl) ExportEnv(ctx context.Context, envName string) (*apisv1.ExportEnvResponse, error) {
+	env, err := repository.GetEnv(ctx, p.Store, envName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(apisv1.EnvManifest{
+		Name:        env.Name,
+		Alias:       env.Alias,
+		Description: env.Description,
+		Project:     env.Project,
+		Namespace:   env.Namespace,
+		Namespaces:  env.Namespaces,
+		Targets:     env.Targets,
+		Labels:      env.Labels,
+		AppQuota:    env.AppQuota,
+		Variables:   env.Variables,
+		Annotations: env.Annotations,
+
+		DefaultAppLabels: env.DefaultAppLabels,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &apisv1.ExportEnvResponse{YAML: string(data)}, nil
+}
+
+// ImportEnv create an env from the YAML manifest exported by ExportEnv
+func (p *envServiceImpl) ImportEnv(ctx context.Context, req apisv1.ImportEnvRequest) (*apisv1.Env, error) {
+	var manifest apisv1.EnvManifest
+	if err := yaml.UnmarshalStrict([]byte(req.YAML), &manifest); err != nil {
+		return nil, bcode.ErrEnvManifestInvalid.SetMessage(fmt.Sprintf("the env manifest is invalid: %s", err.Error()))
+	}
+	if manifest.Name == "" || manifest.Project == "" {
+		return nil, bcode.ErrEnvManifestInvalid.SetMessage("the name and the project of the env are required")
+	}
+	return p.CreateEnv(ctx, apisv1.CreateEnvRequest{
+		Name:        manifest.Name,
+		Alias:       manifest.Alias,
+		Description: manifest.Description,
+		Project:     manifest.Project,
+		Namespace:   manifest.Namespace,
+		Namespaces:  manifest.Namespaces,
+		Targets:     manifest.Targets,
+		Labels:      manifest.Labels,
+		AppQuota:    manifest.AppQuota,
+		Variables:   manifest.Variables,
+		Annotations: manifest.Annotations,
+
+		DefaultAppLabels: manifest.DefaultAppLabels,
+	})
+}
+
+// BatchCreateEnv create the envs one by one, if one of them fails, all created envs will be rolled back
+func (p *envServiceImpl) BatchCreateEnv(ctx context.Context, reqs []apisv1.CreateEnvRequest) (*apisv1.BatchCreateEnvResponse, error) {
+	resp := &apisv1.BatchCreateEnvResponse{}
+	var failed string
+	for _, req := range reqs {
+		result := &apisv1.BatchCreateEnvResult{Name: req.Name}
+		resp.Results = append(resp.Results, result)
+		if failed != "" {
+			result.Message = fmt.Sprintf("skipped because the env %s is failed to create", failed)
+			continue
+		}
+		env, err := p.CreateEnv(ctx, req)
+		if err != nil {
+			failed = req.Name
+			result.Message = err.Error()
+			continue
+		}
+		result.Success = true
+		result.Env = env
+	}
+	if failed == "" {
+		return resp, nil
+	}
+	// rollback the created envs, the namespaces are reserved and the privileges are revoked.
+	// the envs failed to roll back are kept in the results, so that the caller could clean them up.
+	for _, result := range resp.Results {
+		if !result.Success {
+			continue
+		}
+		if err := p.DeleteEnv(ctx, result.Name, true, false); err != nil {
+			klog.Errorf("failed to rollback the env %s: %s", result.Name, err.Error())
+			result.RollbackFailed = true
+			result.Message = fmt.Sprintf("failed to roll back after the env %s is failed to create: %s", failed, err.Error())
+			continue
+		}
+		result.Success = false
+		result.Env = nil
+		result.Message = fmt.Sprintf("rolled back because the env %s is failed to create", failed)
+	}
+	return resp, nil
+}
+
+// ListUnassignedTargets list the targets in the project that do not belong to any env
+func (p *envServiceImpl) ListUnassignedTargets(ctx context.Context, project string) (*apisv1.ListTargetResponse, error) {
+	targets, err := repository.ListTarget(ctx, p.Store, project, &datastore.ListOptions{
+		SortBy: []datastore.SortOption{{Key: "createTime", Order: datastore.SortOrderDescending}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	envs, err := repository.ListEnvs(ctx, p.Store, &datastore.ListOptions{
+		FilterOptions: datastore.FilterOptions{
+			In: []datastore.InQueryOption{{Key: "project", Values: []string{project}}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	assigned := make(map[string]bool)
+	for _, env := range envs {
+		if env.Archived {
+			continue
+		}
+		for _, target := range env.Targets {
+			assigned[target] = true
+		}
+	}
+	resp := &apisv1.ListTargetResponse{
+		Targets: []apisv1.TargetBase{},
+	}
+	for _, target := range targets {
+		if assigned[target.Name] {
+			continue
+		}
+		resp.Targets = append(resp.Targets, *convertTargetModel2Base(ctx, p.Store, target))
+	}
+	resp.Total = int64(len(resp.Targets))
+	return resp, nil
+}
+
+// GetEnvAccess describe the identity groups used by the users of the project and the privileges granted to them,
+// the privileges are checked against the role bindings in the clusters.
+func (p *envServiceImpl) GetEnvAccess(ctx context.Context, envName string) (*apisv1.EnvAccessResponse, error) {
+	env, err := repository.GetEnv(ctx, p.Store, envName)
+	if err != nil {
+		return nil, err
+	}
+	// Reading the role bindings can't use the login user permissions.
+	checkCtx := utils.WithProject(ctx, "")
+	privileges, identity := environmentPrivileges(env)
+	resp := &apisv1.EnvAccessResponse{Name: env.Name, Project: env.Project, Groups: identity.Groups}
+	for _, privilege := range privileges {
+		resp.Privileges = append(resp.Privileges, p.describeEnvPrivilege(checkCtx, privilege, identity, ""))
+	}
+	if env.Archived {
+		// the privileges of the archived env are revoked
+		return resp, nil
+	}
+	if len(env.Targets) > 0 {
+		targets, err := repository.ListTarget(ctx, p.Store, "", &datastore.ListOptions{
+			FilterOptions: datastore.FilterOptions{In: []datastore.InQueryOption{{Key: "name", Values: env.Targets}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+		for _, target := range targets {
+			if target.Cluster == nil {
+				continue
+			}
+			// the same privilege as managePrivilegesForTarget
+			targetIdentity := &auth.Identity{Groups: []string{utils.KubeVelaProjectGroupPrefix + target.Project}}
+			if !util.StringsContain(resp.Groups, targetIdentity.Groups[0]) {
+				resp.Groups = append(resp.Groups, targetIdentity.Groups[0])
+			}
+			targetPrivilege := &auth.ScopedPrivilege{Cluster: target.Cluster.ClusterName, Namespace: target.Cluster.Namespace}
+			resp.Privileges = append(resp.Privileges, p.describeEnvPrivilege(checkCtx, targetPrivilege, targetIdentity, target.Name))
+		}
+	}
+	return resp, nil
+}
+
+// describeEnvPrivilege describe the privilege and check whether the role binding grants it to the identity
+func (p *envServiceImpl) describeEnvPrivilege(ctx context.Context, privilege auth.PrivilegeDescription, identity *auth.Identity, target string) *apisv1.EnvPrivilege {
+	binding := privilege.GetRoleBinding(identity.Subjects())
+	desc := &apisv1.EnvPrivilege{Target: target, Cluster: privilege.GetCluster(), Namespace: binding.GetNamespace()}
+	switch pr := privilege.(type) {
+	case *auth.ApplicationPrivilege:
+		desc.ReadOnly = pr.ReadOnly
+	case *auth.ScopedPrivilege:
+		desc.ReadOnly = pr.ReadOnly
+	}
+	desc.RoleBinding = "ClusterRoleBinding " + binding.GetName()
+	if binding.GetNamespace() != "" {
+		desc.RoleBinding = "RoleBinding " + binding.GetNamespace() + "/" + binding.GetName()
+	}
+	for _, sub := range identity.Subjects() {
+		desc.Subjects = append(desc.Subjects, sub.Kind+" "+sub.Name)
+	}
+
+	existing, ok := binding.DeepCopyObject().(client.Object)
+	if !ok {
+		desc.Status = EnvPrivilegeUnknown
+		return desc
+	}
+	if err := p.KubeClient.Get(multicluster.ContextWithClusterName(ctx, desc.Cluster), client.ObjectKeyFromObject(binding), existing); err != nil {
+		if apierror.IsNotFound(err) {
+			desc.Status, desc.Message = EnvPrivilegeMissing, "the role binding is not found"
+			return desc
+		}
+		desc.Status, desc.Message = EnvPrivilegeUnknown, err.Error()
+		return desc
+	}
+	var subjects []rbacv1.Subject
+	switch b := existing.(type) {
+	case *rbacv1.RoleBinding:
+		subjects = b.Subjects
+	case *rbacv1.ClusterRoleBinding:
+		subjects = b.Subjects
+	}
+	desc.Status = EnvPrivilegeGranted
+	for _, expected := range identity.Subjects() {
+		found := false
+		for _, sub := range subjects {
+			if sub.Kind == expected.Kind && sub.Name == expected.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			desc.Status, desc.Message = EnvPrivilegeMissing, fmt.Sprintf("the %s %s is not bound", expected.Kind, expected.Name)
+			break
+		}
+	}
+	return desc
+}
+
+// PreviewEnvPrivileges describe the privileges that will be granted when creating the env, nothing is applied
+func (p *envServiceImpl) PreviewEnvPrivileges(ctx context.Context, req apisv1.CreateEnvRequest) (*apisv1.PreviewEnvPrivilegesResponse, error) {
+	env := &model.Env{
+		Name:       req.Name,
+		Namespace:  req.Namespace,
+		Namespaces: req.Namespaces,
+		Project:    req.Project,
+	}
+	if env.Namespace == "" {
+		env.Namespace = env.Name
+	}
+	return &apisv1.PreviewEnvPrivilegesResponse{Privileges: describePrivilegesForEnvironment(env)}, nil
+}
+
+// checkEnvTarget In one project, a delivery target can only belong to one env.
+// It returns the ErrEnvTargetConflict with the conflicting env and target in the message.
+func (p *envServiceImpl) checkEnvTarget(ctx context.Context, project string, envName string, targets []string) error {
+	conflictEnv, conflictTarget, err := p.findConflictEnvTarget(ctx, project, envName, targets)
+	if err != nil {
+		return err
+	}
+	if conflictEnv != "" {
+		return bcode.ErrEnvTargetConflict.SetMessage(fmt.Sprintf("the target %s already belongs to the env %s, in one project, one target can only belong to one env.", conflictTarget, conflictEnv))
+	}
+	return nil
+}
+
+// ValidateEnvTargets check the targets proposed for the env before creating or updating it,
+// report whether each target exists and whether it already belongs to another env of the project.
+func (p *envServiceImpl) ValidateEnvTargets(ctx context.Context, project, envName string, targets []string) (*apisv1.ValidateEnvTargetsResponse, error) {
+	exists, err := repository.ListTarget(ctx, p.Store, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	missing := findMissingTargets(targets, exists)
+	resp := &apisv1.ValidateEnvTargetsResponse{Targets: []apisv1.EnvTargetStatus{}}
+	checked := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		if checked[target] {
+			continue
+		}
+		checked[target] = true
+		status := apisv1.EnvTargetStatus{Name: target, Status: EnvTargetStatusAvailable}
+		if util.StringsContain(missing, target) {
+			status.Status = EnvTargetStatusNotExist
+			resp.Targets = append(resp.Targets, status)
+			continue
+		}
+		// the same check as checkEnvTarget, but the conflicting env is kept for each target
+		conflictEnv, _, err := p.findConflictEnvTarget(ctx, project, envName, []string{target})
+		if err != nil {
+			return nil, err
+		}
+		if conflictEnv != "" {
+			status.Status = EnvTargetStatusConflict
+			status.Env = conflictEnv
+		}
+		resp.Targets = append(resp.Targets, status)
+	}
+	return resp, nil
+}
+
+// FindOrphanedTargets report the targets referenced by the envs of the project but deleted from the datastore,
+// they are removed from the envs if repair is true.
+func (p *envServiceImpl) FindOrphanedTargets(ctx context.Context, project string, repair bool) (*apisv1.ListOrphanedEnvTargetsResponse, error) {
+	envs, err := repository.ListEnvs(ctx, p.Store, &datastore.ListOptions{
+		FilterOptions: datastore.FilterOptions{
+			In: []datastore.InQueryOption{{Key: "project", Values: []string{project}}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	exists, err := repository.ListTarget(ctx, p.Store, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp := &apisv1.ListOrphanedEnvTargetsResponse{Targets: []apisv1.OrphanedEnvTarget{}, Repaired: repair}
+	for _, env := range envs {
+		missing := findMissingTargets(env.Targets, exists)
+		if len(missing) == 0 {
+			continue
+		}
+		for _, target := range missing {
+			resp.Targets = append(resp.Targets, apisv1.OrphanedEnvTarget{Env: env.Name, Target: target})
+		}
+		if !repair {
+			continue
+		}
+		event := newAuditEvent(ctx, "env", env.Name, AuditActionUpdate)
+		event.OldTargets = env.Targets
+		var targets []string
+		for _, target := range env.Targets {
+			if !util.StringsContain(missing, target) {
+				targets = append(targets, target)
+			}
+		}
+		env.Targets = targets
+		// the targets assigned by others after listing the envs are kept
+		if err := p.Store.PutIfUnchanged(ctx, env, env.UpdateTime); err != nil {
+			return nil, convertEnvPutError(err)
+		}
+		event.NewTargets = env.Targets
+		event.Message = fmt.Sprintf("the orphaned targets %s are removed", strings.Join(missing, ", "))
+		p.audit(ctx, event)
+		envLogger(ctx, env).Info("removed the orphaned targets from the env", "targets", missing)
+	}
+	return resp, nil
+}
+
+const (
+	// EnvEntrySame the key has the same value in both envs
+	EnvEntrySame = "Same"
+	// EnvEntryChanged the key has different values in the envs
+	EnvEntryChanged = "Changed"
+	// EnvEntryOnlyInA the key only exists in the env compared
+	EnvEntryOnlyInA = "OnlyInA"
+	// EnvEntryOnlyInB the key only exists in the env compared with
+	EnvEntryOnlyInB = "OnlyInB"
+)
+
+// CompareEnvs compare the namespace, the targets, the labels, the variables and the application counts of two envs.
+// Both envs must belong to the projects of the login user.
+func (p *envServiceImpl) CompareEnvs(ctx context.Context, envA, envB string) (*apisv1.CompareEnvsResponse, error) {
+	userName, ok := ctx.Value(&apisv1.CtxKeyUser).(string)
+	if !ok || userName == "" {
+		return nil, bcode.ErrUnauthorized
+	}
+	a, err := repository.GetEnv(ctx, p.Store, envA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := repository.GetEnv(ctx, p.Store, envB)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := p.ProjectService.ListUserProjects(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	accessible := map[string]bool{}
+	for _, project := range projects {
+		accessible[project.Name] = true
+	}
+	if !accessible[a.Project] || !accessible[b.Project] {
+		return nil, bcode.ErrEnvNotAccessible
+	}
+	counts, err := p.getAppCountInNamespaces(ctx, a, b)
+	if err != nil {
+		return nil, err
+	}
+	countApps := func(env *model.Env) int {
+		count := 0
+		for _, ns := range env.AllNamespaces() {
+			count += counts[ns]
+		}
+		return count
+	}
+
+	resp := &apisv1.CompareEnvsResponse{
+		EnvA:       apisv1.NameAlias{Name: a.Name, Alias: a.Alias},
+		EnvB:       apisv1.NameAlias{Name: b.Name, Alias: b.Alias},
+		Namespaces: compareEnvNames(a.AllNamespaces(), b.AllNamespaces()),
+		AppCount:   apisv1.EnvCountComparison{A: countApps(a), B: countApps(b)},
+		Targets:    compareEnvNames(a.Targets, b.Targets),
+		Labels:     compareEnvEntries(a.Labels, b.Labels),
+		Variables:  compareEnvEntries(a.Variables, b.Variables),
+	}
+	resp.AppCount.Same = resp.AppCount.A == resp.AppCount.B
+	resp.Identical = isEnvNamesSame(resp.Namespaces) && resp.AppCount.Same && isEnvNamesSame(resp.Targets) &&
+		isEnvEntriesSame(resp.Labels) && isEnvEntriesSame(resp.Variables)
+	return resp, nil
+}
+
+// compareEnvNames split the targets or the namespaces of two envs into the common ones and the ones only in either env
+func compareEnvNames(a, b []string) apisv1.EnvNamesComparison {
+	comparison := apisv1.EnvNamesComparison{Common: []string{}, OnlyInA: []string{}, OnlyInB: []string{}}
+	for _, name := range a {
+		if util.StringsContain(b, name) {
+			comparison.Common = append(comparison.Common, name)
+		} else {
+			comparison.OnlyInA = append(comparison.OnlyInA, name)
+		}
+	}
+	for _, name := range b {
+		if !util.StringsContain(a, name) {
+			comparison.OnlyInB = append(comparison.OnlyInB, name)
+		}
+	}
+	sort.Strings(comparison.Common)
+	sort.Strings(comparison.OnlyInA)
+	sort.Strings(comparison.OnlyInB)
+	return comparison
+}
+
+// compareEnvEntries compare the values of the keys in two maps, the entries are sorted by the key
+func compareEnvEntries(a, b map[string]string) []apisv1.EnvEntryComparison {
+	entries := []apisv1.EnvEntryComparison{}
+	for key, valueA := range a {
+		entry := apisv1.EnvEntryComparison{Key: key, A: valueA}
+		valueB, ok := b[key]
+		switch {
+		case !ok:
+			entry.Status = EnvEntryOnlyInA
+		case valueA == valueB:
+			entry.B, entry.Status = valueB, EnvEntrySame
+		default:
+			entry.B, entry.Status = valueB, EnvEntryChanged
+		}
+		entries = append(entries, entry)
+	}
+	for key, valueB := range b {
+		if _, ok := a[key]; !ok {
+			entries = append(entries, apisv1.EnvEntryComparison{Key: key, B: valueB, Status: EnvEntryOnlyInB})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+func isEnvEntriesSame(entries []apisv1.EnvEntryComparison) bool {
+	for _, entry := range entries {
+		if entry.Status != EnvEntrySame {
+			return false
+		}
+	}
+	return true
+}
+
+func isEnvNamesSame(comparison apisv1.EnvNamesComparison) bool {
+	return len(comparison.OnlyInA) == 0 && len(comparison.OnlyInB) == 0
+}
+
+// MigrateApplications move the applications of the env to another env of the same project. The applications deployed
+// to the source env are redeployed to the target env and removed from the source env. The applications already in the
+// target env are skipped, the failure of an application doesn't stop migrating the others.
+func (p *envServiceImpl) MigrateApplications(ctx context.Context, fromEnv, toEnv string, dryRun bool) (*apisv1.MigrateApplicationsResponse, error) {
+	if fromEnv == toEnv {
+		return nil, bcode.ErrEnvMigrateToSelf
+	}
+	source, err := repository.GetEnv(ctx, p.Store, fromEnv)
+	if err != nil {
+		return nil, err
+	}
+	target, err := repository.GetEnv(ctx, p.Store, toEnv)
+	if err != nil {
+		return nil, err
+	}
+	if source.Project != target.Project {
+		return nil, bcode.ErrEnvProjectMismatch
+	}
+	if target.Archived {
+		return nil, bcode.ErrEnvArchived
+	}
+	bindings, err := p.Store.List(ctx, &model.EnvBinding{Name: source.Name}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var apps []*model.Application
+	skipped := map[string]bool{}
+	for _, raw := range bindings {
+		binding, ok := raw.(*model.EnvBinding)
+		if !ok {
+			continue
+		}
+		app := &model.Application{Name: binding.AppPrimaryKey}
+		if err := p.Store.Get(ctx, app); err != nil {
+			if errors.Is(err, datastore.ErrRecordNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		exist, err := p.Store.IsExist(ctx, &model.EnvBinding{AppPrimaryKey: app.PrimaryKey(), Name: target.Name})
+		if err != nil {
+			return nil, err
+		}
+		skipped[app.Name] = exist
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Name < apps[j].Name
+	})
+	// refuse to migrate any application if the target env can't hold all of them
+	if target.AppQuota > 0 {
+		count, err := p.GetAppCountInEnv(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		moving := 0
+		for _, app := range apps {
+			if !skipped[app.Name] {
+				moving++
+			}
+		}
+		if count+moving > target.AppQuota {
+			return nil, bcode.ErrEnvQuotaExceeded.SetMessage(fmt.Sprintf("the env %s has %d applications, migrating %d applications exceeds the quota %d", target.Name, count, moving, target.AppQuota))
+		}
+	}
+
+	logger := envLogger(ctx, source)
+	resp := &apisv1.MigrateApplicationsResponse{Applications: []apisv1.MigratedApplication{}, DryRun: dryRun}
+	migrated := 0
+	for _, app := range apps {
+		result := apisv1.MigratedApplication{NameAlias: apisv1.NameAlias{Name: app.Name, Alias: app.Alias}}
+		switch {
+		case skipped[app.Name]:
+			result.Status = AppMigrationStatusSkipped
+			result.Message = fmt.Sprintf("the application is already in the env %s", target.Name)
+		case dryRun:
+			result.Status = AppMigrationStatusPending
+		default:
+			redeployed, err := p.migrateApplication(ctx, app, source, target)
+			result.Redeployed = redeployed
+			if err != nil {
+				logger.Error(err, "failed to migrate the application", "app", app.Name, "targetEnv", target.Name)
+				result.Status = AppMigrationStatusFailed
+				result.Message = err.Error()
+			} else {
+				result.Status = AppMigrationStatusMigrated
+				migrated++
+			}
+		}
+		resp.Applications = append(resp.Applications, result)
+	}
+	if migrated > 0 {
+		event := newAuditEvent(ctx, "env", source.Name, AuditActionUpdate)
+		event.Message = fmt.Sprintf("%d applications are migrated to the env %s", migrated, target.Name)
+		p.audit(ctx, event)
+		logger.Info("migrated the applications", "targetEnv", target.Name, "count", migrated)
+	}
+	return resp, nil
+}
+
+// migrateApplication bind the application to the target env, redeploy it if it is deployed to the source env,
+// then remove it from the source env. Return whether the application is redeployed.
+func (p *envServiceImpl) migrateApplication(ctx context.Context, app *model.Application, source, target *model.Env) (bool, error) {
+	binding := &model.EnvBinding{AppPrimaryKey: app.PrimaryKey(), Name: source.Name}
+	if err := p.Store.Get(ctx, binding); err != nil {
+		return false, err
+	}
+	if _, err := p.EnvBindingService.CreateEnvBinding(ctx, app, apisv1.CreateApplicationEnvbindingRequest{EnvBinding: apisv1.EnvBinding{Name: target.Name}}); err != nil {
+		return false, err
+	}
+	// keep the patches of the components and the name of the deployed application
+	newBinding := &model.EnvBinding{AppPrimaryKey: app.PrimaryKey(), Name: target.Name}
+	if err := p.Store.Get(ctx, newBinding); err != nil {
+		return false, err
+	}
+	newBinding.ComponentsPatch = binding.ComponentsPatch
+	newBinding.AppDeployName = binding.AppDeployName
+	if err := p.Store.Put(ctx, newBinding); err != nil {
+		return false, err
+	}
+	if workflow, err := repository.GetWorkflowByEnv(ctx, p.Store, app, source.Name); err == nil && workflow.Default != nil && *workflow.Default {
+		if newWorkflow, err := repository.GetWorkflowByEnv(ctx, p.Store, app, target.Name); err == nil {
+			newWorkflow.Default = workflow.Default
+			if err := p.Store.Put(ctx, newWorkflow); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	deployName := binding.AppDeployName
+	if deployName == "" {
+		deployName = app.Name
+	}
+	var deployed v1beta1.Application
+	redeployed := false
+	if err := p.KubeClient.Get(ctx, k8stypes.NamespacedName{Namespace: source.Namespace, Name: deployName}, &deployed); err == nil {
+		if _, err := p.ApplicationService.Deploy(ctx, app, apisv1.ApplicationDeployRequest{
+			WorkflowName: repository.ConvertWorkflowName(target.Name),
+			Note:         fmt.Sprintf("migrated from the env %s", source.Name),
+			TriggerType:  apisv1.TriggerTypeAPI,
+			Force:        true,
+		}); err != nil {
+			return false, err
+		}
+		redeployed = true
+		if err := p.KubeClient.Delete(ctx, &deployed); err != nil && !apierror.IsNotFound(err) {
+			return redeployed, err
+		}
+	} else if !apierror.IsNotFound(err) {
+		return false, err
+	}
+
+	// the deployed application may be still deleting, so the binding is removed without waiting for it
+	if err := p.Store.Delete(ctx, binding); err != nil && !errors.Is(err, datastore.ErrRecordNotExist) {
+		return redeployed, err
+	}
+	if err := p.WorkflowService.DeleteWorkflow(ctx, app, repository.ConvertWorkflowName(source.Name)); err != nil && !errors.Is(err, bcode.ErrWorkflowNotExist) {
+		return redeployed, err
+	}
+	return redeployed, repository.DeleteApplicationEnvPolicies(ctx, p.Store, app, source.Name)
+}
+
+// GetDefaultEnv get the default env of the project
+func (p *envServiceImpl) GetDefaultEnv(ctx context.Context, project string) (*apisv1.Env, error) {
+	defaultEnvs, err := p.listDefaultEnvs(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	if len(defaultEnvs) == 0 {
+		return nil, bcode.ErrDefaultEnvNotExist
+	}
+	targets, err := repository.ListTarget(ctx, p.Store, project, nil)
+	if err != nil {
+		return nil, err
+	}
+	return convertEnvModel2Base(defaultEnvs[0], targets), nil
+}
+
+// listDefaultEnvs list the unarchived envs marked as the default of the project, the winner of the racing updates is the first one.
+// The env updated latest wins, the name decides the winner if the update times are equal.
+func (p *envServiceImpl) listDefaultEnvs(ctx context.Context, project string) ([]*model.Env, error) {
+	entities, err := p.Store.List(ctx, &model.Env{Project: project, Default: true}, &datastore.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var envs []*model.Env
+	for _, entity := range entities {
+		env := entity.(*model.Env)
+		if env.Default && !env.Archived {
+			envs = append(envs, env)
+		}
+	}
+	sort.Slice(envs, func(i, j int) bool {
+		if !envs[i].UpdateTime.Equal(envs[j].UpdateTime) {
+			return envs[i].UpdateTime.After(envs[j].UpdateTime)
+		}
+		return envs[i].Name < envs[j].Name
+	})
+	return envs, nil
+}
+
+// resolveDefaultEnv clear the default flag of the other envs in the project after the env is set as the default.
+// The racing requests may set several defaults, all of them agree on the same winner, so only one default is kept.
+// The flag of the given env is cleared if it loses the race.
+func (p *envServiceImpl) resolveDefaultEnv(ctx context.Context, env *model.Env) error {
+	defaultEnvs, err := p.listDefaultEnvs(ctx, env.Project)
+	if err != nil {
+		return err
+	}
+	for i, defaultEnv := range defaultEnvs {
+		if i == 0 {
+			continue
+		}
+		defaultEnv.Default = false
+		if err := p.Store.Put(ctx, defaultEnv); err != nil {
+			return err
+		}
+		if defaultEnv.Name == env.Name {
+			env.Default = false
+		}
+		event := newAuditEvent(ctx, "env", defaultEnv.Name, AuditActionUpdate)
+		event.Message = "the env is no longer the default env of the project"
+		p.audit(ctx, event)
+	}
+	return nil
+}
+
+// verifyEnvTargetOwner check whether the targets of the created env are also claimed by other envs.
+// The env created earlier owns the target, the name decides the owner if the create times are equal.
+func (p *envServiceImpl) verifyEnvTargetOwner(ctx context.Context, env *model.Env) error {
+	if len(env.Targets) == 0 {
+		return nil
+	}
+	entities, err := p.Store.List(ctx, &model.Env{Project: env.Project}, &datastore.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, entity := range entities {
+		other := entity.(*model.Env)
+		if other.Name == env.Name || other.Archived {
+			continue
+		}
+		if other.CreateTime.After(env.CreateTime) || (other.CreateTime.Equal(env.CreateTime) && other.Name > env.Name) {
+			continue
+		}
+		for _, target := range other.Targets {
+			if util.StringsContain(env.Targets, target) {
+				return bcode.ErrEnvTargetConflict.SetMessage(fmt.Sprintf("the target %s already belongs to the env %s, in one project, one target can only belong to one env.", target, other.Name))
+			}
+		}
+	}
+	return nil
+}
+
+// findConflictEnvTarget return the first env in the project that already claims one of the targets
+func (p *envServiceImpl) findConflictEnvTarget(ctx context.Context, project string, envName string, targets []string) (conflictEnv, conflictTarget string, err error) {
+	if len(targets) == 0 {
+		return "", "", nil
+	}
+	entities, err := p.Store.List(ctx, &model.Env{Project: project}, &datastore.ListOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	newMap := make(map[string]bool, len(targets))
+	for _, new := range targets {
+		newMap[new] = true
+	}
+	for _, entity := range entities {
+		env := entity.(*model.Env)
+		// the targets of the archived envs are free to use
+		if env.Archived {
+			continue
+		}
+		for _, existTarget := range env.Targets {
+			if ok := newMap[existTarget]; ok && env.Name != envName {
+				return env.Name, existTarget, nil
+			}
+		}
+	}
+	return "", "", nil
+}
+
+// resolveTargetSelector return the names of the targets in the project that match the selector
+func (p *envServiceImpl) resolveTargetSelector(ctx context.Context, project string, selector map[string]string) ([]string, error) {
+	targets, err := repository.ListTarget(ctx, p.Store, project, nil)
+	if err != nil {
+		return nil, err
+	}
+	return matchTargetSelector(targets, selector), nil
+}
+
+// matchTargetSelector return the sorted names of the targets that have all of the tags of the selector
+func matchTargetSelector(targets []*model.Target, selector map[string]string) []string {
+	var names []string
+	for _, target := range targets {
+		matched := true
+		for key, value := range selector {
+			if tag, exist := target.Tags[key]; !exist || tag != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			names = append(names, target.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mergeTargetNames append the selected targets that are not in the names
+func mergeTargetNames(names []string, selected []string) []string {
+	merged := append([]string{}, names...)
+	for _, name := range selected {
+		if !util.StringsContain(merged, name) {
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
+
+// findMissingTargets return the names of the targets that don't exist, in the order of the names
+func findMissingTargets(names []string, targets []*model.Target) []string {
+	exist := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		exist[target.Name] = true
+	}
+	var missing []string
+	for _, name := range names {
+		if !exist[name] && !util.StringsContain(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// errTargetsNotExist return the error with all of the missing targets in the details
+func errTargetsNotExist(missing []string) error {
+	return bcode.ErrTargetNotExist.SetMessage(fmt.Sprintf("the targets %s are not exist", strings.Join(missing, ", "))).SetDetails(missing)
+}
+
+// replaceEnvLabels remove the old labels of the env from the namespace and merge the new labels,
+// the reserved labels binding the namespace to the env are never changed.
+func replaceEnvLabels(oldLabels, newLabels map[string]string) util.MutateOption {
+	return func(object metav1.Object) error {
+		labels := object.GetLabels()
+		for k := range oldLabels {
+			if _, exist := newLabels[k]; !exist && !isReservedEnvLabel(k) {
+				delete(labels, k)
+			}
+		}
+		object.SetLabels(labels)
+		merged := make(map[string]string, len(newLabels))
+		for k, v := range newLabels {
+			if !isReservedEnvLabel(k) {
+				merged[k] = v
+			}
+		}
+		return util.MergeOverrideLabels(merged)(object)
+	}
+}
+
+func isReservedEnvLabel(key string) bool {
+	for _, reserved := range reservedEnvLabels {
+		if key == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+func convertEnvModel2Base(env *model.Env, targets []*model.Target) *apisv1.Env {
+	return convertEnvModel2BaseWithTargetMap(env, newTargetMap(targets))
+}
+
+// newTargetMap index the targets by the name
+func newTargetMap(targets []*model.Target) map[string]*model.Target {
+	targetMap := make(map[string]*model.Target, len(targets))
+	for i := range targets {
+		targetMap[targets[i].Name] = targets[i]
+	}
+	return targetMap
+}
+
+// convertEnvModel2BaseWithTargetMap convert the env with the indexed targets, it saves scanning the targets for every env
+func convertEnvModel2BaseWithTargetMap(env *model.Env, targetMap map[string]*model.Target) *apisv1.Env {
+	data := apisv1.Env{
+		Name:           env.Name,
+		Labels:         env.Labels,
+		TargetSelector: env.TargetSelector,
+		Archived:       env.Archived,
+		Default:        env.Default,
+		AppQuota:       env.AppQuota,
+		Variables:      env.Variables,
+		Alias:          env.Alias,
+		Description:    env.Description,
+		Project:        apisv1.NameAlias{Name: env.Project},
+		Namespace:      env.Namespace,
+		Namespaces:     env.Namespaces,
+		CreateTime:     env.CreateTime,
+		UpdateTime:     env.UpdateTime,
+		Annotations:    env.Annotations,
+
+		DefaultAppLabels: env.DefaultAppLabels,
+	}
+	for _, dt := range env.Targets {
+		if t := targetMap[dt]; t != nil {
+			data.Targets = append(data.Targets, apisv1.EnvTarget{NameAlias: apisv1.NameAlias{
+				Name:  dt,
+				Alias: t.Alias,
+			}})
+		} else {
+			data.Targets = append(data.Targets, apisv1.EnvTarget{NameAlias: apisv1.NameAlias{
+				Name: dt,
+			}})
+		}
+	}
+	return &data
+}
+
+// environmentPrivileges build the privileges of all namespaces and the identity of the project group for the environment
+func environmentPrivileges(env *model.Env) ([]*auth.ApplicationPrivilege, *auth.Identity) {
+	var privileges []*auth.ApplicationPrivilege
+	for _, ns := range env.AllNamespaces() {
+		privileges = append(privileges, &auth.ApplicationPrivilege{Cluster: types.ClusterLocalName, Namespace: ns})
+	}
+	identity := &auth.Identity{Groups: []string{utils.KubeVelaProjectGroupPrefix + env.Project}}
+	return privileges, identity
+}
+
+// describePrivilegesForEnvironment describe the roles and the role binding that will be granted for environment
+func describePrivilegesForEnvironment(env *model.Env) string {
+	privileges, identity := environmentPrivileges(env)
+	writer := &bytes.Buffer{}
+	// the cluster roles are shared by the namespaces, describe them once
+	described := map[string]bool{}
+	for _, p := range privileges {
+		for _, role := range p.GetRoles() {
+			var rules []rbacv1.PolicyRule
+			kind, key := "ClusterRole", role.GetName()
+			switch r := role.(type) {
+			case *rbacv1.ClusterRole:
+				rules = r.Rules
+			case *rbacv1.Role:
+				kind, key = "Role", r.Namespace+"/"+r.Name
+				rules = r.Rules
+			}
+			if described[kind+" "+key] {
+				continue
+			}
+			described[kind+" "+key] = true
+			_, _ = fmt.Fprintf(writer, "%s %s will be created or updated in %s.\n", kind, key, p.GetCluster())
+			for _, rule := range rules {
+				_, _ = fmt.Fprintf(writer, "  APIGroups: %v Resources: %v Verbs: %v\n", rule.APIGroups, rule.Resources, rule.Verbs)
+			}
+		}
+		binding := p.GetRoleBinding(identity.Subjects())
+		kind, key := "ClusterRoleBinding", binding.GetName()
+		if binding.GetNamespace() != "" {
+			kind, key = "RoleBinding", binding.GetNamespace()+"/"+binding.GetName()
+		}
+		_, _ = fmt.Fprintf(writer, "%s %s will be created or updated in %s.\n", kind, key, p.GetCluster())
+		for _, sub := range identity.Subjects() {
+			_, _ = fmt.Fprintf(writer, "  Subject: %s %s\n", sub.Kind, sub.Name)
+		}
+	}
+	return writer.String()
+}
+
+// managePrivilegesForEnvironment grant or revoke privileges for environment
+func managePrivilegesForEnvironment(ctx context.Context, cli client.Client, env *model.Env, revoke bool) error {
+	logger := envLogger(ctx, env)
+	privileges, identity := environmentPrivileges(env)
+	descriptions := make([]auth.PrivilegeDescription, 0, len(privileges))
+	for _, p := range privileges {
+		descriptions = append(descriptions, p)
+	}
+	writer := &bytes.Buffer{}
+	f, msg := auth.GrantPrivileges, "GrantPrivileges"
+	if revoke {
+		f, msg = auth.RevokePrivileges, "RevokePrivileges"
+	}
+	attempt := 0
+	err := retry.OnError(privilegesBackoff, func(err error) bool {
+		// the request can't succeed if the context is done
+		return ctx.Err() == nil && isTransientError(err)
+	}, func() error {
+		attempt++
+		writer.Reset()
+		if err := f(ctx, cli, descriptions, identity, writer); err != nil {
+			logger.Error(err, msg+" failed", "attempt", attempt)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info(msg, "result", writer.String())
+	return nil
+}
+
+// isTransientError check whether the request may succeed by retrying, such as the conflicts and the overloaded server.
+// The errors like Forbidden, Invalid and NotFound are certain, retrying only delays them.
+func isTransientError(err error) bool {
+	return apierror.IsConflict(err) || apierror.IsServerTimeout(err) || apierror.IsTimeout(err) ||
+		apierror.IsTooManyRequests(err) || apierror.IsServiceUnavailable(err) || apierror.IsInternalError(err)
+}
+
+// envLogger return the logger of the env operation, every line has the request id, the user, the project and the env,
+// so that an operation could be traced across the logs. The project of the env is used as the project in the context
+// may be cleared to use the permissions of VelaUX.
+func envLogger(ctx context.Context, env *model.Env) klog.Logger {
+	requestID, _ := utils.RequestIDFrom(ctx)
+	userName, _ := ctx.Value(&apisv1.CtxKeyUser).(string)
+	project := env.Project
+	if project == "" {
+		project, _ = utils.ProjectFrom(ctx)
+	}
+	return klog.Background().WithValues("requestID", requestID, "user", userName, "project", project, "env", util.Sanitize(env.Name))
+}
+
 // NewTestEnvService create the env service instance for testing
 func NewTestEnvService(ds datastore.DataStore, c client.Client) EnvService {
-	return &envServiceImpl{Store: ds, KubeClient: c, ProjectService: NewTestProjectService(ds, c)}
+	return &envServiceImpl{Store: ds, KubeClient: c, ProjectService: NewTestProjectService(ds, c), RbacService: &rbacServiceImpl{KubeClient: c, Store: ds}, AuditLogger: NewNoopAuditLogger(), caches: utils.NewMemoryCacheStore(context.Background())}
 }
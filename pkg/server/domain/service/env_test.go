@@ -18,24 +18,42 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	velatypes "github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/auth"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	util "github.com/oam-dev/kubevela/pkg/utils"
+	utilcommon "github.com/oam-dev/kubevela/pkg/utils/common"
 
 	"github.com/kubevela/velaux/pkg/server/domain/model"
+	"github.com/kubevela/velaux/pkg/server/domain/repository"
+	"github.com/kubevela/velaux/pkg/server/infrastructure/datastore"
+	"github.com/kubevela/velaux/pkg/server/infrastructure/datastore/kubeapi"
 	apisv1 "github.com/kubevela/velaux/pkg/server/interfaces/api/dto/v1"
+	"github.com/kubevela/velaux/pkg/server/utils"
 	"github.com/kubevela/velaux/pkg/server/utils/bcode"
 )
 
@@ -77,6 +95,13 @@ var _ = Describe("Test env service functions", func() {
 		equal := cmp.Equal(err, bcode.ErrEnvNamespaceAlreadyBound, cmpopts.EquateErrors())
 		Expect(equal).Should(BeTrue())
 
+		By("test the invalid namespace to create env")
+		_, err = envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{
+			Name:      "test-env-invalid",
+			Namespace: "Invalid_Namespace",
+		})
+		Expect(cmp.Equal(err, bcode.ErrEnvNamespaceInvalid, cmpopts.EquateErrors())).Should(BeTrue())
+
 		req3 := apisv1.CreateEnvRequest{
 			Name:        "test-env-2",
 			Description: "this is a env description",
@@ -107,6 +132,8 @@ var _ = Describe("Test env service functions", func() {
 		}
 		_, err = envService.CreateEnv(context.TODO(), req4)
 		Expect(cmp.Equal(err, bcode.ErrEnvTargetConflict, cmpopts.EquateErrors())).Should(BeTrue())
+		// the conflicting env and target should be reported
+		Expect(err.Error()).Should(ContainSubstring("the target env-test already belongs to the env test-env-2"))
 
 		// test update env
 		req5 := apisv1.UpdateEnvRequest{
@@ -126,6 +153,15 @@ var _ = Describe("Test env service functions", func() {
 		Expect(err).Should(BeNil())
 		Expect(cmp.Diff(len(env.Targets), len(req6.Targets))).Should(BeEmpty())
 
+		By("Test clear the alias and the description of the env")
+		env, err = envService.UpdateEnv(context.TODO(), "test-env-2", apisv1.UpdateEnvRequest{Targets: req6.Targets})
+		Expect(err).Should(BeNil())
+		Expect(env.Description).Should(Equal(req6.Description))
+		env, err = envService.UpdateEnv(context.TODO(), "test-env-2", apisv1.UpdateEnvRequest{Targets: req6.Targets, ClearAlias: true, ClearDescription: true})
+		Expect(err).Should(BeNil())
+		Expect(env.Alias).Should(BeEmpty())
+		Expect(env.Description).Should(BeEmpty())
+
 		Expect(k8sClient.Create(context.TODO(), &v1beta1.Application{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "env-app",
@@ -146,10 +182,36 @@ var _ = Describe("Test env service functions", func() {
 		_, err = envService.UpdateEnv(context.TODO(), "test-env-2", req7)
 		Expect(err).Should(Equal(bcode.ErrEnvTargetNotAllowDelete))
 
+		By("Test clone the env")
+		_, err = envService.CloneEnv(context.TODO(), "not-exist-env", apisv1.CloneEnvRequest{Name: "test-env-clone"})
+		Expect(err).Should(Equal(bcode.ErrEnvNotExisted))
+		// the targets of the source env are not cloned
+		cloned, err := envService.CloneEnv(context.TODO(), "test-env-2", apisv1.CloneEnvRequest{Name: "test-env-clone-empty", Namespace: "test-env-clone-empty"})
+		Expect(err).Should(BeNil())
+		Expect(cloned.Targets).Should(BeEmpty())
+		Expect(envService.DeleteEnv(context.TODO(), "test-env-clone-empty", false, false)).Should(BeNil())
+		_, err = envService.CloneEnv(context.TODO(), "test-env-2", apisv1.CloneEnvRequest{Name: "test-env-clone", Namespace: "test-env-clone", Targets: req6.Targets})
+		Expect(cmp.Equal(err, bcode.ErrEnvTargetConflict, cmpopts.EquateErrors())).Should(BeTrue())
+		cloned, err = envService.CloneEnv(context.TODO(), "test-env-2", apisv1.CloneEnvRequest{Name: "test-env-clone", Namespace: "test-env-clone", Targets: req6.Targets, AllowTargetConflict: true})
+		Expect(err).Should(BeNil())
+		Expect(cmp.Diff(cloned.Namespace, "test-env-clone")).Should(BeEmpty())
+		Expect(cmp.Diff(cloned.Description, env.Description)).Should(BeEmpty())
+		Expect(cmp.Diff(cloned.Project.Name, "env-project")).Should(BeEmpty())
+		Expect(cmp.Diff(len(cloned.Targets), len(env.Targets))).Should(BeEmpty())
+		err = k8sClient.Get(context.TODO(), types.NamespacedName{Name: auth.KubeVelaWriterAppRoleName + ":binding", Namespace: cloned.Namespace}, &roleBinding)
+		Expect(err).Should(BeNil())
+
 		// clean up the env
-		err = envService.DeleteEnv(context.TODO(), "test-env")
+		err = envService.DeleteEnv(context.TODO(), "test-env", false, false)
 		Expect(err).Should(BeNil())
-		err = envService.DeleteEnv(context.TODO(), "test-env-2")
+		By("the env with applications can not be deleted without force")
+		err = envService.DeleteEnv(context.TODO(), "test-env-2", false, false)
+		Expect(err).Should(Equal(bcode.ErrDeleteEnvButAppExist))
+		_, err = envService.GetEnv(context.TODO(), "test-env-2")
+		Expect(err).Should(BeNil())
+		err = envService.DeleteEnv(context.TODO(), "test-env-2", true, false)
+		Expect(err).Should(BeNil())
+		err = envService.DeleteEnv(context.TODO(), "test-env-clone", false, false)
 		Expect(err).Should(BeNil())
 
 		By("Test ListEnvs function")
@@ -157,8 +219,1868 @@ var _ = Describe("Test env service functions", func() {
 		Expect(err).Should(BeNil())
 	})
 
+	It("Test BatchCreateEnv function", func() {
+		err := ds.Add(context.TODO(), &model.Target{Name: "env-batch-target"})
+		Expect(err).Should(BeNil())
+		err = ds.Add(context.TODO(), &model.Target{Name: "env-batch-target-2"})
+		Expect(err).Should(BeNil())
+
+		By("all envs should be rolled back if one is conflict")
+		resp, err := envService.BatchCreateEnv(context.TODO(), []apisv1.CreateEnvRequest{
+			{Name: "env-batch-1", Project: "env-batch-project", Targets: []string{"env-batch-target"}},
+			{Name: "env-batch-2", Project: "env-batch-project", Targets: []string{"env-batch-target"}},
+			{Name: "env-batch-3", Project: "env-batch-project"},
+		})
+		Expect(err).Should(BeNil())
+		Expect(len(resp.Results)).Should(Equal(3))
+		for _, result := range resp.Results {
+			Expect(result.Success).Should(BeFalse())
+			Expect(result.Message).ShouldNot(BeEmpty())
+		}
+		_, err = envService.GetEnv(context.TODO(), "env-batch-1")
+		Expect(err).ShouldNot(BeNil())
+
+		By("all envs should be created")
+		resp, err = envService.BatchCreateEnv(context.TODO(), []apisv1.CreateEnvRequest{
+			{Name: "env-batch-1", Project: "env-batch-project", Targets: []string{"env-batch-target"}},
+			{Name: "env-batch-2", Project: "env-batch-project", Targets: []string{"env-batch-target-2"}},
+		})
+		Expect(err).Should(BeNil())
+		for _, result := range resp.Results {
+			Expect(result.Success).Should(BeTrue())
+			Expect(result.Env).ShouldNot(BeNil())
+		}
+		Expect(envService.DeleteEnv(context.TODO(), "env-batch-1", false, false)).Should(BeNil())
+		Expect(envService.DeleteEnv(context.TODO(), "env-batch-2", false, false)).Should(BeNil())
+	})
+
+	It("Test the labels of the env", func() {
+		Expect(ds.Add(context.TODO(), &model.Project{Name: "env-label-project"})).Should(BeNil())
+		Expect(ds.Add(context.TODO(), &model.ProjectUser{Username: FakeAdminName, ProjectName: "env-label-project"})).Should(BeNil())
+		base, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{
+			Name:    "env-labels",
+			Project: "env-label-project",
+			Labels:  map[string]string{"team": "payments", "cost-center": "c1"},
+		})
+		Expect(err).Should(BeNil())
+		Expect(base.Labels["team"]).Should(Equal("payments"))
+		var namespace corev1.Namespace
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: base.Namespace}, &namespace)).Should(BeNil())
+		Expect(namespace.Labels["team"]).Should(Equal("payments"))
+		Expect(namespace.Labels[oam.LabelNamespaceOfEnvName]).Should(Equal("env-labels"))
+		_, err = envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{
+			Name:    "env-labels-2",
+			Project: "env-label-project",
+			Labels:  map[string]string{"team": "orders"},
+		})
+		Expect(err).Should(BeNil())
+
+		By("list the envs by labels")
+		userCtx := context.WithValue(context.TODO(), &apisv1.CtxKeyUser, FakeAdminName)
+		envs, err := envService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Labels: map[string]string{"team": "payments"}})
+		Expect(err).Should(BeNil())
+		Expect(envs.Total).Should(Equal(int64(1)))
+		Expect(envs.Envs[0].Name).Should(Equal("env-labels"))
+		envs, err = envService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Labels: map[string]string{"team": "payments", "cost-center": "c2"}})
+		Expect(err).Should(BeNil())
+		Expect(envs.Total).Should(Equal(int64(0)))
+		envs, err = envService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Project: "env-label-project"})
+		Expect(err).Should(BeNil())
+		Expect(envs.Total).Should(Equal(int64(2)))
+
+		By("list the envs with the application count")
+		Expect(k8sClient.Create(context.TODO(), &v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "env-label-app",
+				Namespace: base.Namespace,
+				Labels: map[string]string{
+					velatypes.LabelSourceOfTruth: velatypes.FromUX,
+				},
+			},
+			Spec: v1beta1.ApplicationSpec{
+				Components: []common.ApplicationComponent{},
+			},
+		})).Should(BeNil())
+		envs, err = envService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Project: "env-label-project", IncludeAppCount: true})
+		Expect(err).Should(BeNil())
+		for _, e := range envs.Envs {
+			if e.Name == "env-labels" {
+				Expect(e.AppCount).Should(Equal(1))
+			} else {
+				Expect(e.AppCount).Should(Equal(0))
+			}
+		}
+		envs, err = envService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Project: "env-label-project"})
+		Expect(err).Should(BeNil())
+		for _, e := range envs.Envs {
+			Expect(e.AppCount).Should(Equal(0))
+		}
+
+		env, err := envService.UpdateEnv(context.TODO(), "env-labels", apisv1.UpdateEnvRequest{
+			Labels: map[string]string{"team": "orders"},
+		})
+		Expect(err).Should(BeNil())
+		Expect(env.Labels).Should(Equal(map[string]string{"team": "orders"}))
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: base.Namespace}, &namespace)).Should(BeNil())
+		Expect(namespace.Labels["team"]).Should(Equal("orders"))
+		Expect(namespace.Labels).ShouldNot(HaveKey("cost-center"))
+		Expect(namespace.Labels[oam.LabelNamespaceOfEnvName]).Should(Equal("env-labels"))
+
+		Expect(envService.DeleteEnv(context.TODO(), "env-labels", true, false)).Should(BeNil())
+		Expect(envService.DeleteEnv(context.TODO(), "env-labels-2", false, false)).Should(BeNil())
+	})
+
+	It("Test moving the targets across envs", func() {
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-steal-target"})).Should(BeNil())
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-steal-target-2"})).Should(BeNil())
+		_, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-steal-a", Project: "env-steal-project", Targets: []string{"env-steal-target", "env-steal-target-2"}})
+		Expect(err).Should(BeNil())
+		_, err = envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-steal-b", Project: "env-steal-project"})
+		Expect(err).Should(BeNil())
+
+		_, err = envService.UpdateEnv(context.TODO(), "env-steal-b", apisv1.UpdateEnvRequest{Targets: []string{"env-steal-target"}})
+		Expect(cmp.Equal(err, bcode.ErrEnvTargetConflict, cmpopts.EquateErrors())).Should(BeTrue())
+
+		envB, err := envService.UpdateEnv(context.TODO(), "env-steal-b", apisv1.UpdateEnvRequest{Targets: []string{"env-steal-target"}, AllowTargetSteal: true})
+		Expect(err).Should(BeNil())
+		Expect(envB.Targets).Should(HaveLen(1))
+		envA, err := envService.GetEnv(context.TODO(), "env-steal-a")
+		Expect(err).Should(BeNil())
+		Expect(envA.Targets).Should(Equal([]string{"env-steal-target-2"}))
+
+		By("the target can not be moved if there are applications in the losing env")
+		Expect(k8sClient.Create(context.TODO(), &v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "env-steal-app",
+				Namespace: envA.Namespace,
+				Labels: map[string]string{
+					velatypes.LabelSourceOfTruth: velatypes.FromUX,
+				},
+			},
+			Spec: v1beta1.ApplicationSpec{
+				Components: []common.ApplicationComponent{},
+			},
+		})).Should(BeNil())
+		_, err = envService.UpdateEnv(context.TODO(), "env-steal-b", apisv1.UpdateEnvRequest{Targets: []string{"env-steal-target", "env-steal-target-2"}, AllowTargetSteal: true})
+		Expect(err).Should(Equal(bcode.ErrEnvTargetNotAllowDelete))
+		envA, err = envService.GetEnv(context.TODO(), "env-steal-a")
+		Expect(err).Should(BeNil())
+		Expect(envA.Targets).Should(Equal([]string{"env-steal-target-2"}))
+
+		Expect(envService.DeleteEnv(context.TODO(), "env-steal-a", true, false)).Should(BeNil())
+		Expect(envService.DeleteEnv(context.TODO(), "env-steal-b", false, false)).Should(BeNil())
+	})
+
+	It("Test ListUnassignedTargets function", func() {
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-unassigned-target", Project: "env-unassigned-project"})).Should(BeNil())
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-assigned-target", Project: "env-unassigned-project"})).Should(BeNil())
+		_, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-unassigned", Project: "env-unassigned-project", Targets: []string{"env-assigned-target"}})
+		Expect(err).Should(BeNil())
+
+		targets, err := envService.ListUnassignedTargets(context.TODO(), "env-unassigned-project")
+		Expect(err).Should(BeNil())
+		Expect(targets.Total).Should(Equal(int64(1)))
+		Expect(targets.Targets[0].Name).Should(Equal("env-unassigned-target"))
+
+		Expect(envService.DeleteEnv(context.TODO(), "env-unassigned", false, false)).Should(BeNil())
+	})
+
+	It("Test the audit events of the env", func() {
+		logger := &fakeAuditLogger{}
+		auditEnvService := &envServiceImpl{Store: ds, KubeClient: k8sClient, ProjectService: projectService, AuditLogger: logger}
+		userCtx := context.WithValue(context.TODO(), &apisv1.CtxKeyUser, FakeAdminName)
+		_, err := auditEnvService.CreateEnv(userCtx, apisv1.CreateEnvRequest{Name: "env-audit"})
+		Expect(err).Should(BeNil())
+		Expect(logger.actions()).Should(Equal([]string{AuditActionCreate, AuditActionGrantPrivileges}))
+		Expect(logger.events[0].User).Should(Equal(FakeAdminName))
+		Expect(logger.events[0].Name).Should(Equal("env-audit"))
+
+		logger.events = nil
+		Expect(auditEnvService.DeleteEnv(userCtx, "env-audit", false, false)).Should(BeNil())
+		Expect(logger.actions()).Should(Equal([]string{AuditActionDelete, AuditActionRevokePrivileges}))
+	})
+
+	It("Test ArchiveEnv and UnarchiveEnv function", func() {
+		Expect(ds.Add(context.TODO(), &model.Project{Name: "env-archive-project"})).Should(BeNil())
+		Expect(ds.Add(context.TODO(), &model.ProjectUser{Username: FakeAdminName, ProjectName: "env-archive-project"})).Should(BeNil())
+		_, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-archive", Namespace: "env-archive", Project: "env-archive-project"})
+		Expect(err).Should(BeNil())
+
+		archived, err := envService.ArchiveEnv(context.TODO(), "env-archive")
+		Expect(err).Should(BeNil())
+		Expect(archived.Archived).Should(BeTrue())
+		var roleBinding rbacv1.RoleBinding
+		err = k8sClient.Get(context.TODO(), types.NamespacedName{Name: auth.KubeVelaWriterAppRoleName + ":binding", Namespace: "env-archive"}, &roleBinding)
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		var namespace corev1.Namespace
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: "env-archive"}, &namespace)).Should(BeNil())
+		Expect(namespace.Labels[oam.LabelNamespaceOfEnvName]).Should(Equal("env-archive"))
+
+		// the archived env is excluded by default
+		userCtx := context.WithValue(context.TODO(), &apisv1.CtxKeyUser, FakeAdminName)
+		envs, err := envService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Project: "env-archive-project"})
+		Expect(err).Should(BeNil())
+		Expect(envs.Total).Should(Equal(int64(0)))
+		envs, err = envService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Project: "env-archive-project", IncludeArchived: true})
+		Expect(err).Should(BeNil())
+		Expect(envs.Total).Should(Equal(int64(1)))
+		Expect(envs.Envs[0].Archived).Should(BeTrue())
+
+		unarchived, err := envService.UnarchiveEnv(context.TODO(), "env-archive")
+		Expect(err).Should(BeNil())
+		Expect(unarchived.Archived).Should(BeFalse())
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: auth.KubeVelaWriterAppRoleName + ":binding", Namespace: "env-archive"}, &roleBinding)).Should(BeNil())
+
+		// the canceled request is aborted
+		canceledCtx, cancel := context.WithCancel(userCtx)
+		cancel()
+		_, err = envService.ListEnvs(canceledCtx, 0, 0, apisv1.ListEnvOptions{Project: "env-archive-project"})
+		Expect(errors.Is(err, context.Canceled)).Should(BeTrue())
+
+		// the admin could update and delete all of the envs
+		envs, err = envService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Project: "env-archive-project", WritableOnly: true})
+		Expect(err).Should(BeNil())
+		Expect(envs.Total).Should(Equal(int64(1)))
+
+		Expect(envService.DeleteEnv(context.TODO(), "env-archive", false, false)).Should(BeNil())
+
+		By("the targets of the archived env could be assigned to the other envs")
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-archive-target", Project: "env-archive-project"})).Should(BeNil())
+		_, err = envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-archive-old", Namespace: "env-archive-old", Project: "env-archive-project", Targets: []string{"env-archive-target"}})
+		Expect(err).Should(BeNil())
+		_, err = envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-archive-new", Namespace: "env-archive-new", Project: "env-archive-project", Targets: []string{"env-archive-target"}})
+		Expect(cmp.Equal(err, bcode.ErrEnvTargetConflict, cmpopts.EquateErrors())).Should(BeTrue())
+		_, err = envService.ArchiveEnv(context.TODO(), "env-archive-old")
+		Expect(err).Should(BeNil())
+		unassigned, err := envService.ListUnassignedTargets(context.TODO(), "env-archive-project")
+		Expect(err).Should(BeNil())
+		Expect(unassigned.Total).Should(Equal(int64(1)))
+		created, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-archive-new", Namespace: "env-archive-new", Project: "env-archive-project", Targets: []string{"env-archive-target"}})
+		Expect(err).Should(BeNil())
+		Expect(created.Targets[0].Name).Should(Equal("env-archive-target"))
+		// the env can't be unarchived as its target is claimed by the new env
+		_, err = envService.UnarchiveEnv(context.TODO(), "env-archive-old")
+		Expect(cmp.Equal(err, bcode.ErrEnvTargetConflict, cmpopts.EquateErrors())).Should(BeTrue())
+
+		Expect(envService.DeleteEnv(context.TODO(), "env-archive-new", false, false)).Should(BeNil())
+		Expect(envService.DeleteEnv(context.TODO(), "env-archive-old", false, false)).Should(BeNil())
+	})
+
+	It("Test the warnings of the unreachable target clusters", func() {
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-warning-reachable", Cluster: &model.ClusterTarget{ClusterName: "reachable"}})).Should(BeNil())
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-warning-unreachable", Cluster: &model.ClusterTarget{ClusterName: "unreachable"}})).Should(BeNil())
+		probeService := &envServiceImpl{
+			Store:          ds,
+			KubeClient:     k8sClient,
+			ProjectService: NewTestProjectService(ds, k8sClient),
+			clusterProbe: func(ctx context.Context, clusterName string) error {
+				if clusterName == "unreachable" {
+					return errors.New("connection refused")
+				}
+				return nil
+			},
+		}
+		env, err := probeService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{
+			Name:    "env-warning",
+			Project: "env-warning-project",
+			Targets: []string{"env-warning-reachable", "env-warning-unreachable"},
+		})
+		Expect(err).Should(BeNil())
+		Expect(env.Warnings).Should(Equal([]string{"the cluster unreachable of the target env-warning-unreachable is unreachable: connection refused"}))
+		_, err = envService.GetEnv(context.TODO(), "env-warning")
+		Expect(err).Should(BeNil())
+		Expect(envService.DeleteEnv(context.TODO(), "env-warning", false, false)).Should(BeNil())
+	})
+
+	It("Test the racing creates claiming the same target", func() {
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-race-target", Project: "env-race-project"})).Should(BeNil())
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, errs[i] = envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{
+					Name:    fmt.Sprintf("env-race-%d", i),
+					Project: "env-race-project",
+					Targets: []string{"env-race-target"},
+				})
+			}(i)
+		}
+		wg.Wait()
+
+		entities, err := ds.List(context.TODO(), &model.Env{Project: "env-race-project"}, nil)
+		Expect(err).Should(BeNil())
+		var owners []string
+		for _, entity := range entities {
+			env := entity.(*model.Env)
+			if util.StringsContain(env.Targets, "env-race-target") {
+				owners = append(owners, env.Name)
+			}
+		}
+		Expect(len(owners)).Should(Equal(1))
+		for _, err := range errs {
+			if err != nil {
+				Expect(cmp.Equal(err, bcode.ErrEnvTargetConflict, cmpopts.EquateErrors())).Should(BeTrue())
+			}
+		}
+		Expect(envService.DeleteEnv(context.TODO(), owners[0], false, false)).Should(BeNil())
+	})
+
+	It("Test the missing targets of the env", func() {
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-missing-exist", Project: "env-missing-project"})).Should(BeNil())
+		_, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-missing", Project: "env-missing-project", Targets: []string{"env-missing-a", "env-missing-exist", "env-missing-b"}})
+		var bcodeErr *bcode.Bcode
+		Expect(errors.As(err, &bcodeErr)).Should(BeTrue())
+		Expect(errors.Is(err, bcode.ErrTargetNotExist)).Should(BeTrue())
+		Expect(bcodeErr.Details).Should(Equal([]string{"env-missing-a", "env-missing-b"}))
+
+		_, err = envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-missing", Project: "env-missing-project", Targets: []string{"env-missing-exist"}})
+		Expect(err).Should(BeNil())
+		_, err = envService.UpdateEnv(context.TODO(), "env-missing", apisv1.UpdateEnvRequest{Targets: []string{"env-missing-exist", "env-missing-c"}})
+		Expect(errors.As(err, &bcodeErr)).Should(BeTrue())
+		Expect(bcodeErr.Details).Should(Equal([]string{"env-missing-c"}))
+		Expect(envService.DeleteEnv(context.TODO(), "env-missing", false, false)).Should(BeNil())
+	})
+
+	It("Test the default env of the project", func() {
+		_, err := envService.GetDefaultEnv(context.TODO(), "env-default-project")
+		Expect(err).Should(Equal(bcode.ErrDefaultEnvNotExist))
+
+		first, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-default-1", Project: "env-default-project", Default: true})
+		Expect(err).Should(BeNil())
+		Expect(first.Default).Should(BeTrue())
+		defaultEnv, err := envService.GetDefaultEnv(context.TODO(), "env-default-project")
+		Expect(err).Should(BeNil())
+		Expect(defaultEnv.Name).Should(Equal("env-default-1"))
+
+		By("the flag of the previous default env should be cleared")
+		second, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-default-2", Project: "env-default-project", Default: true})
+		Expect(err).Should(BeNil())
+		Expect(second.Default).Should(BeTrue())
+		env, err := envService.GetEnv(context.TODO(), "env-default-1")
+		Expect(err).Should(BeNil())
+		Expect(env.Default).Should(BeFalse())
+
+		By("set the default env by updating")
+		_, err = envService.UpdateEnv(context.TODO(), "env-default-1", apisv1.UpdateEnvRequest{Default: pointer.Bool(true)})
+		Expect(err).Should(BeNil())
+		defaultEnv, err = envService.GetDefaultEnv(context.TODO(), "env-default-project")
+		Expect(err).Should(BeNil())
+		Expect(defaultEnv.Name).Should(Equal("env-default-1"))
+		env, err = envService.GetEnv(context.TODO(), "env-default-2")
+		Expect(err).Should(BeNil())
+		Expect(env.Default).Should(BeFalse())
+
+		By("the racing updates should keep only one default env")
+		var wg sync.WaitGroup
+		for _, name := range []string{"env-default-1", "env-default-2"} {
+			wg.Add(1)
+			go func(name string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := envService.UpdateEnv(context.TODO(), name, apisv1.UpdateEnvRequest{Default: pointer.Bool(true)})
+				Expect(err).Should(BeNil())
+			}(name)
+		}
+		wg.Wait()
+		entities, err := ds.List(context.TODO(), &model.Env{Project: "env-default-project"}, nil)
+		Expect(err).Should(BeNil())
+		var defaults int
+		for _, entity := range entities {
+			if entity.(*model.Env).Default {
+				defaults++
+			}
+		}
+		Expect(defaults).Should(Equal(1))
+
+		_, err = envService.UpdateEnv(context.TODO(), "env-default-1", apisv1.UpdateEnvRequest{Default: pointer.Bool(false)})
+		Expect(err).Should(BeNil())
+		_, err = envService.UpdateEnv(context.TODO(), "env-default-2", apisv1.UpdateEnvRequest{Default: pointer.Bool(false)})
+		Expect(err).Should(BeNil())
+		_, err = envService.GetDefaultEnv(context.TODO(), "env-default-project")
+		Expect(err).Should(Equal(bcode.ErrDefaultEnvNotExist))
+
+		Expect(envService.DeleteEnv(context.TODO(), "env-default-1", false, false)).Should(BeNil())
+		Expect(envService.DeleteEnv(context.TODO(), "env-default-2", false, false)).Should(BeNil())
+	})
+
+	It("Test ExportEnv and ImportEnv function", func() {
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-export-target", Project: "env-export-project"})).Should(BeNil())
+		source, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{
+			Name:        "env-export",
+			Alias:       "Export",
+			Description: "the env to export",
+			Project:     "env-export-project",
+			Targets:     []string{"env-export-target"},
+			Labels:      map[string]string{"team": "payments"},
+		})
+		Expect(err).Should(BeNil())
+		exported, err := envService.ExportEnv(context.TODO(), "env-export")
+		Expect(err).Should(BeNil())
+		Expect(exported.YAML).Should(ContainSubstring("name: env-export"))
+
+		By("import the env after it is deleted")
+		Expect(envService.DeleteEnv(context.TODO(), "env-export", false, false)).Should(BeNil())
+		imported, err := envService.ImportEnv(context.TODO(), apisv1.ImportEnvRequest{YAML: exported.YAML})
+		Expect(err).Should(BeNil())
+		Expect(imported.Alias).Should(Equal(source.Alias))
+		Expect(imported.Description).Should(Equal(source.Description))
+		Expect(imported.Project).Should(Equal(source.Project))
+		Expect(imported.Namespace).Should(Equal(source.Namespace))
+		Expect(imported.Targets).Should(Equal(source.Targets))
+		Expect(imported.Labels).Should(Equal(source.Labels))
+
+		_, err = envService.ImportEnv(context.TODO(), apisv1.ImportEnvRequest{YAML: "name: env-import\nproject: env-export-project\ntargets: [not-exist]\n"})
+		Expect(err).Should(Equal(bcode.ErrTargetNotExist))
+		_, err = envService.ImportEnv(context.TODO(), apisv1.ImportEnvRequest{YAML: "name: env-import\nprojects: env-export-project\n"})
+		Expect(cmp.Equal(err, bcode.ErrEnvManifestInvalid, cmpopts.EquateErrors())).Should(BeTrue())
+		Expect(envService.DeleteEnv(context.TODO(), "env-export", false, false)).Should(BeNil())
+	})
+
+	It("Test the status of the env targets", func() {
+		Expect(ds.Add(context.TODO(), &model.Project{Name: "env-status-project"})).Should(BeNil())
+		Expect(ds.Add(context.TODO(), &model.ProjectUser{Username: FakeAdminName, ProjectName: "env-status-project"})).Should(BeNil())
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-status-healthy", Project: "env-status-project", Cluster: &model.ClusterTarget{ClusterName: "local", Namespace: "default"}})).Should(BeNil())
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-status-no-namespace", Project: "env-status-project", Cluster: &model.ClusterTarget{ClusterName: "local", Namespace: "env-status-not-exist"}})).Should(BeNil())
+		Expect(ds.Add(context.TODO(), &model.Target{Name: "env-status-unreachable", Project: "env-status-project", Cluster: &model.ClusterTarget{ClusterName: "unreachable", Namespace: "default"}})).Should(BeNil())
+		statusService := &envServiceImpl{
+			Store:          ds,
+			KubeClient:     k8sClient,
+			ProjectService: NewTestProjectService(ds, k8sClient),
+			clusterProbe: func(ctx context.Context, clusterName string) error {
+				if clusterName == "unreachable" {
+					return errors.New("connection refused")
+				}
+				return nil
+			},
+		}
+		_, err := statusService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{
+			Name:    "env-status",
+			Project: "env-status-project",
+			Targets: []string{"env-status-healthy", "env-status-no-namespace", "env-status-unreachable"},
+		})
+		Expect(err).Should(BeNil())
+
+		userCtx := context.WithValue(context.TODO(), &apisv1.CtxKeyUser, FakeAdminName)
+		envs, err := statusService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Project: "env-status-project"})
+		Expect(err).Should(BeNil())
+		for _, target := range envs.Envs[0].Targets {
+			Expect(target.Status).Should(BeEmpty())
+		}
+		envs, err = statusService.ListEnvs(userCtx, 0, 0, apisv1.ListEnvOptions{Project: "env-status-project", IncludeTargetStatus: true})
+		Expect(err).Should(BeNil())
+		status := map[string]string{}
+		for _, target := range envs.Envs[0].Targets {
+			status[target.Name] = target.Status
+		}
+		Expect(status).Should(Equal(map[string]string{
+			"env-status-healthy":      TargetStatusHealthy,
+			"env-status-no-namespace": TargetStatusNamespaceNotExist,
+			"env-status-unreachable":  TargetStatusClusterUnreachable,
+		}))
+		Expect(statusService.DeleteEnv(context.TODO(), "env-status", false, false)).Should(BeNil())
+	})
+
+	It("Test PreviewEnvPrivileges function", func() {
+		preview, err := envService.PreviewEnvPrivileges(context.TODO(), apisv1.CreateEnvRequest{Name: "env-preview", Project: "project-preview"})
+		Expect(err).Should(BeNil())
+		Expect(preview.Privileges).Should(ContainSubstring("RoleBinding env-preview/" + auth.KubeVelaWriterAppRoleName + ":binding"))
+		Expect(preview.Privileges).Should(ContainSubstring("Group " + utils.KubeVelaProjectGroupPrefix + "project-preview"))
+		var namespace corev1.Namespace
+		err = k8sClient.Get(context.TODO(), types.NamespacedName{Name: "env-preview"}, &namespace)
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+	})
+
+	It("Test ReconcileEnv function", func() {
+		Expect(ds.Add(context.TODO(), &model.Project{Name: "env-reconcile-project"})).Should(BeNil())
+		_, err := envService.CreateEnv(context.TODO(), apisv1.CreateEnvRequest{Name: "env-reconcile", Namespace: "env-reconcile", Project: "env-reconcile-project", Labels: map[string]string{"team": "a"}})
+		Expect(err).Should(BeNil())
+
+		report, err := envService.ReconcileEnv(context.TODO(), "env-reconcile")
+		Expect(err).Should(BeNil())
+		Expect(report.NamespaceCreated).Should(BeFalse())
+		Expect(report.CorrectedLabels).Should(BeEmpty())
+		Expect(report.PrivilegesGranted).Should(BeTrue())
+
+		By("the drifted labels and the deleted role binding are corrected")
+		var namespace corev1.Namespace
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: "env-reconcile"}, &namespace)).Should(BeNil())
+		delete(namespace.Labels, oam.LabelNamespaceOfEnvName)
+		namespace.Labels["team"] = "b"
+		Expect(k8sClient.Update(context.TODO(), &namespace)).Should(BeNil())
+		var roleBinding rbacv1.RoleBinding
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: auth.KubeVelaWriterAppRoleName + ":binding", Namespace: "env-reconcile"}, &roleBinding)).Should(BeNil())
+		Expect(k8sClient.Delete(context.TODO(), &roleBinding)).Should(BeNil())
+
+		report, err = envService.ReconcileEnv(context.TODO(), "env-reconcile")
+		Expect(err).Should(BeNil())
+		Expect(report.CorrectedLabels).Should(Equal(map[string]string{oam.LabelNamespaceOfEnvName: "env-reconcile", "team": "a"}))
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: "env-reconcile"}, &namespace)).Should(BeNil())
+		Expect(namespace.Labels[oam.LabelNamespaceOfEnvName]).Should(Equal("env-reconcile"))
+		Expect(namespace.Labels["team"]).Should(Equal("a"))
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: auth.KubeVelaWriterAppRoleName + ":binding", Namespace: "env-reconcile"}, &roleBinding)).Should(BeNil())
+
+		By("the archived env can't be reconciled")
+		_, err = envService.ArchiveEnv(context.TODO(), "env-reconcile")
+		Expect(err).Should(BeNil())
+		_, err = envService.ReconcileEnv(context.TODO(), "env-reconcile")
+		Expect(cmp.Equal(err, bcode.ErrEnvArchived, cmpopts.EquateErrors())).Should(BeTrue())
+		Expect(envService.DeleteEnv(context.TODO(), "env-reconcile", false, false)).Should(BeNil())
+	})
+
 	It("test checkEqual", func() {
 		Expect(checkEqual([]string{"default"}, []string{"default", "dev"})).Should(BeFalse())
 		Expect(checkEqual([]string{"default"}, []string{"default"})).Should(BeTrue())
 	})
 })
+
+type fakeAuditLogger struct {
+	events []*AuditEvent
+}
+
+func (f *fakeAuditLogger) Audit(ctx context.Context, event *AuditEvent) {
+	f.events = append(f.events, event)
+}
+
+func (f *fakeAuditLogger) actions() []string {
+	var actions []string
+	for _, event := range f.events {
+		actions = append(actions, event.Action)
+	}
+	return actions
+}
+
+// flakyClient fails the first requests to get the objects
+type flakyClient struct {
+	client.Client
+	failures int
+}
+
+func (f *flakyClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if f.failures > 0 {
+		f.failures--
+		return apierrors.NewServiceUnavailable("the server is currently unable to handle the request")
+	}
+	return f.Client.Get(ctx, key, obj, opts...)
+}
+
+// rbacFailingClient fails the requests of the roles and the role bindings
+type rbacFailingClient struct {
+	client.Client
+}
+
+func (f *rbacFailingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	switch obj.(type) {
+	case *rbacv1.Role, *rbacv1.RoleBinding, *rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding:
+		return apierrors.NewForbidden(schema.GroupResource{Group: rbacv1.GroupName, Resource: "roles"}, key.Name, errors.New("the role is forbidden"))
+	}
+	return f.Client.Get(ctx, key, obj, opts...)
+}
+
+// projectUserClient forbids the requests for the roles made with the permissions of the project users
+type projectUserClient struct {
+	client.Client
+}
+
+func (c *projectUserClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if project, _ := utils.ProjectFrom(ctx); project != "" {
+		switch obj.(type) {
+		case *rbacv1.Role, *rbacv1.RoleBinding:
+			return apierrors.NewForbidden(schema.GroupResource{Group: rbacv1.GroupName, Resource: "roles"}, key.Name, errors.New("the project user can't manage the roles"))
+		}
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+// countingClient counts the requests to get the objects and records the namespaces listed
+type countingClient struct {
+	client.Client
+	gets           int
+	listNamespaces []string
+}
+
+func (c *countingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	c.gets++
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *countingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOptions := &client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+	c.listNamespaces = append(c.listNamespaces, listOptions.Namespace)
+	return c.Client.List(ctx, list, opts...)
+}
+
+// newTestEnvService builds the env service on a fake client with the given objects and a datastore in the database
+func newTestEnvService(t *testing.T, database string, objs ...client.Object) (*envServiceImpl, client.Client, datastore.DataStore) {
+	cli := fake.NewClientBuilder().WithScheme(utilcommon.Scheme).WithObjects(objs...).Build()
+	ds, err := kubeapi.New(context.TODO(), datastore.Config{Database: database}, cli)
+	assert.NoError(t, err)
+	return &envServiceImpl{Store: ds, KubeClient: cli}, cli, ds
+}
+
+func TestCreateEnvRollback(t *testing.T) {
+	backoff := privilegesBackoff
+	privilegesBackoff.Duration = time.Millisecond
+	privilegesBackoff.Steps = 2
+	defer func() { privilegesBackoff = backoff }()
+
+	ctx := context.TODO()
+	envService, baseClient, _ := newTestEnvService(t, "env-create-rollback",
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "rollback-existing", Labels: map[string]string{"team": "a"}}},
+	)
+	envService.KubeClient = &rbacFailingClient{Client: baseClient}
+
+	_, err := envService.CreateEnv(ctx, apisv1.CreateEnvRequest{
+		Name:       "env-rollback",
+		Project:    "rollback",
+		Namespace:  "rollback-created",
+		Namespaces: []string{"rollback-existing"},
+	})
+	assert.Error(t, err)
+
+	// no env record remains, the created namespace is deleted and the existing one is released
+	_, err = envService.GetEnv(ctx, "env-rollback")
+	assert.Equal(t, bcode.ErrEnvNotExisted, err)
+	var namespace corev1.Namespace
+	assert.True(t, apierrors.IsNotFound(baseClient.Get(ctx, types.NamespacedName{Name: "rollback-created"}, &namespace)))
+	assert.NoError(t, baseClient.Get(ctx, types.NamespacedName{Name: "rollback-existing"}, &namespace))
+	assert.Empty(t, namespace.Labels[oam.LabelNamespaceOfEnvName])
+	assert.Equal(t, "a", namespace.Labels["team"])
+
+	// the env could be created again once the privileges could be granted
+	envService.KubeClient = baseClient
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{
+		Name:       "env-rollback",
+		Project:    "rollback",
+		Namespace:  "rollback-created",
+		Namespaces: []string{"rollback-existing"},
+	})
+	assert.NoError(t, err)
+}
+
+// deleteFailingClient fails the requests to delete the objects
+type deleteFailingClient struct {
+	client.Client
+}
+
+func (f *deleteFailingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return errors.New("the deletion is forbidden")
+}
+
+func TestBatchCreateEnvRollbackFailure(t *testing.T) {
+	ctx := context.TODO()
+	envService, cli, ds := newTestEnvService(t, "env-batch-rollback")
+	envService.KubeClient = &deleteFailingClient{Client: cli}
+	assert.NoError(t, ds.Add(ctx, &model.Target{Name: "batch-rollback-target"}))
+
+	resp, err := envService.BatchCreateEnv(ctx, []apisv1.CreateEnvRequest{
+		{Name: "env-batch-left", Project: "p", Targets: []string{"batch-rollback-target"}},
+		{Name: "env-batch-conflict", Project: "p", Targets: []string{"batch-rollback-target"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(resp.Results))
+	// the env failed to roll back is reported instead of being dropped
+	left := resp.Results[0]
+	assert.True(t, left.Success)
+	assert.True(t, left.RollbackFailed)
+	assert.NotNil(t, left.Env)
+	assert.Contains(t, left.Message, "the deletion is forbidden")
+	assert.False(t, resp.Results[1].Success)
+	assert.False(t, resp.Results[1].RollbackFailed)
+	_, err = envService.GetEnv(ctx, "env-batch-left")
+	assert.NoError(t, err)
+}
+
+// putFailingStore fails the requests to update the env with the name
+type putFailingStore struct {
+	datastore.DataStore
+	envName string
+}
+
+func (f *putFailingStore) Put(ctx context.Context, entity datastore.Entity) error {
+	if env, ok := entity.(*model.Env); ok && env.Name == f.envName {
+		return errors.New("the datastore is unavailable")
+	}
+	return f.DataStore.Put(ctx, entity)
+}
+
+func (f *putFailingStore) PutIfUnchanged(ctx context.Context, entity datastore.Entity, updateTime time.Time) error {
+	if env, ok := entity.(*model.Env); ok && env.Name == f.envName {
+		return errors.New("the datastore is unavailable")
+	}
+	return f.DataStore.PutIfUnchanged(ctx, entity, updateTime)
+}
+
+func TestCreateDefaultEnvRollback(t *testing.T) {
+	ctx := context.TODO()
+	envService, cli, ds := newTestEnvService(t, "env-default-rollback")
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-default-old", Namespace: "env-default-old", Project: "p", Default: true}))
+	envService.Store = &putFailingStore{DataStore: ds, envName: "env-default-old"}
+
+	// the env is rolled back if the default env of the project can't be resolved
+	_, err := envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-default-new", Namespace: "env-default-new", Project: "p", Default: true})
+	assert.Error(t, err)
+	assert.ErrorIs(t, ds.Get(ctx, &model.Env{Name: "env-default-new"}), datastore.ErrRecordNotExist)
+	assert.True(t, apierrors.IsNotFound(cli.Get(ctx, types.NamespacedName{Name: "env-default-new"}, &corev1.Namespace{})))
+	defaultEnv, err := envService.GetDefaultEnv(ctx, "p")
+	assert.NoError(t, err)
+	assert.Equal(t, "env-default-old", defaultEnv.Name)
+}
+
+func TestUpdateEnvStealTargetsRollback(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-steal-rollback")
+	for _, target := range []string{"steal-target-1", "steal-target-2"} {
+		assert.NoError(t, ds.Add(ctx, &model.Target{Name: target, Project: "p"}))
+	}
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-losing", Namespace: "env-losing", Project: "p", Targets: []string{"steal-target-1", "steal-target-2"}}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-stealing", Namespace: "env-stealing", Project: "p"}))
+	envService.Store = &putFailingStore{DataStore: ds, envName: "env-stealing"}
+
+	_, err := envService.UpdateEnv(ctx, "env-stealing", apisv1.UpdateEnvRequest{Targets: []string{"steal-target-1"}, AllowTargetSteal: true})
+	assert.Error(t, err)
+	// the targets are kept by the losing env if they can't be assigned to the stealing env
+	losing, err := repository.GetEnv(ctx, ds, "env-losing")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"steal-target-1", "steal-target-2"}, losing.Targets)
+	stealing, err := repository.GetEnv(ctx, ds, "env-stealing")
+	assert.NoError(t, err)
+	assert.Empty(t, stealing.Targets)
+}
+
+func TestArchiveEnvFailure(t *testing.T) {
+	backoff := privilegesBackoff
+	privilegesBackoff.Duration = time.Millisecond
+	privilegesBackoff.Steps = 2
+	defer func() { privilegesBackoff = backoff }()
+
+	ctx := context.TODO()
+	envService, cli, ds := newTestEnvService(t, "env-archive-failure")
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-archive-failure", Project: "p"})
+	assert.NoError(t, err)
+	roleBindingKey := types.NamespacedName{Name: auth.KubeVelaWriterAppRoleName + ":binding", Namespace: "env-archive-failure"}
+	assert.NoError(t, cli.Get(ctx, roleBindingKey, &rbacv1.RoleBinding{}))
+
+	// the env is not archived if the privileges can't be revoked
+	envService.KubeClient = &rbacFailingClient{Client: cli}
+	_, err = envService.ArchiveEnv(ctx, "env-archive-failure")
+	assert.Error(t, err)
+	env, err := repository.GetEnv(ctx, ds, "env-archive-failure")
+	assert.NoError(t, err)
+	assert.False(t, env.Archived)
+
+	// the privileges are granted again if the archived state can't be stored
+	envService.KubeClient = cli
+	envService.Store = &putFailingStore{DataStore: ds, envName: "env-archive-failure"}
+	_, err = envService.ArchiveEnv(ctx, "env-archive-failure")
+	assert.Error(t, err)
+	env, err = repository.GetEnv(ctx, ds, "env-archive-failure")
+	assert.NoError(t, err)
+	assert.False(t, env.Archived)
+	assert.NoError(t, cli.Get(ctx, roleBindingKey, &rbacv1.RoleBinding{}))
+
+	// the privileges are changed without the permissions of the project user
+	envService.Store = ds
+	envService.KubeClient = &projectUserClient{Client: cli}
+	projectCtx := utils.WithProject(ctx, "p")
+	_, err = envService.ArchiveEnv(projectCtx, "env-archive-failure")
+	assert.NoError(t, err)
+	assert.True(t, apierrors.IsNotFound(cli.Get(ctx, roleBindingKey, &rbacv1.RoleBinding{})))
+	_, err = envService.UnarchiveEnv(projectCtx, "env-archive-failure")
+	assert.NoError(t, err)
+	assert.NoError(t, cli.Get(ctx, roleBindingKey, &rbacv1.RoleBinding{}))
+}
+
+func TestManagePrivilegesForEnvironmentRetry(t *testing.T) {
+	backoff := privilegesBackoff
+	privilegesBackoff.Duration = time.Millisecond
+	defer func() { privilegesBackoff = backoff }()
+
+	env := &model.Env{Name: "env-retry", Namespace: "env-retry", Project: "env-retry-project"}
+	cli := &flakyClient{Client: fake.NewClientBuilder().Build(), failures: 2}
+	assert.NoError(t, managePrivilegesForEnvironment(context.TODO(), cli, env, false))
+	assert.Equal(t, 0, cli.failures)
+	var roleBinding rbacv1.RoleBinding
+	assert.NoError(t, cli.Get(context.TODO(), types.NamespacedName{Name: auth.KubeVelaWriterAppRoleName + ":binding", Namespace: "env-retry"}, &roleBinding))
+
+	cli = &flakyClient{Client: fake.NewClientBuilder().Build(), failures: 100}
+	assert.Error(t, managePrivilegesForEnvironment(context.TODO(), cli, env, false))
+
+	// the certain errors are not retried
+	forbidden := &countingClient{Client: &rbacFailingClient{Client: fake.NewClientBuilder().Build()}}
+	assert.Error(t, managePrivilegesForEnvironment(context.TODO(), forbidden, env, false))
+	assert.Equal(t, 1, forbidden.gets)
+}
+
+func TestIsTransientError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "rolebindings"}
+	assert.True(t, isTransientError(apierrors.NewConflict(gr, "binding", errors.New("changed"))))
+	assert.True(t, isTransientError(apierrors.NewServerTimeout(gr, "get", 1)))
+	assert.True(t, isTransientError(apierrors.NewTooManyRequests("slow down", 1)))
+	assert.False(t, isTransientError(apierrors.NewForbidden(gr, "binding", errors.New("denied"))))
+	assert.False(t, isTransientError(apierrors.NewNotFound(gr, "binding")))
+	assert.False(t, isTransientError(apierrors.NewInvalid(schema.GroupKind{Kind: "RoleBinding"}, "binding", nil)))
+	assert.False(t, isTransientError(errors.New("unknown")))
+}
+
+func TestFindMissingTargets(t *testing.T) {
+	targets := []*model.Target{{Name: "dev"}, {Name: "prod"}}
+	assert.Nil(t, findMissingTargets([]string{"dev", "prod"}, targets))
+	assert.Equal(t, []string{"qa", "staging"}, findMissingTargets([]string{"qa", "dev", "staging", "qa"}, targets))
+	err := errTargetsNotExist([]string{"qa", "staging"})
+	assert.True(t, errors.Is(err, bcode.ErrTargetNotExist))
+	assert.Equal(t, "the targets qa, staging are not exist", err.(*bcode.Bcode).Message)
+}
+
+func TestSortAndPaginateEnvs(t *testing.T) {
+	envs := []*model.Env{
+		{Name: "env-c", Alias: "beta"},
+		{Name: "env-a", Alias: "Gamma"},
+		{Name: "env-b", Alias: "alpha"},
+		{Name: "env-d", Alias: "Beta"},
+	}
+	names := func(envs []*model.Env) []string {
+		var res []string
+		for _, env := range envs {
+			res = append(res, env.Name)
+		}
+		return res
+	}
+	sortEnvs(envs, EnvSortByName, false)
+	assert.Equal(t, []string{"env-a", "env-b", "env-c", "env-d"}, names(envs))
+	sortEnvs(envs, EnvSortByName, true)
+	assert.Equal(t, []string{"env-d", "env-c", "env-b", "env-a"}, names(envs))
+	sortEnvs(envs, EnvSortByAlias, false)
+	assert.Equal(t, []string{"env-b", "env-c", "env-d", "env-a"}, names(envs))
+
+	assert.Equal(t, []string{"env-b", "env-c", "env-d", "env-a"}, names(paginateEnvs(envs, 0, 0)))
+	assert.Equal(t, []string{"env-d", "env-a"}, names(paginateEnvs(envs, 2, 2)))
+	assert.Equal(t, []string{"env-a"}, names(paginateEnvs(envs, 2, 3)))
+	assert.Empty(t, paginateEnvs(envs, 3, 2))
+}
+
+func TestNewListEnvResponse(t *testing.T) {
+	res := newListEnvResponse(nil, 5, 1, 2)
+	assert.Equal(t, 1, res.Page)
+	assert.Equal(t, 2, res.PageSize)
+	assert.Equal(t, 3, res.TotalPages)
+	assert.Equal(t, 2, newListEnvResponse(nil, 4, 2, 2).TotalPages)
+	assert.Equal(t, 1, newListEnvResponse(nil, 5, 0, 0).TotalPages)
+	assert.Equal(t, 1, newListEnvResponse(nil, 0, 1, 10).TotalPages)
+}
+
+func TestMatchTargetSelector(t *testing.T) {
+	targets := []*model.Target{
+		{Name: "eu-2", Tags: map[string]string{"region": "eu", "tier": "prod"}},
+		{Name: "eu-1", Tags: map[string]string{"region": "eu"}},
+		{Name: "us-1", Tags: map[string]string{"region": "us", "tier": "prod"}},
+		{Name: "untagged"},
+	}
+	assert.Equal(t, []string{"eu-1", "eu-2"}, matchTargetSelector(targets, map[string]string{"region": "eu"}))
+	assert.Equal(t, []string{"eu-2"}, matchTargetSelector(targets, map[string]string{"region": "eu", "tier": "prod"}))
+	assert.Nil(t, matchTargetSelector(targets, map[string]string{"region": "ap"}))
+
+	assert.Equal(t, []string{"us-1", "eu-1", "eu-2"}, mergeTargetNames([]string{"us-1", "eu-1"}, []string{"eu-1", "eu-2"}))
+}
+
+func TestUpdateEnvTargetSelector(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-update-target-selector")
+	for _, target := range []*model.Target{
+		{Name: "selector-dev", Project: "p", Tags: map[string]string{"region": "us"}},
+		{Name: "selector-eu-1", Project: "p", Tags: map[string]string{"region": "eu"}},
+		{Name: "selector-eu-2", Project: "p", Tags: map[string]string{"region": "eu"}},
+	} {
+		assert.NoError(t, ds.Add(ctx, target))
+	}
+	targetNames := func(env *apisv1.Env) []string {
+		var names []string
+		for _, target := range env.Targets {
+			names = append(names, target.Name)
+		}
+		return names
+	}
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-selector", Project: "p", Targets: []string{"selector-dev"}})
+	assert.NoError(t, err)
+
+	// the selected targets are added to the existing targets
+	env, err := envService.UpdateEnv(ctx, "env-selector", apisv1.UpdateEnvRequest{TargetSelector: map[string]string{"region": "eu"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"selector-dev", "selector-eu-1", "selector-eu-2"}, targetNames(env))
+	assert.Equal(t, map[string]string{"region": "eu"}, env.TargetSelector)
+
+	// the selected targets are added to the targets of the request
+	env, err = envService.UpdateEnv(ctx, "env-selector", apisv1.UpdateEnvRequest{Targets: []string{"selector-eu-1"}, TargetSelector: map[string]string{"region": "us"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"selector-eu-1", "selector-dev"}, targetNames(env))
+}
+
+func TestFindDriftedLabels(t *testing.T) {
+	env := &model.Env{Name: "env-drift", Labels: map[string]string{"team": "a"}}
+	expected := expectedEnvNamespaceLabels(env)
+	assert.Equal(t, map[string]string{"team": "a", oam.LabelControlPlaneNamespaceUsage: oam.VelaNamespaceUsageEnv, oam.LabelNamespaceOfEnvName: "env-drift"}, expected)
+	assert.Nil(t, findDriftedLabels(expected, expected))
+	actual := map[string]string{"team": "b", oam.LabelControlPlaneNamespaceUsage: oam.VelaNamespaceUsageEnv, "other": "x"}
+	assert.Equal(t, map[string]string{"team": "a", oam.LabelNamespaceOfEnvName: "env-drift"}, findDriftedLabels(actual, expected))
+}
+
+func TestIsEnvWritable(t *testing.T) {
+	path, err := checkResourcePath("environment")
+	assert.NoError(t, err)
+	env := &model.Env{Name: "env-writable", Project: "env-writable-project"}
+	viewer := []*model.Permission{{Resources: []string{"project:env-writable-project/environment:*"}, Actions: []string{"detail", "list"}}}
+	assert.False(t, isEnvWritable(path, env, viewer))
+	developer := []*model.Permission{{Resources: []string{"project:env-writable-project/environment:*"}, Actions: []string{"*"}}}
+	assert.True(t, isEnvWritable(path, env, developer))
+	otherProject := []*model.Permission{{Resources: []string{"project:other/environment:*"}, Actions: []string{"*"}}}
+	assert.False(t, isEnvWritable(path, env, otherProject))
+	denied := append(developer, &model.Permission{Resources: []string{"project:env-writable-project/environment:env-writable"}, Actions: []string{"update"}, Effect: "Deny"})
+	assert.False(t, isEnvWritable(path, env, denied))
+	// all write actions are required
+	updater := []*model.Permission{{Resources: []string{"project:env-writable-project/environment:*"}, Actions: []string{"detail", "update"}}}
+	assert.False(t, isEnvWritable(path, env, updater))
+	editor := []*model.Permission{{Resources: []string{"project:env-writable-project/environment:*"}, Actions: []string{"update", "delete"}}}
+	assert.True(t, isEnvWritable(path, env, editor))
+	deleteDenied := append(developer, &model.Permission{Resources: []string{"project:env-writable-project/environment:*"}, Actions: []string{"delete"}, Effect: "Deny"})
+	assert.False(t, isEnvWritable(path, env, deleteDenied))
+}
+
+func newBenchmarkEnvs(envCount, targetsPerEnv int) ([]*model.Env, []*model.Target) {
+	var envs []*model.Env
+	var targets []*model.Target
+	for i := 0; i < envCount; i++ {
+		env := &model.Env{Name: fmt.Sprintf("env-%d", i), Project: "benchmark"}
+		for j := 0; j < targetsPerEnv; j++ {
+			target := &model.Target{Name: fmt.Sprintf("target-%d-%d", i, j), Alias: fmt.Sprintf("Target %d-%d", i, j), Project: "benchmark"}
+			targets = append(targets, target)
+			env.Targets = append(env.Targets, target.Name)
+		}
+		envs = append(envs, env)
+	}
+	return envs, targets
+}
+
+// BenchmarkConvertEnvs compares converting the envs of a project with 50 envs by indexing the targets for every env and once for the request
+func BenchmarkConvertEnvs(b *testing.B) {
+	envs, targets := newBenchmarkEnvs(50, 4)
+	b.Run("PerEnv", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, env := range envs {
+				convertEnvModel2Base(env, targets)
+			}
+		}
+	})
+	b.Run("PerRequest", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			targetMap := newTargetMap(targets)
+			for _, env := range envs {
+				convertEnvModel2BaseWithTargetMap(env, targetMap)
+			}
+		}
+	})
+}
+
+func TestDescribeEnvPrivilege(t *testing.T) {
+	cli := fake.NewClientBuilder().Build()
+	envService := &envServiceImpl{KubeClient: cli}
+	env := &model.Env{Name: "env-access", Namespace: "env-access", Project: "env-access-project"}
+	privileges, identity := environmentPrivileges(env)
+	privilege := privileges[0]
+
+	desc := envService.describeEnvPrivilege(context.TODO(), privilege, identity, "")
+	assert.Equal(t, EnvPrivilegeMissing, desc.Status)
+	assert.Equal(t, "env-access", desc.Namespace)
+	assert.Equal(t, "RoleBinding env-access/"+auth.KubeVelaWriterAppRoleName+":binding", desc.RoleBinding)
+	assert.Equal(t, []string{"Group " + utils.KubeVelaProjectGroupPrefix + "env-access-project"}, desc.Subjects)
+
+	assert.NoError(t, managePrivilegesForEnvironment(context.TODO(), cli, env, false))
+	desc = envService.describeEnvPrivilege(context.TODO(), privilege, identity, "")
+	assert.Equal(t, EnvPrivilegeGranted, desc.Status)
+
+	// the binding exists but the group of another project is not bound
+	_, otherIdentity := environmentPrivileges(&model.Env{Name: "env-access", Namespace: "env-access", Project: "other-project"})
+	desc = envService.describeEnvPrivilege(context.TODO(), privilege, otherIdentity, "")
+	assert.Equal(t, EnvPrivilegeMissing, desc.Status)
+}
+
+func TestDeleteEnvNamespace(t *testing.T) {
+	ctx := context.TODO()
+	newNamespace := func(name, envName string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{oam.LabelNamespaceOfEnvName: envName},
+			Annotations: map[string]string{model.AnnotationNamespaceCreatedByEnv: envName}}}
+	}
+	newDeployment := func(namespace, name, appName string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{oam.LabelAppName: appName}}}
+	}
+	newApp := func(namespace, name string, fromUX bool) *v1beta1.Application {
+		app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{}}}
+		if fromUX {
+			app.Labels[velatypes.LabelSourceOfTruth] = velatypes.FromUX
+		}
+		return app
+	}
+	envService, cli, ds := newTestEnvService(t, "env-delete-namespace",
+		newNamespace("ns-owned", "env-owned"),
+		newApp("ns-owned", "app-ux", true),
+		newDeployment("ns-owned", "app-ux-web", "app-ux"),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns-owned", Name: "token"}, Type: corev1.SecretTypeServiceAccountToken},
+		newNamespace("ns-foreign", "env-other"),
+		newNamespace("ns-busy", "env-busy"),
+		newApp("ns-busy", "app-cli", false),
+		newNamespace("ns-kept", "env-kept"),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-existing", Labels: map[string]string{oam.LabelNamespaceOfEnvName: "env-existing"}}},
+		newNamespace("ns-workloads", "env-workloads"),
+		newApp("ns-workloads", "app-ux", true),
+		newDeployment("ns-workloads", "manual", ""),
+		newNamespace("ns-data", "env-data"),
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "ns-data", Name: "data"}},
+		newNamespace("ns-locked", "env-locked"),
+	)
+	for _, env := range []*model.Env{
+		{Name: "env-owned", Namespace: "ns-owned", Project: "p"},
+		{Name: "env-foreign", Namespace: "ns-foreign", Project: "p"},
+		{Name: "env-busy", Namespace: "ns-busy", Project: "p"},
+		{Name: "env-kept", Namespace: "ns-kept", Project: "p"},
+		{Name: "env-existing", Namespace: "ns-existing", Project: "p"},
+		{Name: "env-workloads", Namespace: "ns-workloads", Project: "p"},
+		{Name: "env-data", Namespace: "ns-data", Project: "p"},
+		{Name: "env-locked", Namespace: "ns-locked", Project: "p"},
+	} {
+		assert.NoError(t, ds.Add(ctx, env))
+	}
+	namespaceExists := func(name string) bool {
+		err := cli.Get(ctx, client.ObjectKey{Name: name}, &corev1.Namespace{})
+		assert.True(t, err == nil || apierrors.IsNotFound(err))
+		return err == nil
+	}
+	envExists := func(name string) bool {
+		err := ds.Get(ctx, &model.Env{Name: name})
+		return err == nil
+	}
+
+	// the namespace labeled with the env is deleted, the applications of VelaUX don't block it
+	assert.NoError(t, envService.DeleteEnv(ctx, "env-owned", true, true))
+	assert.False(t, namespaceExists("ns-owned"))
+	assert.False(t, envExists("env-owned"))
+
+	// the namespace bound to another env is refused and the env is kept
+	assert.Equal(t, bcode.ErrEnvNamespaceNotManaged, envService.DeleteEnv(ctx, "env-foreign", true, true))
+	assert.True(t, namespaceExists("ns-foreign"))
+	assert.True(t, envExists("env-foreign"))
+
+	// the applications not managed by VelaUX block the deletion
+	assert.Equal(t, bcode.ErrEnvNamespaceNotEmpty, envService.DeleteEnv(ctx, "env-busy", true, true))
+	assert.True(t, namespaceExists("ns-busy"))
+	assert.True(t, envExists("env-busy"))
+
+	// the namespace is kept by default
+	assert.NoError(t, envService.DeleteEnv(ctx, "env-kept", true, false))
+	assert.True(t, namespaceExists("ns-kept"))
+	assert.False(t, envExists("env-kept"))
+
+	// the namespace existed before the env is only labeled, it is never deleted with the env
+	assert.Equal(t, bcode.ErrEnvNamespaceNotManaged, envService.DeleteEnv(ctx, "env-existing", true, true))
+	assert.True(t, namespaceExists("ns-existing"))
+
+	// the workloads and the data not belonging to the applications of the env block the deletion
+	for _, name := range []string{"workloads", "data"} {
+		err := envService.DeleteEnv(ctx, "env-"+name, true, true)
+		assert.True(t, errors.Is(err, bcode.ErrEnvNamespaceNotEmpty))
+		assert.True(t, namespaceExists("ns-"+name))
+		assert.True(t, envExists("env-"+name))
+	}
+
+	// the env is kept if its namespace fails to be deleted, so the deletion can be retried
+	failingService := &envServiceImpl{Store: ds, KubeClient: &deleteFailingClient{Client: cli}}
+	assert.Error(t, failingService.DeleteEnv(ctx, "env-locked", true, true))
+	assert.True(t, namespaceExists("ns-locked"))
+	assert.True(t, envExists("env-locked"))
+	assert.NoError(t, envService.DeleteEnv(ctx, "env-locked", true, true))
+	assert.False(t, namespaceExists("ns-locked"))
+	assert.False(t, envExists("env-locked"))
+}
+
+func TestCreateEnvMarkNamespaces(t *testing.T) {
+	ctx := context.TODO()
+	envService, cli, _ := newTestEnvService(t, "env-mark-namespaces",
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-mark-existing"}},
+	)
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-mark", Project: "p", Namespace: "ns-mark-created", Namespaces: []string{"ns-mark-existing"}})
+	assert.NoError(t, err)
+
+	// only the namespace created by the env is annotated
+	var namespace corev1.Namespace
+	assert.NoError(t, cli.Get(ctx, client.ObjectKey{Name: "ns-mark-created"}, &namespace))
+	assert.Equal(t, "env-mark", namespace.Annotations[model.AnnotationNamespaceCreatedByEnv])
+	assert.NoError(t, cli.Get(ctx, client.ObjectKey{Name: "ns-mark-existing"}, &namespace))
+	assert.Equal(t, "env-mark", namespace.Labels[oam.LabelNamespaceOfEnvName])
+	assert.Empty(t, namespace.Annotations[model.AnnotationNamespaceCreatedByEnv])
+}
+
+func TestDeleteEnvWithApplications(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-delete-applications",
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-deployed"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-bound"}},
+		&v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "ns-deployed", Name: "app-deployed",
+			Labels: map[string]string{velatypes.LabelSourceOfTruth: velatypes.FromUX}}},
+	)
+	for _, env := range []*model.Env{
+		{Name: "env-deployed", Namespace: "ns-deployed", Project: "p"},
+		{Name: "env-bound", Namespace: "ns-bound", Project: "p"},
+	} {
+		assert.NoError(t, ds.Add(ctx, env))
+	}
+	assert.NoError(t, ds.Add(ctx, &model.EnvBinding{AppPrimaryKey: "app-draft", Name: "env-bound"}))
+
+	for _, name := range []string{"env-deployed", "env-bound"} {
+		assert.Equal(t, bcode.ErrDeleteEnvButAppExist, envService.DeleteEnv(ctx, name, false, false))
+		assert.NoError(t, ds.Get(ctx, &model.Env{Name: name}))
+		// the env is deleted with the applications when forced
+		assert.NoError(t, envService.DeleteEnv(ctx, name, true, false))
+		assert.ErrorIs(t, ds.Get(ctx, &model.Env{Name: name}), datastore.ErrRecordNotExist)
+	}
+}
+
+func TestEnvWithMultipleNamespaces(t *testing.T) {
+	ctx := context.TODO()
+	newApp := func(namespace, name string) *v1beta1.Application {
+		return &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name,
+			Labels: map[string]string{velatypes.LabelSourceOfTruth: velatypes.FromUX}}}
+	}
+	envService, cli, _ := newTestEnvService(t, "env-multiple-namespaces",
+		newApp("ns-multi-a", "app-1"),
+		newApp("ns-multi-b", "app-2"),
+		newApp("ns-multi-b", "app-3"),
+	)
+
+	// the env created before the additional namespaces are supported only has the namespace
+	legacy := &model.Env{Name: "env-legacy", Namespace: "ns-legacy"}
+	assert.Equal(t, []string{"ns-legacy"}, legacy.AllNamespaces())
+
+	env, err := envService.CreateEnv(ctx, apisv1.CreateEnvRequest{
+		Name:       "env-multi",
+		Project:    "multi",
+		Namespace:  "ns-multi-a",
+		Namespaces: []string{"ns-multi-b", "ns-multi-a", "ns-multi-b"},
+		Labels:     map[string]string{"tier": "backend"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ns-multi-b"}, env.Namespaces)
+
+	for _, ns := range []string{"ns-multi-a", "ns-multi-b"} {
+		var namespace corev1.Namespace
+		assert.NoError(t, cli.Get(ctx, client.ObjectKey{Name: ns}, &namespace))
+		assert.Equal(t, "env-multi", namespace.Labels[oam.LabelNamespaceOfEnvName])
+		assert.Equal(t, "backend", namespace.Labels["tier"])
+		var bindings rbacv1.RoleBindingList
+		assert.NoError(t, cli.List(ctx, &bindings, client.InNamespace(ns)))
+		assert.Len(t, bindings.Items, 1)
+	}
+
+	stored, err := envService.GetEnv(ctx, "env-multi")
+	assert.NoError(t, err)
+	count, err := envService.GetAppCountInEnv(ctx, stored)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// only the namespaces of the requested envs are listed, each of them once
+	listing := &countingClient{Client: cli}
+	counts, err := (&envServiceImpl{KubeClient: listing}).getAppCountInNamespaces(ctx, stored, stored, legacy)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"ns-multi-a": 1, "ns-multi-b": 2, "ns-legacy": 0}, counts)
+	assert.Equal(t, []string{"ns-multi-a", "ns-multi-b", "ns-legacy"}, listing.listNamespaces)
+
+	// the namespace bound to the env can't be added to another env
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-other", Project: "multi", Namespaces: []string{"ns-multi-b"}})
+	assert.Equal(t, bcode.ErrEnvNamespaceAlreadyBound, err)
+
+	assert.NoError(t, envService.DeleteEnv(ctx, "env-multi", true, true))
+	for _, ns := range []string{"ns-multi-a", "ns-multi-b"} {
+		assert.True(t, apierrors.IsNotFound(cli.Get(ctx, client.ObjectKey{Name: ns}, &corev1.Namespace{})))
+	}
+}
+
+func TestUpdateEnvConflict(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, _ := newTestEnvService(t, "env-update-conflict")
+
+	created, err := envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-conflict", Project: "conflict"})
+	assert.NoError(t, err)
+	loaded := created.UpdateTime
+
+	// the first admin saves the env loaded by both admins
+	time.Sleep(5 * time.Millisecond)
+	updated, err := envService.UpdateEnv(ctx, "env-conflict", apisv1.UpdateEnvRequest{Alias: "first", ExpectedUpdateTime: &loaded})
+	assert.NoError(t, err)
+	assert.Equal(t, "first", updated.Alias)
+
+	// the second admin still holds the stale env
+	_, err = envService.UpdateEnv(ctx, "env-conflict", apisv1.UpdateEnvRequest{Alias: "second", ExpectedUpdateTime: &loaded})
+	assert.Equal(t, bcode.ErrEnvUpdateConflict, err)
+	env, err := envService.GetEnv(ctx, "env-conflict")
+	assert.NoError(t, err)
+	assert.Equal(t, "first", env.Alias)
+
+	// reloading the env makes the update succeed
+	reloaded := env.UpdateTime
+	updated, err = envService.UpdateEnv(ctx, "env-conflict", apisv1.UpdateEnvRequest{Alias: "second", ExpectedUpdateTime: &reloaded})
+	assert.NoError(t, err)
+	assert.Equal(t, "second", updated.Alias)
+
+	// the check is skipped without the expected update time
+	_, err = envService.UpdateEnv(ctx, "env-conflict", apisv1.UpdateEnvRequest{Alias: "third"})
+	assert.NoError(t, err)
+}
+
+// racingStore updates the env by others right before the env is written
+type racingStore struct {
+	datastore.DataStore
+	envName string
+}
+
+func (r *racingStore) PutIfUnchanged(ctx context.Context, entity datastore.Entity, updateTime time.Time) error {
+	if env, ok := entity.(*model.Env); ok && env.Name == r.envName {
+		other := &model.Env{Name: env.Name}
+		if err := r.DataStore.Get(ctx, other); err != nil {
+			return err
+		}
+		other.Alias = "others"
+		time.Sleep(5 * time.Millisecond)
+		if err := r.DataStore.Put(ctx, other); err != nil {
+			return err
+		}
+	}
+	return r.DataStore.PutIfUnchanged(ctx, entity, updateTime)
+}
+
+func TestUpdateEnvConcurrently(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-update-concurrently")
+	_, err := envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-racing", Project: "racing"})
+	assert.NoError(t, err)
+
+	// the env changed by others between the check and the write is not overwritten
+	envService.Store = &racingStore{DataStore: ds, envName: "env-racing"}
+	_, err = envService.UpdateEnv(ctx, "env-racing", apisv1.UpdateEnvRequest{Alias: "mine"})
+	assert.Equal(t, bcode.ErrEnvUpdateConflict, err)
+	env, err := repository.GetEnv(ctx, ds, "env-racing")
+	assert.NoError(t, err)
+	assert.Equal(t, "others", env.Alias)
+}
+
+func TestEnvAppQuota(t *testing.T) {
+	ctx := context.TODO()
+	newApp := func(namespace, name string) *v1beta1.Application {
+		return &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name,
+			Labels: map[string]string{velatypes.LabelSourceOfTruth: velatypes.FromUX}}}
+	}
+	envService, _, _ := newTestEnvService(t, "env-app-quota",
+		newApp("ns-quota", "app-1"),
+		newApp("ns-quota", "app-2"),
+	)
+
+	// the existing applications exceed the quota
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-quota", Project: "quota", Namespace: "ns-quota", AppQuota: 1})
+	assert.True(t, errors.Is(err, bcode.ErrEnvQuotaExceeded))
+
+	// the env is at the limit
+	env, err := envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-quota", Project: "quota", Namespace: "ns-quota", AppQuota: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, env.AppQuota)
+	assert.Equal(t, 2, env.AppCount)
+	stored, err := envService.GetEnv(ctx, "env-quota")
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(envService.CheckAppQuota(ctx, stored), bcode.ErrEnvQuotaExceeded))
+
+	assert.True(t, hasAppQuota([]*model.Env{{Name: "env-no-quota"}, stored}))
+
+	// the quota can't be lower than the existing applications
+	quota := 1
+	_, err = envService.UpdateEnv(ctx, "env-quota", apisv1.UpdateEnvRequest{AppQuota: &quota})
+	assert.True(t, errors.Is(err, bcode.ErrEnvQuotaExceeded))
+
+	quota = 3
+	env, err = envService.UpdateEnv(ctx, "env-quota", apisv1.UpdateEnvRequest{AppQuota: &quota})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, env.AppQuota)
+	assert.Equal(t, 2, env.AppCount)
+	stored, err = envService.GetEnv(ctx, "env-quota")
+	assert.NoError(t, err)
+	assert.NoError(t, envService.CheckAppQuota(ctx, stored))
+
+	// no limit
+	quota = 0
+	_, err = envService.UpdateEnv(ctx, "env-quota", apisv1.UpdateEnvRequest{AppQuota: &quota})
+	assert.NoError(t, err)
+	stored, err = envService.GetEnv(ctx, "env-quota")
+	assert.NoError(t, err)
+	assert.NoError(t, envService.CheckAppQuota(ctx, stored))
+}
+
+func TestGetEnvDetail(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-detail")
+
+	assert.NoError(t, ds.Add(ctx, &model.Project{Name: "detail", Alias: "Detail Project"}))
+	assert.NoError(t, ds.Add(ctx, &model.Target{Name: "detail-target", Alias: "Detail Target", Project: "detail"}))
+	assert.NoError(t, ds.Add(ctx, &model.Target{Name: "other-target", Alias: "Other Target", Project: "detail"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-detail", Project: "detail", Namespace: "ns-detail", Targets: []string{"detail-target", "missing-target"}}))
+
+	env, err := envService.GetEnvDetail(ctx, "env-detail")
+	assert.NoError(t, err)
+	assert.Equal(t, apisv1.NameAlias{Name: "detail", Alias: "Detail Project"}, env.Project)
+	assert.Equal(t, []apisv1.EnvTarget{
+		{NameAlias: apisv1.NameAlias{Name: "detail-target", Alias: "Detail Target"}},
+		{NameAlias: apisv1.NameAlias{Name: "missing-target"}},
+	}, env.Targets)
+
+	_, err = envService.GetEnvDetail(ctx, "env-not-exist")
+	assert.Equal(t, bcode.ErrEnvNotExisted, err)
+}
+
+func TestCreateEnvIdempotent(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-create-retry")
+	assert.NoError(t, ds.Add(ctx, &model.Target{Name: "retry-target-1", Project: "retry"}))
+	assert.NoError(t, ds.Add(ctx, &model.Target{Name: "retry-target-2", Project: "retry"}))
+
+	req := apisv1.CreateEnvRequest{
+		Name:    "env-retry",
+		Alias:   "Retry",
+		Project: "retry",
+		Targets: []string{"retry-target-1", "retry-target-2"},
+		Labels:  map[string]string{"tier": "backend"},
+	}
+	created, err := envService.CreateEnv(ctx, req)
+	assert.NoError(t, err)
+
+	// the retry with the same spec returns the existing env, the order of the targets doesn't matter
+	retry := req
+	retry.Targets = []string{"retry-target-2", "retry-target-1"}
+	env, err := envService.CreateEnv(ctx, retry)
+	assert.NoError(t, err)
+	assert.Equal(t, created.Name, env.Name)
+	assert.Equal(t, created.Namespace, env.Namespace)
+	assert.Equal(t, 2, len(env.Targets))
+	// the request is not changed by the check
+	assert.Equal(t, []string{"retry-target-2", "retry-target-1"}, retry.Targets)
+
+	// the retry with a different spec conflicts with the existing env
+	conflict := req
+	conflict.Description = "another env"
+	_, err = envService.CreateEnv(ctx, conflict)
+	assert.Equal(t, bcode.ErrEnvSpecConflict, err)
+	conflict = req
+	conflict.Targets = []string{"retry-target-1"}
+	_, err = envService.CreateEnv(ctx, conflict)
+	assert.Equal(t, bcode.ErrEnvSpecConflict, err)
+
+	stored, err := envService.GetEnv(ctx, "env-retry")
+	assert.NoError(t, err)
+	assert.Equal(t, "", stored.Description)
+	assert.Equal(t, 2, len(stored.Targets))
+}
+
+func TestValidateEnvTargets(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-validate-targets")
+
+	for _, name := range []string{"target-free", "target-dev", "target-archived", "target-other-project"} {
+		assert.NoError(t, ds.Add(ctx, &model.Target{Name: name, Project: "validate"}))
+	}
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-dev", Project: "validate", Namespace: "env-dev", Targets: []string{"target-dev"}}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-archived", Project: "validate", Namespace: "env-archived", Targets: []string{"target-archived"}, Archived: true}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-other", Project: "other", Namespace: "env-other", Targets: []string{"target-other-project"}}))
+
+	resp, err := envService.ValidateEnvTargets(ctx, "validate", "", []string{"target-free", "target-dev", "target-missing", "target-archived", "target-other-project", "target-free"})
+	assert.NoError(t, err)
+	assert.Equal(t, []apisv1.EnvTargetStatus{
+		{Name: "target-free", Status: EnvTargetStatusAvailable},
+		{Name: "target-dev", Status: EnvTargetStatusConflict, Env: "env-dev"},
+		{Name: "target-missing", Status: EnvTargetStatusNotExist},
+		{Name: "target-archived", Status: EnvTargetStatusAvailable},
+		{Name: "target-other-project", Status: EnvTargetStatusAvailable},
+	}, resp.Targets)
+
+	// the targets of the env itself are not conflicting when updating it
+	resp, err = envService.ValidateEnvTargets(ctx, "validate", "env-dev", []string{"target-dev"})
+	assert.NoError(t, err)
+	assert.Equal(t, []apisv1.EnvTargetStatus{{Name: "target-dev", Status: EnvTargetStatusAvailable}}, resp.Targets)
+}
+
+func TestEnvVariables(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, _ := newTestEnvService(t, "env-variables")
+
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-invalid-variables", Project: "variables", Variables: map[string]string{"not valid": "x"}})
+	assert.True(t, errors.Is(err, bcode.ErrEnvVariableInvalid))
+
+	env, err := envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-variables", Project: "variables", Variables: map[string]string{"registry": "docker.io"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"registry": "docker.io"}, env.Variables)
+
+	value, err := envService.GetEnvVariable(ctx, "env-variables", "registry")
+	assert.NoError(t, err)
+	assert.Equal(t, "docker.io", value)
+	_, err = envService.GetEnvVariable(ctx, "env-variables", "domain")
+	assert.Equal(t, bcode.ErrEnvVariableNotExist, err)
+
+	assert.NoError(t, envService.SetEnvVariable(ctx, "env-variables", "domain", "example.com"))
+	assert.True(t, errors.Is(envService.SetEnvVariable(ctx, "env-variables", "a/b", "x"), bcode.ErrEnvVariableInvalid))
+	detail, err := envService.GetEnvDetail(ctx, "env-variables")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"registry": "docker.io", "domain": "example.com"}, detail.Variables)
+
+	// the variables are replaced by updating the env, the empty map clears them
+	env, err = envService.UpdateEnv(ctx, "env-variables", apisv1.UpdateEnvRequest{Variables: map[string]string{"domain": "example.org"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"domain": "example.org"}, env.Variables)
+	env, err = envService.UpdateEnv(ctx, "env-variables", apisv1.UpdateEnvRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"domain": "example.org"}, env.Variables)
+	env, err = envService.UpdateEnv(ctx, "env-variables", apisv1.UpdateEnvRequest{Variables: map[string]string{}})
+	assert.NoError(t, err)
+	assert.Empty(t, env.Variables)
+
+	_, err = envService.GetEnvVariable(ctx, "env-not-exist", "domain")
+	assert.Equal(t, bcode.ErrEnvNotExisted, err)
+}
+
+func TestEnvReservedLabels(t *testing.T) {
+	ctx := context.TODO()
+	envService, cli, _ := newTestEnvService(t, "env-reserved-labels")
+
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-reserved", Project: "labels",
+		Labels: map[string]string{oam.LabelNamespaceOfEnvName: "other"}})
+	assert.True(t, errors.Is(err, bcode.ErrEnvLabelsInvalid))
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-reserved", Project: "labels", Labels: map[string]string{"team": "a"}})
+	assert.NoError(t, err)
+	_, err = envService.UpdateEnv(ctx, "env-reserved", apisv1.UpdateEnvRequest{
+		Labels: map[string]string{oam.LabelControlPlaneNamespaceUsage: ""}})
+	assert.True(t, errors.Is(err, bcode.ErrEnvLabelsInvalid))
+
+	// the reserved labels are kept on the namespace when the labels of the env are replaced
+	_, err = envService.UpdateEnv(ctx, "env-reserved", apisv1.UpdateEnvRequest{Labels: map[string]string{"team": "b"}})
+	assert.NoError(t, err)
+	var namespace corev1.Namespace
+	assert.NoError(t, cli.Get(ctx, types.NamespacedName{Name: "env-reserved"}, &namespace))
+	assert.Equal(t, "env-reserved", namespace.Labels[oam.LabelNamespaceOfEnvName])
+	assert.Equal(t, oam.VelaNamespaceUsageEnv, namespace.Labels[oam.LabelControlPlaneNamespaceUsage])
+	assert.Equal(t, "b", namespace.Labels["team"])
+
+	namespace.Labels = map[string]string{oam.LabelNamespaceOfEnvName: "env-reserved", "team": "b"}
+	assert.NoError(t, replaceEnvLabels(map[string]string{oam.LabelNamespaceOfEnvName: "x", "team": "b"}, map[string]string{oam.LabelNamespaceOfEnvName: ""})(&namespace))
+	assert.Equal(t, map[string]string{oam.LabelNamespaceOfEnvName: "env-reserved"}, namespace.Labels)
+}
+
+func TestEnvDefaultAppLabels(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, _ := newTestEnvService(t, "env-default-app-labels")
+
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-invalid-labels", Project: "labels", DefaultAppLabels: map[string]string{"team": "not valid"}})
+	assert.True(t, errors.Is(err, bcode.ErrEnvDefaultAppLabelsInvalid))
+
+	env, err := envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-labels", Project: "labels", DefaultAppLabels: map[string]string{"team": "a", "cost-center": "100"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "a", "cost-center": "100"}, env.DefaultAppLabels)
+
+	// the labels are replaced by updating the env, the empty map clears them
+	_, err = envService.UpdateEnv(ctx, "env-labels", apisv1.UpdateEnvRequest{DefaultAppLabels: map[string]string{"a/b/c": "x"}})
+	assert.True(t, errors.Is(err, bcode.ErrEnvDefaultAppLabelsInvalid))
+	env, err = envService.UpdateEnv(ctx, "env-labels", apisv1.UpdateEnvRequest{DefaultAppLabels: map[string]string{"team": "b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "b"}, env.DefaultAppLabels)
+	env, err = envService.UpdateEnv(ctx, "env-labels", apisv1.UpdateEnvRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "b"}, env.DefaultAppLabels)
+
+	envModel, err := envService.GetEnv(ctx, "env-labels")
+	assert.NoError(t, err)
+	labels := renderAppLabels(envModel, &model.Application{Name: "app", Labels: map[string]string{"team": "c", "tier": "web"}})
+	assert.Equal(t, "c", labels["team"])
+	assert.Equal(t, "web", labels["tier"])
+	assert.Equal(t, "app", labels[oam.AnnotationAppName])
+
+	env, err = envService.UpdateEnv(ctx, "env-labels", apisv1.UpdateEnvRequest{DefaultAppLabels: map[string]string{}})
+	assert.NoError(t, err)
+	assert.Empty(t, env.DefaultAppLabels)
+}
+
+func TestEnvAnnotations(t *testing.T) {
+	ctx := context.TODO()
+	envService, cli, _ := newTestEnvService(t, "env-annotations")
+
+	_, err = envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-invalid-annotations", Project: "annotations", Annotations: map[string]string{"not valid": "x"}})
+	assert.True(t, errors.Is(err, bcode.ErrEnvAnnotationsInvalid))
+
+	env, err := envService.CreateEnv(ctx, apisv1.CreateEnvRequest{Name: "env-annotations", Project: "annotations", Annotations: map[string]string{"owner": "team a", "example.com/ticket": "OPS-1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "team a", "example.com/ticket": "OPS-1"}, env.Annotations)
+
+	// the annotations are only recorded in the datastore
+	var namespace corev1.Namespace
+	assert.NoError(t, cli.Get(ctx, types.NamespacedName{Name: env.Namespace}, &namespace))
+	assert.NotContains(t, namespace.Labels, "owner")
+	assert.NotContains(t, namespace.Annotations, "owner")
+
+	_, err = envService.UpdateEnv(ctx, "env-annotations", apisv1.UpdateEnvRequest{Annotations: map[string]string{"a/b/c": "x"}})
+	assert.True(t, errors.Is(err, bcode.ErrEnvAnnotationsInvalid))
+	env, err = envService.UpdateEnv(ctx, "env-annotations", apisv1.UpdateEnvRequest{Annotations: map[string]string{"owner": "team b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "team b"}, env.Annotations)
+	env, err = envService.UpdateEnv(ctx, "env-annotations", apisv1.UpdateEnvRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "team b"}, env.Annotations)
+
+	env, err = envService.UpdateEnv(ctx, "env-annotations", apisv1.UpdateEnvRequest{Annotations: map[string]string{}})
+	assert.NoError(t, err)
+	assert.Empty(t, env.Annotations)
+}
+
+func TestFindOrphanedTargets(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-orphaned-targets")
+
+	assert.NoError(t, ds.Add(ctx, &model.Target{Name: "target-live", Project: "orphaned"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-dangling", Project: "orphaned", Namespace: "env-dangling", Targets: []string{"target-live", "target-deleted"}}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-healthy", Project: "orphaned", Namespace: "env-healthy", Targets: []string{"target-live"}}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-other", Project: "other", Namespace: "env-other", Targets: []string{"target-gone"}}))
+
+	resp, err := envService.FindOrphanedTargets(ctx, "orphaned", false)
+	assert.NoError(t, err)
+	assert.False(t, resp.Repaired)
+	assert.Equal(t, []apisv1.OrphanedEnvTarget{{Env: "env-dangling", Target: "target-deleted"}}, resp.Targets)
+	env, err := envService.GetEnv(ctx, "env-dangling")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"target-live", "target-deleted"}, env.Targets)
+
+	resp, err = envService.FindOrphanedTargets(ctx, "orphaned", true)
+	assert.NoError(t, err)
+	assert.True(t, resp.Repaired)
+	assert.Equal(t, 1, len(resp.Targets))
+	env, err = envService.GetEnv(ctx, "env-dangling")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"target-live"}, env.Targets)
+
+	resp, err = envService.FindOrphanedTargets(ctx, "orphaned", false)
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Targets)
+	// the envs of the other projects are not touched
+	env, err = envService.GetEnv(ctx, "env-other")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"target-gone"}, env.Targets)
+
+	// the env changed by others while repairing is not overwritten
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-racing", Project: "racing", Namespace: "env-racing", Targets: []string{"target-live", "target-gone"}}))
+	envService.Store = &racingStore{DataStore: ds, envName: "env-racing"}
+	_, err = envService.FindOrphanedTargets(ctx, "racing", true)
+	assert.Equal(t, bcode.ErrEnvUpdateConflict, err)
+	env, err = envService.GetEnv(ctx, "env-racing")
+	assert.NoError(t, err)
+	assert.Equal(t, "others", env.Alias)
+	assert.Equal(t, []string{"target-live", "target-gone"}, env.Targets)
+}
+
+// fakeMigrationServices binds and deploys the applications in the datastore and the fake client
+type fakeMigrationServices struct {
+	EnvBindingService
+	ApplicationService
+	WorkflowService
+	store    datastore.DataStore
+	cli      client.Client
+	deployed []string
+}
+
+func (f *fakeMigrationServices) CreateEnvBinding(ctx context.Context, app *model.Application, req apisv1.CreateApplicationEnvbindingRequest) (*apisv1.EnvBinding, error) {
+	if err := f.store.Add(ctx, &model.EnvBinding{AppPrimaryKey: app.PrimaryKey(), Name: req.Name}); err != nil {
+		return nil, err
+	}
+	isDefault := false
+	if err := f.store.Add(ctx, &model.Workflow{Name: repository.ConvertWorkflowName(req.Name), EnvName: req.Name, AppPrimaryKey: app.PrimaryKey(), Default: &isDefault}); err != nil {
+		return nil, err
+	}
+	return &req.EnvBinding, nil
+}
+
+func (f *fakeMigrationServices) Deploy(ctx context.Context, app *model.Application, req apisv1.ApplicationDeployRequest) (*apisv1.ApplicationDeployResponse, error) {
+	workflow := &model.Workflow{AppPrimaryKey: app.PrimaryKey(), Name: req.WorkflowName}
+	if err := f.store.Get(ctx, workflow); err != nil {
+		return nil, err
+	}
+	env, err := repository.GetEnv(ctx, f.store, workflow.EnvName)
+	if err != nil {
+		return nil, err
+	}
+	f.deployed = append(f.deployed, app.Name)
+	return nil, f.cli.Create(ctx, &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: app.Name, Namespace: env.Namespace}})
+}
+
+func (f *fakeMigrationServices) DeleteWorkflow(ctx context.Context, app *model.Application, workflowName string) error {
+	return f.store.Delete(ctx, &model.Workflow{AppPrimaryKey: app.PrimaryKey(), Name: workflowName})
+}
+
+func TestMigrateApplications(t *testing.T) {
+	ctx := context.TODO()
+	envService, cli, ds := newTestEnvService(t, "env-migrate",
+		&v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app-deployed", Namespace: "migrate-old", Labels: map[string]string{velatypes.LabelSourceOfTruth: velatypes.FromUX}}},
+	)
+	services := &fakeMigrationServices{store: ds, cli: cli}
+	envService.EnvBindingService, envService.ApplicationService, envService.WorkflowService = services, services, services
+
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-old", Project: "migrate", Namespace: "migrate-old"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-new", Project: "migrate", Namespace: "migrate-new"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-full", Project: "migrate", Namespace: "migrate-full", AppQuota: 1}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-foreign", Project: "other", Namespace: "migrate-foreign"}))
+	isDefault := true
+	for _, name := range []string{"app-deployed", "app-draft", "app-both"} {
+		assert.NoError(t, ds.Add(ctx, &model.Application{Name: name, Project: "migrate"}))
+		assert.NoError(t, ds.Add(ctx, &model.EnvBinding{AppPrimaryKey: name, Name: "env-old", ComponentsPatch: []model.ComponentPatch{{Name: "web"}}}))
+		assert.NoError(t, ds.Add(ctx, &model.Workflow{Name: repository.ConvertWorkflowName("env-old"), EnvName: "env-old", AppPrimaryKey: name, Default: &isDefault}))
+	}
+	assert.NoError(t, ds.Add(ctx, &model.EnvBinding{AppPrimaryKey: "app-both", Name: "env-new"}))
+
+	_, err = envService.MigrateApplications(ctx, "env-old", "env-old", false)
+	assert.Equal(t, bcode.ErrEnvMigrateToSelf, err)
+	_, err = envService.MigrateApplications(ctx, "env-old", "env-foreign", false)
+	assert.Equal(t, bcode.ErrEnvProjectMismatch, err)
+	_, err = envService.MigrateApplications(ctx, "env-old", "env-full", true)
+	assert.True(t, errors.Is(err, bcode.ErrEnvQuotaExceeded))
+
+	status := func(resp *apisv1.MigrateApplicationsResponse) map[string]string {
+		res := map[string]string{}
+		for _, app := range resp.Applications {
+			res[app.Name] = app.Status
+		}
+		return res
+	}
+	// the dry run changes nothing
+	resp, err := envService.MigrateApplications(ctx, "env-old", "env-new", true)
+	assert.NoError(t, err)
+	assert.True(t, resp.DryRun)
+	assert.Equal(t, map[string]string{"app-both": AppMigrationStatusSkipped, "app-deployed": AppMigrationStatusPending, "app-draft": AppMigrationStatusPending}, status(resp))
+	assert.NoError(t, ds.Get(ctx, &model.EnvBinding{AppPrimaryKey: "app-deployed", Name: "env-old"}))
+	assert.Empty(t, services.deployed)
+
+	resp, err = envService.MigrateApplications(ctx, "env-old", "env-new", false)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"app-both": AppMigrationStatusSkipped, "app-deployed": AppMigrationStatusMigrated, "app-draft": AppMigrationStatusMigrated}, status(resp))
+	assert.Equal(t, []string{"app-deployed"}, services.deployed)
+
+	// the deployed application is moved to the namespace of the target env
+	var app v1beta1.Application
+	assert.True(t, apierrors.IsNotFound(cli.Get(ctx, types.NamespacedName{Namespace: "migrate-old", Name: "app-deployed"}, &app)))
+	assert.NoError(t, cli.Get(ctx, types.NamespacedName{Namespace: "migrate-new", Name: "app-deployed"}, &app))
+
+	// the bindings keep the patches and the default workflow
+	binding := &model.EnvBinding{AppPrimaryKey: "app-draft", Name: "env-new"}
+	assert.NoError(t, ds.Get(ctx, binding))
+	assert.Equal(t, "web", binding.ComponentsPatch[0].Name)
+	assert.True(t, errors.Is(ds.Get(ctx, &model.EnvBinding{AppPrimaryKey: "app-draft", Name: "env-old"}), datastore.ErrRecordNotExist))
+	workflow, err := repository.GetWorkflowByEnv(ctx, ds, &model.Application{Name: "app-draft"}, "env-new")
+	assert.NoError(t, err)
+	assert.True(t, *workflow.Default)
+	_, err = repository.GetWorkflowByEnv(ctx, ds, &model.Application{Name: "app-draft"}, "env-old")
+	assert.Equal(t, bcode.ErrWorkflowNotExist, err)
+	// the skipped application stays in the source env
+	assert.NoError(t, ds.Get(ctx, &model.EnvBinding{AppPrimaryKey: "app-both", Name: "env-old"}))
+}
+
+func TestListAllEnvs(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-list-all")
+
+	assert.NoError(t, ds.Add(ctx, &model.User{Name: "platform-admin", UserRoles: []string{model.RoleAdmin}}))
+	assert.NoError(t, ds.Add(ctx, &model.User{Name: "developer"}))
+	assert.NoError(t, ds.Add(ctx, &model.Project{Name: "team-a", Alias: "Team A"}))
+	assert.NoError(t, ds.Add(ctx, &model.Project{Name: "team-b", Alias: "Team B"}))
+	assert.NoError(t, ds.Add(ctx, &model.Target{Name: "target-a", Alias: "Target A", Project: "team-a"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-a", Project: "team-a", Namespace: "env-a", Targets: []string{"target-a"}}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-b", Project: "team-b", Namespace: "env-b"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-orphan", Project: "team-deleted", Namespace: "env-orphan"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-archived", Project: "team-b", Namespace: "env-archived", Archived: true}))
+
+	_, err = envService.ListAllEnvs(ctx, 0, 0, apisv1.ListEnvOptions{})
+	assert.Equal(t, bcode.ErrUnauthorized, err)
+	_, err = envService.ListAllEnvs(context.WithValue(ctx, &apisv1.CtxKeyUser, "developer"), 0, 0, apisv1.ListEnvOptions{})
+	assert.Equal(t, bcode.ErrUnauthorized, err)
+	_, err = envService.ListAllEnvs(context.WithValue(ctx, &apisv1.CtxKeyUser, "nobody"), 0, 0, apisv1.ListEnvOptions{})
+	assert.Equal(t, bcode.ErrUnauthorized, err)
+
+	adminCtx := context.WithValue(ctx, &apisv1.CtxKeyUser, "platform-admin")
+	resp, err := envService.ListAllEnvs(adminCtx, 0, 0, apisv1.ListEnvOptions{SortBy: EnvSortByName})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), resp.Total)
+	var names []string
+	for _, env := range resp.Envs {
+		names = append(names, env.Name)
+	}
+	assert.Equal(t, []string{"env-a", "env-b", "env-orphan"}, names)
+	assert.Equal(t, apisv1.NameAlias{Name: "team-a", Alias: "Team A"}, resp.Envs[0].Project)
+	assert.Equal(t, "Target A", resp.Envs[0].Targets[0].Alias)
+
+	resp, err = envService.ListAllEnvs(adminCtx, 1, 10, apisv1.ListEnvOptions{Project: "team-b", IncludeArchived: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Total)
+	assert.Equal(t, 2, len(resp.Envs))
+}
+
+func TestListEnvsByTarget(t *testing.T) {
+	ctx := context.TODO()
+	envService, cli, ds := newTestEnvService(t, "env-list-by-target")
+
+	assert.NoError(t, ds.Add(ctx, &model.User{Name: "platform-admin", UserRoles: []string{model.RoleAdmin}}))
+	assert.NoError(t, ds.Add(ctx, &model.Target{Name: "target-prod", Project: "team-a"}))
+	assert.NoError(t, ds.Add(ctx, &model.Target{Name: "target-dev", Project: "team-a"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-prod", Project: "team-a", Namespace: "env-prod", Targets: []string{"target-dev", "target-prod"}}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-dev", Project: "team-a", Namespace: "env-dev", Targets: []string{"target-dev"}}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-empty", Project: "team-a", Namespace: "env-empty"}))
+
+	adminCtx := context.WithValue(ctx, &apisv1.CtxKeyUser, "platform-admin")
+	resp, err := envService.ListAllEnvs(adminCtx, 1, 10, apisv1.ListEnvOptions{Target: "target-prod"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Total)
+	assert.Equal(t, 1, len(resp.Envs))
+	assert.Equal(t, "env-prod", resp.Envs[0].Name)
+
+	resp, err = envService.ListAllEnvs(adminCtx, 0, 0, apisv1.ListEnvOptions{Target: "target-not-exist"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), resp.Total)
+	assert.Empty(t, resp.Envs)
+
+	// the envs of the projects that the user doesn't join are not listed
+	assert.NoError(t, ds.Add(ctx, &model.Project{Name: "team-a"}))
+	assert.NoError(t, ds.Add(ctx, &model.Project{Name: "team-b"}))
+	assert.NoError(t, ds.Add(ctx, &model.ProjectUser{ProjectName: "team-a", Username: "team-a-member"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-other", Project: "team-b", Namespace: "env-other", Targets: []string{"target-prod"}}))
+	envService.ProjectService = NewTestProjectService(ds, cli)
+	memberCtx := context.WithValue(ctx, &apisv1.CtxKeyUser, "team-a-member")
+	resp, err = envService.ListEnvs(memberCtx, 1, 10, apisv1.ListEnvOptions{Target: "target-prod"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Total)
+	assert.Equal(t, 1, len(resp.Envs))
+	assert.Equal(t, "env-prod", resp.Envs[0].Name)
+
+	resp, err = envService.ListEnvs(memberCtx, 1, 10, apisv1.ListEnvOptions{Target: "target-dev"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Total)
+}
+
+// fakeUserProjectService lists the projects of the users from the map
+type fakeUserProjectService struct {
+	ProjectService
+	projects map[string][]string
+	aliases  map[string]string
+}
+
+func (f *fakeUserProjectService) ListUserProjects(ctx context.Context, userName string) ([]*apisv1.ProjectBase, error) {
+	var projects []*apisv1.ProjectBase
+	for _, name := range f.projects[userName] {
+		projects = append(projects, &apisv1.ProjectBase{Name: name, Alias: f.aliases[name]})
+	}
+	return projects, nil
+}
+
+func TestCompareEnvs(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-compare",
+		&v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "env-dev", Labels: map[string]string{velatypes.LabelSourceOfTruth: velatypes.FromUX}}},
+		&v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app-2", Namespace: "env-dev-extra", Labels: map[string]string{velatypes.LabelSourceOfTruth: velatypes.FromUX}}},
+		&v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "env-prod", Labels: map[string]string{velatypes.LabelSourceOfTruth: velatypes.FromUX}}},
+	)
+	envService.ProjectService = &fakeUserProjectService{projects: map[string][]string{
+		"dev-user":   {"team-a"},
+		"other-user": {"team-b"},
+	}}
+
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-dev", Alias: "Dev", Project: "team-a", Namespace: "env-dev", Namespaces: []string{"env-dev-extra"},
+		Targets: []string{"target-shared", "target-dev"}, Labels: map[string]string{"team": "a", "tier": "dev"}, Variables: map[string]string{"registry": "docker.io", "debug": "true"}}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-prod", Alias: "Prod", Project: "team-a", Namespace: "env-prod",
+		Targets: []string{"target-prod", "target-shared"}, Labels: map[string]string{"team": "a", "tier": "prod"}, Variables: map[string]string{"registry": "docker.io"}}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-other", Project: "team-b", Namespace: "env-other"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-dev-shared", Project: "team-a", Namespace: "env-dev"}))
+
+	_, err = envService.CompareEnvs(ctx, "env-dev", "env-prod")
+	assert.Equal(t, bcode.ErrUnauthorized, err)
+	devCtx := context.WithValue(ctx, &apisv1.CtxKeyUser, "dev-user")
+	_, err = envService.CompareEnvs(devCtx, "env-dev", "env-other")
+	assert.Equal(t, bcode.ErrEnvNotAccessible, err)
+	_, err = envService.CompareEnvs(context.WithValue(ctx, &apisv1.CtxKeyUser, "other-user"), "env-dev", "env-other")
+	assert.Equal(t, bcode.ErrEnvNotAccessible, err)
+	_, err = envService.CompareEnvs(devCtx, "env-dev", "env-not-exist")
+	assert.Equal(t, bcode.ErrEnvNotExisted, err)
+
+	resp, err := envService.CompareEnvs(devCtx, "env-dev", "env-prod")
+	assert.NoError(t, err)
+	assert.Equal(t, &apisv1.CompareEnvsResponse{
+		EnvA:       apisv1.NameAlias{Name: "env-dev", Alias: "Dev"},
+		EnvB:       apisv1.NameAlias{Name: "env-prod", Alias: "Prod"},
+		Namespaces: apisv1.EnvNamesComparison{Common: []string{}, OnlyInA: []string{"env-dev", "env-dev-extra"}, OnlyInB: []string{"env-prod"}},
+		AppCount:   apisv1.EnvCountComparison{A: 2, B: 1},
+		Targets:    apisv1.EnvNamesComparison{Common: []string{"target-shared"}, OnlyInA: []string{"target-dev"}, OnlyInB: []string{"target-prod"}},
+		Labels: []apisv1.EnvEntryComparison{
+			{Key: "team", A: "a", B: "a", Status: EnvEntrySame},
+			{Key: "tier", A: "dev", B: "prod", Status: EnvEntryChanged},
+		},
+		Variables: []apisv1.EnvEntryComparison{
+			{Key: "debug", A: "true", Status: EnvEntryOnlyInA},
+			{Key: "registry", A: "docker.io", B: "docker.io", Status: EnvEntrySame},
+		},
+	}, resp)
+
+	resp, err = envService.CompareEnvs(devCtx, "env-prod", "env-prod")
+	assert.NoError(t, err)
+	assert.True(t, resp.Identical)
+
+	// the envs sharing the namespace differ if the other namespaces of them differ
+	resp, err = envService.CompareEnvs(devCtx, "env-dev", "env-dev-shared")
+	assert.NoError(t, err)
+	assert.Equal(t, apisv1.EnvNamesComparison{Common: []string{"env-dev"}, OnlyInA: []string{"env-dev-extra"}, OnlyInB: []string{}}, resp.Namespaces)
+	assert.False(t, resp.Identical)
+}
+
+func TestListEnvsGroupedByProject(t *testing.T) {
+	ctx := context.TODO()
+	envService, _, ds := newTestEnvService(t, "env-grouped-by-project")
+	envService.ProjectService = &fakeUserProjectService{
+		projects: map[string][]string{"dev-user": {"team-b", "team-a", "team-empty"}},
+		aliases:  map[string]string{"team-a": "Team A", "team-b": "Team B"},
+	}
+
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-a-prod", Project: "team-a", Namespace: "env-a-prod"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-a-dev", Project: "team-a", Namespace: "env-a-dev"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-a-old", Project: "team-a", Namespace: "env-a-old", Archived: true}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-b", Project: "team-b", Namespace: "env-b"}))
+	assert.NoError(t, ds.Add(ctx, &model.Env{Name: "env-c", Project: "team-c", Namespace: "env-c"}))
+
+	_, err = envService.ListEnvsGroupedByProject(ctx)
+	assert.Equal(t, bcode.ErrUnauthorized, err)
+
+	resp, err := envService.ListEnvsGroupedByProject(context.WithValue(ctx, &apisv1.CtxKeyUser, "nobody"))
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Projects)
+
+	resp, err = envService.ListEnvsGroupedByProject(context.WithValue(ctx, &apisv1.CtxKeyUser, "dev-user"))
+	assert.NoError(t, err)
+	grouped := map[string][]string{}
+	var projects []apisv1.NameAlias
+	for _, group := range resp.Projects {
+		projects = append(projects, group.Project)
+		grouped[group.Project.Name] = []string{}
+		for _, env := range group.Envs {
+			assert.Equal(t, group.Project, env.Project)
+			grouped[group.Project.Name] = append(grouped[group.Project.Name], env.Name)
+		}
+	}
+	assert.Equal(t, []apisv1.NameAlias{{Name: "team-a", Alias: "Team A"}, {Name: "team-b", Alias: "Team B"}, {Name: "team-empty"}}, projects)
+	assert.Equal(t, map[string][]string{
+		"team-a":     {"env-a-dev", "env-a-prod"},
+		"team-b":     {"env-b"},
+		"team-empty": {},
+	}, grouped)
+}
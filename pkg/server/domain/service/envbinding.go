@@ -108,6 +108,9 @@ func (e *envBindingServiceImpl) CreateEnvBinding(ctx context.Context, app *model
 	if err != nil {
 		return nil, err
 	}
+	if err := e.EnvService.CheckAppQuota(ctx, env); err != nil {
+		return nil, err
+	}
 	envBindingModel := assembler.CreateEnvBindingModel(app, envReq)
 	err = e.createEnvWorkflow(ctx, app, env, false)
 	if err != nil {
@@ -121,6 +124,16 @@ func (e *envBindingServiceImpl) CreateEnvBinding(ctx context.Context, app *model
 }
 
 func (e *envBindingServiceImpl) BatchCreateEnvBinding(ctx context.Context, app *model.Application, envbindings apisv1.EnvBindingList) error {
+	// refuse all bindings before adding any of them if one of the envs is full
+	for i := range envbindings {
+		env, err := repository.GetEnv(ctx, e.Store, envbindings[i].Name)
+		if err != nil {
+			continue
+		}
+		if err := e.EnvService.CheckAppQuota(ctx, env); err != nil {
+			return err
+		}
+	}
 	for i := range envbindings {
 		envBindingModel := assembler.ConvertToEnvBindingModel(app, *envbindings[i])
 		env, err := repository.GetEnv(ctx, e.Store, envBindingModel.Name)
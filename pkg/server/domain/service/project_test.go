@@ -64,7 +64,7 @@ var _ = Describe("Test project service functions", func() {
 		Expect(err).Should(BeNil())
 		// reset all projects
 		for _, e := range envs.Envs {
-			_ = envService.DeleteEnv(context.TODO(), e.Name)
+			_ = envService.DeleteEnv(context.TODO(), e.Name, true, false)
 		}
 		targets, err := targetService.ListTargets(context.TODO(), 0, 0, "")
 		Expect(err).Should(BeNil())
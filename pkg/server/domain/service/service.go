@@ -36,7 +36,7 @@ func InitServiceBean(c config.Config) []interface{} {
 	workflowService := NewWorkflowService()
 	oamApplicationService := NewOAMApplicationService()
 	velaQLService := NewVelaQLService()
-	definitionService := NewDefinitionService()
+	definitionService := NewDefinitionService(c.DefinitionNamespace)
 	addonService := NewAddonService(c.AddonCacheTime)
 	envBindingService := NewEnvBindingService()
 	systemInfoService := NewSystemInfoService()
@@ -55,7 +55,7 @@ func InitServiceBean(c config.Config) []interface{} {
 		clusterService, rbacService, projectService, envService, targetService, workflowService, oamApplicationService,
 		velaQLService, definitionService, addonService, envBindingService, systemInfoService, helmService, userService,
 		authenticationService, configService, applicationService, webhookService, pipelineService, pipelineRunService,
-		contextService, NewImageService(), NewCloudShellService(), pluginService,
+		contextService, NewImageService(), NewCloudShellService(), pluginService, NewNoopAuditLogger(),
 	}
 }
 
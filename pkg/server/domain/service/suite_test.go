@@ -93,7 +93,7 @@ func InitAllServices(ds datastore.DataStore) {
 	pipelineService = NewTestPipelineService(ds, k8sClient, cfg).(*pipelineServiceImpl)
 	cloudShellService = NewTestCloudShellService(ds, k8sClient, cfg).(*cloudShellServiceImpl)
 
-	definitionService = &definitionServiceImpl{KubeClient: k8sClient}
+	definitionService = &definitionServiceImpl{KubeClient: k8sClient, Store: ds}
 	envBindingService = &envBindingServiceImpl{KubeClient: k8sClient, Store: ds, DefinitionService: definitionService, WorkflowService: workflowService}
 	sysService = &systemInfoServiceImpl{Store: ds, KubeClient: k8sClient}
 	authService = &authenticationServiceImpl{KubeClient: k8sClient, Store: ds, ProjectService: projectService, SysService: sysService, UserService: userService}
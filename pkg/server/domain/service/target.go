@@ -198,6 +198,7 @@ func convertUpdateReqToTargetModel(target *model.Target, req apisv1.UpdateTarget
 	target.Alias = req.Alias
 	target.Description = req.Description
 	target.Variable = req.Variable
+	target.Tags = req.Tags
 	return target
 }
 
@@ -208,12 +209,17 @@ func convertCreateReqToTargetModel(req apisv1.CreateTargetRequest) model.Target
 		Description: req.Description,
 		Cluster:     (*model.ClusterTarget)(req.Cluster),
 		Variable:    req.Variable,
+		Tags:        req.Tags,
 		Project:     req.Project,
 	}
 	return target
 }
 
 func (dt *targetServiceImpl) convertFromTargetModel(ctx context.Context, target *model.Target) *apisv1.TargetBase {
+	return convertTargetModel2Base(ctx, dt.Store, target)
+}
+
+func convertTargetModel2Base(ctx context.Context, ds datastore.DataStore, target *model.Target) *apisv1.TargetBase {
 	var appNum int64
 	// TODO: query app num in target
 	targetBase := &apisv1.TargetBase{
@@ -222,6 +228,7 @@ func (dt *targetServiceImpl) convertFromTargetModel(ctx context.Context, target
 		Description: target.Description,
 		Cluster:     (*apisv1.ClusterTarget)(target.Cluster),
 		Variable:    target.Variable,
+		Tags:        target.Tags,
 		CreateTime:  target.CreateTime,
 		UpdateTime:  target.UpdateTime,
 		AppNum:      appNum,
@@ -230,13 +237,13 @@ func (dt *targetServiceImpl) convertFromTargetModel(ctx context.Context, target
 		var project = model.Project{
 			Name: target.Project,
 		}
-		if err := dt.Store.Get(ctx, &project); err != nil {
+		if err := ds.Get(ctx, &project); err != nil {
 			klog.Errorf("get project failure %s", err.Error())
 		}
 		targetBase.Project = apisv1.NameAlias{Name: project.Name, Alias: project.Alias}
 	}
 	if targetBase.Cluster != nil && targetBase.Cluster.ClusterName != "" {
-		cluster, err := _getClusterFromDataStore(ctx, dt.Store, target.Cluster.ClusterName)
+		cluster, err := _getClusterFromDataStore(ctx, ds, target.Cluster.ClusterName)
 		if err != nil {
 			klog.Errorf("query cluster info failure %s", err.Error())
 		}
@@ -220,6 +220,11 @@ func (c *CR2UX) generateEnv(ctx context.Context, defaultProject string, envNames
 			envName = env
 		}
 	}
+	// The additional namespaces of an env are labeled with the env name too, but the env is bound to its own namespace
+	boundEnv := &model.Env{Name: envName}
+	if err := c.ds.Get(ctx, boundEnv); err == nil && boundEnv.Namespace != envNamespace {
+		return nil, "", fmt.Errorf("the namespace %s is an additional namespace of the env %s, the applications in it are not synced", envNamespace, envName)
+	}
 	env := &model.Env{
 		Name:        envName,
 		Namespace:   envNamespace,
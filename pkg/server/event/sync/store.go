@@ -111,7 +111,7 @@ func StoreEnv(ctx context.Context, app *DataStoreApp, ds datastore.DataStore, en
 		Targets:             app.Env.Targets,
 		AllowTargetConflict: true,
 	})
-	if err != nil && !errors.Is(err, bcode.ErrEnvAlreadyExists) {
+	if err != nil && !errors.Is(err, bcode.ErrEnvAlreadyExists) && !errors.Is(err, bcode.ErrEnvSpecConflict) {
 		return err
 	}
 	return nil
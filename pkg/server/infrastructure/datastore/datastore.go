@@ -39,6 +39,9 @@ var (
 	// ErrRecordNotExist Error that entity primary key is not exist
 	ErrRecordNotExist = NewDBError(fmt.Errorf("data record is not exist"))
 
+	// ErrRecordChanged Error that entity has been changed since it was read
+	ErrRecordChanged = NewDBError(fmt.Errorf("data record has been changed"))
+
 	// ErrIndexInvalid Error that entity index is invalid
 	ErrIndexInvalid = NewDBError(fmt.Errorf("entity index is invalid"))
 
@@ -58,6 +61,11 @@ func (d *DBError) Error() string {
 	return d.err.Error()
 }
 
+// Unwrap return the cause of the error, such as the context error
+func (d *DBError) Unwrap() error {
+	return d.err
+}
+
 // NewDBError new datastore error
 func NewDBError(err error) error {
 	return &DBError{err: err}
@@ -152,6 +160,9 @@ type DataStore interface {
 	// Put will update entity to database, Name() and TableName() can't return zero value.
 	Put(ctx context.Context, entity Entity) error
 
+	// PutIfUnchanged will update entity to database only if it was not updated after updateTime, otherwise it returns ErrRecordChanged.
+	PutIfUnchanged(ctx context.Context, entity Entity, updateTime time.Time) error
+
 	// Delete entity from database, Name() and TableName() can't return zero value.
 	Delete(ctx context.Context, entity Entity) error
 
@@ -17,7 +17,9 @@ limitations under the License.
 package datastore
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/google/go-cmp/cmp"
@@ -57,4 +59,11 @@ var _ = Describe("Test new entity function", func() {
 		Expect(diff).Should(BeEmpty())
 	})
 
+	It("Test the cause of the datastore error", func() {
+		err := NewDBError(fmt.Errorf("list failure: %w", context.Canceled))
+		Expect(errors.Is(err, context.Canceled)).Should(BeTrue())
+		Expect(errors.Is(err, ErrRecordNotExist)).Should(BeFalse())
+		Expect(errors.Is(ErrRecordNotExist, ErrRecordNotExist)).Should(BeTrue())
+	})
+
 })
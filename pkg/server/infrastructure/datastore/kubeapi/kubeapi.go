@@ -166,6 +166,21 @@ func (m *kubeapi) Get(ctx context.Context, entity datastore.Entity) error {
 
 // Put update data model
 func (m *kubeapi) Put(ctx context.Context, entity datastore.Entity) error {
+	return m.put(ctx, entity, nil)
+}
+
+// PutIfUnchanged update data model only if it was not updated after the update time
+func (m *kubeapi) PutIfUnchanged(ctx context.Context, entity datastore.Entity, updateTime time.Time) error {
+	return m.put(ctx, entity, func(configMap *corev1.ConfigMap) error {
+		stored := gjson.GetBytes(configMap.BinaryData["data"], "updateTime").Time()
+		if !stored.Truncate(time.Millisecond).Equal(updateTime.Truncate(time.Millisecond)) {
+			return datastore.ErrRecordChanged
+		}
+		return nil
+	})
+}
+
+func (m *kubeapi) put(ctx context.Context, entity datastore.Entity, check func(configMap *corev1.ConfigMap) error) error {
 	if entity.PrimaryKey() == "" {
 		return datastore.ErrPrimaryEmpty
 	}
@@ -190,6 +205,11 @@ func (m *kubeapi) Put(ctx context.Context, entity datastore.Entity) error {
 		}
 		return datastore.NewDBError(err)
 	}
+	if check != nil {
+		if err := check(&configMap); err != nil {
+			return err
+		}
+	}
 	data, err := json.Marshal(entity)
 	if err != nil {
 		return datastore.NewDBError(err)
@@ -197,6 +217,9 @@ func (m *kubeapi) Put(ctx context.Context, entity datastore.Entity) error {
 	configMap.BinaryData["data"] = data
 	configMap.Labels = labels
 	if err := m.kubeClient.Update(ctx, &configMap); err != nil {
+		if check != nil && apierrors.IsConflict(err) {
+			return datastore.ErrRecordChanged
+		}
 		return datastore.NewDBError(err)
 	}
 	return nil
@@ -72,6 +72,25 @@ var _ = Describe("Test kubeapi datastore driver", func() {
 		err := kubeStore.Put(context.TODO(), &model.Application{Name: "kubevela-app", Description: "this is demo"})
 		Expect(err).ToNot(HaveOccurred())
 	})
+	It("Test put if unchanged function", func() {
+		app := &model.Application{Name: "kubevela-app"}
+		err := kubeStore.Get(context.TODO(), app)
+		Expect(err).ToNot(HaveOccurred())
+		loaded := app.UpdateTime
+		// the update times are compared in milliseconds
+		time.Sleep(time.Millisecond)
+		err = kubeStore.PutIfUnchanged(context.TODO(), app, loaded)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the app has been updated after the time
+		err = kubeStore.PutIfUnchanged(context.TODO(), app, loaded)
+		equal := cmp.Equal(err, datastore.ErrRecordChanged, cmpopts.EquateErrors())
+		Expect(equal).Should(BeTrue())
+
+		err = kubeStore.PutIfUnchanged(context.TODO(), &model.Application{Name: "kubevela-app-not-exist"}, loaded)
+		equal = cmp.Equal(err, datastore.ErrRecordNotExist, cmpopts.EquateErrors())
+		Expect(equal).Should(BeTrue())
+	})
 	It("Test application index", func() {
 		var app = model.Application{
 			Name: "test",
@@ -144,6 +144,35 @@ func (m *mongodb) Put(ctx context.Context, entity datastore.Entity) error {
 	return nil
 }
 
+// PutIfUnchanged update data model only if it was not updated after the update time
+func (m *mongodb) PutIfUnchanged(ctx context.Context, entity datastore.Entity, updateTime time.Time) error {
+	if entity.PrimaryKey() == "" {
+		return datastore.ErrPrimaryEmpty
+	}
+	if entity.TableName() == "" {
+		return datastore.ErrTableNameEmpty
+	}
+	entity.SetUpdateTime(time.Now())
+	collection := m.client.Database(m.database).Collection(entity.TableName())
+	// the times are stored in milliseconds
+	filter := append(makeNameFilter(entity.PrimaryKey()), bson.E{Key: "basemodel.updatetime", Value: updateTime.Truncate(time.Millisecond)})
+	res, err := collection.UpdateOne(ctx, filter, makeEntityUpdate(entity))
+	if err != nil {
+		return datastore.NewDBError(err)
+	}
+	if res.MatchedCount == 0 {
+		exist, err := m.IsExist(ctx, entity)
+		if err != nil {
+			return err
+		}
+		if exist {
+			return datastore.ErrRecordChanged
+		}
+		return datastore.ErrRecordNotExist
+	}
+	return nil
+}
+
 // IsExist determine whether data exists.
 func (m *mongodb) IsExist(ctx context.Context, entity datastore.Entity) (bool, error) {
 	if entity.PrimaryKey() == "" {
@@ -196,14 +225,57 @@ func _applyFilterOptions(filter bson.D, filterOptions datastore.FilterOptions) b
 		filter = append(filter, bson.E{Key: strings.ToLower(queryOp.Key), Value: bsonx.Regex(".*"+queryOp.Query+".*", "s")})
 	}
 	for _, queryOp := range filterOptions.In {
-		filter = append(filter, bson.E{Key: strings.ToLower(queryOp.Key), Value: bson.D{bson.E{Key: "$in", Value: queryOp.Values}}})
+		filter = appendFieldFilter(filter, queryOp.Key, "$in", queryOp.Values)
 	}
+	// the value is empty if the field is missing, such as the documents stored before the field is added,
+	// or it is the zero value of the string and the bool fields
 	for _, queryOp := range filterOptions.IsNotExist {
-		filter = append(filter, bson.E{Key: strings.ToLower(queryOp.Key), Value: bson.D{bson.E{Key: "$eq", Value: ""}}})
+		filter = appendFieldFilter(filter, queryOp.Key, "$in", []interface{}{"", nil, false})
+	}
+	return filter
+}
+
+// makeIndexFilter make the filter of the index of the entity
+func makeIndexFilter(index map[string]interface{}) bson.D {
+	filter := bson.D{}
+	for k, v := range index {
+		filter = appendFieldFilter(filter, k, "$eq", v)
 	}
 	return filter
 }
 
+// appendFieldFilter append the condition of the key to the filter. The field names of the documents are lower case,
+// but a key like "labels.app.kubernetes.io/Name" refers to the key of a map field, which is kept as it is because
+// the map keys are case-sensitive and may have the dots that can't be queried by the dot notation.
+func appendFieldFilter(filter bson.D, key, operator string, value interface{}) bson.D {
+	i := strings.Index(key, ".")
+	if i < 0 {
+		return append(filter, bson.E{Key: strings.ToLower(key), Value: bson.D{{Key: operator, Value: value}}})
+	}
+	field, mapKey := strings.ToLower(key[:i]), key[i+1:]
+	// the missing key is taken as the empty value
+	mapValue := bson.D{{Key: "$ifNull", Value: bson.A{
+		bson.D{{Key: "$arrayElemAt", Value: bson.A{
+			bson.D{{Key: "$map", Value: bson.D{
+				{Key: "input", Value: bson.D{{Key: "$filter", Value: bson.D{
+					{Key: "input", Value: bson.D{{Key: "$objectToArray", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$" + field, bson.D{}}}}}}},
+					{Key: "cond", Value: bson.D{{Key: "$eq", Value: bson.A{"$$this.k", mapKey}}}},
+				}}}},
+				{Key: "in", Value: "$$this.v"},
+			}}}, 0}}},
+		"",
+	}}}
+	condition := bson.D{{Key: operator, Value: bson.A{mapValue, value}}}
+	// all conditions of the map keys are combined into one expression, the filter can't have the duplicated keys
+	for j := range filter {
+		if filter[j].Key == "$expr" {
+			filter[j].Value = bson.D{{Key: "$and", Value: append(filter[j].Value.(bson.D)[0].Value.(bson.A), condition)}}
+			return filter
+		}
+	}
+	return append(filter, bson.E{Key: "$expr", Value: bson.D{{Key: "$and", Value: bson.A{condition}}}})
+}
+
 // List list entity function
 func (m *mongodb) List(ctx context.Context, entity datastore.Entity, op *datastore.ListOptions) ([]datastore.Entity, error) {
 	if entity.TableName() == "" {
@@ -211,13 +283,7 @@ func (m *mongodb) List(ctx context.Context, entity datastore.Entity, op *datasto
 	}
 	collection := m.client.Database(m.database).Collection(entity.TableName())
 	// bson.D{{}} specifies 'all documents'
-	filter := bson.D{}
-	for k, v := range entity.Index() {
-		filter = append(filter, bson.E{
-			Key:   strings.ToLower(k),
-			Value: v,
-		})
-	}
+	filter := makeIndexFilter(entity.Index())
 	if op != nil {
 		filter = _applyFilterOptions(filter, op.FilterOptions)
 	}
@@ -269,13 +335,7 @@ func (m *mongodb) Count(ctx context.Context, entity datastore.Entity, filterOpti
 		return 0, datastore.ErrTableNameEmpty
 	}
 	collection := m.client.Database(m.database).Collection(entity.TableName())
-	filter := bson.D{}
-	for k, v := range entity.Index() {
-		filter = append(filter, bson.E{
-			Key:   strings.ToLower(k),
-			Value: v,
-		})
-	}
+	filter := makeIndexFilter(entity.Index())
 	if filterOptions != nil {
 		filter = _applyFilterOptions(filter, *filterOptions)
 	}
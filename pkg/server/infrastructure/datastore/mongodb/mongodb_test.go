@@ -105,6 +105,25 @@ var _ = Describe("Test mongodb datastore driver", func() {
 		err := mongodbDriver.Put(context.TODO(), &model.Application{Name: "kubevela-app", Description: "this is demo"})
 		Expect(err).ToNot(HaveOccurred())
 	})
+	It("Test put if unchanged function", func() {
+		app := &model.Application{Name: "kubevela-app"}
+		err := mongodbDriver.Get(context.TODO(), app)
+		Expect(err).ToNot(HaveOccurred())
+		loaded := app.UpdateTime
+		// the update times are compared in milliseconds
+		time.Sleep(time.Millisecond)
+		err = mongodbDriver.PutIfUnchanged(context.TODO(), app, loaded)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the app has been updated after the time
+		err = mongodbDriver.PutIfUnchanged(context.TODO(), app, loaded)
+		equal := cmp.Equal(err, datastore.ErrRecordChanged, cmpopts.EquateErrors())
+		Expect(equal).Should(BeTrue())
+
+		err = mongodbDriver.PutIfUnchanged(context.TODO(), &model.Application{Name: "kubevela-app-not-exist"}, loaded)
+		equal = cmp.Equal(err, datastore.ErrRecordNotExist, cmpopts.EquateErrors())
+		Expect(equal).Should(BeTrue())
+	})
 	It("Test list function", func() {
 		var app model.Application
 		list, err := mongodbDriver.List(context.TODO(), &app, &datastore.ListOptions{Page: -1})
@@ -205,6 +224,73 @@ var _ = Describe("Test mongodb datastore driver", func() {
 		}
 	})
 
+	It("Test list the envs by the labels", func() {
+		Expect(mongodbDriver.Add(context.TODO(), &model.Env{Name: "env-team-a", Project: "env-labels",
+			Labels: map[string]string{"app.kubernetes.io/Team": "a", "tier": "web"}})).Should(Succeed())
+		Expect(mongodbDriver.Add(context.TODO(), &model.Env{Name: "env-team-b", Project: "env-labels",
+			Labels: map[string]string{"app.kubernetes.io/Team": "b"}})).Should(Succeed())
+		listNames := func(filter datastore.FilterOptions) []string {
+			entities, err := mongodbDriver.List(context.TODO(), &model.Env{Project: "env-labels"}, &datastore.ListOptions{FilterOptions: filter})
+			Expect(err).Should(Succeed())
+			var names []string
+			for _, entity := range entities {
+				names = append(names, entity.(*model.Env).Name)
+			}
+			return names
+		}
+		// the keys of the labels are case-sensitive and may have the dots
+		Expect(listNames(datastore.FilterOptions{In: []datastore.InQueryOption{
+			{Key: model.EnvLabelIndexKey("app.kubernetes.io/Team"), Values: []string{"a"}},
+		}})).Should(Equal([]string{"env-team-a"}))
+		Expect(listNames(datastore.FilterOptions{In: []datastore.InQueryOption{
+			{Key: model.EnvLabelIndexKey("app.kubernetes.io/team"), Values: []string{"a"}},
+		}})).Should(BeEmpty())
+		Expect(listNames(datastore.FilterOptions{In: []datastore.InQueryOption{
+			{Key: model.EnvLabelIndexKey("app.kubernetes.io/Team"), Values: []string{"a", "b"}},
+			{Key: model.EnvLabelIndexKey("tier"), Values: []string{"web"}},
+		}})).Should(Equal([]string{"env-team-a"}))
+		Expect(listNames(datastore.FilterOptions{IsNotExist: []datastore.IsNotExistQueryOption{
+			{Key: model.EnvLabelIndexKey("tier")},
+		}})).Should(Equal([]string{"env-team-b"}))
+
+		count, err := mongodbDriver.Count(context.TODO(), &model.Env{Labels: map[string]string{"app.kubernetes.io/Team": "b"}}, nil)
+		Expect(err).Should(Succeed())
+		Expect(count).Should(Equal(int64(1)))
+	})
+
+	It("Test the is not exist filter", func() {
+		Expect(mongodbDriver.Add(context.TODO(), &model.Role{Name: "role-platform"})).Should(Succeed())
+		Expect(mongodbDriver.Add(context.TODO(), &model.Role{Name: "role-project", Project: "not-exist-project"})).Should(Succeed())
+		roles, err := mongodbDriver.List(context.TODO(), &model.Role{}, &datastore.ListOptions{FilterOptions: datastore.FilterOptions{
+			IsNotExist: []datastore.IsNotExistQueryOption{{Key: "project"}},
+		}})
+		Expect(err).Should(Succeed())
+		Expect(len(roles)).Should(Equal(1))
+		Expect(roles[0].(*model.Role).Name).Should(Equal("role-platform"))
+
+		Expect(mongodbDriver.Add(context.TODO(), &model.ApplicationPolicy{AppPrimaryKey: "not-exist-app", Name: "policy-common"})).Should(Succeed())
+		Expect(mongodbDriver.Add(context.TODO(), &model.ApplicationPolicy{AppPrimaryKey: "not-exist-app", Name: "policy-env", EnvName: "dev"})).Should(Succeed())
+		count, err := mongodbDriver.Count(context.TODO(), &model.ApplicationPolicy{AppPrimaryKey: "not-exist-app"}, &datastore.FilterOptions{
+			IsNotExist: []datastore.IsNotExistQueryOption{{Key: "envName"}},
+		})
+		Expect(err).Should(Succeed())
+		Expect(count).Should(Equal(int64(1)))
+
+		// the false bool field and the missing field are empty
+		Expect(mongodbDriver.Add(context.TODO(), &model.Env{Name: "env-active", Project: "env-archived"})).Should(Succeed())
+		Expect(mongodbDriver.Add(context.TODO(), &model.Env{Name: "env-archived", Project: "env-archived", Archived: true})).Should(Succeed())
+		count, err = mongodbDriver.Count(context.TODO(), &model.Env{Project: "env-archived"}, &datastore.FilterOptions{
+			IsNotExist: []datastore.IsNotExistQueryOption{{Key: "archived"}},
+		})
+		Expect(err).Should(Succeed())
+		Expect(count).Should(Equal(int64(1)))
+		count, err = mongodbDriver.Count(context.TODO(), &model.Env{Project: "env-archived"}, &datastore.FilterOptions{
+			IsNotExist: []datastore.IsNotExistQueryOption{{Key: "notExistField"}},
+		})
+		Expect(err).Should(Succeed())
+		Expect(count).Should(Equal(int64(2)))
+	})
+
 	It("Test count function", func() {
 		var app model.Application
 		count, err := mongodbDriver.Count(context.TODO(), &app, nil)
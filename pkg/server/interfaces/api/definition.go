@@ -17,15 +17,21 @@ limitations under the License.
 package api
 
 import (
+	"errors"
+	"net/http"
 	"strconv"
+	"strings"
 
 	restfulspec "github.com/emicklei/go-restful-openapi/v2"
 	restful "github.com/emicklei/go-restful/v3"
+	"k8s.io/klog/v2"
 
 	"github.com/oam-dev/kubevela/pkg/utils/schema"
 
 	"github.com/kubevela/velaux/pkg/server/domain/service"
+	"github.com/kubevela/velaux/pkg/server/infrastructure/datastore"
 	apis "github.com/kubevela/velaux/pkg/server/interfaces/api/dto/v1"
+	"github.com/kubevela/velaux/pkg/server/utils"
 	"github.com/kubevela/velaux/pkg/server/utils/bcode"
 )
 
@@ -52,19 +58,131 @@ func (d *definition) GetWebServiceRoute() *restful.WebService {
 		Param(ws.QueryParameter("queryAll", "query all definitions include hidden in UI").DataType("boolean").DefaultValue("false")).
 		Param(ws.QueryParameter("appliedWorkload", "if specified, query the trait definition applied to the workload").DataType("string")).
 		Param(ws.QueryParameter("ownerAddon", "query by which addon created the definition").DataType("string")).
+		Param(ws.QueryParameter("ownerAddons", "query by any of the addons created the definition, separated by commas").DataType("string")).
 		Param(ws.QueryParameter("scope", "query by the specified scope like WorkflowRun or Application").DataType("string")).
+		Param(ws.QueryParameter("sortBy", "sort the definitions by the specified key").DataType("string").PossibleValues([]string{"name", "alias", "createTime"}).DefaultValue("name")).
+		Param(ws.QueryParameter("sortOrder", "the order of sorting").DataType("string").PossibleValues([]string{"asc", "desc"}).DefaultValue("asc")).
+		Param(ws.QueryParameter("brief", "only return the identity and the description fields, the specs are skipped").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("cluster", "query the definitions installed on the cluster, default is the control plane").DataType("string")).
+		Param(ws.QueryParameter("category", "query the definitions of the category").DataType("string")).
+		Param(ws.QueryParameter("includeSchemaStats", "count the parameters and the required parameters of each definition").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("includeUsage", "count the applications that use each definition").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("keyword", "query the definitions whose name or alias contains the keyword").DataType("string")).
+		Param(ws.QueryParameter("parameterKeyword", "query the definitions that have the parameter of the name, the matched parameters are returned").DataType("string")).
+		Param(ws.QueryParameter("schemaReady", "query the definitions whose schema is generated or not, all definitions are returned if it is not set").DataType("boolean")).
+		Param(ws.QueryParameter("includeDeprecated", "query the deprecated definitions as well").DataType("boolean").DefaultValue("false")).
 		Returns(200, "OK", apis.ListDefinitionResponse{}).
 		Writes(apis.ListDefinitionResponse{}).Do(returns200, returns500))
 
+	ws.Route(ws.GET("/stats/count").To(d.countDefinitions).
+		Doc("count the definitions by type").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.QueryParameter("queryAll", "count all definitions include hidden in UI").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("ownerAddon", "count by which addon created the definition").DataType("string")).
+		Param(ws.QueryParameter("ownerAddons", "count by any of the addons created the definition, separated by commas").DataType("string")).
+		Param(ws.QueryParameter("scope", "count by the specified scope like WorkflowRun or Application").DataType("string")).
+		Param(ws.QueryParameter("cluster", "count the definitions installed on the cluster, default is the control plane").DataType("string")).
+		Param(ws.QueryParameter("category", "count the definitions of the category").DataType("string")).
+		Param(ws.QueryParameter("keyword", "count the definitions whose name or alias contains the keyword").DataType("string")).
+		Param(ws.QueryParameter("parameterKeyword", "count the definitions that have the parameter of the name").DataType("string")).
+		Param(ws.QueryParameter("schemaReady", "count the definitions whose schema is generated or not, all definitions are counted if it is not set").DataType("boolean")).
+		Param(ws.QueryParameter("includeDeprecated", "count the deprecated definitions as well").DataType("boolean").DefaultValue("false")).
+		Returns(200, "OK", apis.CountDefinitionsResponse{}).
+		Writes(apis.CountDefinitionsResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.GET("/favorites/list").To(d.listFavoriteDefinitions).
+		Doc("list the definitions pinned by the login user").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.QueryParameter("type", "query the definition type, all types are listed if it is empty").DataType("string").PossibleValues([]string{"component", "trait", "workflowstep", "policy"})).
+		Returns(200, "OK", apis.ListDefinitionResponse{}).
+		Writes(apis.ListDefinitionResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.PUT("/{definitionName}/favorite").To(d.addFavoriteDefinition).
+		Doc("Pin a definition for the login user").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string").Required(true)).
+		Param(ws.QueryParameter("type", "the definition type").DataType("string").Required(true)).
+		Returns(200, "OK", apis.EmptyResponse{}).
+		Writes(apis.EmptyResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.DELETE("/{definitionName}/favorite").To(d.removeFavoriteDefinition).
+		Doc("Unpin a definition for the login user").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string").Required(true)).
+		Param(ws.QueryParameter("type", "the definition type").DataType("string").Required(true)).
+		Returns(200, "OK", apis.EmptyResponse{}).
+		Writes(apis.EmptyResponse{}).Do(returns200, returns500))
+
 	ws.Route(ws.GET("/{definitionName}").To(d.detailDefinition).
 		Doc("Detail a definition").
 		// Filter(d.RbacService.CheckPerm("definition", "detail")).
-		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string")).
-		Param(ws.QueryParameter("type", "query the definition type").DataType("string")).
+		Param(ws.PathParameter("definitionName", "identifier of the definition, the alias is matched if there is no definition with the name").DataType("string")).
+		Param(ws.QueryParameter("type", "query the definition type, it is detected by the name if empty").DataType("string")).
+		Param(ws.QueryParameter("cluster", "query the definition installed on the cluster, default is the control plane").DataType("string")).
+		Param(ws.QueryParameter("excludeHidden", "refuse to return the definition hidden in UI unless the user is the platform admin").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("noCache", "read the schema from the cluster instead of the cache").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("locale", "translate the labels and the descriptions of the parameters to the locale, such as zh-CN, default is the preferred language of the Accept-Language header").DataType("string")).
+		Param(ws.HeaderParameter("If-None-Match", "the ETags of the definition, nothing is returned if the definition is not modified").DataType("string")).
 		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Returns(200, "create successfully", apis.DetailDefinitionResponse{}).
+		Returns(304, "the definition is not modified", nil).
 		Writes(apis.DetailDefinitionResponse{}).Do(returns200, returns500))
 
+	ws.Route(ws.GET("/{definitionName}/schema/diff").To(d.diffDefinitionSchema).
+		Doc("Compare the parameter schemas of two revisions of a definition").
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string")).
+		Param(ws.QueryParameter("type", "query the definition type").DataType("string").Required(true)).
+		Param(ws.QueryParameter("from", "the revision to compare from, such as v1").DataType("string").Required(true)).
+		Param(ws.QueryParameter("to", "the revision to compare to, the latest schema is used if it is empty").DataType("string")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Returns(200, "OK", apis.DefinitionSchemaDiffResponse{}).
+		Writes(apis.DefinitionSchemaDiffResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.GET("/{definitionName}/usage").To(d.countDefinitionUsage).
+		Doc("Count the applications that use a definition").
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string")).
+		Param(ws.QueryParameter("type", "query the definition type").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Returns(200, "OK", apis.DefinitionUsageResponse{}).
+		Writes(apis.DefinitionUsageResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.GET("/{definitionName}/dependencies").To(d.resolveDependencies).
+		Doc("Check which dependencies of a definition are installed and which are missing").
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string")).
+		Param(ws.QueryParameter("type", "query the definition type").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Returns(200, "OK", apis.DefinitionDependenciesResponse{}).
+		Writes(apis.DefinitionDependenciesResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.GET("/{definitionName}/compatible-components").To(d.listCompatibleComponents).
+		Doc("List the component definitions that a trait could be attached to").
+		Param(ws.PathParameter("definitionName", "identifier of the trait definition").DataType("string")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Returns(200, "OK", apis.ListDefinitionResponse{}).
+		Writes(apis.ListDefinitionResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.GET("/{definitionName}/jsonschema").To(d.exportJSONSchema).
+		Doc("Export the parameters of a definition as the draft-07 JSON schema").
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string")).
+		Param(ws.QueryParameter("type", "query the definition type").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Returns(200, "OK", nil).Do(returns500))
+
+	ws.Route(ws.POST("/{definitionName}/validate").To(d.validateParameters).
+		Doc("Validate the parameter values against the schema of a definition").
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(apis.ValidateParametersRequest{}).
+		Returns(200, "OK", apis.ValidateParametersResponse{}).
+		Writes(apis.ValidateParametersResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.POST("/uischema/render").To(d.renderUISchema).
+		Doc("Render the default UI schema for an openapi schema, no definition is needed").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(apis.RenderUISchemaRequest{}).
+		Returns(200, "OK", apis.RenderUISchemaResponse{}).
+		Writes(apis.RenderUISchemaResponse{}).Do(returns200, returns500))
+
 	ws.Route(ws.PUT("/{definitionName}/uischema").To(d.updateUISchema).
 		Doc("Update the UI schema for a definition").
 		Filter(d.RbacService.CheckPerm("definition", "update")).
@@ -74,6 +192,66 @@ func (d *definition) GetWebServiceRoute() *restful.WebService {
 		Returns(200, "update successfully", schema.UISchema{}).
 		Writes(apis.DetailDefinitionResponse{}).Do(returns200, returns500))
 
+	ws.Route(ws.GET("/{definitionName}/uischema/history").To(d.listUISchemaHistory).
+		Doc("List the edit history of the custom UI schema of a definition, the newest one is the first").
+		Filter(d.RbacService.CheckPerm("definition", "detail")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string").Required(true)).
+		Param(ws.QueryParameter("type", "the definition type").DataType("string").Required(true)).
+		Param(ws.QueryParameter("modifiedBy", "only list the edits of the user").DataType("string")).
+		Param(ws.QueryParameter("page", "query the page number").DataType("integer")).
+		Param(ws.QueryParameter("pageSize", "query the page size number").DataType("integer")).
+		Returns(200, "OK", apis.ListUISchemaHistoryResponse{}).
+		Writes(apis.ListUISchemaHistoryResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.DELETE("/{definitionName}/uischema").To(d.resetUISchema).
+		Doc("Remove the custom UI schema of a definition, the default UI schema is returned").
+		Filter(d.RbacService.CheckPerm("definition", "update")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string").Required(true)).
+		Param(ws.QueryParameter("type", "the definition type").DataType("string").Required(true)).
+		Returns(200, "reset successfully", schema.UISchema{}).
+		Writes(schema.UISchema{}).Do(returns200, returns500))
+
+	ws.Route(ws.DELETE("/{definitionName}").To(d.deleteDefinition).
+		Doc("Delete a definition added by the users, it is refused if any application uses it").
+		Filter(d.RbacService.CheckPerm("definition", "delete")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.PathParameter("definitionName", "identifier of the definition").DataType("string").Required(true)).
+		Param(ws.QueryParameter("type", "the definition type").DataType("string").Required(true)).
+		Returns(200, "OK", apis.EmptyResponse{}).
+		Writes(apis.EmptyResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.POST("/").To(d.applyDefinition).
+		Doc("Create or update a definition from the YAML manifest").
+		Filter(d.RbacService.CheckPerm("definition", "create")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(apis.ApplyDefinitionRequest{}).
+		Returns(200, "OK", apis.DetailDefinitionResponse{}).
+		Writes(apis.DetailDefinitionResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.PUT("/status").To(d.batchUpdateDefinitionStatus).
+		Doc("Update the status for the definitions in batch, the failure of one definition doesn't abort the others").
+		Filter(d.RbacService.CheckPerm("definition", "update")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(apis.BatchUpdateDefinitionStatusRequest{}).
+		Returns(200, "update successfully", apis.BatchUpdateDefinitionStatusResponse{}).
+		Writes(apis.BatchUpdateDefinitionStatusResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.POST("/schemas/regenerate").To(d.regenerateSchemas).
+		Doc("Regenerate the schemas of the definitions, the controller rebuilds them asynchronously").
+		Filter(d.RbacService.CheckPerm("definition", "update")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(apis.RegenerateDefinitionSchemasRequest{}).
+		Returns(200, "OK", apis.RegenerateDefinitionSchemasResponse{}).
+		Writes(apis.RegenerateDefinitionSchemasResponse{}).Do(returns200, returns500))
+
+	ws.Route(ws.GET("/schemas/cache").To(d.schemaCacheStats).
+		Doc("Get the hits, misses and evictions of the definition schema cache").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Returns(200, "OK", apis.DefinitionSchemaCacheStats{}).
+		Writes(apis.DefinitionSchemaCacheStats{}).Do(returns200, returns500))
+
 	ws.Route(ws.PUT("/{definitionName}/status").To(d.updateDefinitionStatus).
 		Doc("Update the status for a definition").
 		Filter(d.RbacService.CheckPerm("definition", "update")).
@@ -97,12 +275,48 @@ func (d *definition) listDefinitions(req *restful.Request, res *restful.Response
 	if err != nil {
 		queryAll = false
 	}
+	var ownerAddons []string
+	if req.QueryParameter("ownerAddons") != "" {
+		ownerAddons = strings.Split(req.QueryParameter("ownerAddons"), ",")
+	}
+	brief, err := strconv.ParseBool(req.QueryParameter("brief"))
+	if err != nil {
+		brief = false
+	}
+	includeSchemaStats, err := strconv.ParseBool(req.QueryParameter("includeSchemaStats"))
+	if err != nil {
+		includeSchemaStats = false
+	}
+	includeUsage, err := strconv.ParseBool(req.QueryParameter("includeUsage"))
+	if err != nil {
+		includeUsage = false
+	}
+	includeDeprecated, err := strconv.ParseBool(req.QueryParameter("includeDeprecated"))
+	if err != nil {
+		includeDeprecated = false
+	}
+	sortOrder := datastore.SortOrderAscending
+	if req.QueryParameter("sortOrder") == "desc" {
+		sortOrder = datastore.SortOrderDescending
+	}
 	definitions, err := d.DefinitionService.ListDefinitions(req.Request.Context(), service.DefinitionQueryOption{
-		Type:             req.QueryParameter("type"),
-		AppliedWorkloads: req.QueryParameter("appliedWorkload"),
-		OwnerAddon:       req.QueryParameter("ownerAddon"),
-		Scope:            req.QueryParameter("scope"),
-		QueryAll:         queryAll,
+		Type:               req.QueryParameter("type"),
+		AppliedWorkloads:   req.QueryParameter("appliedWorkload"),
+		OwnerAddon:         req.QueryParameter("ownerAddon"),
+		OwnerAddons:        ownerAddons,
+		Scope:              req.QueryParameter("scope"),
+		QueryAll:           queryAll,
+		SortBy:             req.QueryParameter("sortBy"),
+		SortOrder:          sortOrder,
+		Brief:              brief,
+		Cluster:            req.QueryParameter("cluster"),
+		Category:           req.QueryParameter("category"),
+		IncludeSchemaStats: includeSchemaStats,
+		IncludeUsage:       includeUsage,
+		Keyword:            req.QueryParameter("keyword"),
+		ParameterKeyword:   req.QueryParameter("parameterKeyword"),
+		SchemaReady:        parseOptionalBool(req.QueryParameter("schemaReady")),
+		IncludeDeprecated:  includeDeprecated,
 	})
 	if err != nil {
 		bcode.ReturnError(req, res, err)
@@ -114,12 +328,213 @@ func (d *definition) listDefinitions(req *restful.Request, res *restful.Response
 	}
 }
 
+func (d *definition) countDefinitions(req *restful.Request, res *restful.Response) {
+	queryAll, err := strconv.ParseBool(req.QueryParameter("queryAll"))
+	if err != nil {
+		queryAll = false
+	}
+	var ownerAddons []string
+	if req.QueryParameter("ownerAddons") != "" {
+		ownerAddons = strings.Split(req.QueryParameter("ownerAddons"), ",")
+	}
+	includeDeprecated, err := strconv.ParseBool(req.QueryParameter("includeDeprecated"))
+	if err != nil {
+		includeDeprecated = false
+	}
+	counts, err := d.DefinitionService.CountDefinitionsByType(req.Request.Context(), service.DefinitionQueryOption{
+		OwnerAddon:        req.QueryParameter("ownerAddon"),
+		OwnerAddons:       ownerAddons,
+		Scope:             req.QueryParameter("scope"),
+		QueryAll:          queryAll,
+		Cluster:           req.QueryParameter("cluster"),
+		Category:          req.QueryParameter("category"),
+		Keyword:           req.QueryParameter("keyword"),
+		ParameterKeyword:  req.QueryParameter("parameterKeyword"),
+		SchemaReady:       parseOptionalBool(req.QueryParameter("schemaReady")),
+		IncludeDeprecated: includeDeprecated,
+	})
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(apis.CountDefinitionsResponse{Counts: counts}); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) diffDefinitionSchema(req *restful.Request, res *restful.Response) {
+	diff, err := d.DefinitionService.DiffDefinitionSchema(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type"),
+		req.QueryParameter("from"), req.QueryParameter("to"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(diff); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) countDefinitionUsage(req *restful.Request, res *restful.Response) {
+	usage, err := d.DefinitionService.CountDefinitionUsage(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(usage); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) listFavoriteDefinitions(req *restful.Request, res *restful.Response) {
+	definitions, err := d.DefinitionService.ListFavoriteDefinitions(req.Request.Context(), req.QueryParameter("type"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(apis.ListDefinitionResponse{Definitions: definitions}); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) addFavoriteDefinition(req *restful.Request, res *restful.Response) {
+	if err := d.DefinitionService.AddFavoriteDefinition(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type")); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(apis.EmptyResponse{}); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) removeFavoriteDefinition(req *restful.Request, res *restful.Response) {
+	if err := d.DefinitionService.RemoveFavoriteDefinition(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type")); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(apis.EmptyResponse{}); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) resolveDependencies(req *restful.Request, res *restful.Response) {
+	dependencies, err := d.DefinitionService.ResolveDependencies(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(dependencies); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) applyDefinition(req *restful.Request, res *restful.Response) {
+	var applyReq apis.ApplyDefinitionRequest
+	if err := req.ReadEntity(&applyReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	definition, err := d.DefinitionService.ApplyDefinition(req.Request.Context(), []byte(applyReq.YAML))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(definition); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) listCompatibleComponents(req *restful.Request, res *restful.Response) {
+	definitions, err := d.DefinitionService.ListCompatibleComponents(req.Request.Context(), req.PathParameter("definitionName"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(apis.ListDefinitionResponse{Definitions: definitions}); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) exportJSONSchema(req *restful.Request, res *restful.Response) {
+	data, err := d.DefinitionService.ExportDefinitionJSONSchema(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	res.AddHeader(restful.HEADER_ContentType, "application/schema+json")
+	if _, err := res.Write(data); err != nil {
+		klog.Errorf("write the json schema failure %s", err.Error())
+	}
+}
+
+func (d *definition) validateParameters(req *restful.Request, res *restful.Response) {
+	var validateReq apis.ValidateParametersRequest
+	if err := req.ReadEntity(&validateReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	resp, err := d.DefinitionService.ValidateParameters(req.Request.Context(), req.PathParameter("definitionName"), validateReq.DefinitionType, validateReq.Values)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) renderUISchema(req *restful.Request, res *restful.Response) {
+	var renderReq apis.RenderUISchemaRequest
+	if err := req.ReadEntity(&renderReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	uiSchema, err := d.DefinitionService.RenderUISchemaForSchema(req.Request.Context(), renderReq.Schema)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(apis.RenderUISchemaResponse{UISchema: uiSchema}); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
 func (d *definition) detailDefinition(req *restful.Request, res *restful.Response) {
-	definition, err := d.DefinitionService.DetailDefinition(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type"))
+	excludeHidden, err := strconv.ParseBool(req.QueryParameter("excludeHidden"))
+	if err != nil {
+		excludeHidden = false
+	}
+	noCache, err := strconv.ParseBool(req.QueryParameter("noCache"))
+	if err != nil {
+		noCache = false
+	}
+	definition, err := d.DefinitionService.DetailDefinition(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type"), service.DetailDefinitionOption{
+		Cluster:       req.QueryParameter("cluster"),
+		ExcludeHidden: excludeHidden,
+		IfNoneMatch:   req.HeaderParameter("If-None-Match"),
+		NoCache:       noCache,
+		Locale:        requestLocale(req),
+	})
+	if errors.Is(err, bcode.ErrDefinitionNotModified) {
+		res.Header().Set("ETag", definition.ETag)
+		res.WriteHeader(http.StatusNotModified)
+		return
+	}
 	if err != nil {
 		bcode.ReturnError(req, res, err)
 		return
 	}
+	res.Header().Set("ETag", definition.ETag)
 	if err := res.WriteEntity(definition); err != nil {
 		bcode.ReturnError(req, res, err)
 		return
@@ -141,7 +556,7 @@ func (d *definition) updateUISchema(req *restful.Request, res *restful.Response)
 		bcode.ReturnError(req, res, bcode.ErrInvalidDefinitionUISchema.SetMessage(err.Error()))
 		return
 	}
-	schema, err := d.DefinitionService.AddDefinitionUISchema(req.Request.Context(), req.PathParameter("definitionName"), updateReq.DefinitionType, updateReq.UISchema)
+	schema, err := d.DefinitionService.AddDefinitionUISchema(req.Request.Context(), req.PathParameter("definitionName"), updateReq.DefinitionType, updateReq.UISchema, updateReq.InheritsFrom)
 	if err != nil {
 		bcode.ReturnError(req, res, err)
 		return
@@ -152,6 +567,46 @@ func (d *definition) updateUISchema(req *restful.Request, res *restful.Response)
 	}
 }
 
+func (d *definition) listUISchemaHistory(req *restful.Request, res *restful.Response) {
+	page, pageSize, err := utils.ExtractPagingParams(req, minPageSize, maxPageSize)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	history, err := d.DefinitionService.ListDefinitionUISchemaHistory(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type"), page, pageSize, req.QueryParameter("modifiedBy"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(history); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) resetUISchema(req *restful.Request, res *restful.Response) {
+	schema, err := d.DefinitionService.ResetDefinitionUISchema(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(schema); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) deleteDefinition(req *restful.Request, res *restful.Response) {
+	if err := d.DefinitionService.DeleteDefinition(req.Request.Context(), req.PathParameter("definitionName"), req.QueryParameter("type")); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(apis.EmptyResponse{}); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
 func (d *definition) updateDefinitionStatus(req *restful.Request, res *restful.Response) {
 	// Verify the validity of parameters
 	var updateReq apis.UpdateDefinitionStatusRequest
@@ -173,3 +628,93 @@ func (d *definition) updateDefinitionStatus(req *restful.Request, res *restful.R
 		return
 	}
 }
+
+func (d *definition) batchUpdateDefinitionStatus(req *restful.Request, res *restful.Response) {
+	// Verify the validity of parameters
+	var batchReq apis.BatchUpdateDefinitionStatusRequest
+	if err := req.ReadEntity(&batchReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	for i := range batchReq.Definitions {
+		if err := validate.Struct(&batchReq.Definitions[i]); err != nil {
+			bcode.ReturnError(req, res, err)
+			return
+		}
+	}
+	resp, err := d.DefinitionService.BatchUpdateDefinitionStatus(req.Request.Context(), batchReq.Definitions)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) regenerateSchemas(req *restful.Request, res *restful.Response) {
+	var regenerateReq apis.RegenerateDefinitionSchemasRequest
+	if err := req.ReadEntity(&regenerateReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	resp, err := d.DefinitionService.RegenerateAllDefinitionSchemas(req.Request.Context(), regenerateReq)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (d *definition) schemaCacheStats(req *restful.Request, res *restful.Response) {
+	stats, err := d.DefinitionService.SchemaCacheStats(req.Request.Context())
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(stats); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+// requestLocale return the locale of the query parameter, or the most preferred language of the Accept-Language header
+func requestLocale(req *restful.Request) string {
+	if locale := strings.TrimSpace(req.QueryParameter("locale")); locale != "" {
+		return locale
+	}
+	var locale string
+	var preference = -1.0
+	for _, item := range strings.Split(req.HeaderParameter("Accept-Language"), ",") {
+		language, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		language = strings.TrimSpace(language)
+		if language == "" || language == "*" {
+			continue
+		}
+		quality := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > preference {
+			locale, preference = language, quality
+		}
+	}
+	return locale
+}
+
+// parseOptionalBool parse the boolean query parameter, return nil if it is not set or invalid
+func parseOptionalBool(value string) *bool {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
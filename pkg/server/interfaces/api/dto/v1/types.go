@@ -853,24 +853,92 @@ type Env struct {
 	Project NameAlias `json:"project"`
 	// Namespace defines the K8s namespace of the Env in control plane
 	Namespace string `json:"namespace"`
+	// Namespaces defines the additional K8s namespaces of the Env in control plane
+	Namespaces []string `json:"namespaces,omitempty"  optional:"true"`
 
 	// Targets defines the name of delivery target that belongs to this env
 	// In one project, a delivery target can only belong to one env.
-	Targets []NameAlias `json:"targets,omitempty"  optional:"true"`
+	Targets []EnvTarget `json:"targets,omitempty"  optional:"true"`
+
+	Labels map[string]string `json:"labels,omitempty"  optional:"true"`
+
+	// TargetSelector selects the targets of the project by the tags
+	TargetSelector map[string]string `json:"targetSelector,omitempty"  optional:"true"`
+
+	// AppCount is the number of the applications in the env, it is set when listing with IncludeAppCount or the env has the AppQuota
+	AppCount int `json:"appCount,omitempty"  optional:"true"`
+
+	// AppQuota is the max number of the applications in the env, there is no limit if it is 0
+	AppQuota int `json:"appQuota,omitempty"  optional:"true"`
+
+	// Variables the values shared by the applications in the env
+	Variables map[string]string `json:"variables,omitempty"  optional:"true"`
+
+	// DefaultAppLabels the labels stamped onto every application deployed to the env
+	DefaultAppLabels map[string]string `json:"defaultAppLabels,omitempty"  optional:"true"`
+
+	// Annotations the free-form metadata of the env, such as the runbook URL or the owner email
+	Annotations map[string]string `json:"annotations,omitempty"  optional:"true"`
+
+	Archived bool `json:"archived,omitempty"  optional:"true"`
+
+	// Default means the env is the default env of the project
+	Default bool `json:"default,omitempty"  optional:"true"`
+
+	// Warnings are the problems that don't block the operation, such as the cluster of a target is unreachable
+	Warnings []string `json:"warnings,omitempty"  optional:"true"`
 
 	CreateTime time.Time `json:"createTime"`
 	UpdateTime time.Time `json:"updateTime"`
 }
 
+// EnvTarget the delivery target of the env
+type EnvTarget struct {
+	NameAlias
+	// Status the health of the cluster and the namespace of the target, it is only set when listing with IncludeTargetStatus
+	Status string `json:"status,omitempty"  optional:"true"`
+}
+
 // ListEnvOptions list envs by query options
 type ListEnvOptions struct {
 	Project string `json:"project"`
+	// Labels only list the envs that have all of these labels
+	Labels map[string]string `json:"labels"`
+	// Target only list the envs that own the delivery target
+	Target string `json:"target"`
+	// IncludeAppCount means counting the applications in each env
+	IncludeAppCount bool `json:"includeAppCount"`
+	// IncludeArchived means listing the archived envs too
+	IncludeArchived bool `json:"includeArchived"`
+	// IncludeTargetStatus means checking the health of the cluster and the namespace of each target
+	IncludeTargetStatus bool `json:"includeTargetStatus"`
+	// WritableOnly means only listing the envs that the current user has the permissions to update and delete
+	WritableOnly bool `json:"writableOnly"`
+	// SortBy the key to sort the envs, support name, alias and createTime, default is createTime
+	SortBy string `json:"sortBy"`
+	// SortOrder asc or desc, default is desc when sorting by createTime, asc otherwise
+	SortOrder string `json:"sortOrder"`
+}
+
+// ListEnvsGroupedByProjectResponse the envs grouped by the projects of the login user, the projects are sorted by the name
+type ListEnvsGroupedByProjectResponse struct {
+	Projects []*ProjectEnvs `json:"projects"`
+}
+
+// ProjectEnvs the envs of the project, sorted by the name
+type ProjectEnvs struct {
+	Project NameAlias `json:"project"`
+	Envs    []*Env    `json:"envs"`
 }
 
 // ListEnvResponse response the while env list
 type ListEnvResponse struct {
-	Envs  []*Env `json:"envs"`
-	Total int64  `json:"total"`
+	Envs     []*Env `json:"envs"`
+	Total    int64  `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+	// TotalPages is 1 if the envs are not paginated or there is no env
+	TotalPages int `json:"totalPages"`
 }
 
 // CreateEnvRequest contains the env data as request body
@@ -883,13 +951,232 @@ type CreateEnvRequest struct {
 	Project string `json:"project"`
 	// Namespace defines the K8s namespace of the Env in control plane
 	Namespace string `json:"namespace"`
+	// Namespaces defines the additional K8s namespaces of the Env in control plane,
+	// the labels and the privileges of the env are applied to them as well as the Namespace
+	Namespaces []string `json:"namespaces,omitempty"  optional:"true"`
 
 	// Targets defines the name of delivery target that belongs to this env
 	// In one project, a delivery target can only belong to one env.
 	Targets []string `json:"targets,omitempty"  optional:"true"`
 
+	// TargetSelector selects the targets of the project that have all of these tags, they are added to the Targets
+	TargetSelector map[string]string `json:"targetSelector,omitempty"  optional:"true"`
+
 	// AllowTargetConflict means allow binding the targets that belong to other envs
 	AllowTargetConflict bool `json:"allowTargetConflict,omitempty"  optional:"true"`
+
+	// Labels defines the labels of the env, they are also patched to the namespace
+	Labels map[string]string `json:"labels,omitempty"  optional:"true"`
+
+	// Default means the env is the default env of the project, the flag of the previous default env is cleared
+	Default bool `json:"default,omitempty"  optional:"true"`
+
+	// AppQuota limits the number of the applications in the env, there is no limit if it is 0
+	AppQuota int `json:"appQuota,omitempty" validate:"min=0" optional:"true"`
+
+	// Variables the values shared by the applications in the env, such as the registry and the base domain
+	Variables map[string]string `json:"variables,omitempty"  optional:"true"`
+
+	// DefaultAppLabels the labels stamped onto every application deployed to the env, the labels of the application take precedence
+	DefaultAppLabels map[string]string `json:"defaultAppLabels,omitempty"  optional:"true"`
+
+	// Annotations the free-form metadata of the env, such as the runbook URL or the owner email, unlike the labels they are not applied to the namespaces
+	Annotations map[string]string `json:"annotations,omitempty"  optional:"true"`
+}
+
+// BatchCreateEnvRequest contains the data of the envs to be created in one call
+type BatchCreateEnvRequest struct {
+	Envs []CreateEnvRequest `json:"envs"`
+}
+
+// BatchCreateEnvResult the result of creating one env in the batch
+type BatchCreateEnvResult struct {
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Env     *Env   `json:"env,omitempty"`
+	// Message the reason why the env is not created
+	Message string `json:"message,omitempty"`
+	// RollbackFailed the env is created but failed to roll back, it is left in the system
+	RollbackFailed bool `json:"rollbackFailed,omitempty"`
+}
+
+// BatchCreateEnvResponse the response of creating the envs in batch
+type BatchCreateEnvResponse struct {
+	Results []*BatchCreateEnvResult `json:"results"`
+}
+
+// EnvManifest the self-contained description of an env, it is used to export and import the env
+type EnvManifest struct {
+	Name        string            `json:"name"`
+	Alias       string            `json:"alias,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Project     string            `json:"project"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Namespaces  []string          `json:"namespaces,omitempty"`
+	Targets     []string          `json:"targets,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	AppQuota    int               `json:"appQuota,omitempty"`
+	Variables   map[string]string `json:"variables,omitempty"`
+	// DefaultAppLabels the labels stamped onto every application deployed to the env
+	DefaultAppLabels map[string]string `json:"defaultAppLabels,omitempty"`
+	// Annotations the free-form metadata of the env
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SetEnvVariableRequest the value of the env variable
+type SetEnvVariableRequest struct {
+	Value string `json:"value"`
+}
+
+// EnvVariable the variable shared by the applications in the env
+type EnvVariable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ExportEnvResponse the YAML of the env manifest
+type ExportEnvResponse struct {
+	YAML string `json:"yaml"`
+}
+
+// ImportEnvRequest create an env from the YAML of the env manifest
+type ImportEnvRequest struct {
+	YAML string `json:"yaml"`
+}
+
+// ReconcileEnvResponse the report of what is corrected when reconciling the env
+type ReconcileEnvResponse struct {
+	Name string `json:"name"`
+	// NamespaceCreated means any namespace of the env is missing and created again
+	NamespaceCreated bool `json:"namespaceCreated"`
+	// CorrectedLabels the labels of the namespaces that are added or reverted, the values are the expected ones
+	CorrectedLabels map[string]string `json:"correctedLabels,omitempty"`
+	// PrivilegesGranted means the privileges of the project are granted to the namespace again
+	PrivilegesGranted bool `json:"privilegesGranted"`
+}
+
+// EnvAccessResponse the identity and the privileges that the users of the project use to access the env
+type EnvAccessResponse struct {
+	Name    string `json:"name"`
+	Project string `json:"project"`
+	// Groups the identity groups that the privileges are granted to
+	Groups     []string        `json:"groups"`
+	Privileges []*EnvPrivilege `json:"privileges"`
+}
+
+// EnvPrivilege the privilege granted on a namespace of a cluster
+type EnvPrivilege struct {
+	// Target the target that the privilege is granted for, it is empty for the namespace of the env in the control plane
+	Target    string `json:"target,omitempty"`
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	ReadOnly  bool   `json:"readOnly"`
+	// RoleBinding the kind and the key of the binding, such as RoleBinding namespace/name
+	RoleBinding string   `json:"roleBinding"`
+	Subjects    []string `json:"subjects"`
+	// Status Granted, Missing or Unknown if the binding can't be read
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ValidateEnvTargetsRequest the targets proposed for the env of the project
+type ValidateEnvTargetsRequest struct {
+	Project string `json:"project"`
+	// EnvName the env to update, the targets of the env itself are not conflicting. It is empty when creating the env.
+	EnvName string   `json:"envName,omitempty" optional:"true"`
+	Targets []string `json:"targets"`
+}
+
+// ValidateEnvTargetsResponse the status of each proposed target, in the order of the request
+type ValidateEnvTargetsResponse struct {
+	Targets []EnvTargetStatus `json:"targets"`
+}
+
+// EnvTargetStatus the status of the target proposed for the env
+type EnvTargetStatus struct {
+	Name string `json:"name"`
+	// Status Available, Conflict if it belongs to another env of the project, or NotExist
+	Status string `json:"status"`
+	// Env the env that the target belongs to if the status is Conflict
+	Env string `json:"env,omitempty"`
+}
+
+// MigrateApplicationsRequest the request to move the applications of the env to another env of the project
+type MigrateApplicationsRequest struct {
+	TargetEnv string `json:"targetEnv" validate:"checkname"`
+	// DryRun only list the applications that would be migrated
+	DryRun bool `json:"dryRun,omitempty" optional:"true"`
+}
+
+// MigrateApplicationsResponse the result of migrating each application of the env
+type MigrateApplicationsResponse struct {
+	Applications []MigratedApplication `json:"applications"`
+	DryRun       bool                  `json:"dryRun"`
+}
+
+// MigratedApplication the result of migrating the application
+type MigratedApplication struct {
+	NameAlias
+	// Status Pending if it is a dry run, Migrated, Skipped if it is already in the target env, or Failed
+	Status string `json:"status"`
+	// Redeployed whether the application is deployed to the target env, only the applications deployed to the source env are redeployed
+	Redeployed bool   `json:"redeployed"`
+	Message    string `json:"message,omitempty"`
+}
+
+// CompareEnvsResponse the differences between two envs, A is the env compared and B is the env compared with
+type CompareEnvsResponse struct {
+	EnvA NameAlias `json:"envA"`
+	EnvB NameAlias `json:"envB"`
+	// Identical whether all the compared fields are the same
+	Identical bool `json:"identical"`
+	// Namespaces compares all namespaces of the envs, the order of the namespaces doesn't matter
+	Namespaces EnvNamesComparison   `json:"namespaces"`
+	AppCount   EnvCountComparison   `json:"appCount"`
+	Targets    EnvNamesComparison   `json:"targets"`
+	Labels     []EnvEntryComparison `json:"labels"`
+	Variables  []EnvEntryComparison `json:"variables"`
+}
+
+// EnvCountComparison the counts of two envs
+type EnvCountComparison struct {
+	A    int  `json:"a"`
+	B    int  `json:"b"`
+	Same bool `json:"same"`
+}
+
+// EnvNamesComparison the names of the targets or the namespaces of two envs, the names are sorted
+type EnvNamesComparison struct {
+	Common  []string `json:"common"`
+	OnlyInA []string `json:"onlyInA"`
+	OnlyInB []string `json:"onlyInB"`
+}
+
+// EnvEntryComparison the values of a key of the labels or the variables in two envs
+type EnvEntryComparison struct {
+	Key string `json:"key"`
+	A   string `json:"a,omitempty"`
+	B   string `json:"b,omitempty"`
+	// Status Same, Changed, OnlyInA or OnlyInB
+	Status string `json:"status"`
+}
+
+// ListOrphanedEnvTargetsResponse the targets referenced by the envs of the project but deleted from the datastore
+type ListOrphanedEnvTargetsResponse struct {
+	Targets []OrphanedEnvTarget `json:"targets"`
+	// Repaired whether the orphaned targets have been removed from the envs
+	Repaired bool `json:"repaired"`
+}
+
+// OrphanedEnvTarget the target referenced by the env that doesn't exist
+type OrphanedEnvTarget struct {
+	Env    string `json:"env"`
+	Target string `json:"target"`
+}
+
+// PreviewEnvPrivilegesResponse the privileges that will be granted when creating the env
+type PreviewEnvPrivilegesResponse struct {
+	Privileges string `json:"privileges"`
 }
 
 // UpdateEnvRequest defines the data of Env for update
@@ -899,6 +1186,53 @@ type UpdateEnvRequest struct {
 	// Targets defines the name of delivery target that belongs to this env
 	// In one project, a delivery target can only belong to one env.
 	Targets []string `json:"targets,omitempty"  optional:"true"`
+
+	// TargetSelector selects the targets of the project that have all of these tags, they are added to the Targets,
+	// or to the existing targets of the env if the Targets is empty. The selector is not changed if it is nil, an empty selector clears it.
+	TargetSelector map[string]string `json:"targetSelector,omitempty"  optional:"true"`
+
+	// Labels defines the labels of the env, the existing labels are replaced if it is set
+	Labels map[string]string `json:"labels,omitempty"  optional:"true"`
+
+	// AllowTargetSteal means moving the targets from the other envs in the same project to this env
+	AllowTargetSteal bool `json:"allowTargetSteal,omitempty"  optional:"true"`
+
+	// ClearAlias and ClearDescription mean setting the field to empty, an empty Alias or Description is ignored otherwise
+	ClearAlias       bool `json:"clearAlias,omitempty"  optional:"true"`
+	ClearDescription bool `json:"clearDescription,omitempty"  optional:"true"`
+
+	// Default set or unset the env as the default env of the project, it is ignored if it is nil
+	Default *bool `json:"default,omitempty"  optional:"true"`
+
+	// AppQuota limits the number of the applications in the env, 0 removes the limit and it is ignored if it is nil.
+	// The quota can't be lower than the number of the existing applications.
+	AppQuota *int `json:"appQuota,omitempty" validate:"omitempty,min=0" optional:"true"`
+
+	// Variables the values shared by the applications in the env, the existing variables are replaced if it is set
+	Variables map[string]string `json:"variables,omitempty"  optional:"true"`
+
+	// DefaultAppLabels the labels stamped onto every application deployed to the env, the existing ones are replaced if it is set.
+	// The applications already deployed get the labels when they are deployed again.
+	DefaultAppLabels map[string]string `json:"defaultAppLabels,omitempty"  optional:"true"`
+
+	// Annotations the free-form metadata of the env, the existing annotations are replaced if it is set, the empty map clears them
+	Annotations map[string]string `json:"annotations,omitempty"  optional:"true"`
+
+	// ExpectedUpdateTime the update time of the env when the client loaded it, the update is rejected if the env is changed since then.
+	// The check is skipped if it is nil.
+	ExpectedUpdateTime *time.Time `json:"expectedUpdateTime,omitempty"  optional:"true"`
+}
+
+// CloneEnvRequest defines the data of the new Env cloned from an existing Env
+type CloneEnvRequest struct {
+	Name string `json:"name" validate:"checkname"`
+	// Namespace defines the K8s namespace of the new Env in control plane
+	Namespace string `json:"namespace"`
+	// Targets defines the targets of the new Env, the targets of the source Env are not cloned because they belong to the source Env
+	Targets []string `json:"targets,omitempty" optional:"true"`
+
+	// AllowTargetConflict means allow binding the targets that belong to other envs
+	AllowTargetConflict bool `json:"allowTargetConflict,omitempty"  optional:"true"`
 }
 
 // ListDefinitionResponse list definition response model
@@ -906,24 +1240,206 @@ type ListDefinitionResponse struct {
 	Definitions []*DefinitionBase `json:"definitions"`
 }
 
+// CountDefinitionsResponse the count of the definitions by type
+type CountDefinitionsResponse struct {
+	Counts map[string]int `json:"counts"`
+}
+
+// ValidateParametersRequest the parameter values to validate against the schema of the definition
+type ValidateParametersRequest struct {
+	DefinitionType string                 `json:"type"`
+	Values         map[string]interface{} `json:"values"`
+}
+
+// RenderUISchemaRequest the openapi schema to render the default ui schema for
+type RenderUISchemaRequest struct {
+	Schema json.RawMessage `json:"schema"`
+}
+
+// RenderUISchemaResponse the default ui schema rendered from the openapi schema
+type RenderUISchemaResponse struct {
+	UISchema schema.UISchema `json:"uiSchema"`
+}
+
+// ValidateParametersResponse the result of validating the parameter values
+type ValidateParametersResponse struct {
+	Valid  bool              `json:"valid"`
+	Errors []*ParameterError `json:"errors,omitempty"`
+}
+
+// ParameterError the parameter value doesn't match the schema
+type ParameterError struct {
+	// Field the path of the parameter, the array items are indexed by number, such as volumes.0.name
+	Field string `json:"field"`
+	// Rule the keyword of the schema that is violated, such as type, required, enum, minimum and maximum
+	Rule   string `json:"rule,omitempty"`
+	Reason string `json:"reason"`
+}
+
 // DetailDefinitionResponse get definition detail
 type DetailDefinitionResponse struct {
 	DefinitionBase
 	APISchema *openapi3.Schema `json:"schema"`
 	UISchema  schema.UISchema  `json:"uiSchema"`
+	// LastModifiedBy the user who last updated the custom ui schema
+	LastModifiedBy string `json:"lastModifiedBy,omitempty" optional:"true"`
+	// LastModifiedTime the time when the custom ui schema was last updated
+	LastModifiedTime *time.Time `json:"lastModifiedTime,omitempty" optional:"true"`
+	// Template the source of the definition schematic, such as the CUE template or the raw kube/helm resources
+	Template string `json:"template,omitempty" optional:"true"`
+	// TemplateWithheld means the template and the schematic are not returned because they declare the default values of the sensitive parameters
+	TemplateWithheld bool `json:"templateWithheld,omitempty" optional:"true"`
+	// HiddenInUI means the definition is hidden in UI, same as the status is disable
+	HiddenInUI bool `json:"hiddenInUI"`
+	// Placeholders the placeholders of the form fields rendered from the examples of the parameters, keyed by the parameter path like resources.cpu or cmd[]
+	Placeholders map[string]string `json:"placeholders,omitempty" optional:"true"`
+	// ETag changes whenever the definition, the schema or the custom ui schemas change, it is also set in the ETag header
+	ETag string `json:"etag,omitempty" optional:"true"`
+	// UIGroups the sections of the form, the label is the group name and the keys are the top-level parameters in the group.
+	// It is empty if no parameter declares the group with the x-vela-group extension.
+	UIGroups []schema.GroupOption `json:"uiGroups,omitempty" optional:"true"`
+	// UIHints the unit and format hints of the form fields, keyed by the parameter path like resources.cpu or volumes[].size
+	UIHints map[string]UIParameterHint `json:"uiHints,omitempty" optional:"true"`
+}
+
+// UIParameterHint the hints to render the form field, such as the "MiB" suffix or the duration picker
+type UIParameterHint struct {
+	// Unit the unit of the value declared by the x-vela-unit extension, such as MiB or core
+	Unit string `json:"unit,omitempty"`
+	// Format the format of the value declared by the x-vela-format extension or the openapi format, such as byte or duration
+	Format string `json:"format,omitempty"`
+	// Sensitive the value is a secret declared by the x-vela-sensitive extension, it should be masked in the form
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// DefinitionSchemaDiffResponse the changes of the parameters between two revisions of the definition
+type DefinitionSchemaDiffResponse struct {
+	Name         string                   `json:"name"`
+	Type         string                   `json:"type"`
+	FromRevision string                   `json:"fromRevision"`
+	ToRevision   string                   `json:"toRevision"`
+	Added        []DefinitionPropertyDiff `json:"added"`
+	Removed      []DefinitionPropertyDiff `json:"removed"`
+	Changed      []DefinitionPropertyDiff `json:"changed"`
+}
+
+// DefinitionPropertyDiff the change of one parameter
+type DefinitionPropertyDiff struct {
+	// Path the dot separated path of the parameter, the items of an array is suffixed with [], such as volumes[].name
+	Path string                     `json:"path"`
+	From *DefinitionPropertySummary `json:"from,omitempty" optional:"true"`
+	To   *DefinitionPropertySummary `json:"to,omitempty" optional:"true"`
+}
+
+// DefinitionPropertySummary the attributes of one parameter that are compared
+type DefinitionPropertySummary struct {
+	Type        string        `json:"type,omitempty" optional:"true"`
+	Required    bool          `json:"required"`
+	Default     interface{}   `json:"default,omitempty" optional:"true"`
+	Enum        []interface{} `json:"enum,omitempty" optional:"true"`
+	Description string        `json:"description,omitempty" optional:"true"`
 }
 
 // UpdateUISchemaRequest the request body struct about updated ui schema
 type UpdateUISchemaRequest struct {
 	DefinitionType string          `json:"type"`
 	UISchema       schema.UISchema `json:"uiSchema"`
+	// InheritsFrom the definition of the same type whose custom ui schema is merged before this one
+	InheritsFrom string `json:"inheritsFrom,omitempty" optional:"true"`
+}
+
+// UISchemaHistoryEntry an edit of the custom ui schema of a definition
+type UISchemaHistoryEntry struct {
+	// ModifiedBy the user who updated the custom ui schema
+	ModifiedBy   string    `json:"modifiedBy"`
+	ModifiedTime time.Time `json:"modifiedTime"`
+	// Summary the parameters added, removed or changed by the edit
+	Summary string `json:"summary"`
+}
+
+// ListUISchemaHistoryResponse the edit history of the custom ui schema, the newest one is the first
+type ListUISchemaHistoryResponse struct {
+	Entries  []*UISchemaHistoryEntry `json:"entries"`
+	Total    int64                   `json:"total"`
+	Page     int                     `json:"page"`
+	PageSize int                     `json:"pageSize"`
+}
+
+// ApplyDefinitionRequest the request to create or update a definition
+type ApplyDefinitionRequest struct {
+	// YAML the manifest of the ComponentDefinition, TraitDefinition, WorkflowStepDefinition or PolicyDefinition
+	YAML string `json:"yaml"`
 }
 
 // UpdateDefinitionStatusRequest the request body struct about updated definition
 // Only support set the status of definition
 type UpdateDefinitionStatusRequest struct {
+	// Name is only used when updating in batch, the name in the path is used otherwise
+	Name           string `json:"name,omitempty" optional:"true"`
 	DefinitionType string `json:"type"`
 	HiddenInUI     bool   `json:"hiddenInUI"`
+	// DryRun means only compute the result status, the definition will not be updated
+	DryRun bool `json:"dryRun,omitempty" optional:"true"`
+}
+
+// BatchUpdateDefinitionStatusRequest update the status of the definitions in one call
+type BatchUpdateDefinitionStatusRequest struct {
+	Definitions []UpdateDefinitionStatusRequest `json:"definitions"`
+}
+
+// BatchUpdateDefinitionStatusResult the result of updating the status of one definition in the batch
+type BatchUpdateDefinitionStatusResult struct {
+	Name           string `json:"name"`
+	DefinitionType string `json:"type"`
+	Success        bool   `json:"success"`
+	HiddenInUI     bool   `json:"hiddenInUI"`
+	// Message the reason why the status is not updated
+	Message string `json:"message,omitempty"`
+}
+
+// BatchUpdateDefinitionStatusResponse the response of updating the status of the definitions in batch
+type BatchUpdateDefinitionStatusResponse struct {
+	Results []*BatchUpdateDefinitionStatusResult `json:"results"`
+}
+
+// RegenerateDefinitionSchemasRequest the request to regenerate the schemas of the definitions
+type RegenerateDefinitionSchemasRequest struct {
+	// DefinitionType only regenerate the schemas of the definitions of the type, all types are regenerated if it is empty
+	DefinitionType string `json:"type,omitempty" optional:"true"`
+	// Concurrency the max number of the definitions regenerated at the same time, the default is 5 and the max is 20
+	Concurrency int `json:"concurrency,omitempty" optional:"true"`
+}
+
+// RegenerateDefinitionSchemasResponse the summary of regenerating the schemas, the schemas are rebuilt by the controller asynchronously
+type RegenerateDefinitionSchemasResponse struct {
+	Total       int                                 `json:"total"`
+	Regenerated int                                 `json:"regenerated"`
+	Skipped     int                                 `json:"skipped"`
+	Failed      int                                 `json:"failed"`
+	Results     []*RegenerateDefinitionSchemaResult `json:"results"`
+}
+
+// RegenerateDefinitionSchemaResult the result of regenerating the schema of the definition
+type RegenerateDefinitionSchemaResult struct {
+	Name           string `json:"name"`
+	DefinitionType string `json:"type"`
+	// Status Regenerated, Skipped if the definition has no schema, or Failed
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// DefinitionSchemaCacheStats the counters of the definition schema cache
+type DefinitionSchemaCacheStats struct {
+	// Enabled whether the schemas are cached, the counters are always zero if it is false
+	Enabled bool  `json:"enabled"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	// HitRate the ratio of the hits to all lookups, it is zero if there is no lookup
+	HitRate float64 `json:"hitRate"`
+	// Entries the number of the cached schemas
+	Entries int `json:"entries"`
+	// Evictions the number of the cached schemas dropped because they are expired or the definitions are changed
+	Evictions int64 `json:"evictions"`
 }
 
 // DefinitionBase is the definition base model
@@ -939,11 +1455,58 @@ type DefinitionBase struct {
 	// Deprecated: it same as component.workload.type
 	WorkloadType string `json:"workloadType,omitempty"`
 	// OwnerAddon indicates which addon created this definition
-	OwnerAddon   string                              `json:"ownerAddon"`
+	OwnerAddon string `json:"ownerAddon"`
+	// BuiltIn means the definition is installed with KubeVela in the system namespace, not by an addon
+	BuiltIn      bool                                `json:"builtIn"`
 	Trait        *v1beta1.TraitDefinitionSpec        `json:"trait,omitempty"`
 	Component    *v1beta1.ComponentDefinitionSpec    `json:"component,omitempty"`
 	Policy       *v1beta1.PolicyDefinitionSpec       `json:"policy,omitempty"`
 	WorkflowStep *v1beta1.WorkflowStepDefinitionSpec `json:"workflowStep,omitempty"`
+	// ParameterCount and RequiredCount are the count of the top level parameters and the required ones, only set when listing with the schema stats
+	ParameterCount int `json:"parameterCount,omitempty" optional:"true"`
+	RequiredCount  int `json:"requiredCount,omitempty" optional:"true"`
+	// UsageCount the count of the applications that use the definition, only set when listing with the usage
+	UsageCount *int `json:"usageCount,omitempty" optional:"true"`
+	// MatchedParameters the paths of the parameters matched by the parameter keyword, only set when searching by the parameter
+	MatchedParameters []string `json:"matchedParameters,omitempty" optional:"true"`
+	// Dependencies the definitions that this definition depends on, declared by the definition.oam.dev/depends-on annotation
+	Dependencies []DefinitionReference `json:"dependencies,omitempty" optional:"true"`
+	// SchemaReady means the schema configmap of the definition is generated, the APISchema is empty if it is not ready
+	SchemaReady bool `json:"schemaReady"`
+	// Favorited means the definition is pinned by the login user
+	Favorited bool `json:"favorited,omitempty" optional:"true"`
+	// Deprecated is set if the definition is deprecated, it should not be used by the new applications
+	Deprecated *DefinitionDeprecation `json:"deprecated,omitempty" optional:"true"`
+	// SchemaError the reason why the schema can't be loaded, the schema is empty if it is set
+	SchemaError string `json:"schemaError,omitempty" optional:"true"`
+}
+
+// DefinitionDeprecation the deprecation of the definition
+type DefinitionDeprecation struct {
+	// Replacement the definition of the same type to use instead
+	Replacement string `json:"replacement,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+// DefinitionReference refer to a definition by the type and the name
+type DefinitionReference struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// DefinitionDependenciesResponse the dependencies of the definition that are installed or missing
+type DefinitionDependenciesResponse struct {
+	Name      string                `json:"name"`
+	Type      string                `json:"type"`
+	Installed []DefinitionReference `json:"installed"`
+	Missing   []DefinitionReference `json:"missing"`
+}
+
+// DefinitionUsageResponse the count of the applications that use the definition
+type DefinitionUsageResponse struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	UsageCount int    `json:"usageCount"`
 }
 
 // CreatePolicyRequest create app policy
@@ -1241,6 +1804,7 @@ type CreateTargetRequest struct {
 	Description string                 `json:"description,omitempty" optional:"true"`
 	Cluster     *ClusterTarget         `json:"cluster,omitempty"`
 	Variable    map[string]interface{} `json:"variable,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty" optional:"true"`
 }
 
 // UpdateTargetRequest only support full quantity update
@@ -1248,6 +1812,7 @@ type UpdateTargetRequest struct {
 	Alias       string                 `json:"alias,omitempty" validate:"checkalias" optional:"true"`
 	Description string                 `json:"description,omitempty" optional:"true"`
 	Variable    map[string]interface{} `json:"variable,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty" optional:"true"`
 }
 
 // ClusterTarget kubernetes delivery target
@@ -1275,6 +1840,7 @@ type TargetBase struct {
 	Cluster      *ClusterTarget         `json:"cluster,omitempty"`
 	ClusterAlias string                 `json:"clusterAlias,omitempty"`
 	Variable     map[string]interface{} `json:"variable,omitempty"`
+	Tags         map[string]string      `json:"tags,omitempty"`
 	CreateTime   time.Time              `json:"createTime"`
 	UpdateTime   time.Time              `json:"updateTime"`
 	AppNum       int64                  `json:"appNum,omitempty"`
@@ -17,6 +17,9 @@ limitations under the License.
 package api
 
 import (
+	"strconv"
+	"strings"
+
 	restfulspec "github.com/emicklei/go-restful-openapi/v2"
 	"github.com/emicklei/go-restful/v3"
 	"k8s.io/klog/v2"
@@ -28,9 +31,8 @@ import (
 )
 
 type env struct {
-	EnvService         service.EnvService         `inject:""`
-	ApplicationService service.ApplicationService `inject:""`
-	RBACService        service.RBACService        `inject:""`
+	EnvService  service.EnvService  `inject:""`
+	RBACService service.RBACService `inject:""`
 }
 
 // NewEnv new env
@@ -53,6 +55,16 @@ func (n *env) GetWebServiceRoute() *restful.WebService {
 		// This api will filter the environments by user's permissions
 		// Filter(n.RbacService.CheckPerm("environment", "list")).
 		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.QueryParameter("project", "list the envs of the project").DataType("string")).
+		Param(ws.QueryParameter("labels", "list the envs that have all of the labels, e.g. team=payments,tier=1").DataType("string")).
+		Param(ws.QueryParameter("target", "list the envs that own the delivery target").DataType("string")).
+		Param(ws.QueryParameter("includeAppCount", "count the applications in each env").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("includeArchived", "list the archived envs too").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("includeTargetStatus", "check the health of the cluster and the namespace of each target").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("writableOnly", "only list the envs that the current user has the permissions to update and delete").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("allProjects", "list the envs of all projects, only the platform admin is allowed").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("sortBy", "sort the envs by the specified key").DataType("string").PossibleValues([]string{"name", "alias", "createTime"}).DefaultValue("createTime")).
+		Param(ws.QueryParameter("sortOrder", "the order of sorting, default is desc when sorting by createTime, asc otherwise").DataType("string").PossibleValues([]string{"asc", "desc"})).
 		Returns(200, "OK", apis.ListEnvResponse{}).
 		Writes(apis.ListEnvResponse{}))
 
@@ -65,22 +77,217 @@ func (n *env) GetWebServiceRoute() *restful.WebService {
 		Returns(200, "OK", apis.Env{}).
 		Writes(apis.Env{}))
 
+	ws.Route(ws.POST("/batch").To(n.batchCreate).
+		Operation("envbatchcreate").
+		Doc("create the envs in batch, all of them will be rolled back if one fails").
+		Filter(n.RBACService.CheckPerm("environment", "create")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(apis.BatchCreateEnvRequest{}).
+		Returns(200, "OK", apis.BatchCreateEnvResponse{}).
+		Writes(apis.BatchCreateEnvResponse{}))
+
+	ws.Route(ws.POST("/import").To(n.importEnv).
+		Operation("envimport").
+		Doc("create an env from the YAML manifest exported by the export api").
+		Filter(n.RBACService.CheckPerm("environment", "create")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(apis.ImportEnvRequest{}).
+		Returns(200, "OK", apis.Env{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.Env{}))
+
+	ws.Route(ws.POST("/privileges/preview").To(n.previewPrivileges).
+		Operation("envprivilegespreview").
+		Doc("preview the privileges that will be granted when creating an env").
+		Filter(n.RBACService.CheckPerm("environment", "create")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(apis.CreateEnvRequest{}).
+		Returns(200, "OK", apis.PreviewEnvPrivilegesResponse{}).
+		Writes(apis.PreviewEnvPrivilegesResponse{}))
+
+	ws.Route(ws.POST("/targets/validate").To(n.validateTargets).
+		Operation("envtargetsvalidate").
+		Doc("check whether the targets exist and whether they belong to other envs of the project before creating or updating an env").
+		Filter(n.RBACService.CheckPerm("environment", "create")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(apis.ValidateEnvTargetsRequest{}).
+		Returns(200, "OK", apis.ValidateEnvTargetsResponse{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.ValidateEnvTargetsResponse{}))
+
+	ws.Route(ws.GET("/targets/orphaned").To(n.listOrphanedTargets).
+		Operation("envorphanedtargets").
+		Doc("list the targets referenced by the envs of the project but deleted").
+		Filter(n.RBACService.CheckPerm("environment", "list")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.QueryParameter("project", "the project of the envs").DataType("string").Required(true)).
+		Returns(200, "OK", apis.ListOrphanedEnvTargetsResponse{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.ListOrphanedEnvTargetsResponse{}))
+
+	ws.Route(ws.POST("/targets/orphaned/repair").To(n.repairOrphanedTargets).
+		Operation("envorphanedtargetsrepair").
+		Doc("remove the targets deleted from the envs of the project").
+		Filter(n.RBACService.CheckPerm("environment", "update")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.QueryParameter("project", "the project of the envs").DataType("string").Required(true)).
+		Returns(200, "OK", apis.ListOrphanedEnvTargetsResponse{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.ListOrphanedEnvTargetsResponse{}))
+
+	ws.Route(ws.GET("/projects/grouped").To(n.listGroupedByProject).
+		Operation("envlistgrouped").
+		Doc("list the envs grouped by the projects that the current user could access").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Returns(200, "OK", apis.ListEnvsGroupedByProjectResponse{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.ListEnvsGroupedByProjectResponse{}))
+
+	ws.Route(ws.GET("/projects/default").To(n.getDefault).
+		Operation("envdefault").
+		Doc("get the default env of the project").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "detail")).
+		Param(ws.QueryParameter("project", "the project of the env").DataType("string").Required(true)).
+		Returns(200, "OK", apis.Env{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.Env{}))
+
+	ws.Route(ws.GET("/{envName}").To(n.detail).
+		Operation("envdetail").
+		Doc("get the env with the aliases of the targets and the project").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "detail")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Returns(200, "OK", apis.Env{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.Env{}))
+
 	ws.Route(ws.PUT("/{envName}").To(n.update).
 		Operation("envupdate").
 		Doc("update an env").
 		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Filter(n.RBACService.CheckPerm("environment", "update")).
 		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
-		Reads(apis.CreateEnvRequest{}).
+		Reads(apis.UpdateEnvRequest{}).
 		Returns(200, "OK", apis.Env{}).
+		Returns(409, "the env is changed since the expected update time", bcode.Bcode{}).
 		Writes(apis.Env{}))
 
+	ws.Route(ws.POST("/{envName}/clone").To(n.clone).
+		Operation("envclone").
+		Doc("create an env by cloning an existing env").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "create")).
+		Param(ws.PathParameter("envName", "identifier of the source environment").DataType("string")).
+		Reads(apis.CloneEnvRequest{}).
+		Returns(200, "OK", apis.Env{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.Env{}))
+
+	ws.Route(ws.GET("/{envName}/export").To(n.exportEnv).
+		Operation("envexport").
+		Doc("export an env as a YAML manifest").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "detail")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Returns(200, "OK", apis.ExportEnvResponse{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.ExportEnvResponse{}))
+
+	ws.Route(ws.POST("/{envName}/archive").To(n.archive).
+		Operation("envarchive").
+		Doc("archive an env, the privileges are revoked but the env could be unarchived later").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "update")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Returns(200, "OK", apis.Env{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.Env{}))
+
+	ws.Route(ws.POST("/{envName}/unarchive").To(n.unarchive).
+		Operation("envunarchive").
+		Doc("unarchive an env, the privileges are granted again").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "update")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Returns(200, "OK", apis.Env{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.Env{}))
+
+	ws.Route(ws.GET("/{envName}/access").To(n.access).
+		Operation("envaccess").
+		Doc("describe the identity groups of the project users and the privileges granted on the env and its targets").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "detail")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Returns(200, "OK", apis.EnvAccessResponse{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.EnvAccessResponse{}))
+
+	ws.Route(ws.GET("/{envName}/variables/{variableName}").To(n.getVariable).
+		Operation("envvariabledetail").
+		Doc("get the variable shared by the applications in the env").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "detail")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Param(ws.PathParameter("variableName", "the name of the variable").DataType("string")).
+		Returns(200, "OK", apis.EnvVariable{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.EnvVariable{}))
+
+	ws.Route(ws.PUT("/{envName}/variables/{variableName}").To(n.setVariable).
+		Operation("envvariableset").
+		Doc("add or update the variable shared by the applications in the env").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "update")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Param(ws.PathParameter("variableName", "the name of the variable").DataType("string")).
+		Reads(apis.SetEnvVariableRequest{}).
+		Returns(200, "OK", apis.EnvVariable{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.EnvVariable{}))
+
+	ws.Route(ws.POST("/{envName}/reconcile").To(n.reconcile).
+		Operation("envreconcile").
+		Doc("re-apply the expected labels to the namespace of the env and grant the privileges again").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "update")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Returns(200, "OK", apis.ReconcileEnvResponse{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.ReconcileEnvResponse{}))
+
+	ws.Route(ws.GET("/{envName}/compare").To(n.compare).
+		Operation("envcompare").
+		Doc("compare the namespace, the targets, the labels, the variables and the application counts with another env").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "detail")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Param(ws.QueryParameter("with", "identifier of the environment to compare with").DataType("string").Required(true)).
+		Returns(200, "OK", apis.CompareEnvsResponse{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.CompareEnvsResponse{}))
+
+	ws.Route(ws.POST("/{envName}/migrate").To(n.migrateApplications).
+		Operation("envmigrate").
+		Doc("move the applications of the env to another env of the project, the deployed applications are redeployed").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Filter(n.RBACService.CheckPerm("environment", "update")).
+		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Reads(apis.MigrateApplicationsRequest{}).
+		Returns(200, "OK", apis.MigrateApplicationsResponse{}).
+		Returns(400, "Bad Request", bcode.Bcode{}).
+		Writes(apis.MigrateApplicationsResponse{}))
+
 	ws.Route(ws.DELETE("/{envName}").To(n.delete).
 		Operation("envdelete").
 		Doc("delete one env").
 		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Filter(n.RBACService.CheckPerm("environment", "delete")).
 		Param(ws.PathParameter("envName", "identifier of the environment").DataType("string")).
+		Param(ws.QueryParameter("force", "force delete the env even if there are applications in its namespace").DataType("boolean").DefaultValue("false")).
+		Param(ws.QueryParameter("deleteNamespace", "delete the namespace created by the env, it's refused if there are applications not managed by VelaUX inside").DataType("boolean").DefaultValue("false")).
 		Returns(200, "OK", apis.EmptyResponse{}).
 		Returns(400, "Bad Request", bcode.Bcode{}).
 		Writes(apis.EmptyResponse{}))
@@ -96,7 +303,51 @@ func (n *env) list(req *restful.Request, res *restful.Response) {
 		return
 	}
 	project := req.QueryParameter("project")
-	envs, err := n.EnvService.ListEnvs(req.Request.Context(), page, pageSize, apis.ListEnvOptions{Project: project})
+	labels := map[string]string{}
+	if req.QueryParameter("labels") != "" {
+		allLabels := strings.Split(req.QueryParameter("labels"), ",")
+		for _, label := range allLabels {
+			kv := strings.Split(label, "=")
+			if len(kv) == 2 {
+				labels[kv[0]] = kv[1]
+			}
+		}
+	}
+	includeAppCount, err := strconv.ParseBool(req.QueryParameter("includeAppCount"))
+	if err != nil {
+		includeAppCount = false
+	}
+	includeArchived, err := strconv.ParseBool(req.QueryParameter("includeArchived"))
+	if err != nil {
+		includeArchived = false
+	}
+	includeTargetStatus, err := strconv.ParseBool(req.QueryParameter("includeTargetStatus"))
+	if err != nil {
+		includeTargetStatus = false
+	}
+	writableOnly, err := strconv.ParseBool(req.QueryParameter("writableOnly"))
+	if err != nil {
+		writableOnly = false
+	}
+	allProjects, err := strconv.ParseBool(req.QueryParameter("allProjects"))
+	if err != nil {
+		allProjects = false
+	}
+	listEnvs := n.EnvService.ListEnvs
+	if allProjects {
+		listEnvs = n.EnvService.ListAllEnvs
+	}
+	envs, err := listEnvs(req.Request.Context(), page, pageSize, apis.ListEnvOptions{
+		Project:             project,
+		Labels:              labels,
+		Target:              req.QueryParameter("target"),
+		IncludeAppCount:     includeAppCount,
+		IncludeArchived:     includeArchived,
+		IncludeTargetStatus: includeTargetStatus,
+		WritableOnly:        writableOnly,
+		SortBy:              req.QueryParameter("sortBy"),
+		SortOrder:           req.QueryParameter("sortOrder"),
+	})
 	if err != nil {
 		bcode.ReturnError(req, res, err)
 		return
@@ -107,28 +358,195 @@ func (n *env) list(req *restful.Request, res *restful.Response) {
 	}
 }
 
-// it will prevent the deletion if there's still application in it.
+// it will prevent the deletion if there's still application in it, unless force is set.
 func (n *env) delete(req *restful.Request, res *restful.Response) {
 	envname := req.PathParameter("envName")
 
 	ctx := req.Request.Context()
-	lists, err := n.ApplicationService.ListApplications(ctx, apis.ListApplicationOptions{Env: envname})
+	force, err := strconv.ParseBool(req.QueryParameter("force"))
+	if err != nil {
+		force = false
+	}
+	deleteNamespace, err := strconv.ParseBool(req.QueryParameter("deleteNamespace"))
+	if err != nil {
+		deleteNamespace = false
+	}
+	err = n.EnvService.DeleteEnv(ctx, envname, force, deleteNamespace)
 	if err != nil {
 		bcode.ReturnError(req, res, err)
 		return
 	}
-	if len(lists) > 0 {
-		klog.Infof("detected %d applications in this env, the first is %s", len(lists), lists[0].Name)
-		bcode.ReturnError(req, res, bcode.ErrDeleteEnvButAppExist)
+	if err = res.WriteEntity(apis.EmptyResponse{}); err != nil {
+		bcode.ReturnError(req, res, err)
 		return
 	}
+}
 
-	err = n.EnvService.DeleteEnv(ctx, envname)
+func (n *env) detail(req *restful.Request, res *restful.Response) {
+	env, err := n.EnvService.GetEnvDetail(req.Request.Context(), req.PathParameter("envName"))
 	if err != nil {
 		bcode.ReturnError(req, res, err)
 		return
 	}
-	if err = res.WriteEntity(apis.EmptyResponse{}); err != nil {
+	if err := res.WriteEntity(env); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) getDefault(req *restful.Request, res *restful.Response) {
+	env, err := n.EnvService.GetDefaultEnv(req.Request.Context(), req.QueryParameter("project"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(env); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) exportEnv(req *restful.Request, res *restful.Response) {
+	manifest, err := n.EnvService.ExportEnv(req.Request.Context(), req.PathParameter("envName"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(manifest); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) importEnv(req *restful.Request, res *restful.Response) {
+	var importReq apis.ImportEnvRequest
+	if err := req.ReadEntity(&importReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	env, err := n.EnvService.ImportEnv(req.Request.Context(), importReq)
+	if err != nil {
+		klog.Errorf("import environment failure %s", err.Error())
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(env); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) archive(req *restful.Request, res *restful.Response) {
+	env, err := n.EnvService.ArchiveEnv(req.Request.Context(), req.PathParameter("envName"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(env); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) access(req *restful.Request, res *restful.Response) {
+	access, err := n.EnvService.GetEnvAccess(req.Request.Context(), req.PathParameter("envName"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(access); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) getVariable(req *restful.Request, res *restful.Response) {
+	name := req.PathParameter("variableName")
+	value, err := n.EnvService.GetEnvVariable(req.Request.Context(), req.PathParameter("envName"), name)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(apis.EnvVariable{Name: name, Value: value}); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) setVariable(req *restful.Request, res *restful.Response) {
+	var setReq apis.SetEnvVariableRequest
+	if err := req.ReadEntity(&setReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	name := req.PathParameter("variableName")
+	if err := n.EnvService.SetEnvVariable(req.Request.Context(), req.PathParameter("envName"), name, setReq.Value); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(apis.EnvVariable{Name: name, Value: setReq.Value}); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) reconcile(req *restful.Request, res *restful.Response) {
+	report, err := n.EnvService.ReconcileEnv(req.Request.Context(), req.PathParameter("envName"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(report); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) compare(req *restful.Request, res *restful.Response) {
+	with := req.QueryParameter("with")
+	if with == "" {
+		bcode.ReturnError(req, res, bcode.ErrEnvCompareWithRequired)
+		return
+	}
+	resp, err := n.EnvService.CompareEnvs(req.Request.Context(), req.PathParameter("envName"), with)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) migrateApplications(req *restful.Request, res *restful.Response) {
+	var migrateReq apis.MigrateApplicationsRequest
+	if err := req.ReadEntity(&migrateReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := validate.Struct(&migrateReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	resp, err := n.EnvService.MigrateApplications(req.Request.Context(), req.PathParameter("envName"), migrateReq.TargetEnv, migrateReq.DryRun)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) unarchive(req *restful.Request, res *restful.Response) {
+	env, err := n.EnvService.UnarchiveEnv(req.Request.Context(), req.PathParameter("envName"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(env); err != nil {
 		bcode.ReturnError(req, res, err)
 		return
 	}
@@ -160,6 +578,108 @@ func (n *env) create(req *restful.Request, res *restful.Response) {
 	}
 }
 
+func (n *env) batchCreate(req *restful.Request, res *restful.Response) {
+	// Verify the validity of parameters
+	var batchReq apis.BatchCreateEnvRequest
+	if err := req.ReadEntity(&batchReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	for i := range batchReq.Envs {
+		if err := validate.Struct(&batchReq.Envs[i]); err != nil {
+			bcode.ReturnError(req, res, err)
+			return
+		}
+	}
+	resp, err := n.EnvService.BatchCreateEnv(req.Request.Context(), batchReq.Envs)
+	if err != nil {
+		klog.Errorf("batch create environments failure %s", err.Error())
+		bcode.ReturnError(req, res, err)
+		return
+	}
+
+	// Write back response data
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) previewPrivileges(req *restful.Request, res *restful.Response) {
+	// Verify the validity of parameters
+	var createReq apis.CreateEnvRequest
+	if err := req.ReadEntity(&createReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := validate.Struct(&createReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	privileges, err := n.EnvService.PreviewEnvPrivileges(req.Request.Context(), createReq)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(privileges); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) validateTargets(req *restful.Request, res *restful.Response) {
+	var validateReq apis.ValidateEnvTargetsRequest
+	if err := req.ReadEntity(&validateReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	resp, err := n.EnvService.ValidateEnvTargets(req.Request.Context(), validateReq.Project, validateReq.EnvName, validateReq.Targets)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) listGroupedByProject(req *restful.Request, res *restful.Response) {
+	resp, err := n.EnvService.ListEnvsGroupedByProject(req.Request.Context())
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) listOrphanedTargets(req *restful.Request, res *restful.Response) {
+	resp, err := n.EnvService.FindOrphanedTargets(req.Request.Context(), req.QueryParameter("project"), false)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
+func (n *env) repairOrphanedTargets(req *restful.Request, res *restful.Response) {
+	resp, err := n.EnvService.FindOrphanedTargets(req.Request.Context(), req.QueryParameter("project"), true)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := res.WriteEntity(resp); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
 func (n *env) update(req *restful.Request, res *restful.Response) {
 	// Verify the validity of parameters
 	var updateReq apis.UpdateEnvRequest
@@ -184,3 +704,28 @@ func (n *env) update(req *restful.Request, res *restful.Response) {
 		return
 	}
 }
+
+func (n *env) clone(req *restful.Request, res *restful.Response) {
+	// Verify the validity of parameters
+	var cloneReq apis.CloneEnvRequest
+	if err := req.ReadEntity(&cloneReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	if err := validate.Struct(&cloneReq); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	env, err := n.EnvService.CloneEnv(req.Request.Context(), req.PathParameter("envName"), cloneReq)
+	if err != nil {
+		klog.Errorf("clone environment failure %s", err.Error())
+		bcode.ReturnError(req, res, err)
+		return
+	}
+
+	// Write back response data
+	if err := res.WriteEntity(env); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
@@ -33,6 +33,7 @@ type project struct {
 	RbacService        service.RBACService        `inject:""`
 	ProjectService     service.ProjectService     `inject:""`
 	TargetService      service.TargetService      `inject:""`
+	EnvService         service.EnvService         `inject:""`
 	ConfigService      service.ConfigService      `inject:""`
 	PipelineService    service.PipelineService    `inject:""`
 	PipelineRunService service.PipelineRunService `inject:""`
@@ -102,6 +103,14 @@ func (n *project) GetWebServiceRoute() *restful.WebService {
 		Returns(200, "OK", apis.EmptyResponse{}).
 		Writes(apis.EmptyResponse{}))
 
+	ws.Route(ws.GET("/{projectName}/unassigned_targets").To(n.listProjectUnassignedTargets).
+		Doc("get the targets that do not belong to any env in a project").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.PathParameter("projectName", "identifier of the project").DataType("string")).
+		Filter(n.RbacService.CheckPerm("project", "detail")).
+		Returns(200, "OK", apis.ListTargetResponse{}).
+		Writes(apis.ListTargetResponse{}))
+
 	ws.Route(ws.POST("/{projectName}/users").To(n.createProjectUser).
 		Doc("add a user to a project").
 		Metadata(restfulspec.KeyOpenAPITags, tags).
@@ -434,6 +443,24 @@ func (n *project) listProjectTargets(req *restful.Request, res *restful.Response
 	}
 }
 
+func (n *project) listProjectUnassignedTargets(req *restful.Request, res *restful.Response) {
+	project, err := n.ProjectService.GetProject(req.Request.Context(), req.PathParameter("projectName"))
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	targets, err := n.EnvService.ListUnassignedTargets(req.Request.Context(), project.Name)
+	if err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+	// Write back response data
+	if err := res.WriteEntity(targets); err != nil {
+		bcode.ReturnError(req, res, err)
+		return
+	}
+}
+
 func (n *project) createProjectUser(req *restful.Request, res *restful.Response) {
 	// Verify the validity of parameters
 	var createReq apis.AddProjectUserRequest
@@ -304,11 +304,15 @@ func (s *restServer) requestLog(req *restful.Request, resp *restful.Response, ch
 		return
 	}
 	start := time.Now()
+	requestID := utils.RequestID(req.Request)
+	req.Request = req.Request.WithContext(utils.WithRequestID(req.Request.Context(), requestID))
+	resp.AddHeader(utils.HeaderRequestID, requestID)
 	c := utils.NewResponseCapture(resp.ResponseWriter)
 	resp.ResponseWriter = c
 	chain.ProcessFilter(req, resp)
 	takeTime := time.Since(start)
 	klog.InfoS("request log",
+		"requestID", requestID,
 		"clientIP", pkgUtils.Sanitize(utils.ClientIP(req.Request)),
 		"path", pkgUtils.Sanitize(req.Request.URL.Path),
 		"method", req.Request.Method,
@@ -27,3 +27,39 @@ var ErrDefinitionTypeNotSupport = NewBcode(400, 70003, "definition type not supp
 
 // ErrInvalidDefinitionUISchema invalid custom definition ui schema
 var ErrInvalidDefinitionUISchema = NewBcode(400, 70004, "invalid custom defnition ui schema")
+
+// ErrDefinitionSortByNotSupport the sort key of the definitions is not supported
+var ErrDefinitionSortByNotSupport = NewBcode(400, 70005, "the sort key of the definitions is not supported")
+
+// ErrDefinitionRevisionNotFound the schema of the definition revision is not found
+var ErrDefinitionRevisionNotFound = NewBcode(404, 70006, "the schema of the definition revision is not found")
+
+// ErrDefinitionHidden the definition is hidden in UI and only the platform admin could get it
+var ErrDefinitionHidden = NewBcode(403, 70007, "the definition is hidden in UI")
+
+// ErrDefinitionUISchemaInheritanceCycle the inheritance of the custom ui schema makes a cycle
+var ErrDefinitionUISchemaInheritanceCycle = NewBcode(400, 70008, "the inheritance of the custom ui schema makes a cycle")
+
+// ErrDefinitionAliasAmbiguous more than one definition of the type share the alias
+var ErrDefinitionAliasAmbiguous = NewBcode(400, 70009, "more than one definition share the alias, use the name instead")
+
+// ErrDefinitionParameterSearchTooBroad there are too many definitions to search the parameter
+var ErrDefinitionParameterSearchTooBroad = NewBcode(400, 70010, "too many definitions to search the parameter, narrow them with the keyword or the other filters")
+
+// ErrDefinitionSchemaInvalid the openapi schema to render the ui schema is invalid
+var ErrDefinitionSchemaInvalid = NewBcode(400, 70011, "the openapi schema is invalid")
+
+// ErrDefinitionNotModified the definition is not changed since the caller got it, the detail is not returned
+var ErrDefinitionNotModified = NewBcode(304, 70012, "the definition is not modified")
+
+// ErrDefinitionManifestInvalid the YAML of the definition to apply is invalid
+var ErrDefinitionManifestInvalid = NewBcode(400, 70013, "the definition manifest is invalid")
+
+// ErrDefinitionTypeAmbiguous the type of the definition is not specified and the definitions of more than one type share the name
+var ErrDefinitionTypeAmbiguous = NewBcode(400, 70014, "more than one type of definition share the name, specify the type")
+
+// ErrDefinitionInUse the definition is still used by the applications, it can't be deleted
+var ErrDefinitionInUse = NewBcode(400, 70015, "the definition is used by the applications, it can't be deleted")
+
+// ErrDefinitionProtected the definition is installed with KubeVela or by an addon, it can't be deleted or updated from VelaUX
+var ErrDefinitionProtected = NewBcode(403, 70016, "the definition is installed with KubeVela or by an addon, it can't be deleted or updated")
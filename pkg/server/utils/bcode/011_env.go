@@ -36,3 +36,60 @@ var ErrEnvTargetConflict = NewBcode(400, 11006, "in one project, one target can
 
 // ErrEnvTargetNotAllowDelete means can not remove existing targets from this environment, because there are applications deployed.
 var ErrEnvTargetNotAllowDelete = NewBcode(400, 11007, "target can not be deleted, because there are applications deployed.")
+
+// ErrEnvNamespaceInvalid means the namespace of the env is not a valid DNS-1123 label
+var ErrEnvNamespaceInvalid = NewBcode(400, 11008, "the namespace of the env must be a valid DNS-1123 label")
+
+// ErrEnvManifestInvalid means the YAML of the env to import is invalid
+var ErrEnvManifestInvalid = NewBcode(400, 11009, "the env manifest is invalid")
+
+// ErrDefaultEnvNotExist the project has no default env
+var ErrDefaultEnvNotExist = NewBcode(404, 11010, "the project has no default env")
+
+// ErrEnvSortByNotSupport the sort key of the envs is not supported
+var ErrEnvSortByNotSupport = NewBcode(400, 11011, "the sort key of the envs is not supported")
+
+// ErrEnvArchived the operation is not allowed for the archived env
+var ErrEnvArchived = NewBcode(400, 11012, "the env is archived, unarchive it at first")
+
+// ErrEnvNamespaceNotManaged the namespace is not created by the env, so it can't be deleted with the env
+var ErrEnvNamespaceNotManaged = NewBcode(400, 11013, "the namespace is not managed by the env, it can't be deleted")
+
+// ErrEnvNamespaceNotEmpty there are the applications not managed by VelaUX or the workloads not belonging to the env in the namespace
+var ErrEnvNamespaceNotEmpty = NewBcode(400, 11014, "the namespace can't be deleted as there are applications not managed by VelaUX inside")
+
+// ErrEnvUpdateConflict the env is changed by others after the client loaded it
+var ErrEnvUpdateConflict = NewBcode(409, 11015, "the env has been changed by others, reload it and try again")
+
+// ErrEnvQuotaExceeded the number of the applications in the env would exceed the quota
+var ErrEnvQuotaExceeded = NewBcode(400, 11016, "the application quota of the env is exceeded")
+
+// ErrEnvSpecConflict the env to create already exists with a different spec
+var ErrEnvSpecConflict = NewBcode(409, 11017, "the env already exists with a different spec")
+
+// ErrEnvVariableInvalid the name of the env variable is invalid
+var ErrEnvVariableInvalid = NewBcode(400, 11018, "the name of the env variable is invalid")
+
+// ErrEnvVariableNotExist the env doesn't have the variable
+var ErrEnvVariableNotExist = NewBcode(404, 11019, "the env variable is not exist")
+
+// ErrEnvDefaultAppLabelsInvalid the default labels of the applications in the env are not valid labels
+var ErrEnvDefaultAppLabelsInvalid = NewBcode(400, 11020, "the default application labels of the env are invalid")
+
+// ErrEnvProjectMismatch the applications can't be migrated between the envs of different projects
+var ErrEnvProjectMismatch = NewBcode(400, 11021, "the envs belong to different projects")
+
+// ErrEnvMigrateToSelf the applications can't be migrated to the env they belong to
+var ErrEnvMigrateToSelf = NewBcode(400, 11022, "the applications can't be migrated to the same env")
+
+// ErrEnvAnnotationsInvalid the annotations of the env are not valid annotations
+var ErrEnvAnnotationsInvalid = NewBcode(400, 11023, "the annotations of the env are invalid")
+
+// ErrEnvNotAccessible the env doesn't belong to the projects of the login user
+var ErrEnvNotAccessible = NewBcode(403, 11024, "the env doesn't belong to your projects")
+
+// ErrEnvLabelsInvalid the labels of the env are not valid labels or use the keys reserved for binding the namespace
+var ErrEnvLabelsInvalid = NewBcode(400, 11025, "the labels of the env are invalid")
+
+// ErrEnvCompareWithRequired the env to compare with is not specified
+var ErrEnvCompareWithRequired = NewBcode(400, 11026, "the env to compare with is required")
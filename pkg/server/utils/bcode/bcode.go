@@ -54,6 +54,8 @@ type Bcode struct {
 	HTTPCode     int32 `json:"-"`
 	BusinessCode int32
 	Message      string
+	// Details the items related to the error, such as the names of the missing resources
+	Details []string `json:",omitempty"`
 }
 
 func (b *Bcode) Error() string {
@@ -66,9 +68,26 @@ func (b *Bcode) SetMessage(message string) *Bcode {
 		HTTPCode:     b.HTTPCode,
 		BusinessCode: b.BusinessCode,
 		Message:      message,
+		Details:      b.Details,
 	}
 }
 
+// SetDetails set the details and return a new bcode instance
+func (b *Bcode) SetDetails(details []string) *Bcode {
+	return &Bcode{
+		HTTPCode:     b.HTTPCode,
+		BusinessCode: b.BusinessCode,
+		Message:      b.Message,
+		Details:      details,
+	}
+}
+
+// Is reports whether the target error has the same business code, the message is ignored
+func (b *Bcode) Is(target error) bool {
+	t, ok := target.(*Bcode)
+	return ok && t.BusinessCode == b.BusinessCode
+}
+
 var bcodeMap map[int32]*Bcode
 
 // NewBcode new business code
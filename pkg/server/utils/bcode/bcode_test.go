@@ -17,6 +17,8 @@ limitations under the License.
 package bcode
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -28,4 +30,21 @@ var _ = Describe("Test bcode package", func() {
 		Expect(bcode.Message).ShouldNot(BeNil())
 		Expect(bcode.Error()).ShouldNot(BeNil())
 	})
+
+	It("Test the bcode with new message", func() {
+		bcode := NewBcode(400, 4001, "test")
+		newBcode := bcode.SetMessage("new message")
+		Expect(newBcode.Message).Should(Equal("new message"))
+		Expect(errors.Is(newBcode, bcode)).Should(BeTrue())
+		Expect(errors.Is(newBcode, ErrServer)).Should(BeFalse())
+	})
+
+	It("Test the bcode with details", func() {
+		bcode := NewBcode(400, 4002, "test")
+		newBcode := bcode.SetDetails([]string{"a", "b"}).SetMessage("new message")
+		Expect(newBcode.Details).Should(Equal([]string{"a", "b"}))
+		Expect(newBcode.Message).Should(Equal("new message"))
+		Expect(bcode.Details).Should(BeNil())
+		Expect(errors.Is(newBcode, bcode)).Should(BeTrue())
+	})
 })
@@ -19,6 +19,7 @@ package utils
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -51,7 +52,8 @@ func (m *memoryCache) GetData() interface{} {
 // MemoryCacheStore a sample memory cache instance, if data set cache duration, will auto clear after timeout.
 // But, Expired cleanup is not necessarily accurate, it has a 3-second window.
 type MemoryCacheStore struct {
-	store sync.Map
+	store     sync.Map
+	evictions atomic.Int64
 }
 
 // NewMemoryCacheStore memory cache store
@@ -74,6 +76,7 @@ func (m *MemoryCacheStore) run(ctx context.Context) {
 			m.store.Range(func(key, value interface{}) bool {
 				if value.(*memoryCache).IsExpired() {
 					m.store.Delete(key)
+					m.evictions.Add(1)
 				}
 				return true
 			})
@@ -92,6 +95,23 @@ func (m *MemoryCacheStore) Delete(key interface{}) {
 	m.store.Delete(key)
 }
 
+// Len return the number of the cached data that is not expired
+func (m *MemoryCacheStore) Len() int {
+	count := 0
+	m.store.Range(func(key, value interface{}) bool {
+		if !value.(*memoryCache).IsExpired() {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// Evictions return the number of the cached data cleared from the store because of the timeout
+func (m *MemoryCacheStore) Evictions() int64 {
+	return m.evictions.Load()
+}
+
 // Get cache data from store, if not exist or timeout, will return nil
 func (m *MemoryCacheStore) Get(key interface{}) (value interface{}) {
 	mc, ok := m.store.Load(key)
@@ -41,6 +41,7 @@ var _ = Describe("Test cache utils", func() {
 		Expect(store.Get("test")).Should(BeNil())
 		Expect(store.Get("test2")).Should(Equal("test data"))
 		Expect(store.Get("test3")).Should(Equal("test data"))
+		Expect(store.Len()).Should(Equal(2))
 	})
 
 	It("test cache store delete key", func() {
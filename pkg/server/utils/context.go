@@ -26,6 +26,7 @@ const (
 	projectKey contextKey = iota
 	usernameKey
 	permissionKey
+	requestIDKey
 )
 
 // WithProject carries project in context
@@ -55,6 +56,17 @@ func WithUserRole(parent context.Context, roles []string) context.Context {
 	return context.WithValue(parent, permissionKey, roles)
 }
 
+// WithRequestID carries the request id in context
+func WithRequestID(parent context.Context, requestID string) context.Context {
+	return context.WithValue(parent, requestIDKey, requestID)
+}
+
+// RequestIDFrom extract the request id from context
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey).(string)
+	return requestID, ok
+}
+
 // UserRoleFrom extract user role from context
 func UserRoleFrom(ctx context.Context) ([]string, bool) {
 	roles, ok := ctx.Value(permissionKey).([]string)
@@ -21,9 +21,17 @@ import (
 	"net"
 	"net/http"
 	"path/filepath"
+	"regexp"
 	"strings"
+
+	"github.com/google/uuid"
 )
 
+// HeaderRequestID the header of the request id, it is returned in the response
+const HeaderRequestID = "X-Request-Id"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
 // ClientIP get client ip
 func ClientIP(r *http.Request) string {
 	xForwardedFor := r.Header.Get("X-Forwarded-For")
@@ -44,6 +52,15 @@ func ClientIP(r *http.Request) string {
 	return ""
 }
 
+// RequestID return the request id from the header, a new one is generated if the header is empty or invalid
+func RequestID(r *http.Request) string {
+	requestID := r.Header.Get(HeaderRequestID)
+	if requestIDPattern.MatchString(requestID) {
+		return requestID
+	}
+	return uuid.New().String()
+}
+
 // ResponseCapture capture response and get response info
 type ResponseCapture struct {
 	http.ResponseWriter
@@ -38,6 +38,20 @@ var _ = Describe("Test http utils", func() {
 		Expect(cmp.Diff(clientIP, "198.23.1.2")).Should(BeEmpty())
 	})
 
+	It("Test get RequestID function", func() {
+		req, err := http.NewRequest("GET", "/xx", nil)
+		Expect(err).Should(BeNil())
+		generated := RequestID(req)
+		Expect(generated).ShouldNot(BeEmpty())
+		Expect(RequestID(req)).ShouldNot(Equal(generated))
+
+		req.Header.Set(HeaderRequestID, "req-1.a_b")
+		Expect(RequestID(req)).Should(Equal("req-1.a_b"))
+
+		req.Header.Set(HeaderRequestID, "bad\nid")
+		Expect(RequestID(req)).ShouldNot(Equal("bad\nid"))
+	})
+
 	It("Test CleanRelativePath", func() {
 		path, err := CleanRelativePath("../module.js?_cache=0.0.1")
 		Expect(err).Should(BeNil())